@@ -4,14 +4,20 @@ import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/models/scripts"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2"
+	"github.com/cloudreve/Cloudreve/v3/pkg/audit"
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/changes"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/crontab"
 	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/leader"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/cloudreve/Cloudreve/v3/pkg/progress"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/traffic"
+	"github.com/cloudreve/Cloudreve/v3/pkg/webhook"
 	"github.com/gin-gonic/gin"
 	"io/fs"
 )
@@ -41,12 +47,42 @@ func Init(path string, statics fs.FS) {
 				cache.Init(conf.SystemConfig.Mode == "slave")
 			},
 		},
+		{
+			"master",
+			func() {
+				mq.Init()
+			},
+		},
+		{
+			"master",
+			func() {
+				progress.Init()
+			},
+		},
+		{
+			"master",
+			func() {
+				changes.Init()
+			},
+		},
 		{
 			"master",
 			func() {
 				model.Init()
 			},
 		},
+		{
+			"master",
+			func() {
+				leader.Init()
+			},
+		},
+		{
+			"master",
+			func() {
+				webhook.Init()
+			},
+		},
 		{
 			"both",
 			func() {
@@ -71,12 +107,24 @@ func Init(path string, statics fs.FS) {
 				email.Init()
 			},
 		},
+		{
+			"master",
+			func() {
+				audit.Init()
+			},
+		},
 		{
 			"master",
 			func() {
 				crontab.Init()
 			},
 		},
+		{
+			"master",
+			func() {
+				traffic.Init()
+			},
+		},
 		{
 			"master",
 			func() {
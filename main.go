@@ -17,6 +17,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/bootstrap"
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/leader"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/cloudreve/Cloudreve/v3/routers"
 
@@ -79,6 +80,11 @@ func main() {
 			defer cancel()
 		}
 
+		// 释放主节点身份，以便其他实例立即接管
+		if leader.Default != nil {
+			leader.Default.Stop()
+		}
+
 		err := server.Shutdown(ctx)
 		if err != nil {
 			util.Log().Error("关闭 server 错误, %s", err)
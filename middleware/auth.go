@@ -4,15 +4,19 @@ import (
 	"bytes"
 	"context"
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/oss"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/upyun"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ldap"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/qiniu/go-sdk/v7/auth/qbox"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
@@ -47,7 +51,7 @@ func SignRequired(authInstance auth.Auth) gin.HandlerFunc {
 	}
 }
 
-// CurrentUser 获取登录用户
+// CurrentUser 获取登录用户，优先使用会话，其次使用 Authorization 头中的访问令牌
 func CurrentUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		session := sessions.Default(c)
@@ -57,6 +61,10 @@ func CurrentUser() gin.HandlerFunc {
 			if err == nil {
 				c.Set("user", &user)
 			}
+		} else if token := accessTokenFromRequest(c); token != "" {
+			if user, ok := userFromAccessToken(c, token); ok {
+				c.Set("user", user)
+			}
 		}
 		c.Next()
 	}
@@ -96,26 +104,57 @@ func WebDAVAuth() gin.HandlerFunc {
 
 		expectedUser, err := model.GetActiveUserByEmail(username)
 		if err != nil {
-			c.Status(http.StatusUnauthorized)
-			c.Abort()
-			return
+			// 目录用户首次登录时本地尚无账号
+			directoryUser, ok := webDAVDirectoryAuth(username, password)
+			if !ok {
+				c.Status(http.StatusUnauthorized)
+				c.Abort()
+				return
+			}
+			expectedUser = *directoryUser
 		}
 
 		// 密码正确？
 		webdav, err := model.GetWebdavByPassword(password, expectedUser.ID)
 		if err != nil {
+			directoryUser, ok := webDAVDirectoryAuth(username, password)
+			if !ok || directoryUser.ID != expectedUser.ID {
+				c.Status(http.StatusUnauthorized)
+				c.Abort()
+				return
+			}
+
+			// 使用目录密码登录时没有对应的 WebDAV 账号，挂载根目录
+			expectedUser = *directoryUser
+			webdav = &model.Webdav{Name: "LDAP", Root: "/", UserID: expectedUser.ID}
+		}
+
+		// 用户组已启用WebDAV？
+		if !expectedUser.Group.WebDAVEnabled {
+			c.Status(http.StatusForbidden)
+			c.Abort()
+			return
+		}
+
+		// 账号已过期？
+		if webdav.Expired() {
 			c.Status(http.StatusUnauthorized)
 			c.Abort()
 			return
 		}
 
-		// 用户组已启用WebDAV？
-		if !expectedUser.Group.WebDAVEnabled {
+		// 来源 IP 是否允许访问
+		if !webdav.AllowsIP(auth.ConnectingIP(c)) {
 			c.Status(http.StatusForbidden)
 			c.Abort()
 			return
 		}
 
+		if webdav.ID > 0 && (webdav.LastUsedAt == nil || time.Since(*webdav.LastUsedAt) > tokenTouchInterval ||
+			webdav.LastUsedClient != c.Request.UserAgent()) {
+			webdav.Touch(auth.ConnectingIP(c), c.Request.UserAgent())
+		}
+
 		c.Set("user", &expectedUser)
 		c.Set("webdav", webdav)
 		c.Next()
@@ -297,3 +336,51 @@ func IsAdmin() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// WritableRequired 拒绝超额使用被限制为只读的用户写入新数据
+func WritableRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user, ok := c.Get("user"); ok {
+			if u, ok := user.(*model.User); ok && u.ReadOnly {
+				c.JSON(200, serializer.Err(serializer.CodeOveruseReadOnly, "", nil))
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// webDAVDirectoryAuth 在开启时使用 LDAP 目录密码认证 WebDAV 请求，成功结果会短暂缓存以免每个请求都访问目录
+func webDAVDirectoryAuth(username, password string) (*model.User, bool) {
+	if !model.IsTrueVal(model.GetSettingByName("ldap_webdav")) {
+		return nil, false
+	}
+
+	sum := sha256.Sum256([]byte(username + ":" + password))
+	key := "ldap_webdav_" + hex.EncodeToString(sum[:])
+	if uid, ok := cache.Get(key); ok {
+		if user, err := model.GetActiveUserByID(uid); err == nil {
+			return &user, true
+		}
+	}
+
+	user, err := ldap.Login(username, password)
+	if err != nil {
+		return nil, false
+	}
+
+	if user.Status != model.Active {
+		return nil, false
+	}
+
+	// 重新读取以加载用户组
+	expectedUser, err := model.GetActiveUserByID(user.ID)
+	if err != nil {
+		return nil, false
+	}
+
+	cache.Set(key, expectedUser.ID, 300)
+	return &expectedUser, true
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit 按客户端 IP 和登录用户对请求计数，超出给定规则的限制后在锁定期内拒绝请求
+func RateLimit(rule string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limiter := ratelimit.New(rule)
+		keys := []string{ratelimit.IPKey(auth.ConnectingIP(c))}
+		if user, ok := c.Get("user"); ok {
+			if u, ok := user.(*model.User); ok && !u.IsAnonymous() {
+				keys = append(keys, ratelimit.UserKey(u.ID))
+			}
+		}
+
+		if wait := limiter.Locked(keys...); wait > 0 {
+			c.JSON(200, ratelimit.LockedErr(wait))
+			c.Abort()
+			return
+		}
+
+		limiter.Hit(keys...)
+		c.Next()
+	}
+}
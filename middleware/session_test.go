@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
@@ -70,4 +71,14 @@ func TestCSRFCheck(t *testing.T) {
 		CSRFCheck()(c)
 		asserts.True(c.IsAborted())
 	}
+
+	// 使用访问令牌
+	{
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		sessionFunc(c)
+		c.Set("access_token", &model.AccessToken{})
+		CSRFCheck()(c)
+		asserts.False(c.IsAborted())
+	}
 }
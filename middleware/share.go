@@ -131,3 +131,27 @@ func BeforeShareDownload() gin.HandlerFunc {
 		c.Abort()
 	}
 }
+
+// InternalShareAccessible 检查当前用户是否为站内共享的接收者
+func InternalShareAccessible() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user *model.User
+		if userCtx, ok := c.Get("user"); ok {
+			user = userCtx.(*model.User)
+		} else {
+			c.JSON(200, serializer.Err(serializer.CodeCheckLogin, "请先登录", nil))
+			c.Abort()
+			return
+		}
+
+		share := model.GetInternalShareByHashID(c.Param("id"))
+		if share == nil || !share.AccessibleBy(user) || !share.IsAvailable() {
+			c.JSON(200, serializer.Err(serializer.CodeInternalShareNotFound, "", nil))
+			c.Abort()
+			return
+		}
+
+		c.Set("internal_share", share)
+		c.Next()
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// scopeResponse 以拥有 scopes 权限的访问令牌请求 route，返回响应中的错误码
+func scopeResponse(method, route, path, scopes string) int {
+	r := gin.New()
+	r.Handle(method, route, func(c *gin.Context) {
+		c.Set("access_token", &model.AccessToken{Scopes: scopes})
+	}, TokenScopeRequired(), func(c *gin.Context) {
+		c.JSON(200, serializer.Response{})
+	})
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	r.ServeHTTP(rec, req)
+
+	var res serializer.Response
+	json.Unmarshal(rec.Body.Bytes(), &res)
+	return res.Code
+}
+
+func TestTokenScopeRequired(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 使用会话登录
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/api/v3/admin/summary", nil)
+		TokenScopeRequired()(c)
+		asserts.False(c.IsAborted())
+	}
+
+	// 分享权限的令牌不能操作站内共享
+	asserts.Equal(serializer.CodeNoPermissionErr, scopeResponse("DELETE", "/api/v3/shared/object/:id", "/api/v3/shared/object/1", model.ScopeShare))
+	asserts.Equal(serializer.CodeNoPermissionErr, scopeResponse("GET", "/api/v3/shared/list/:id/*path", "/api/v3/shared/list/1/", model.ScopeShare))
+
+	// 站内共享需要文件权限
+	asserts.Equal(0, scopeResponse("GET", "/api/v3/shared/list/:id/*path", "/api/v3/shared/list/1/", model.ScopeFilesRead))
+	asserts.Equal(serializer.CodeNoPermissionErr, scopeResponse("DELETE", "/api/v3/shared/object/:id", "/api/v3/shared/object/1", model.ScopeFilesRead))
+	asserts.Equal(0, scopeResponse("DELETE", "/api/v3/shared/object/:id", "/api/v3/shared/object/1", model.ScopeFilesWrite))
+
+	// 分享接口仍使用分享权限
+	asserts.Equal(0, scopeResponse("POST", "/api/v3/share", "/api/v3/share", model.ScopeShare))
+
+	// 同步客户端读取文件变更
+	asserts.Equal(0, scopeResponse("GET", "/api/v3/delta", "/api/v3/delta", model.ScopeFilesRead))
+	asserts.Equal(serializer.CodeNoPermissionErr, scopeResponse("GET", "/api/v3/delta", "/api/v3/delta", model.ScopeShare))
+
+	// 任务进度
+	asserts.Equal(0, scopeResponse("GET", "/api/v3/user/progress", "/api/v3/user/progress", model.ScopeFilesRead))
+
+	// 订阅目录变更
+	asserts.Equal(0, scopeResponse("GET", "/api/v3/user/changes", "/api/v3/user/changes", model.ScopeFilesRead))
+	asserts.Equal(serializer.CodeNoPermissionErr, scopeResponse("GET", "/api/v3/user/changes", "/api/v3/user/changes", model.ScopeOfflineDownload))
+
+	// 未列出的路由不接受访问令牌
+	asserts.Equal(serializer.CodeNoPermissionErr, scopeResponse("POST", "/api/v3/user/redeem", "/api/v3/user/redeem", model.ScopeAdmin))
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+// 操作记录类型
+const (
+	// ActivityCreate 创建文件或目录
+	ActivityCreate = iota
+	// ActivityUpload 上传文件
+	ActivityUpload
+	// ActivityRename 重命名
+	ActivityRename
+	// ActivityMove 移动
+	ActivityMove
+	// ActivityCopy 复制
+	ActivityCopy
+	// ActivityDelete 删除
+	ActivityDelete
+	// ActivityShareCreate 创建分享
+	ActivityShareCreate
+	// ActivityShareDownload 分享被下载
+	ActivityShareDownload
+	// ActivityOfflineDownload 创建离线下载
+	ActivityOfflineDownload
+)
+
+// Activity 用户文件操作记录
+type Activity struct {
+	gorm.Model
+	UserID  uint   `gorm:"index:activity_user_id"` // 被操作对象所属用户
+	ActorID uint   // 操作者UID，0 表示匿名访客
+	Type    int    // 操作类型
+	Client  string // 客户端类型
+	App     string // 客户端应用名称，如 WebDAV 账户名
+	IP      string // 操作者 IP
+	Src     string `gorm:"type:text"` // 对象路径
+	Dst     string `gorm:"type:text"` // 目标路径
+}
+
+// Create 创建操作记录
+func (activity *Activity) Create() (uint, error) {
+	if err := DB.Create(activity).Error; err != nil {
+		util.Log().Warning("无法插入操作记录, %s", err)
+		return 0, err
+	}
+	return activity.ID, nil
+}
+
+// ListActivities 列出用户的操作记录，types 为空时列出所有类型
+func ListActivities(uid uint, page, pageSize int, order string, types []int) ([]Activity, int) {
+	var (
+		activities []Activity
+		total      int
+	)
+	dbChain := DB.Where("user_id = ?", uid)
+	if len(types) > 0 {
+		dbChain = dbChain.Where("type in (?)", types)
+	}
+
+	// 计算总数用于分页
+	dbChain.Model(&Activity{}).Count(&total)
+
+	// 查询记录
+	dbChain.Limit(pageSize).Offset((page - 1) * pageSize).Order(order).Find(&activities)
+
+	return activities, total
+}
+
+// DeleteActivitiesBefore 删除早于给定时间的操作记录
+func DeleteActivitiesBefore(before time.Time) error {
+	return DB.Unscoped().Where("created_at < ?", before).Delete(&Activity{}).Error
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActivity_Create(t *testing.T) {
+	asserts := assert.New(t)
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		activity := Activity{UserID: 1, Src: "/a.txt"}
+		id, err := activity.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(1, id)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		activity := Activity{UserID: 1, Src: "/a.txt"}
+		id, err := activity.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.EqualValues(0, id)
+	}
+}
+
+func TestListActivities(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 不过滤类型
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+		res, total := ListActivities(1, 1, 10, "", nil)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(5, total)
+		asserts.Len(res, 1)
+	}
+
+	// 过滤类型
+	{
+		mock.ExpectQuery("SELECT(.+)type in(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery("SELECT(.+)type in(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+		res, total := ListActivities(1, 1, 10, "", []int{ActivityDelete})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(1, total)
+		asserts.Len(res, 1)
+	}
+}
+
+func TestDeleteActivitiesBefore(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	asserts.NoError(DeleteActivitiesBefore(time.Now()))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
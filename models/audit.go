@@ -0,0 +1,40 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+// ErrAuditLogImmutable 审计日志只允许追加
+var ErrAuditLogImmutable = errors.New("audit log is append-only")
+
+// AuditLog 管理员操作审计日志
+type AuditLog struct {
+	gorm.Model
+	AdminID uint   `gorm:"index:audit_admin_id"` // 操作管理员UID
+	Action  string `gorm:"index:audit_action"`   // 操作类型
+	Targets string `gorm:"type:text"`            // 操作对象ID列表，JSON 数组
+	Diff    string `gorm:"type:text"`            // 变更前后差异，JSON 对象
+	IP      string // 操作者 IP
+}
+
+// Create 创建审计日志
+func (log *AuditLog) Create() (uint, error) {
+	if err := DB.Create(log).Error; err != nil {
+		util.Log().Warning("无法插入审计日志, %s", err)
+		return 0, err
+	}
+	return log.ID, nil
+}
+
+// BeforeUpdate 禁止修改已有审计日志
+func (log *AuditLog) BeforeUpdate() error {
+	return ErrAuditLogImmutable
+}
+
+// BeforeDelete 禁止删除审计日志
+func (log *AuditLog) BeforeDelete() error {
+	return ErrAuditLogImmutable
+}
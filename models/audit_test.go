@@ -0,0 +1,60 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLog_Create(t *testing.T) {
+	asserts := assert.New(t)
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		log := AuditLog{AdminID: 1, Action: "user.ban", Targets: "[2]"}
+		id, err := log.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(1, id)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		log := AuditLog{AdminID: 1, Action: "user.ban", Targets: "[2]"}
+		id, err := log.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.EqualValues(0, id)
+	}
+}
+
+func TestAuditLog_Immutable(t *testing.T) {
+	asserts := assert.New(t)
+	log := AuditLog{AdminID: 1, Action: "user.ban"}
+	log.ID = 1
+
+	// 修改
+	{
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+		err := DB.Model(&log).Update("action", "user.unban").Error
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(ErrAuditLogImmutable, err)
+	}
+
+	// 删除
+	{
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+		err := DB.Delete(&log).Error
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(ErrAuditLogImmutable, err)
+	}
+}
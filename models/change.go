@@ -0,0 +1,129 @@
+package model
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 变更日志类型
+const (
+	// ChangeCreate 新建文件或目录
+	ChangeCreate = "create"
+	// ChangeModify 修改文件内容
+	ChangeModify = "modify"
+	// ChangeDelete 删除文件或目录，目录被删除时其下所有对象一并删除
+	ChangeDelete = "delete"
+	// ChangeMove 移动或重命名文件或目录，目录被移动时其下所有对象随之移动
+	ChangeMove = "move"
+)
+
+// Change 用户文件系统变更日志，自增的 ID 即为增量同步使用的游标
+type Change struct {
+	ID        uint      `gorm:"primary_key"`
+	UserID    uint      `gorm:"index:change_user_id"`
+	Action    string    `gorm:"size:16"`
+	IsDir     bool      // 对象是否为目录
+	ObjectID  uint      // 文件或目录ID
+	ParentID  uint      // 变更后所在目录ID
+	Path      string    `gorm:"type:text"` // 变更后的完整路径
+	OldPath   string    `gorm:"type:text"` // 移动前的完整路径
+	Size      uint64    // 文件大小
+	Hash      string    `gorm:"size:40"` // 文件内容标识，内容变化时随之改变
+	CreatedAt time.Time `gorm:"index:change_created_at"`
+}
+
+// NewFileChange 构建文件变更记录，dir 为文件所在目录的完整路径
+func NewFileChange(action string, file *File, dir string) Change {
+	return Change{
+		UserID:   file.UserID,
+		Action:   action,
+		ObjectID: file.ID,
+		ParentID: file.FolderID,
+		Path:     path.Join(dir, file.Name),
+		Size:     file.Size,
+		Hash:     file.ContentHash(),
+	}
+}
+
+// NewFolderChange 构建目录变更记录，dir 为目录所在父目录的完整路径
+func NewFolderChange(action string, folder *Folder, dir string) Change {
+	change := Change{
+		UserID:   folder.OwnerID,
+		Action:   action,
+		IsDir:    true,
+		ObjectID: folder.ID,
+		Path:     path.Join(dir, folder.Name),
+	}
+	if folder.ParentID != nil {
+		change.ParentID = *folder.ParentID
+	}
+
+	return change
+}
+
+// ContentHash 返回文件内容标识。并非所有存储策略都能取得文件内容的摘要，
+// 此处由物理存储路径、大小与修改时间计算，文件被覆盖或更新时随之改变
+func (file *File) ContentHash() string {
+	h := sha1.New()
+	h.Write([]byte(fmt.Sprintf("%s-%d-%d", file.SourceName, file.Size, file.UpdatedAt.UnixNano())))
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// RecordChanges 写入变更日志
+func RecordChanges(changes []Change) error {
+	tx := DB.Begin()
+	if err := RecordChangesTransaction(changes, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// RecordChangesTransaction 在事务中写入变更日志，随事务一同提交或回滚
+func RecordChangesTransaction(changes []Change, tx *gorm.DB) error {
+	for i := range changes {
+		if err := tx.Create(&changes[i]).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListChanges 列出用户在游标之后、早于 before 写入的变更，按游标升序排列
+func ListChanges(uid, cursor uint, before time.Time, limit int) ([]Change, error) {
+	var changes []Change
+	err := DB.Where("user_id = ? and id > ? and created_at < ?", uid, cursor, before).
+		Order("id asc").Limit(limit).Find(&changes).Error
+	return changes, err
+}
+
+// GetLatestChangeCursor 返回最新的游标，没有任何变更时返回 0
+func GetLatestChangeCursor() uint {
+	var change Change
+	DB.Select("id").Order("id desc").First(&change)
+	return change.ID
+}
+
+// GetEarliestChangeCursor 返回仍保留的最早游标，没有任何变更时返回 0
+func GetEarliestChangeCursor() uint {
+	var change Change
+	DB.Select("id").Order("id asc").First(&change)
+	return change.ID
+}
+
+// DeleteChangesBefore 清理早于给定时间的变更日志。最新的一条始终保留，
+// 以便根据最早的游标判断客户端游标之后的日志是否已被清理
+func DeleteChangesBefore(before time.Time) error {
+	latest := GetLatestChangeCursor()
+	if latest == 0 {
+		return nil
+	}
+
+	return DB.Where("created_at < ? and id < ?", before, latest).Delete(&Change{}).Error
+}
@@ -0,0 +1,134 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFileChange(t *testing.T) {
+	asserts := assert.New(t)
+	file := &File{Name: "a.txt", UserID: 1, FolderID: 2, Size: 10, SourceName: "1/a.txt"}
+	file.ID = 3
+
+	change := NewFileChange(ChangeCreate, file, "/dir")
+	asserts.Equal("/dir/a.txt", change.Path)
+	asserts.EqualValues(1, change.UserID)
+	asserts.EqualValues(2, change.ParentID)
+	asserts.EqualValues(3, change.ObjectID)
+	asserts.False(change.IsDir)
+	asserts.Len(change.Hash, 40)
+
+	// 内容变化后标识随之改变
+	file.Size = 11
+	asserts.NotEqual(change.Hash, file.ContentHash())
+}
+
+func TestNewFolderChange(t *testing.T) {
+	asserts := assert.New(t)
+	parent := uint(2)
+
+	// 根目录
+	{
+		change := NewFolderChange(ChangeCreate, &Folder{Name: "/", OwnerID: 1}, "")
+		asserts.Equal("/", change.Path)
+		asserts.EqualValues(0, change.ParentID)
+		asserts.True(change.IsDir)
+	}
+
+	// 子目录
+	{
+		change := NewFolderChange(ChangeDelete, &Folder{Name: "sub", OwnerID: 1, ParentID: &parent}, "/dir")
+		asserts.Equal("/dir/sub", change.Path)
+		asserts.EqualValues(2, change.ParentID)
+		asserts.Equal(ChangeDelete, change.Action)
+	}
+}
+
+func TestRecordChanges(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+		changes := []Change{{UserID: 1}, {UserID: 1}}
+		asserts.NoError(RecordChanges(changes))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(1, changes[0].ID)
+		asserts.EqualValues(2, changes[1].ID)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(RecordChanges([]Change{{UserID: 1}}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestListChanges(t *testing.T) {
+	asserts := assert.New(t)
+	before := time.Now()
+	mock.ExpectQuery("SELECT(.+)changes(.+)user_id(.+)id >(.+)created_at <(.+)").
+		WithArgs(1, 5, before).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6).AddRow(7))
+	res, err := ListChanges(1, 5, before, 10)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(res, 2)
+}
+
+func TestGetChangeCursor(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 最新游标
+	{
+		mock.ExpectQuery("SELECT(.+)desc(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+		asserts.EqualValues(10, GetLatestChangeCursor())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 最早游标
+	{
+		mock.ExpectQuery("SELECT(.+)asc(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		asserts.EqualValues(3, GetEarliestChangeCursor())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 没有变更
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		asserts.EqualValues(0, GetLatestChangeCursor())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestDeleteChangesBefore(t *testing.T) {
+	asserts := assert.New(t)
+	before := time.Now()
+
+	// 没有变更
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		asserts.NoError(DeleteChangesBefore(before))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 保留最新的一条
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)changes(.+)").WithArgs(before, 10).WillReturnResult(sqlmock.NewResult(0, 9))
+		mock.ExpectCommit()
+		asserts.NoError(DeleteChangesBefore(before))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
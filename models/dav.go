@@ -0,0 +1,245 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// CalDAV / CardDAV 集合类型
+const (
+	// DavCalendar 日历
+	DavCalendar = "calendar"
+	// DavAddressBook 通讯录
+	DavAddressBook = "addressbook"
+)
+
+// DavCollection 用户的日历或通讯录
+type DavCollection struct {
+	ID          uint   `gorm:"primary_key"`
+	UserID      uint   `gorm:"unique_index:dav_collection_uri"`
+	Type        string `gorm:"size:16;unique_index:dav_collection_uri"`
+	URI         string `gorm:"size:255;unique_index:dav_collection_uri"` // 集合在 URL 中的名称
+	DisplayName string
+	Description string `gorm:"type:text"`
+	Color       string `gorm:"size:16"`
+	Components  string // 日历支持的组件，以逗号分隔
+	Revision    uint64 // 集合内对象每次变更后递增，用作 ctag 及同步令牌
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// DavObject 日历中的日程、待办或通讯录中的联系人
+type DavObject struct {
+	ID           uint       `gorm:"primary_key"`
+	CollectionID uint       `gorm:"unique_index:dav_object_uri;index:dav_object_revision"`
+	URI          string     `gorm:"size:255;unique_index:dav_object_uri"` // 对象在集合中的名称
+	UID          string     `gorm:"size:255;index:dav_object_uid"`
+	Component    string     `gorm:"size:16"` // VEVENT、VTODO、VJOURNAL 或 VCARD
+	Data         string     `gorm:"type:text"`
+	Etag         string     `gorm:"size:64"`
+	StartAt      *time.Time // 起始时间，为空表示未知
+	EndAt        *time.Time // 结束时间，为空表示无结束时间（如无限重复的日程）
+	Revision     uint64     `gorm:"index:dav_object_revision"` // 最后一次变更时集合的版本
+	Deleted      bool       // 已删除的对象保留记录，供同步客户端获知删除
+	UpdatedAt    time.Time
+}
+
+// Create 创建集合
+func (collection *DavCollection) Create() error {
+	return DB.Create(collection).Error
+}
+
+// Update 更新集合属性
+func (collection *DavCollection) Update(val map[string]interface{}) error {
+	return DB.Model(collection).Updates(val).Error
+}
+
+// Delete 删除集合及其中全部对象
+func (collection *DavCollection) Delete() error {
+	tx := DB.Begin()
+	if err := tx.Where("collection_id = ?", collection.ID).Delete(&DavObject{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Delete(collection).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// nextRevision 在事务中递增集合版本并返回新版本
+func (collection *DavCollection) nextRevision(tx *gorm.DB) (uint64, error) {
+	if err := tx.Model(&DavCollection{}).Where("id = ?", collection.ID).
+		UpdateColumn("revision", gorm.Expr("revision + ?", 1)).Error; err != nil {
+		return 0, err
+	}
+
+	var res DavCollection
+	if err := tx.Select("revision").Where("id = ?", collection.ID).First(&res).Error; err != nil {
+		return 0, err
+	}
+
+	collection.Revision = res.Revision
+	return res.Revision, nil
+}
+
+// PutObject 在集合中创建或更新对象，以 URI 区分
+func (collection *DavCollection) PutObject(object *DavObject) error {
+	tx := DB.Begin()
+	revision, err := collection.nextRevision(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	object.CollectionID = collection.ID
+	object.Revision = revision
+	object.Deleted = false
+
+	var existing DavObject
+	err = tx.Where("collection_id = ? and uri = ?", collection.ID, object.URI).First(&existing).Error
+	if err == nil {
+		object.ID = existing.ID
+		err = tx.Save(object).Error
+	} else if gorm.IsRecordNotFoundError(err) {
+		err = tx.Create(object).Error
+	}
+
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// DeleteObject 删除集合中的对象，仅保留删除记录
+func (collection *DavCollection) DeleteObject(object *DavObject) error {
+	tx := DB.Begin()
+	revision, err := collection.nextRevision(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(object).UpdateColumns(map[string]interface{}{
+		"deleted":  true,
+		"data":     "",
+		"revision": revision,
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// GetDavCollections 列出用户给定类型的集合
+func GetDavCollections(uid uint, collectionType string) ([]DavCollection, error) {
+	var collections []DavCollection
+	err := DB.Where("user_id = ? and type = ?", uid, collectionType).Order("id").Find(&collections).Error
+	return collections, err
+}
+
+// GetDavCollection 根据 URI 查找用户的集合
+func GetDavCollection(uid uint, collectionType, uri string) (*DavCollection, error) {
+	var collection DavCollection
+	err := DB.Where("user_id = ? and type = ? and uri = ?", uid, collectionType, uri).First(&collection).Error
+	return &collection, err
+}
+
+// EnsureDefaultDavCollection 用户没有给定类型的集合时创建默认集合
+func EnsureDefaultDavCollection(uid uint, collectionType, uri, name, components string) error {
+	var count int
+	if err := DB.Model(&DavCollection{}).Where("user_id = ? and type = ?", uid, collectionType).
+		Count(&count).Error; err != nil || count > 0 {
+		return err
+	}
+
+	collection := &DavCollection{
+		UserID:      uid,
+		Type:        collectionType,
+		URI:         uri,
+		DisplayName: name,
+		Components:  components,
+	}
+	return collection.Create()
+}
+
+// DeleteDavCollectionsByUser 删除用户的全部集合及其中的对象
+func DeleteDavCollectionsByUser(uid uint) error {
+	var ids []uint
+	if err := DB.Model(&DavCollection{}).Where("user_id = ?", uid).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	if len(ids) > 0 {
+		if err := DB.Where("collection_id in (?)", ids).Delete(&DavObject{}).Error; err != nil {
+			return err
+		}
+	}
+
+	return DB.Where("user_id = ?", uid).Delete(&DavCollection{}).Error
+}
+
+// GetDavObject 根据 URI 查找集合中未删除的对象
+func GetDavObject(collectionID uint, uri string) (*DavObject, error) {
+	var object DavObject
+	err := DB.Where("collection_id = ? and uri = ? and deleted = ?", collectionID, uri, false).First(&object).Error
+	return &object, err
+}
+
+// GetDavObjectByUID 根据 UID 查找集合中未删除的对象
+func GetDavObjectByUID(collectionID uint, uid string) (*DavObject, error) {
+	var object DavObject
+	err := DB.Where("collection_id = ? and uid = ? and deleted = ?", collectionID, uid, false).First(&object).Error
+	return &object, err
+}
+
+// GetDavObjects 列出集合中未删除的对象
+func GetDavObjects(collectionID uint) ([]DavObject, error) {
+	var objects []DavObject
+	err := DB.Where("collection_id = ? and deleted = ?", collectionID, false).Order("id").Find(&objects).Error
+	return objects, err
+}
+
+// GetDavObjectsByURIs 根据 URI 列出集合中未删除的对象
+func GetDavObjectsByURIs(collectionID uint, uris []string) ([]DavObject, error) {
+	var objects []DavObject
+	if len(uris) == 0 {
+		return objects, nil
+	}
+
+	err := DB.Where("collection_id = ? and uri in (?) and deleted = ?", collectionID, uris, false).Find(&objects).Error
+	return objects, err
+}
+
+// GetDavObjectsInRange 列出集合中给定组件类型且可能与时间范围重叠的对象，start 及 end 为空表示不限制
+func GetDavObjectsInRange(collectionID uint, component string, start, end *time.Time) ([]DavObject, error) {
+	var objects []DavObject
+	query := DB.Where("collection_id = ? and deleted = ?", collectionID, false)
+	if component != "" {
+		query = query.Where("component = ?", component)
+	}
+	if end != nil {
+		query = query.Where("start_at is null or start_at < ?", *end)
+	}
+	if start != nil {
+		// 无持续时间的日程起止时间相同，起始于范围开始时刻时同样视为重叠
+		query = query.Where("end_at is null or end_at > ? or start_at >= ?", *start, *start)
+	}
+
+	err := query.Order("id").Find(&objects).Error
+	return objects, err
+}
+
+// GetDavObjectsChangedSince 列出集合中在给定版本之后变更的对象，包括已删除的对象
+func GetDavObjectsChangedSince(collectionID uint, revision uint64) ([]DavObject, error) {
+	var objects []DavObject
+	err := DB.Where("collection_id = ? and revision > ?", collectionID, revision).Order("revision").Find(&objects).Error
+	return objects, err
+}
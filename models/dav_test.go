@@ -0,0 +1,331 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDavCollection_Create(t *testing.T) {
+	asserts := assert.New(t)
+	collection := DavCollection{UserID: 1, Type: DavCalendar, URI: "default"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT(.+)dav_collections(.+)").WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectCommit()
+	asserts.NoError(collection.Create())
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(5, collection.ID)
+}
+
+func TestDavCollection_Update(t *testing.T) {
+	asserts := assert.New(t)
+	collection := DavCollection{ID: 1}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)dav_collections(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(collection.Update(map[string]interface{}{"display_name": "work"}))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestDavCollection_Delete(t *testing.T) {
+	asserts := assert.New(t)
+	collection := DavCollection{ID: 1}
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)dav_objects(.+)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec("DELETE(.+)dav_collections(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(collection.Delete())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 删除对象失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)dav_objects(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(collection.Delete())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 删除集合失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)dav_objects(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE(.+)dav_collections(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(collection.Delete())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestDavCollection_PutObject(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 新建对象
+	{
+		collection := DavCollection{ID: 1, Revision: 2}
+		object := DavObject{URI: "a.ics", Deleted: true}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)dav_collections(.+)revision(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT(.+)revision(.+)dav_collections(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(3))
+		mock.ExpectQuery("SELECT(.+)dav_objects(.+)").WithArgs(1, "a.ics").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec("INSERT(.+)dav_objects(.+)").WillReturnResult(sqlmock.NewResult(10, 1))
+		mock.ExpectCommit()
+		asserts.NoError(collection.PutObject(&object))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(3, collection.Revision)
+		asserts.EqualValues(3, object.Revision)
+		asserts.EqualValues(1, object.CollectionID)
+		asserts.EqualValues(10, object.ID)
+		asserts.False(object.Deleted)
+	}
+
+	// 更新已有对象
+	{
+		collection := DavCollection{ID: 1, Revision: 3}
+		object := DavObject{URI: "a.ics"}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)dav_collections(.+)revision(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT(.+)revision(.+)dav_collections(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(4))
+		mock.ExpectQuery("SELECT(.+)dav_objects(.+)").WithArgs(1, "a.ics").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+		mock.ExpectExec("UPDATE(.+)dav_objects(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(collection.PutObject(&object))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(10, object.ID)
+		asserts.EqualValues(4, object.Revision)
+	}
+
+	// 递增版本失败
+	{
+		collection := DavCollection{ID: 1}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)dav_collections(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(collection.PutObject(&DavObject{URI: "a.ics"}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 写入失败
+	{
+		collection := DavCollection{ID: 1}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)dav_collections(.+)revision(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT(.+)revision(.+)dav_collections(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(1))
+		mock.ExpectQuery("SELECT(.+)dav_objects(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec("INSERT(.+)dav_objects(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(collection.PutObject(&DavObject{URI: "a.ics"}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestDavCollection_DeleteObject(t *testing.T) {
+	asserts := assert.New(t)
+	collection := DavCollection{ID: 1, Revision: 4}
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)dav_collections(.+)revision(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT(.+)revision(.+)dav_collections(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(5))
+		mock.ExpectExec("UPDATE(.+)dav_objects(.+)deleted(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(collection.DeleteObject(&DavObject{ID: 10}))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(5, collection.Revision)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)dav_collections(.+)revision(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT(.+)revision(.+)dav_collections(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(6))
+		mock.ExpectExec("UPDATE(.+)dav_objects(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(collection.DeleteObject(&DavObject{ID: 10}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetDavCollection(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)dav_collections(.+)").WithArgs(1, DavCalendar, "default").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uri"}).AddRow(2, "default"))
+	collection, err := GetDavCollection(1, DavCalendar, "default")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(2, collection.ID)
+
+	mock.ExpectQuery("SELECT(.+)dav_collections(.+)").WithArgs(1, DavAddressBook).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(4))
+	collections, err := GetDavCollections(1, DavAddressBook)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(collections, 2)
+}
+
+func TestEnsureDefaultDavCollection(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 已有集合
+	{
+		mock.ExpectQuery("SELECT count(.+)dav_collections(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		asserts.NoError(EnsureDefaultDavCollection(1, DavCalendar, "default", "Calendar", "VEVENT"))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 创建默认集合
+	{
+		mock.ExpectQuery("SELECT count(.+)dav_collections(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)dav_collections(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(EnsureDefaultDavCollection(1, DavCalendar, "default", "Calendar", "VEVENT"))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 查询失败
+	{
+		mock.ExpectQuery("SELECT count(.+)dav_collections(.+)").WillReturnError(errors.New("error"))
+		asserts.Error(EnsureDefaultDavCollection(1, DavCalendar, "default", "Calendar", "VEVENT"))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestDeleteDavCollectionsByUser(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 存在集合
+	{
+		mock.ExpectQuery("SELECT(.+)dav_collections(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3))
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)dav_objects(.+)").WithArgs(2, 3).WillReturnResult(sqlmock.NewResult(0, 5))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)dav_collections(.+)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+		asserts.NoError(DeleteDavCollectionsByUser(1))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 不存在集合
+	{
+		mock.ExpectQuery("SELECT(.+)dav_collections(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)dav_collections(.+)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		asserts.NoError(DeleteDavCollectionsByUser(1))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetDavObjects(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 根据 URI 查找
+	{
+		mock.ExpectQuery("SELECT(.+)dav_objects(.+)").WithArgs(1, "a.ics", false).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uri"}).AddRow(10, "a.ics"))
+		object, err := GetDavObject(1, "a.ics")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(10, object.ID)
+	}
+
+	// 根据 UID 查找
+	{
+		mock.ExpectQuery("SELECT(.+)dav_objects(.+)").WithArgs(1, "uid", false).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		_, err := GetDavObjectByUID(1, "uid")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+
+	// 列出全部
+	{
+		mock.ExpectQuery("SELECT(.+)dav_objects(.+)").WithArgs(1, false).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10).AddRow(11))
+		objects, err := GetDavObjects(1)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(objects, 2)
+	}
+
+	// 根据 URI 列表查找，列表为空
+	{
+		objects, err := GetDavObjectsByURIs(1, nil)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(objects, 0)
+	}
+
+	// 根据 URI 列表查找
+	{
+		mock.ExpectQuery("SELECT(.+)dav_objects(.+)").WithArgs(1, "a.ics", "b.ics", false).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+		objects, err := GetDavObjectsByURIs(1, []string{"a.ics", "b.ics"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(objects, 1)
+	}
+}
+
+func TestGetDavObjectsInRange(t *testing.T) {
+	asserts := assert.New(t)
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	// 不限制
+	{
+		mock.ExpectQuery("SELECT(.+)dav_objects(.+)").WithArgs(1, false).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+		objects, err := GetDavObjectsInRange(1, "", nil, nil)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(objects, 1)
+	}
+
+	// 限制组件及时间范围
+	{
+		mock.ExpectQuery("SELECT(.+)dav_objects(.+)component(.+)start_at(.+)end_at(.+)").
+			WithArgs(1, false, "VEVENT", end, start, start).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		objects, err := GetDavObjectsInRange(1, "VEVENT", &start, &end)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(objects, 0)
+	}
+}
+
+func TestGetDavObjectsChangedSince(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)dav_objects(.+)revision(.+)").WithArgs(1, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "revision", "deleted"}).AddRow(10, 4, false).AddRow(11, 5, true))
+	objects, err := GetDavObjectsChangedSince(1, 3)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(objects, 2)
+	asserts.True(objects[1].Deleted)
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// DeadProperty WebDAV 客户端通过 PROPPATCH 设定的自定义属性，以对象ID关联，移动或重命名后保留
+type DeadProperty struct {
+	ID        uint   `gorm:"primary_key"`
+	UserID    uint   `gorm:"index:dead_property_user_id"`
+	IsDir     bool   `gorm:"index:dead_property_object"`
+	ObjectID  uint   `gorm:"index:dead_property_object"`
+	Namespace string `gorm:"size:255"` // 属性的 XML 命名空间
+	Name      string `gorm:"size:255"`
+	Lang      string `gorm:"size:32"`
+	InnerXML  string `gorm:"type:text"`
+}
+
+// DeadPropertyPatch 对单个自定义属性的修改，Remove 为 true 时删除属性
+type DeadPropertyPatch struct {
+	Remove   bool
+	Property DeadProperty
+}
+
+// GetDeadProperties 列出对象的自定义属性
+func GetDeadProperties(isDir bool, id uint) ([]DeadProperty, error) {
+	var props []DeadProperty
+	err := DB.Where("is_dir = ? and object_id = ?", isDir, id).Find(&props).Error
+	return props, err
+}
+
+// PatchDeadProperties 依次应用对对象自定义属性的修改，所有修改在同一事务中生效
+func PatchDeadProperties(uid uint, isDir bool, id uint, patches []DeadPropertyPatch) error {
+	tx := DB.Begin()
+	for _, patch := range patches {
+		prop := patch.Property
+		if err := tx.Where("is_dir = ? and object_id = ? and namespace = ? and name = ?", isDir, id, prop.Namespace, prop.Name).
+			Delete(&DeadProperty{}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if patch.Remove {
+			continue
+		}
+
+		prop.ID, prop.UserID, prop.IsDir, prop.ObjectID = 0, uid, isDir, id
+		if err := tx.Create(&prop).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// CopyDeadProperties 将自定义属性复制给复制出的对象，ids 以源对象ID为键、新对象ID为值
+func CopyDeadProperties(uid uint, isDir bool, ids map[uint]uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	src := make([]uint, 0, len(ids))
+	for id := range ids {
+		src = append(src, id)
+	}
+
+	var props []DeadProperty
+	if err := DB.Where("is_dir = ? and object_id in (?)", isDir, src).Find(&props).Error; err != nil {
+		return err
+	}
+	if len(props) == 0 {
+		return nil
+	}
+
+	tx := DB.Begin()
+	for _, prop := range props {
+		prop.ID, prop.UserID, prop.IsDir, prop.ObjectID = 0, uid, isDir, ids[prop.ObjectID]
+		if err := tx.Create(&prop).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// DeleteDeadPropertiesTransaction 删除对象的全部自定义属性
+func DeleteDeadPropertiesTransaction(isDir bool, ids []uint, tx *gorm.DB) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return tx.Where("is_dir = ? and object_id in (?)", isDir, ids).Delete(&DeadProperty{}).Error
+}
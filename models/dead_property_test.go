@@ -0,0 +1,132 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDeadProperties(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)dead_properties(.+)is_dir(.+)object_id(.+)").
+		WithArgs(true, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"namespace", "name"}).AddRow("urn:test", "a").AddRow("urn:test", "b"))
+	props, err := GetDeadProperties(true, 1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(props, 2)
+	asserts.Equal("urn:test", props[0].Namespace)
+}
+
+func TestPatchDeadProperties(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 设定及删除属性
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)dead_properties(.+)").
+			WithArgs(false, 1, "urn:test", "a").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT(.+)dead_properties(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("DELETE(.+)dead_properties(.+)").
+			WithArgs(false, 1, "urn:test", "b").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		err := PatchDeadProperties(2, false, 1, []DeadPropertyPatch{
+			{Property: DeadProperty{Namespace: "urn:test", Name: "a", InnerXML: "value"}},
+			{Remove: true, Property: DeadProperty{Namespace: "urn:test", Name: "b"}},
+		})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+	}
+
+	// 删除失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)dead_properties(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		err := PatchDeadProperties(2, false, 1, []DeadPropertyPatch{
+			{Property: DeadProperty{Namespace: "urn:test", Name: "a"}},
+		})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+
+	// 插入失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)dead_properties(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT(.+)dead_properties(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		err := PatchDeadProperties(2, false, 1, []DeadPropertyPatch{
+			{Property: DeadProperty{Namespace: "urn:test", Name: "a"}},
+		})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+}
+
+func TestCopyDeadProperties(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 空列表
+	{
+		asserts.NoError(CopyDeadProperties(1, false, map[uint]uint{}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 源对象没有自定义属性
+	{
+		mock.ExpectQuery("SELECT(.+)dead_properties(.+)").WithArgs(false, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"object_id"}))
+		asserts.NoError(CopyDeadProperties(1, false, map[uint]uint{1: 2}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)dead_properties(.+)").WithArgs(true, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "object_id", "namespace", "name"}).
+				AddRow(5, 1, "urn:test", "a"))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)dead_properties(.+)").
+			WithArgs(1, true, 2, "urn:test", "a", "", "").
+			WillReturnResult(sqlmock.NewResult(6, 1))
+		mock.ExpectCommit()
+		asserts.NoError(CopyDeadProperties(1, true, map[uint]uint{1: 2}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 失败
+	{
+		mock.ExpectQuery("SELECT(.+)dead_properties(.+)").WithArgs(true, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "object_id"}).AddRow(5, 1))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)dead_properties(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(CopyDeadProperties(1, true, map[uint]uint{1: 2}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestDeleteDeadPropertiesTransaction(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 空列表
+	{
+		asserts.NoError(DeleteDeadPropertiesTransaction(true, []uint{}, DB))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)dead_properties(.+)").WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+		asserts.NoError(DeleteDeadPropertiesTransaction(true, []uint{1, 2}, DB))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -101,6 +101,13 @@ func GetDownloadByGid(gid string, uid uint) (*Download, error) {
 	return download, result.Error
 }
 
+// GetDownloadByID 根据ID查找下载
+func GetDownloadByID(id uint) (*Download, error) {
+	download := &Download{}
+	result := DB.Where("id = ?", id).First(download)
+	return download, result.Error
+}
+
 // GetOwner 获取下载任务所属用户
 func (task *Download) GetOwner() *User {
 	if task.User == nil {
@@ -120,6 +120,16 @@ func TestGetDownloadByGid(t *testing.T) {
 	asserts.Equal(res.GID, "1")
 }
 
+func TestGetDownloadByID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id", "g_id"}).AddRow(2, "gid"))
+	res, err := GetDownloadByID(2)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Equal("gid", res.GID)
+}
+
 func TestDownload_GetOwner(t *testing.T) {
 	asserts := assert.New(t)
 
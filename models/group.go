@@ -33,6 +33,7 @@ type GroupOption struct {
 	Aria2Options    map[string]interface{} `json:"aria2_options,omitempty"` // 离线下载用户组配置
 	SourceBatchSize int                    `json:"source_batch,omitempty"`
 	Aria2BatchSize  int                    `json:"aria2_batch,omitempty"`
+	TrafficQuota    uint64                 `json:"traffic_quota,omitempty"` // 每月下载流量，0 为不限制
 }
 
 // GetGroupByID 用ID获取用户组
@@ -0,0 +1,120 @@
+package model
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/jinzhu/gorm"
+)
+
+// InternalShare 站内共享，将目录授权给站内其他用户或整个用户组访问
+type InternalShare struct {
+	gorm.Model
+	UserID        uint `gorm:"index:internal_share_owner"` // 共享者ID
+	FolderID      uint // 被共享的目录ID
+	TargetUserID  uint `gorm:"index:internal_share_user"`  // 接收共享的用户ID，为 0 时共享给用户组
+	TargetGroupID uint `gorm:"index:internal_share_group"` // 接收共享的用户组ID
+	Writable      bool // 接收者是否可写入
+
+	// 数据库忽略字段
+	User   User   `gorm:"PRELOAD:false,association_autoupdate:false"`
+	Folder Folder `gorm:"PRELOAD:false,association_autoupdate:false"`
+}
+
+// Create 创建站内共享
+func (share *InternalShare) Create() (uint, error) {
+	if err := DB.Create(share).Error; err != nil {
+		return 0, err
+	}
+	return share.ID, nil
+}
+
+// Update 更新站内共享属性
+func (share *InternalShare) Update(props map[string]interface{}) error {
+	return DB.Model(share).Updates(props).Error
+}
+
+// Delete 删除站内共享
+func (share *InternalShare) Delete() error {
+	return DB.Delete(share).Error
+}
+
+// Creator 获取共享者
+func (share *InternalShare) Creator() *User {
+	if share.User.ID == 0 {
+		share.User, _ = GetUserByID(share.UserID)
+	}
+	return &share.User
+}
+
+// SourceFolder 获取被共享的目录
+func (share *InternalShare) SourceFolder() *Folder {
+	if share.Folder.ID == 0 {
+		folders, _ := GetFoldersByIDs([]uint{share.FolderID}, share.UserID)
+		if len(folders) > 0 {
+			share.Folder = folders[0]
+		}
+	}
+	return &share.Folder
+}
+
+// IsAvailable 返回共享者状态正常且目录仍然存在
+func (share *InternalShare) IsAvailable() bool {
+	return share.Creator().Status == Active && share.SourceFolder().ID != 0
+}
+
+// AccessibleBy 返回给定用户是否为此共享的接收者
+func (share *InternalShare) AccessibleBy(user *User) bool {
+	if user.ID == 0 || user.ID == share.UserID {
+		return false
+	}
+
+	if share.TargetUserID != 0 {
+		return share.TargetUserID == user.ID
+	}
+
+	return share.TargetGroupID == user.GroupID
+}
+
+// GetInternalShareByID 根据ID查找站内共享
+func GetInternalShareByID(id uint) (*InternalShare, error) {
+	share := &InternalShare{}
+	err := DB.First(share, id).Error
+	return share, err
+}
+
+// GetInternalShareByHashID 根据HashID查找站内共享
+func GetInternalShareByHashID(hashID string) *InternalShare {
+	id, err := hashid.DecodeHashID(hashID, hashid.InternalShareID)
+	if err != nil {
+		return nil
+	}
+
+	share, err := GetInternalShareByID(id)
+	if err != nil {
+		return nil
+	}
+
+	return share
+}
+
+// GetInternalShare 查找目录对给定用户或用户组的共享
+func GetInternalShare(folderID, targetUserID, targetGroupID uint) (*InternalShare, error) {
+	share := &InternalShare{}
+	err := DB.Where("folder_id = ? and target_user_id = ? and target_group_id = ?",
+		folderID, targetUserID, targetGroupID).First(share).Error
+	return share, err
+}
+
+// ListInternalShares 列出用户创建的站内共享
+func ListInternalShares(uid uint) []InternalShare {
+	var shares []InternalShare
+	DB.Where("user_id = ?", uid).Order("created_at desc").Find(&shares)
+	return shares
+}
+
+// ListReceivedInternalShares 列出共享给用户本人或其所在用户组的站内共享
+func ListReceivedInternalShares(user *User) []InternalShare {
+	var shares []InternalShare
+	DB.Where("user_id <> ? and (target_user_id = ? or (target_user_id = 0 and target_group_id = ?))",
+		user.ID, user.ID, user.GroupID).Order("created_at desc").Find(&shares)
+	return shares
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInternalShare_Create(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)internal_shares(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		share := InternalShare{UserID: 1, FolderID: 2, TargetUserID: 3}
+		id, err := share.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(1, id)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		share := InternalShare{UserID: 1}
+		id, err := share.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.EqualValues(0, id)
+	}
+}
+
+func TestInternalShare_AccessibleBy(t *testing.T) {
+	asserts := assert.New(t)
+
+	userShare := InternalShare{UserID: 1, TargetUserID: 2}
+	groupShare := InternalShare{UserID: 1, TargetGroupID: 2}
+
+	asserts.True(userShare.AccessibleBy(&User{Model: gorm.Model{ID: 2}}))
+	asserts.False(userShare.AccessibleBy(&User{Model: gorm.Model{ID: 3}, GroupID: 2}))
+	asserts.True(groupShare.AccessibleBy(&User{Model: gorm.Model{ID: 3}, GroupID: 2}))
+	asserts.False(groupShare.AccessibleBy(&User{Model: gorm.Model{ID: 3}, GroupID: 1}))
+
+	// 共享者本人及匿名用户
+	asserts.False(groupShare.AccessibleBy(&User{Model: gorm.Model{ID: 1}, GroupID: 2}))
+	asserts.False(groupShare.AccessibleBy(&User{GroupID: 2}))
+}
+
+func TestInternalShare_SourceFolder(t *testing.T) {
+	asserts := assert.New(t)
+	share := InternalShare{UserID: 1, FolderID: 2}
+
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "shared"))
+	folder := share.SourceFolder()
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal("shared", folder.Name)
+
+	// 已缓存
+	asserts.Equal("shared", share.SourceFolder().Name)
+}
+
+func TestGetInternalShareByHashID(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 无效的 HashID
+	{
+		asserts.Nil(GetInternalShareByHashID("invalid"))
+	}
+
+	// 不存在
+	{
+		mock.ExpectQuery("SELECT(.+)internal_shares(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		asserts.Nil(GetInternalShareByHashID(hashid.HashID(1, hashid.InternalShareID)))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)internal_shares(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "writable"}).AddRow(1, true))
+		share := GetInternalShareByHashID(hashid.HashID(1, hashid.InternalShareID))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(share)
+		asserts.True(share.Writable)
+	}
+}
+
+func TestListReceivedInternalShares(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)internal_shares(.+)").WithArgs(2, 2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	res := ListReceivedInternalShares(&User{Model: gorm.Model{ID: 2}, GroupID: 3})
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(res, 2)
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// LDAPAccount 由 LDAP 目录认证的用户
+type LDAPAccount struct {
+	gorm.Model
+	UserID uint   `gorm:"unique_index:ldap_account_user_id"`
+	DN     string `gorm:"size:1024"` // 用户在目录中的 DN
+	// SyncBaned 用户是否因已从目录中移除而被同步任务封禁，重新出现在目录中时解除
+	SyncBaned bool
+}
+
+// Create 创建记录
+func (account *LDAPAccount) Create() (uint, error) {
+	if err := DB.Create(account).Error; err != nil {
+		return 0, err
+	}
+	return account.ID, nil
+}
+
+// UpdateDN 更新用户在目录中的 DN
+func (account *LDAPAccount) UpdateDN(dn string) error {
+	account.DN = dn
+	return DB.Model(account).Update("dn", dn).Error
+}
+
+// SetSyncBaned 记录用户是否被同步任务封禁
+func (account *LDAPAccount) SetSyncBaned(baned bool) error {
+	account.SyncBaned = baned
+	return DB.Model(account).Update("sync_baned", baned).Error
+}
+
+// GetLDAPAccountByUserID 根据用户ID查找记录
+func GetLDAPAccountByUserID(uid uint) (*LDAPAccount, error) {
+	res := &LDAPAccount{}
+	err := DB.Where("user_id = ?", uid).First(res).Error
+	return res, err
+}
+
+// GetLDAPAccountByDN 根据目录中的 DN 查找记录
+func GetLDAPAccountByDN(dn string) (*LDAPAccount, error) {
+	res := &LDAPAccount{}
+	err := DB.Where("dn = ?", dn).First(res).Error
+	return res, err
+}
+
+// ListLDAPAccounts 列出所有由目录认证的用户
+func ListLDAPAccounts() []LDAPAccount {
+	var accounts []LDAPAccount
+	DB.Find(&accounts)
+	return accounts
+}
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLDAPAccount_Create(t *testing.T) {
+	asserts := assert.New(t)
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)ldap_accounts(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		account := LDAPAccount{UserID: 1, DN: "uid=1,dc=cloudreve,dc=org"}
+		id, err := account.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(1, id)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		account := LDAPAccount{UserID: 1}
+		id, err := account.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.EqualValues(0, id)
+	}
+}
+
+func TestLDAPAccount_UpdateDN(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)ldap_accounts(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	account := LDAPAccount{}
+	account.ID = 1
+	asserts.NoError(account.UpdateDN("uid=2,dc=cloudreve,dc=org"))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal("uid=2,dc=cloudreve,dc=org", account.DN)
+}
+
+func TestLDAPAccount_SetSyncBaned(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)ldap_accounts(.+)").WithArgs(true, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	account := LDAPAccount{}
+	account.ID = 1
+	asserts.NoError(account.SetSyncBaned(true))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.True(account.SyncBaned)
+}
+
+func TestGetLDAPAccountByDN(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)ldap_accounts(.+)").WithArgs("uid=2,dc=cloudreve,dc=org").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, 2))
+	res, err := GetLDAPAccountByDN("uid=2,dc=cloudreve,dc=org")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(2, res.UserID)
+}
+
+func TestGetLDAPAccountByUserID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)ldap_accounts(.+)").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, 2))
+	res, err := GetLDAPAccountByUserID(2)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(1, res.ID)
+}
+
+func TestListLDAPAccounts(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)ldap_accounts(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	res := ListLDAPAccounts()
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(res, 2)
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+)
+
+// Lease 分布式租约，用于在多个主机实例间选举唯一的主节点
+type Lease struct {
+	Name      string `gorm:"primary_key;size:64"`
+	Holder    string `gorm:"size:128"`
+	ExpiresAt time.Time
+}
+
+// AcquireLease 获取或续期租约，返回 holder 是否持有租约
+func AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	res := DB.Model(&Lease{}).
+		Where("name = ? and (holder = ? or expires_at < ?)", name, holder, now).
+		Updates(map[string]interface{}{"holder": holder, "expires_at": now.Add(ttl)})
+	if res.Error != nil {
+		return false, res.Error
+	}
+
+	if res.RowsAffected > 0 {
+		return true, nil
+	}
+
+	// 租约不存在时创建，并发创建时仅有一个实例成功
+	if err := DB.Create(&Lease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl)}).Error; err == nil {
+		return true, nil
+	}
+
+	// 续期时部分数据库在值未变化时不计入影响行数，需再次确认持有者
+	var lease Lease
+	if err := DB.Where("name = ?", name).First(&lease).Error; err != nil {
+		return false, err
+	}
+
+	return lease.Holder == holder && lease.ExpiresAt.After(now), nil
+}
+
+// ReleaseLease 释放 holder 持有的租约
+func ReleaseLease(name, holder string) error {
+	return DB.Where("name = ? and holder = ?", name, holder).Delete(&Lease{}).Error
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquireLease(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 续期或接管成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)leases(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		ok, err := AcquireLease("master", "a", time.Minute)
+		asserts.NoError(err)
+		asserts.True(ok)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 更新出错
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)leases(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		ok, err := AcquireLease("master", "a", time.Minute)
+		asserts.Error(err)
+		asserts.False(ok)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 租约不存在，创建
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)leases(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)leases(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		ok, err := AcquireLease("master", "a", time.Minute)
+		asserts.NoError(err)
+		asserts.True(ok)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 由其他实例持有
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)leases(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)leases(.+)").WillReturnError(errors.New("duplicate"))
+		mock.ExpectRollback()
+		mock.ExpectQuery("SELECT(.+)leases(.+)").WithArgs("master").
+			WillReturnRows(sqlmock.NewRows([]string{"name", "holder", "expires_at"}).AddRow("master", "b", time.Now().Add(time.Minute)))
+		ok, err := AcquireLease("master", "a", time.Minute)
+		asserts.NoError(err)
+		asserts.False(ok)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 续期未产生变化，仍由自身持有
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)leases(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)leases(.+)").WillReturnError(errors.New("duplicate"))
+		mock.ExpectRollback()
+		mock.ExpectQuery("SELECT(.+)leases(.+)").WithArgs("master").
+			WillReturnRows(sqlmock.NewRows([]string{"name", "holder", "expires_at"}).AddRow("master", "a", time.Now().Add(time.Minute)))
+		ok, err := AcquireLease("master", "a", time.Minute)
+		asserts.NoError(err)
+		asserts.True(ok)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 查询出错
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)leases(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)leases(.+)").WillReturnError(errors.New("duplicate"))
+		mock.ExpectRollback()
+		mock.ExpectQuery("SELECT(.+)leases(.+)").WillReturnError(errors.New("error"))
+		ok, err := AcquireLease("master", "a", time.Minute)
+		asserts.Error(err)
+		asserts.False(ok)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestReleaseLease(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)leases(.+)").WithArgs("master", "a").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	asserts.NoError(ReleaseLease("master", "a"))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
@@ -41,7 +41,10 @@ func migration() {
 	}
 
 	DB.AutoMigrate(&User{}, &Setting{}, &Group{}, &Policy{}, &Folder{}, &File{}, &Share{},
-		&Task{}, &Download{}, &Tag{}, &Webdav{}, &Node{})
+		&Task{}, &Download{}, &Tag{}, &Webdav{}, &Node{}, &Activity{}, &AuditLog{},
+		&Webhook{}, &WebhookDelivery{}, &AccessToken{}, &OpenIDIdentity{}, &LDAPAccount{}, &InternalShare{}, &ShareEvent{}, &ShareUploadReservation{}, &Traffic{},
+		&StoragePack{}, &Redeem{}, &Lease{}, &Change{}, &WebdavLock{}, &DeadProperty{},
+		&DavCollection{}, &DavObject{})
 
 	// 创建初始存储策略
 	addDefaultPolicy()
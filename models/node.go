@@ -24,6 +24,8 @@ type Node struct {
 
 // Aria2Option 非公有的Aria2配置属性
 type Aria2Option struct {
+	// 下载器，为空时使用 aria2
+	Downloader string `json:"downloader,omitempty"`
 	// RPC 服务器地址
 	Server string `json:"server,omitempty"`
 	// RPC 密钥
@@ -38,6 +40,14 @@ type Aria2Option struct {
 	Timeout int `json:"timeout,omitempty"`
 }
 
+// 离线下载器
+const (
+	// DownloaderAria2 通过 RPC 调用 aria2
+	DownloaderAria2 = "aria2"
+	// DownloaderBuiltin 内置的 HTTP/FTP 下载器
+	DownloaderBuiltin = "builtin"
+)
+
 type NodeStatus int
 type ModelType int
 
@@ -82,6 +92,11 @@ func (node *Node) BeforeSave() (err error) {
 	return err
 }
 
+// UseBuiltinDownloader 是否使用内置下载器处理离线下载
+func (option *Aria2Option) UseBuiltinDownloader() bool {
+	return option.Downloader == DownloaderBuiltin
+}
+
 // SetStatus 设置节点启用状态
 func (node *Node) SetStatus(status NodeStatus) error {
 	node.Status = status
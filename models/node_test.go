@@ -51,6 +51,17 @@ func TestNode_BeforeSave(t *testing.T) {
 	a.Contains(node.Aria2Options, "1")
 }
 
+func TestAria2Option_UseBuiltinDownloader(t *testing.T) {
+	a := assert.New(t)
+	option := &Aria2Option{}
+
+	a.False(option.UseBuiltinDownloader())
+	option.Downloader = DownloaderAria2
+	a.False(option.UseBuiltinDownloader())
+	option.Downloader = DownloaderBuiltin
+	a.True(option.UseBuiltinDownloader())
+}
+
 func TestNode_SetStatus(t *testing.T) {
 	a := assert.New(t)
 	node := &Node{}
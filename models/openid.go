@@ -0,0 +1,41 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// OpenIDIdentity 用户绑定的 OpenID Connect 身份
+type OpenIDIdentity struct {
+	gorm.Model
+	UserID  uint   `gorm:"index:open_id_identity_user_id"`
+	Issuer  string `gorm:"size:191;unique_index:open_id_identity_subject"` // 身份提供方
+	Subject string `gorm:"size:191;unique_index:open_id_identity_subject"` // 身份提供方中的用户标识
+	Email   string // 绑定时身份提供方返回的邮箱，仅用于展示
+}
+
+// Create 创建绑定记录
+func (identity *OpenIDIdentity) Create() (uint, error) {
+	if err := DB.Create(identity).Error; err != nil {
+		return 0, err
+	}
+	return identity.ID, nil
+}
+
+// GetOpenIDIdentity 根据身份提供方和用户标识查找绑定记录
+func GetOpenIDIdentity(issuer, subject string) (*OpenIDIdentity, error) {
+	res := &OpenIDIdentity{}
+	err := DB.Where("issuer = ? and subject = ?", issuer, subject).First(res).Error
+	return res, err
+}
+
+// ListOpenIDIdentities 列出用户绑定的所有身份
+func ListOpenIDIdentities(uid uint) []OpenIDIdentity {
+	var identities []OpenIDIdentity
+	DB.Where("user_id = ?", uid).Find(&identities)
+	return identities
+}
+
+// DeleteOpenIDIdentityByID 根据ID和UID解除绑定，解除后可被重新绑定，因此直接删除记录
+func DeleteOpenIDIdentityByID(id, uid uint) error {
+	return DB.Unscoped().Where("user_id = ? and id = ?", uid, id).Delete(&OpenIDIdentity{}).Error
+}
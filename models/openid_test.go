@@ -0,0 +1,67 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenIDIdentity_Create(t *testing.T) {
+	asserts := assert.New(t)
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		identity := OpenIDIdentity{Issuer: "http://idp", Subject: "1"}
+		id, err := identity.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(1, id)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		identity := OpenIDIdentity{Issuer: "http://idp", Subject: "1"}
+		id, err := identity.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.EqualValues(0, id)
+	}
+}
+
+func TestGetOpenIDIdentity(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)open_id_identities(.+)").WithArgs("http://idp", "1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, 2))
+	res, err := GetOpenIDIdentity("http://idp", "1")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(2, res.UserID)
+}
+
+func TestListOpenIDIdentities(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)open_id_identities(.+)").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	res := ListOpenIDIdentities(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(res, 2)
+}
+
+func TestDeleteOpenIDIdentityByID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)open_id_identities(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	asserts.NoError(DeleteOpenIDIdentityByID(1, 1))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// RedeemTypePack 兑换容量包
+	RedeemTypePack = iota
+	// RedeemTypeGroup 兑换限时用户组
+	RedeemTypeGroup
+)
+
+// ErrRedeemUsed 兑换码已被使用
+var ErrRedeemUsed = errors.New("redeem code has been used")
+
+// Redeem 兑换码
+type Redeem struct {
+	gorm.Model
+	Type     int
+	Name     string // 容量包名称
+	Size     uint64 // 容量包大小
+	GroupID  uint   // 兑换的用户组ID
+	Duration int64  // 有效时长，单位为秒
+	Code     string `gorm:"size:64;unique_index:redeem_code"`
+	Used     bool
+	UsedBy   uint // 使用者UID
+}
+
+// CreateRedeems 批量创建兑换码
+func CreateRedeems(redeems []Redeem) error {
+	tx := DB.Begin()
+	for i := range redeems {
+		if err := tx.Create(&redeems[i]).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+// GetAvailableRedeem 根据兑换码查找未使用的兑换码
+func GetAvailableRedeem(code string) (*Redeem, error) {
+	var redeem Redeem
+	err := DB.Where("code = ? and used = ?", code, false).First(&redeem).Error
+	return &redeem, err
+}
+
+// Apply 为用户兑换容量包或限时用户组
+func (redeem *Redeem) Apply(user *User) error {
+	tx := DB.Begin()
+	if err := redeem.use(tx, user.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	duration := time.Duration(redeem.Duration) * time.Second
+	switch redeem.Type {
+	case RedeemTypePack:
+		now := time.Now()
+		pack := StoragePack{
+			Name:        redeem.Name,
+			UserID:      user.ID,
+			ActiveTime:  now,
+			ExpiredTime: now.Add(duration),
+			Size:        redeem.Size,
+		}
+		if err := tx.Create(&pack).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	case RedeemTypeGroup:
+		if err := user.UpgradeGroup(tx, redeem.GroupID, duration); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	cache.Deletes([]string{strconv.FormatUint(uint64(user.ID), 10)}, packSizeCachePrefix)
+	return nil
+}
+
+// use 将兑换码标记为已被 uid 使用，并发使用时只有一次成功
+func (redeem *Redeem) use(tx *gorm.DB, uid uint) error {
+	res := tx.Model(&Redeem{}).Where("id = ? and used = ?", redeem.ID, false).
+		Updates(map[string]interface{}{"used": true, "used_by": uid})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRedeemUsed
+	}
+
+	redeem.Used = true
+	redeem.UsedBy = uid
+	return nil
+}
+
+// DeleteRedeems 删除兑换码
+func DeleteRedeems(ids []uint) error {
+	return DB.Where("id in (?)", ids).Delete(&Redeem{}).Error
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateRedeems(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)redeems(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT(.+)redeems(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+		redeems := []Redeem{{Code: "a"}, {Code: "b"}}
+		asserts.NoError(CreateRedeems(redeems))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(2, redeems[1].ID)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)redeems(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(CreateRedeems([]Redeem{{Code: "a"}}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetAvailableRedeem(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)redeems(.+)").WithArgs("code", false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code"}).AddRow(1, "code"))
+	redeem, err := GetAvailableRedeem("code")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(1, redeem.ID)
+}
+
+func TestRedeem_Apply(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 兑换容量包
+	{
+		cache.Set("pack_size_1", uint64(0), 0)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)redeems(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT(.+)storage_packs(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		user := &User{}
+		user.ID = 1
+		redeem := &Redeem{Type: RedeemTypePack, Size: 10, Duration: 3600}
+		asserts.NoError(redeem.Apply(user))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(redeem.Used)
+		asserts.EqualValues(1, redeem.UsedBy)
+		_, ok := cache.Get("pack_size_1")
+		asserts.False(ok)
+	}
+
+	// 兑换限时用户组
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)redeems(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		user := &User{GroupID: 2}
+		user.ID = 1
+		redeem := &Redeem{Type: RedeemTypeGroup, GroupID: 4, Duration: 3600}
+		asserts.NoError(redeem.Apply(user))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(4, user.GroupID)
+		asserts.EqualValues(2, user.PreviousGroupID)
+	}
+
+	// 已被使用
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)redeems(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+		user := &User{}
+		redeem := &Redeem{Type: RedeemTypePack}
+		asserts.Equal(ErrRedeemUsed, redeem.Apply(user))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.False(redeem.Used)
+	}
+
+	// 创建容量包失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)redeems(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT(.+)storage_packs(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		user := &User{}
+		redeem := &Redeem{Type: RedeemTypePack}
+		asserts.Error(redeem.Apply(user))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestDeleteRedeems(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)redeems(.+)").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	asserts.NoError(DeleteRedeems([]uint{1, 2}))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
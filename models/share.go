@@ -1,8 +1,10 @@
 package model
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
 	"strings"
 	"time"
 
@@ -27,10 +29,50 @@ type Share struct {
 	PreviewEnabled  bool       // 是否允许直接预览
 	SourceName      string     `gorm:"index:source"` // 用于搜索的字段
 
+	// 上传分享相关
+	UploadEnabled    bool   // 是否允许访客向目录上传文件
+	UploadMaxSize    uint64 // 单文件大小限制，0 为不限制
+	UploadExtensions string // 允许上传的扩展名，逗号分隔，空值为不限制
+	UploadCapacity   uint64 // 累计上传大小限制，0 为不限制
+	UploadedSize     uint64 // 已累计上传的大小
+
+	// 多对象分享相关
+	IsMulti           bool         // 是否为引用多个文件和目录的分享
+	Objects           string       `gorm:"type:text"` // 序列化后的引用对象
+	ObjectsSerialized ShareObjects `gorm:"-"`
+
 	// 数据库忽略字段
-	User   User   `gorm:"PRELOAD:false,association_autoupdate:false"`
-	File   File   `gorm:"PRELOAD:false,association_autoupdate:false"`
-	Folder Folder `gorm:"PRELOAD:false,association_autoupdate:false"`
+	User    User     `gorm:"PRELOAD:false,association_autoupdate:false"`
+	File    File     `gorm:"PRELOAD:false,association_autoupdate:false"`
+	Folder  Folder   `gorm:"PRELOAD:false,association_autoupdate:false"`
+	Folders []Folder `gorm:"-"`
+	Files   []File   `gorm:"-"`
+
+	objectsLoaded bool
+}
+
+// ShareObjects 多对象分享引用的目录和文件ID
+type ShareObjects struct {
+	Dirs  []uint `json:"dirs"`
+	Files []uint `json:"files"`
+}
+
+// AfterFind 找到分享后的钩子，解析引用对象
+func (share *Share) AfterFind() (err error) {
+	if share.Objects != "" {
+		err = json.Unmarshal([]byte(share.Objects), &share.ObjectsSerialized)
+	}
+	return err
+}
+
+// BeforeSave Save分享前的钩子，序列化引用对象
+func (share *Share) BeforeSave() (err error) {
+	if !share.IsMulti {
+		return nil
+	}
+	objects, err := json.Marshal(&share.ObjectsSerialized)
+	share.Objects = string(objects)
+	return err
 }
 
 // Create 创建分享
@@ -80,6 +122,12 @@ func (share *Share) IsAvailable() bool {
 		return false
 	}
 
+	// 多对象分享至少有一个引用对象存在即可
+	if share.IsMulti {
+		folders, files := share.SourceObjects()
+		return len(folders)+len(files) > 0
+	}
+
 	// 检查源对象是否存在
 	var sourceID uint
 	if share.IsDir {
@@ -105,8 +153,15 @@ func (share *Share) Creator() *User {
 	return &share.User
 }
 
-// Source 返回源对象
+// Source 返回源对象，多对象分享返回其中第一个仍然存在的目录
 func (share *Share) Source() interface{} {
+	if share.IsMulti {
+		folders, _ := share.SourceObjects()
+		if len(folders) > 0 {
+			return &folders[0]
+		}
+		return nil
+	}
 	if share.IsDir {
 		return share.SourceFolder()
 	}
@@ -135,6 +190,74 @@ func (share *Share) SourceFile() *File {
 	return &share.File
 }
 
+// SourceObjects 获取多对象分享中仍然存在的目录和文件
+func (share *Share) SourceObjects() ([]Folder, []File) {
+	if !share.objectsLoaded {
+		if len(share.ObjectsSerialized.Dirs) > 0 {
+			share.Folders, _ = GetFoldersByIDs(share.ObjectsSerialized.Dirs, share.UserID)
+		}
+		if len(share.ObjectsSerialized.Files) > 0 {
+			share.Files, _ = GetFilesByIDs(share.ObjectsSerialized.Files, share.UserID)
+		}
+		share.objectsLoaded = true
+	}
+	return share.Folders, share.Files
+}
+
+// ResolvePath 将分享内的路径解析为实际对象。文件分享总是返回源文件；
+// 目录分享返回源目录及原路径；多对象分享根据路径的首级名称找到引用的文件，
+// 或引用的目录及其下的相对路径。路径为虚拟根目录时对象均为 nil 且相对路径为 "/"，
+// 找不到对象时对象均为 nil 且相对路径为空
+func (share *Share) ResolvePath(p string) (*Folder, *File, string) {
+	if !share.IsDir {
+		return nil, share.SourceFile(), ""
+	}
+	if !share.IsMulti {
+		return share.SourceFolder(), nil, p
+	}
+
+	p = path.Clean("/" + p)
+	if p == "/" {
+		return nil, nil, p
+	}
+
+	name, sub := p[1:], "/"
+	if i := strings.Index(name, "/"); i >= 0 {
+		name, sub = name[:i], name[i:]
+	}
+
+	folders, files := share.SourceObjects()
+	for i := range folders {
+		if folders[i].Name == name {
+			return &folders[i], nil, sub
+		}
+	}
+	if sub == "/" {
+		for i := range files {
+			if files[i].Name == name {
+				return nil, &files[i], ""
+			}
+		}
+	}
+
+	return nil, nil, ""
+}
+
+// ContainsObjects 返回给定的目录和文件是否均为多对象分享直接引用的对象
+func (share *Share) ContainsObjects(dirs, files []uint) bool {
+	for _, id := range dirs {
+		if !util.ContainsUint(share.ObjectsSerialized.Dirs, id) {
+			return false
+		}
+	}
+	for _, id := range files {
+		if !util.ContainsUint(share.ObjectsSerialized.Files, id) {
+			return false
+		}
+	}
+	return true
+}
+
 // CanBeDownloadBy 返回此分享是否可以被给定用户下载
 func (share *Share) CanBeDownloadBy(user *User) error {
 	// 用户组权限
@@ -190,6 +313,81 @@ func (share *Share) Downloaded() {
 	})
 }
 
+// ShareUploadReservation 访客上传会话预留的分享累计上传大小
+type ShareUploadReservation struct {
+	SessionID string `gorm:"primary_key;size:64"`
+	ShareID   uint
+	Size      uint64
+}
+
+// ReserveUploadSize 为访客上传会话预留累计上传大小，超出限制时返回 false
+func (share *Share) ReserveUploadSize(size uint64) bool {
+	result := DB.Model(&Share{}).
+		Where("id = ? and (upload_capacity = 0 or uploaded_size + ? <= upload_capacity)", share.ID, size).
+		UpdateColumn("uploaded_size", gorm.Expr("uploaded_size + ?", size))
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+
+	share.UploadedSize += size
+	return true
+}
+
+// ReleaseUploadSize 释放未能创建上传会话的预留大小
+func (share *Share) ReleaseUploadSize(size uint64) {
+	share.UploadedSize -= size
+	DB.Model(&Share{}).Where("id = ? and uploaded_size >= ?", share.ID, size).
+		UpdateColumn("uploaded_size", gorm.Expr("uploaded_size - ?", size))
+}
+
+// AddUploadReservation 记录上传会话预留的大小，会话过期后据此释放
+func (share *Share) AddUploadReservation(sessionID string, size uint64) error {
+	return DB.Create(&ShareUploadReservation{SessionID: sessionID, ShareID: share.ID, Size: size}).Error
+}
+
+// ClaimShareUploadReservation 上传完成后删除会话的预留记录，预留大小计入累计上传大小。
+// 同一会话仅有一次调用返回 true
+func ClaimShareUploadReservation(sessionID string) (*ShareUploadReservation, bool) {
+	var reservation ShareUploadReservation
+	if err := DB.Where("session_id = ?", sessionID).First(&reservation).Error; err != nil {
+		return nil, false
+	}
+
+	result := DB.Where("session_id = ?", sessionID).Delete(&ShareUploadReservation{})
+	return &reservation, result.Error == nil && result.RowsAffected > 0
+}
+
+// GetShareUploadReservations 列出所有未完成上传会话的预留记录
+func GetShareUploadReservations() []ShareUploadReservation {
+	var reservations []ShareUploadReservation
+	DB.Find(&reservations)
+	return reservations
+}
+
+// Release 释放过期上传会话预留的累计上传大小
+func (reservation *ShareUploadReservation) Release() error {
+	tx := DB.Begin()
+	result := tx.Where("session_id = ?", reservation.SessionID).Delete(&ShareUploadReservation{})
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+
+	// 已由完成的上传处理
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return nil
+	}
+
+	if err := tx.Model(&Share{}).Where("id = ? and uploaded_size >= ?", reservation.ShareID, reservation.Size).
+		UpdateColumn("uploaded_size", gorm.Expr("uploaded_size - ?", reservation.Size)).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
 // Update 更新分享属性
 func (share *Share) Update(props map[string]interface{}) error {
 	return DB.Model(share).Updates(props).Error
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// ShareEventView 查看分享
+	ShareEventView = iota
+	// ShareEventPreview 预览文件
+	ShareEventPreview
+	// ShareEventDownload 下载文件
+	ShareEventDownload
+	// ShareEventArchive 打包下载
+	ShareEventArchive
+)
+
+// ShareEvent 分享访问记录
+type ShareEvent struct {
+	gorm.Model
+	ShareID   uint   `gorm:"index:share_event_share_id"`
+	Type      int    // 访问类型
+	UserID    uint   // 访问者UID，0 表示匿名访客
+	Path      string `gorm:"type:text"` // 目录分享下被访问文件的相对路径
+	IP        string
+	UserAgent string `gorm:"type:text"`
+	Referer   string `gorm:"type:text"`
+}
+
+// Create 创建访问记录
+func (event *ShareEvent) Create() (uint, error) {
+	if err := DB.Create(event).Error; err != nil {
+		util.Log().Warning("无法插入分享访问记录, %s", err)
+		return 0, err
+	}
+	return event.ID, nil
+}
+
+// ListShareEvents 列出分享的访问记录，types 为空时列出所有类型
+func ListShareEvents(shareID uint, page, pageSize int, types []int) ([]ShareEvent, int) {
+	var (
+		events []ShareEvent
+		total  int
+	)
+	dbChain := DB.Where("share_id = ?", shareID)
+	if len(types) > 0 {
+		dbChain = dbChain.Where("type in (?)", types)
+	}
+
+	// 计算总数用于分页
+	dbChain.Model(&ShareEvent{}).Count(&total)
+
+	// 查询记录
+	dbChain.Limit(pageSize).Offset((page - 1) * pageSize).Order("id desc").Find(&events)
+
+	return events, total
+}
+
+// ListShareEventsAfter 按ID顺序列出 afterID 之后的访问记录，用于分批导出
+func ListShareEventsAfter(shareID, afterID uint, limit int) []ShareEvent {
+	var events []ShareEvent
+	DB.Where("share_id = ? and id > ?", shareID, afterID).Order("id asc").Limit(limit).Find(&events)
+	return events
+}
+
+// ShareEventDayCount 分享某天某类访问的次数
+type ShareEventDayCount struct {
+	Date  string // 日期，格式为 2006-01-02
+	Type  int
+	Count int
+}
+
+// CountShareEventsByDay 按天和访问类型统计给定时间之后的访问次数
+func CountShareEventsByDay(shareID uint, since time.Time) []ShareEventDayCount {
+	var res []ShareEventDayCount
+	date := dateExpr("created_at")
+	DB.Model(&ShareEvent{}).
+		Select(date+" as date, type, count(*) as count").
+		Where("share_id = ? and created_at >= ?", shareID, since).
+		Group(date + ", type").
+		Scan(&res)
+	return res
+}
+
+// dateExpr 返回将时间列格式化为 2006-01-02 的 SQL 表达式
+func dateExpr(column string) string {
+	switch DB.Dialect().GetName() {
+	case "mysql":
+		return "DATE_FORMAT(" + column + ", '%Y-%m-%d')"
+	case "postgres":
+		return "TO_CHAR(" + column + ", 'YYYY-MM-DD')"
+	case "mssql":
+		return "CONVERT(VARCHAR(10), " + column + ", 23)"
+	default:
+		// SQLite 中时间以本地时区的文本存储
+		return "SUBSTR(" + column + ", 1, 10)"
+	}
+}
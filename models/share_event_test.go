@@ -0,0 +1,91 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShareEvent_Create(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)share_events(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		event := ShareEvent{ShareID: 1, Type: ShareEventDownload, Path: "/a.txt"}
+		id, err := event.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(1, id)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		event := ShareEvent{ShareID: 1}
+		id, err := event.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.EqualValues(0, id)
+	}
+}
+
+func TestListShareEvents(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 所有类型
+	{
+		mock.ExpectQuery("SELECT(.+)share_events(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(2))
+		mock.ExpectQuery("SELECT(.+)share_events(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(1))
+		res, total := ListShareEvents(1, 1, 10, nil)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Len(res, 2)
+		asserts.Equal(2, total)
+	}
+
+	// 指定类型
+	{
+		mock.ExpectQuery("SELECT(.+)share_events(.+)type in(.+)").WithArgs(1, ShareEventDownload).
+			WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(1))
+		mock.ExpectQuery("SELECT(.+)share_events(.+)type in(.+)").WithArgs(1, ShareEventDownload).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		res, total := ListShareEvents(1, 1, 10, []int{ShareEventDownload})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Len(res, 1)
+		asserts.Equal(1, total)
+	}
+}
+
+func TestListShareEventsAfter(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)share_events(.+)").WithArgs(1, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6).AddRow(7))
+	res := ListShareEventsAfter(1, 5, 100)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(res, 2)
+}
+
+func TestCountShareEventsByDay(t *testing.T) {
+	asserts := assert.New(t)
+	since := time.Now().Add(-24 * time.Hour)
+
+	mock.ExpectQuery("SELECT DATE_FORMAT\\(created_at, '%Y-%m-%d'\\) as date, type, count\\(\\*\\) as count(.+)share_events(.+)GROUP BY(.+)").
+		WithArgs(1, since).
+		WillReturnRows(sqlmock.NewRows([]string{"date", "type", "count"}).
+			AddRow("2022-01-01", ShareEventView, 3).
+			AddRow("2022-01-02", ShareEventDownload, 1))
+	res := CountShareEventsByDay(1, since)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(res, 2)
+	asserts.Equal(ShareEventDayCount{Date: "2022-01-01", Type: ShareEventView, Count: 3}, res[0])
+}
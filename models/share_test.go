@@ -126,6 +126,128 @@ func TestShare_IsAvailable(t *testing.T) {
 		}
 		asserts.False(share.IsAvailable())
 	}
+
+	// 多对象分享，仅部分对象存在
+	{
+		share := Share{
+			RemainDownloads:   -1,
+			IsDir:             true,
+			IsMulti:           true,
+			ObjectsSerialized: ShareObjects{Dirs: []uint{2}, Files: []uint{3, 4}},
+		}
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		asserts.True(share.IsAvailable())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 多对象分享，对象均不存在
+	{
+		share := Share{
+			RemainDownloads:   -1,
+			IsDir:             true,
+			IsMulti:           true,
+			ObjectsSerialized: ShareObjects{Files: []uint{3}},
+		}
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		asserts.False(share.IsAvailable())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestShare_AfterFindAndBeforeSave(t *testing.T) {
+	asserts := assert.New(t)
+
+	share := Share{IsMulti: true, ObjectsSerialized: ShareObjects{Dirs: []uint{1}, Files: []uint{2, 3}}}
+	asserts.NoError(share.BeforeSave())
+	asserts.JSONEq(`{"dirs":[1],"files":[2,3]}`, share.Objects)
+
+	found := Share{Objects: share.Objects}
+	asserts.NoError(found.AfterFind())
+	asserts.Equal(share.ObjectsSerialized, found.ObjectsSerialized)
+
+	// 普通分享不序列化
+	plain := Share{}
+	asserts.NoError(plain.BeforeSave())
+	asserts.Empty(plain.Objects)
+}
+
+func TestShare_ResolvePath(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 文件分享
+	{
+		share := Share{File: File{Model: gorm.Model{ID: 1}}}
+		folder, file, _ := share.ResolvePath("/any")
+		asserts.Nil(folder)
+		asserts.EqualValues(1, file.ID)
+	}
+
+	// 目录分享
+	{
+		share := Share{IsDir: true, Folder: Folder{Model: gorm.Model{ID: 1}}}
+		folder, file, sub := share.ResolvePath("/a/b.txt")
+		asserts.EqualValues(1, folder.ID)
+		asserts.Nil(file)
+		asserts.Equal("/a/b.txt", sub)
+	}
+
+	// 多对象分享
+	share := Share{
+		IsDir:             true,
+		IsMulti:           true,
+		ObjectsSerialized: ShareObjects{Dirs: []uint{1}, Files: []uint{2}},
+	}
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "docs"))
+	mock.ExpectQuery("SELECT(.+)files(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "a.txt"))
+
+	// 虚拟根目录
+	{
+		folder, file, sub := share.ResolvePath("/")
+		asserts.Nil(folder)
+		asserts.Nil(file)
+		asserts.Equal("/", sub)
+	}
+
+	// 引用的文件
+	{
+		folder, file, _ := share.ResolvePath("/a.txt")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(folder)
+		asserts.EqualValues(2, file.ID)
+	}
+
+	// 引用的目录
+	{
+		folder, file, sub := share.ResolvePath("/docs/sub/b.txt")
+		asserts.EqualValues(1, folder.ID)
+		asserts.Nil(file)
+		asserts.Equal("/sub/b.txt", sub)
+
+		folder, _, sub = share.ResolvePath("docs")
+		asserts.EqualValues(1, folder.ID)
+		asserts.Equal("/", sub)
+	}
+
+	// 不存在的对象
+	{
+		folder, file, sub := share.ResolvePath("/a.txt/b")
+		asserts.Nil(folder)
+		asserts.Nil(file)
+		asserts.Empty(sub)
+	}
+}
+
+func TestShare_ContainsObjects(t *testing.T) {
+	asserts := assert.New(t)
+	share := Share{ObjectsSerialized: ShareObjects{Dirs: []uint{1}, Files: []uint{2, 3}}}
+
+	asserts.True(share.ContainsObjects([]uint{1}, []uint{3}))
+	asserts.True(share.ContainsObjects(nil, nil))
+	asserts.False(share.ContainsObjects([]uint{2}, nil))
+	asserts.False(share.ContainsObjects(nil, []uint{1}))
 }
 
 func TestShare_GetCreator(t *testing.T) {
@@ -259,6 +381,143 @@ func TestShare_Viewed(t *testing.T) {
 	asserts.EqualValues(1, share.Views)
 }
 
+func TestShare_ReserveUploadSize(t *testing.T) {
+	asserts := assert.New(t)
+	share := Share{UploadCapacity: 10}
+	share.ID = 1
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)uploaded_size(.+)").
+			WithArgs(8, 1, 8).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.True(share.ReserveUploadSize(8))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(8, share.UploadedSize)
+	}
+
+	// 超出限制
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)uploaded_size(.+)").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		asserts.False(share.ReserveUploadSize(8))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(8, share.UploadedSize)
+	}
+
+	// 释放
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)uploaded_size(.+)").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		share.ReleaseUploadSize(8)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(0, share.UploadedSize)
+	}
+}
+
+func TestShare_AddUploadReservation(t *testing.T) {
+	asserts := assert.New(t)
+	share := Share{}
+	share.ID = 1
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT(.+)share_upload_reservations(.+)").
+		WithArgs("session", 1, 8).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(share.AddUploadReservation("session", 8))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestClaimShareUploadReservation(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 不存在
+	{
+		mock.ExpectQuery("SELECT(.+)share_upload_reservations(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"session_id"}))
+		_, ok := ClaimShareUploadReservation("session")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.False(ok)
+	}
+
+	// 已被其他请求处理
+	{
+		mock.ExpectQuery("SELECT(.+)share_upload_reservations(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"session_id", "share_id", "size"}).AddRow("session", 1, 8))
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)share_upload_reservations(.+)").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		_, ok := ClaimShareUploadReservation("session")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.False(ok)
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)share_upload_reservations(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"session_id", "share_id", "size"}).AddRow("session", 1, 8))
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)share_upload_reservations(.+)").
+			WithArgs("session").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		reservation, ok := ClaimShareUploadReservation("session")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(ok)
+		asserts.EqualValues(1, reservation.ShareID)
+		asserts.EqualValues(8, reservation.Size)
+	}
+}
+
+func TestShareUploadReservation_Release(t *testing.T) {
+	asserts := assert.New(t)
+	reservation := ShareUploadReservation{SessionID: "session", ShareID: 1, Size: 8}
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)share_upload_reservations(.+)").
+			WithArgs("session").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)uploaded_size(.+)").
+			WithArgs(8, 1, 8).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(reservation.Release())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 已完成上传
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)share_upload_reservations(.+)").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+		asserts.NoError(reservation.Release())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)share_upload_reservations(.+)").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)uploaded_size(.+)").
+			WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(reservation.Release())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
 func TestShare_UpdateAndDelete(t *testing.T) {
 	asserts := assert.New(t)
 	share := Share{}
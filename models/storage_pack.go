@@ -0,0 +1,109 @@
+package model
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
+)
+
+// packSizeCachePrefix 用户有效容量包总大小的缓存前缀
+const packSizeCachePrefix = "pack_size_"
+
+// StoragePack 容量包，在有效期内为用户增加可用容量
+type StoragePack struct {
+	gorm.Model
+	Name        string
+	UserID      uint      `gorm:"index:storage_pack_user"`
+	ActiveTime  time.Time // 生效时间
+	ExpiredTime time.Time `gorm:"index:storage_pack_expired"` // 过期时间
+	Size        uint64
+}
+
+// Create 创建容量包
+func (pack *StoragePack) Create() (uint, error) {
+	if err := DB.Create(pack).Error; err != nil {
+		return 0, err
+	}
+	cache.Deletes([]string{strconv.FormatUint(uint64(pack.UserID), 10)}, packSizeCachePrefix)
+	return pack.ID, nil
+}
+
+// GetAvailablePackSize 获取用户有效容量包的总大小
+func GetAvailablePackSize(uid uint) uint64 {
+	key := packSizeCachePrefix + strconv.FormatUint(uint64(uid), 10)
+	if size, ok := cache.Get(key); ok {
+		return size.(uint64)
+	}
+
+	var packs []StoragePack
+	now := time.Now()
+	DB.Where("user_id = ? and expired_time > ?", uid, now).Find(&packs)
+
+	total, ttl := availablePackSize(packs, now)
+	cache.Set(key, total, ttl)
+
+	return total
+}
+
+// availablePackSize 计算 now 时已生效容量包的总大小，以及总大小保持不变的缓存秒数。
+// 总大小在已生效的容量包过期或未生效的容量包生效时变化
+func availablePackSize(packs []StoragePack, now time.Time) (uint64, int) {
+	var (
+		total uint64
+		next  time.Time
+	)
+	for _, pack := range packs {
+		change := pack.ExpiredTime
+		if pack.ActiveTime.After(now) {
+			change = pack.ActiveTime
+		} else {
+			total += pack.Size
+		}
+
+		if next.IsZero() || change.Before(next) {
+			next = change
+		}
+	}
+
+	ttl := 3600
+	if !next.IsZero() && int(next.Sub(now).Seconds()) < ttl {
+		ttl = int(next.Sub(now).Seconds()) + 1
+	}
+
+	return total, ttl
+}
+
+// ListStoragePacks 列出用户尚未过期的容量包
+func ListStoragePacks(uid uint) []StoragePack {
+	var packs []StoragePack
+	DB.Where("user_id = ? and expired_time > ?", uid, time.Now()).Order("expired_time asc").Find(&packs)
+	return packs
+}
+
+// GetExpiredStoragePacks 列出已过期的容量包
+func GetExpiredStoragePacks() []StoragePack {
+	var packs []StoragePack
+	DB.Where("expired_time <= ?", time.Now()).Find(&packs)
+	return packs
+}
+
+// DeleteStoragePacks 删除容量包并清除相关用户的容量缓存
+func DeleteStoragePacks(packs []StoragePack) error {
+	if len(packs) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(packs))
+	users := make([]string, 0, len(packs))
+	for _, pack := range packs {
+		ids = append(ids, pack.ID)
+		users = append(users, strconv.FormatUint(uint64(pack.UserID), 10))
+	}
+
+	if err := DB.Where("id in (?)", ids).Delete(&StoragePack{}).Error; err != nil {
+		return err
+	}
+	return cache.Deletes(users, packSizeCachePrefix)
+}
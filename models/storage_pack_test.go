@@ -0,0 +1,154 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoragePack_Create(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功，清除缓存
+	{
+		cache.Set("pack_size_1", uint64(10), 0)
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)storage_packs(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		pack := StoragePack{UserID: 1, Size: 10}
+		id, err := pack.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(1, id)
+		_, ok := cache.Get("pack_size_1")
+		asserts.False(ok)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		pack := StoragePack{UserID: 1}
+		id, err := pack.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.EqualValues(0, id)
+	}
+}
+
+func TestGetAvailablePackSize(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Deletes([]string{"2"}, "pack_size_")
+
+	// 未命中缓存
+	{
+		mock.ExpectQuery("SELECT(.+)storage_packs(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "size", "expired_time"}).
+				AddRow(1, 10, time.Now().Add(time.Hour)).
+				AddRow(2, 20, time.Now().Add(time.Minute)))
+		asserts.EqualValues(30, GetAvailablePackSize(2))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 命中缓存
+	{
+		asserts.EqualValues(30, GetAvailablePackSize(2))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestAvailablePackSize(t *testing.T) {
+	asserts := assert.New(t)
+	now := time.Now()
+
+	testCases := []struct {
+		name  string
+		packs []StoragePack
+		total uint64
+		ttl   int
+	}{
+		{
+			name:  "没有容量包",
+			total: 0,
+			ttl:   3600,
+		},
+		{
+			name: "缓存至最早的容量包过期",
+			packs: []StoragePack{
+				{Size: 10, ActiveTime: now.Add(-time.Hour), ExpiredTime: now.Add(2 * time.Hour)},
+				{Size: 20, ActiveTime: now.Add(-time.Hour), ExpiredTime: now.Add(time.Minute)},
+			},
+			total: 30,
+			ttl:   61,
+		},
+		{
+			name: "未生效的容量包不计入，缓存至其生效",
+			packs: []StoragePack{
+				{Size: 10, ActiveTime: now.Add(-time.Hour), ExpiredTime: now.Add(2 * time.Hour)},
+				{Size: 20, ActiveTime: now.Add(10 * time.Minute), ExpiredTime: now.Add(3 * time.Hour)},
+			},
+			total: 10,
+			ttl:   601,
+		},
+	}
+
+	for _, testCase := range testCases {
+		total, ttl := availablePackSize(testCase.packs, now)
+		asserts.Equal(testCase.total, total, testCase.name)
+		asserts.Equal(testCase.ttl, ttl, testCase.name)
+	}
+}
+
+func TestListStoragePacks(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)storage_packs(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	asserts.Len(ListStoragePacks(1), 2)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestGetExpiredStoragePacks(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)storage_packs(.+)expired_time <=(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	asserts.Len(GetExpiredStoragePacks(), 1)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestDeleteStoragePacks(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 无需删除
+	{
+		asserts.NoError(DeleteStoragePacks(nil))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功，清除缓存
+	{
+		cache.Set("pack_size_3", uint64(10), 0)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)storage_packs(.+)deleted_at(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(DeleteStoragePacks([]StoragePack{{UserID: 3}}))
+		asserts.NoError(mock.ExpectationsWereMet())
+		_, ok := cache.Get("pack_size_3")
+		asserts.False(ok)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(DeleteStoragePacks([]StoragePack{{UserID: 3}}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -1,19 +1,33 @@
 package model
 
 import (
+	"time"
+
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/jinzhu/gorm"
 )
 
+// 与 task 包中的任务状态保持一致
+const (
+	taskQueued = iota
+	taskProcessing
+	taskError
+	taskCanceled
+)
+
 // Task 任务模型
 type Task struct {
 	gorm.Model
-	Status   int    // 任务状态
-	Type     int    // 任务类型
-	UserID   uint   // 发起者UID，0表示为系统发起
-	Progress int    // 进度
-	Error    string `gorm:"type:text"` // 错误信息
-	Props    string `gorm:"type:text"` // 任务属性
+	Status    int        `gorm:"index:task_status"` // 任务状态
+	Type      int        // 任务类型
+	UserID    uint       // 发起者UID，0表示为系统发起
+	Progress  int        // 进度
+	Error     string     `gorm:"type:text"` // 错误信息
+	Props     string     `gorm:"type:text"` // 任务属性
+	Priority  int        // 优先级，数值越大越先执行
+	Attempts  int        // 已执行次数
+	NextRunAt *time.Time // 重试任务的最早执行时间
+	ClaimedBy string     `gorm:"size:128"` // 执行任务的实例
 }
 
 // Create 创建任务记录
@@ -25,9 +39,19 @@ func (task *Task) Create() (uint, error) {
 	return task.ID, nil
 }
 
-// SetStatus 设定任务状态
+// SetStatus 设定任务状态，已取消的任务不再变更
 func (task *Task) SetStatus(status int) error {
-	return DB.Model(task).Select("status").Updates(map[string]interface{}{"status": status}).Error
+	return DB.Model(task).Where("status <> ?", taskCanceled).Select("status").
+		Updates(map[string]interface{}{"status": status}).Error
+}
+
+// IsCanceled 返回任务是否已被取消
+func (task *Task) IsCanceled() bool {
+	var record Task
+	if err := DB.Select("status").Where("id = ?", task.ID).First(&record).Error; err != nil {
+		return false
+	}
+	return record.Status == taskCanceled
 }
 
 // SetProgress 设定任务进度
@@ -45,6 +69,88 @@ func (task *Task) SetError(err string) error {
 	return DB.Model(task).Select("error").Updates(map[string]interface{}{"error": err}).Error
 }
 
+// Claim 以 holder 的身份领取排队中的任务，多个实例并发领取时仅有一个成功
+func (task *Task) Claim(holder string) (bool, error) {
+	res := DB.Model(&Task{}).Where("id = ? and status = ?", task.ID, taskQueued).
+		Updates(map[string]interface{}{
+			"status":     taskProcessing,
+			"claimed_by": holder,
+			"attempts":   gorm.Expr("attempts + ?", 1),
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return false, nil
+	}
+
+	task.Status = taskProcessing
+	task.ClaimedBy = holder
+	task.Attempts++
+	return true, nil
+}
+
+// Retry 将执行失败的任务重新排队，在 delay 之后再次执行
+func (task *Task) Retry(delay time.Duration) error {
+	next := time.Now().Add(delay)
+	if err := DB.Model(task).Where("status <> ?", taskCanceled).Updates(map[string]interface{}{
+		"status":      taskQueued,
+		"next_run_at": next,
+		"claimed_by":  "",
+	}).Error; err != nil {
+		return err
+	}
+
+	task.Status = taskQueued
+	task.NextRunAt = &next
+	task.ClaimedBy = ""
+	return nil
+}
+
+// Touch 更新任务的活跃时间，执行中的任务需定期调用以免被视为失效
+func (task *Task) Touch() error {
+	return DB.Model(task).UpdateColumn("updated_at", time.Now()).Error
+}
+
+// SetPriority 设定任务优先级
+func (task *Task) SetPriority(priority int) error {
+	return DB.Model(task).Update("priority", priority).Error
+}
+
+// GetRunnableTasks 列出可执行的排队中任务，按优先级和创建顺序排列
+func GetRunnableTasks(limit int) []Task {
+	var tasks []Task
+	DB.Where("status = ? and (next_run_at is null or next_run_at <= ?)", taskQueued, time.Now()).
+		Order("priority desc, id asc").Limit(limit).Find(&tasks)
+	return tasks
+}
+
+// CountProcessingTasks 统计用户正在执行的任务数量
+func CountProcessingTasks(uid uint) int {
+	total := 0
+	DB.Model(&Task{}).Where("user_id = ? and status = ?", uid, taskProcessing).Count(&total)
+	return total
+}
+
+// RequeueStaleTasks 将 before 之后未再活跃的执行中任务重新排队，用于恢复因实例退出而中断的任务
+func RequeueStaleTasks(before time.Time) (int64, error) {
+	res := DB.Model(&Task{}).Where("status = ? and updated_at < ?", taskProcessing, before).
+		Updates(map[string]interface{}{"status": taskQueued, "claimed_by": ""})
+	return res.RowsAffected, res.Error
+}
+
+// CancelTask 取消排队中或执行中的任务，uid 不为 0 时仅能取消该用户的任务
+func CancelTask(id, uid uint) (bool, error) {
+	tx := DB.Model(&Task{}).Where("id = ? and status in (?)", id, []int{taskQueued, taskProcessing})
+	if uid > 0 {
+		tx = tx.Where("user_id = ?", uid)
+	}
+
+	res := tx.Update("status", taskCanceled)
+	return res.RowsAffected > 0, res.Error
+}
+
 // GetTasksByStatus 根据状态检索任务
 func GetTasksByStatus(status ...int) []Task {
 	var tasks []Task
@@ -6,6 +6,7 @@ import (
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestTask_Create(t *testing.T) {
@@ -53,12 +54,34 @@ func TestTask_SetStatus(t *testing.T) {
 		Model: gorm.Model{ID: 1},
 	}
 	mock.ExpectBegin()
-	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE(.+)status(.+)").WithArgs(1, sqlmock.AnyArg(), 1, taskCanceled).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 	asserts.NoError(task.SetStatus(1))
 	asserts.NoError(mock.ExpectationsWereMet())
 }
 
+func TestTask_IsCanceled(t *testing.T) {
+	asserts := assert.New(t)
+	task := Task{
+		Model: gorm.Model{ID: 1},
+	}
+
+	// 已取消
+	mock.ExpectQuery("SELECT status(.+)").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(taskCanceled))
+	asserts.True(task.IsCanceled())
+
+	// 未取消
+	mock.ExpectQuery("SELECT status(.+)").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(taskProcessing))
+	asserts.False(task.IsCanceled())
+
+	// 查询出错
+	mock.ExpectQuery("SELECT status(.+)").WithArgs(1).WillReturnError(errors.New("error"))
+	asserts.False(task.IsCanceled())
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
 func TestTask_SetProgress(t *testing.T) {
 	asserts := assert.New(t)
 	task := Task{
@@ -102,3 +125,159 @@ func TestGetTasksByStatus(t *testing.T) {
 	a.NoError(mock.ExpectationsWereMet())
 	a.Len(res, 1)
 }
+
+func TestTask_Claim(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		task := Task{Model: gorm.Model{ID: 1}}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").
+			WithArgs(1, "a", taskProcessing, sqlmock.AnyArg(), 1, taskQueued).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		ok, err := task.Claim("a")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.True(ok)
+		asserts.Equal(taskProcessing, task.Status)
+		asserts.Equal("a", task.ClaimedBy)
+		asserts.Equal(1, task.Attempts)
+	}
+
+	// 已被其他实例领取
+	{
+		task := Task{Model: gorm.Model{ID: 1}}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		ok, err := task.Claim("a")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.False(ok)
+		asserts.Equal(0, task.Attempts)
+	}
+
+	// 出错
+	{
+		task := Task{Model: gorm.Model{ID: 1}}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		ok, err := task.Claim("a")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.False(ok)
+	}
+}
+
+func TestTask_Retry(t *testing.T) {
+	asserts := assert.New(t)
+	task := Task{Model: gorm.Model{ID: 1}, Status: taskProcessing, ClaimedBy: "a"}
+
+	// 出错
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(task.Retry(time.Minute))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(task.Retry(time.Minute))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(taskQueued, task.Status)
+		asserts.Empty(task.ClaimedBy)
+		asserts.True(task.NextRunAt.After(time.Now()))
+	}
+}
+
+func TestTask_Touch(t *testing.T) {
+	asserts := assert.New(t)
+	task := Task{Model: gorm.Model{ID: 1}}
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	asserts.NoError(task.Touch())
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestTask_SetPriority(t *testing.T) {
+	asserts := assert.New(t)
+	task := Task{Model: gorm.Model{ID: 1}}
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	asserts.NoError(task.SetPriority(10))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(10, task.Priority)
+}
+
+func TestGetRunnableTasks(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)tasks(.+)ORDER BY priority desc, id asc(.+)").
+		WithArgs(taskQueued, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(1))
+	res := GetRunnableTasks(10)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(res, 2)
+	asserts.EqualValues(2, res[0].ID)
+}
+
+func TestCountProcessingTasks(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT count(.+)tasks(.+)").
+		WithArgs(1, taskProcessing).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	asserts.Equal(3, CountProcessingTasks(1))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestRequeueStaleTasks(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)tasks(.+)").
+		WithArgs("", taskQueued, sqlmock.AnyArg(), taskProcessing, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	count, err := RequeueStaleTasks(time.Now())
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(2, count)
+}
+
+func TestCancelTask(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 限定用户
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)user_id(.+)").
+			WithArgs(taskCanceled, sqlmock.AnyArg(), 1, taskQueued, taskProcessing, 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		ok, err := CancelTask(1, 2)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.True(ok)
+	}
+
+	// 任务不存在或已结束
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").
+			WithArgs(taskCanceled, sqlmock.AnyArg(), 1, taskQueued, taskProcessing).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		ok, err := CancelTask(1, 0)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.False(ok)
+	}
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 访问令牌权限
+const (
+	// ScopeFilesRead 读取文件
+	ScopeFilesRead = "files:read"
+	// ScopeFilesWrite 修改文件
+	ScopeFilesWrite = "files:write"
+	// ScopeShare 管理分享
+	ScopeShare = "share"
+	// ScopeOfflineDownload 管理离线下载
+	ScopeOfflineDownload = "offline_download"
+	// ScopeAdmin 站点管理
+	ScopeAdmin = "admin"
+)
+
+// AccessTokenPrefix 访问令牌前缀，用于区分其他 Bearer 凭证
+const AccessTokenPrefix = "cr_"
+
+// AccessToken 个人访问令牌
+type AccessToken struct {
+	gorm.Model
+	UserID     uint       `gorm:"index:access_token_user_id"`
+	Name       string     // 令牌名称
+	Hash       string     `gorm:"unique_index:access_token_hash" json:"-"` // 令牌 SHA256 散列
+	Hint       string     // 令牌末尾几位，便于用户辨认
+	Scopes     string     // 权限，以逗号分隔
+	ExpiresAt  *time.Time // 过期时间，为空表示永不过期
+	LastUsedAt *time.Time // 最后使用时间
+	LastUsedIP string     // 最后使用 IP
+}
+
+// HashAccessToken 计算令牌的散列值
+func HashAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create 创建令牌
+func (token *AccessToken) Create() (uint, error) {
+	if err := DB.Create(token).Error; err != nil {
+		return 0, err
+	}
+	return token.ID, nil
+}
+
+// HasScope 令牌是否拥有给定权限
+func (token *AccessToken) HasScope(scope string) bool {
+	for _, s := range strings.Split(token.Scopes, ",") {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired 令牌是否已过期
+func (token *AccessToken) Expired() bool {
+	return token.ExpiresAt != nil && token.ExpiresAt.Before(time.Now())
+}
+
+// Touch 记录令牌使用情况
+func (token *AccessToken) Touch(ip string) error {
+	now := time.Now()
+	token.LastUsedAt = &now
+	token.LastUsedIP = ip
+	return DB.Model(token).UpdateColumns(map[string]interface{}{
+		"last_used_at": now,
+		"last_used_ip": ip,
+	}).Error
+}
+
+// GetAccessTokenByToken 根据令牌明文查找令牌
+func GetAccessTokenByToken(token string) (*AccessToken, error) {
+	res := &AccessToken{}
+	err := DB.Where("hash = ?", HashAccessToken(token)).First(res).Error
+	return res, err
+}
+
+// ListAccessTokens 列出用户的所有令牌
+func ListAccessTokens(uid uint) []AccessToken {
+	var tokens []AccessToken
+	DB.Where("user_id = ?", uid).Order("created_at desc").Find(&tokens)
+	return tokens
+}
+
+// DeleteAccessTokenByID 根据令牌ID和UID吊销令牌
+func DeleteAccessTokenByID(id, uid uint) error {
+	return DB.Where("user_id = ? and id = ?", uid, id).Delete(&AccessToken{}).Error
+}
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessToken_Create(t *testing.T) {
+	asserts := assert.New(t)
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		token := AccessToken{Name: "test"}
+		id, err := token.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(1, id)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		token := AccessToken{Name: "test"}
+		id, err := token.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.EqualValues(0, id)
+	}
+}
+
+func TestAccessToken_HasScope(t *testing.T) {
+	asserts := assert.New(t)
+
+	token := AccessToken{}
+	asserts.False(token.HasScope(ScopeFilesRead))
+
+	token.Scopes = "files:read,share"
+	asserts.True(token.HasScope(ScopeFilesRead))
+	asserts.True(token.HasScope(ScopeShare))
+	asserts.False(token.HasScope(ScopeFilesWrite))
+	asserts.False(token.HasScope(ScopeAdmin))
+}
+
+func TestAccessToken_Expired(t *testing.T) {
+	asserts := assert.New(t)
+
+	token := AccessToken{}
+	asserts.False(token.Expired())
+
+	future := time.Now().Add(time.Hour)
+	token.ExpiresAt = &future
+	asserts.False(token.Expired())
+
+	past := time.Now().Add(-time.Hour)
+	token.ExpiresAt = &past
+	asserts.True(token.Expired())
+}
+
+func TestHashAccessToken(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.Len(HashAccessToken("cr_token"), 64)
+	asserts.Equal(HashAccessToken("cr_token"), HashAccessToken("cr_token"))
+	asserts.NotEqual(HashAccessToken("cr_token"), HashAccessToken("cr_token2"))
+}
+
+func TestGetAccessTokenByToken(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)access_tokens(.+)").WithArgs(HashAccessToken("cr_token")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	res, err := GetAccessTokenByToken("cr_token")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(1, res.ID)
+}
+
+func TestListAccessTokens(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)access_tokens(.+)").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	res := ListAccessTokens(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(res, 2)
+}
+
+func TestDeleteAccessTokenByID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)access_tokens(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	asserts.NoError(DeleteAccessTokenByID(1, 1))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
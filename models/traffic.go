@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Traffic 用户某个自然月内的下载流量
+type Traffic struct {
+	gorm.Model
+	UserID uint   `gorm:"unique_index:idx_traffic_user_month"`
+	Month  string `gorm:"size:7;unique_index:idx_traffic_user_month"` // 月份，格式为 2006-01
+	Used   uint64 // 已用流量，单位为字节
+}
+
+// TrafficMonth 返回给定时间所属的计费月份
+func TrafficMonth(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+// AddTraffic 为用户本月的流量累加 size 字节
+func AddTraffic(uid uint, size uint64) error {
+	if size == 0 {
+		return nil
+	}
+
+	month := TrafficMonth(time.Now())
+	update := func() *gorm.DB {
+		return DB.Model(&Traffic{}).Where("user_id = ? and month = ?", uid, month).
+			UpdateColumn("used", gorm.Expr("used + ?", size))
+	}
+
+	res := update()
+	if res.Error != nil || res.RowsAffected > 0 {
+		return res.Error
+	}
+
+	// 本月尚无记录时创建，并发创建冲突时回退为累加
+	if err := DB.Create(&Traffic{UserID: uid, Month: month, Used: size}).Error; err != nil {
+		return update().Error
+	}
+
+	return nil
+}
+
+// GetTraffic 获取用户在给定月份的已用流量
+func GetTraffic(uid uint, month string) uint64 {
+	var traffic Traffic
+	DB.Where("user_id = ? and month = ?", uid, month).First(&traffic)
+	return traffic.Used
+}
+
+// ListTraffic 列出用户最近若干个月的流量记录
+func ListTraffic(uid uint, limit int) []Traffic {
+	var traffics []Traffic
+	DB.Where("user_id = ?", uid).Order("month desc").Limit(limit).Find(&traffics)
+	return traffics
+}
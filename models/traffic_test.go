@@ -0,0 +1,102 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrafficMonth(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.Equal("2021-03", TrafficMonth(time.Date(2021, 3, 31, 23, 0, 0, 0, time.Local)))
+}
+
+func TestAddTraffic(t *testing.T) {
+	asserts := assert.New(t)
+	month := TrafficMonth(time.Now())
+
+	// 大小为 0
+	{
+		asserts.NoError(AddTraffic(1, 0))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 已有记录，直接累加
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)traffics(.+)used(.+)").WithArgs(10, 1, month).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(AddTraffic(1, 10))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 无记录，创建
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)traffics(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)traffics(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(AddTraffic(1, 10))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 并发创建冲突，回退为累加
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)traffics(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)traffics(.+)").WillReturnError(errors.New("duplicate"))
+		mock.ExpectRollback()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)traffics(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(AddTraffic(1, 10))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 累加出错
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)traffics(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(AddTraffic(1, 10))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetTraffic(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 有记录
+	{
+		mock.ExpectQuery("SELECT(.+)traffics(.+)").WithArgs(1, "2021-03").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "used"}).AddRow(1, 20))
+		asserts.EqualValues(20, GetTraffic(1, "2021-03"))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 无记录
+	{
+		mock.ExpectQuery("SELECT(.+)traffics(.+)").WithArgs(1, "2021-03").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "used"}))
+		asserts.EqualValues(0, GetTraffic(1, "2021-03"))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestListTraffic(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)traffics(.+)").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "month"}).AddRow(2, "2021-03").AddRow(1, "2021-02"))
+	res := ListTraffic(1, 12)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(res, 2)
+}
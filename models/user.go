@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"strings"
+	"time"
 
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/jinzhu/gorm"
@@ -24,6 +25,14 @@ const (
 	OveruseBaned
 )
 
+// 超额使用宽限期结束后的处理方式
+const (
+	// OveruseActionReadOnly 限制为只读，仍可登录并删除文件
+	OveruseActionReadOnly = "readonly"
+	// OveruseActionBan 封禁账户
+	OveruseActionBan = "ban"
+)
+
 // User 用户模型
 type User struct {
 	// 表字段
@@ -39,6 +48,14 @@ type User struct {
 	Options   string `json:"-" gorm:"size:4294967295"`
 	Authn     string `gorm:"size:4294967295"`
 
+	// 限时用户组
+	PreviousGroupID uint       // 限时升级前所在的用户组ID
+	GroupExpires    *time.Time // 限时用户组的到期时间，为空表示不限时
+
+	// 超额使用
+	OveruseSince *time.Time // 开始超出可用容量的时间，为空表示未超出
+	ReadOnly     bool       // 宽限期结束后被限制为只读
+
 	// 关联模型
 	Group  Group  `gorm:"save_associations:false:false"`
 	Policy Policy `gorm:"PRELOAD:false,association_autoupdate:false"`
@@ -148,13 +165,150 @@ func (user *User) IncreaseStorageWithoutCheck(size uint64) {
 
 // GetRemainingCapacity 获取剩余配额
 func (user *User) GetRemainingCapacity() uint64 {
-	total := user.Group.MaxStorage
+	total := user.GetTotalCapacity()
 	if total <= user.Storage {
 		return 0
 	}
 	return total - user.Storage
 }
 
+// GetTotalCapacity 获取用户组容量与有效容量包之和
+func (user *User) GetTotalCapacity() uint64 {
+	return user.Group.MaxStorage + user.GetAvailablePackSize()
+}
+
+// GetAvailablePackSize 获取用户有效容量包的总大小
+func (user *User) GetAvailablePackSize() uint64 {
+	return GetAvailablePackSize(user.ID)
+}
+
+// UpgradeGroup 将用户限时升级到给定用户组，已处于同一限时用户组时顺延有效期
+func (user *User) UpgradeGroup(tx *gorm.DB, groupID uint, duration time.Duration) error {
+	now := time.Now()
+	expires := now.Add(duration)
+	previous := user.PreviousGroupID
+	if user.GroupExpires == nil {
+		previous = user.GroupID
+	} else if user.GroupID == groupID && user.GroupExpires.After(now) {
+		expires = user.GroupExpires.Add(duration)
+	}
+
+	if err := tx.Model(user).Updates(map[string]interface{}{
+		"group_id":          groupID,
+		"previous_group_id": previous,
+		"group_expires":     expires,
+	}).Error; err != nil {
+		return err
+	}
+
+	user.GroupID = groupID
+	user.PreviousGroupID = previous
+	user.GroupExpires = &expires
+	return nil
+}
+
+// DowngradeGroup 限时用户组到期，恢复到升级前的用户组
+func (user *User) DowngradeGroup() error {
+	previous := user.PreviousGroupID
+	if previous == 0 {
+		previous = uint(GetIntSetting("default_group", 2))
+	}
+
+	if err := DB.Model(user).Updates(map[string]interface{}{
+		"group_id":          previous,
+		"previous_group_id": 0,
+		"group_expires":     gorm.Expr("NULL"),
+	}).Error; err != nil {
+		return err
+	}
+
+	user.GroupID = previous
+	user.PreviousGroupID = 0
+	user.GroupExpires = nil
+	user.Group, _ = GetGroupByID(previous)
+	return nil
+}
+
+// UpdateOveruseStatus 根据已用容量推进超额处理：首次超出时开始宽限期，宽限期结束后
+// 按 action 将账户限制为只读或封禁，容量恢复后解除限制。返回本次是否开始了新的宽限期
+func (user *User) UpdateOveruseStatus(grace time.Duration, action string) (bool, error) {
+	if user.Status != Active && user.Status != OveruseBaned {
+		return false, nil
+	}
+
+	now := time.Now()
+	if user.Storage <= user.GetTotalCapacity() {
+		if user.OveruseSince == nil && !user.ReadOnly && user.Status == Active {
+			return false, nil
+		}
+
+		if err := DB.Model(user).Updates(map[string]interface{}{
+			"overuse_since": gorm.Expr("NULL"),
+			"read_only":     false,
+			"status":        Active,
+		}).Error; err != nil {
+			return false, err
+		}
+
+		user.OveruseSince = nil
+		user.ReadOnly = false
+		user.Status = Active
+		return false, nil
+	}
+
+	// 开始宽限期
+	if user.OveruseSince == nil {
+		if err := DB.Model(user).Update("overuse_since", now).Error; err != nil {
+			return false, err
+		}
+		user.OveruseSince = &now
+		return true, nil
+	}
+
+	if now.Before(user.OveruseSince.Add(grace)) || user.ReadOnly || user.Status == OveruseBaned {
+		return false, nil
+	}
+
+	// 宽限期结束
+	if action == OveruseActionBan {
+		if err := DB.Model(user).Update("status", OveruseBaned).Error; err != nil {
+			return false, err
+		}
+		user.Status = OveruseBaned
+	} else {
+		if err := DB.Model(user).Update("read_only", true).Error; err != nil {
+			return false, err
+		}
+		user.ReadOnly = true
+	}
+
+	return false, nil
+}
+
+// GetOveruseCandidates 列出可能超出可用容量或正处于超额处理中的用户。容量包只会增加
+// 可用容量，已用容量未超出用户组容量的用户无需检查
+func GetOveruseCandidates() []User {
+	var groups []Group
+	DB.Find(&groups)
+
+	tx := DB.Set("gorm:auto_preload", true).
+		Where("overuse_since is not null or read_only = ? or status = ?", true, OveruseBaned)
+	for _, group := range groups {
+		tx = tx.Or("group_id = ? and storage > ?", group.ID, group.MaxStorage)
+	}
+
+	var users []User
+	tx.Find(&users)
+	return users
+}
+
+// GetGroupExpiredUsers 列出限时用户组已到期的用户
+func GetGroupExpiredUsers() []User {
+	var users []User
+	DB.Where("group_expires is not null and group_expires <= ?", time.Now()).Find(&users)
+	return users
+}
+
 // GetPolicyID 获取用户当前的存储策略ID
 func (user *User) GetPolicyID(prefer uint) uint {
 	if len(user.Group.PolicyList) > 0 {
@@ -3,6 +3,7 @@ package model
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
@@ -436,3 +437,284 @@ func TestUser_UpdateOptions(t *testing.T) {
 	asserts.NoError(user.UpdateOptions())
 	asserts.NoError(mock.ExpectationsWereMet())
 }
+
+func TestUser_GetRemainingCapacityWithPacks(t *testing.T) {
+	asserts := assert.New(t)
+	newUser := NewUser()
+	newUser.ID = 5
+	newUser.Group.MaxStorage = 100
+	newUser.Storage = 120
+	cache.Set("pack_size_5", uint64(50), 0)
+
+	asserts.EqualValues(150, newUser.GetTotalCapacity())
+	asserts.EqualValues(30, newUser.GetRemainingCapacity())
+}
+
+func TestUser_UpgradeGroup(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 首次升级，记录原用户组
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		user := User{GroupID: 2}
+		asserts.NoError(user.UpgradeGroup(DB, 4, time.Hour))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(4, user.GroupID)
+		asserts.EqualValues(2, user.PreviousGroupID)
+		asserts.WithinDuration(time.Now().Add(time.Hour), *user.GroupExpires, time.Minute)
+	}
+
+	// 同一用户组续期
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		expires := time.Now().Add(time.Hour)
+		user := User{GroupID: 4, PreviousGroupID: 2, GroupExpires: &expires}
+		asserts.NoError(user.UpgradeGroup(DB, 4, time.Hour))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(2, user.PreviousGroupID)
+		asserts.WithinDuration(time.Now().Add(2*time.Hour), *user.GroupExpires, time.Minute)
+	}
+
+	// 更换为其他限时用户组，保留原用户组
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		expires := time.Now().Add(time.Hour)
+		user := User{GroupID: 4, PreviousGroupID: 2, GroupExpires: &expires}
+		asserts.NoError(user.UpgradeGroup(DB, 5, time.Hour))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(5, user.GroupID)
+		asserts.EqualValues(2, user.PreviousGroupID)
+		asserts.WithinDuration(time.Now().Add(time.Hour), *user.GroupExpires, time.Minute)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		user := User{GroupID: 2}
+		asserts.Error(user.UpgradeGroup(DB, 4, time.Hour))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestUser_DowngradeGroup(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 恢复原用户组
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)groups(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "max_storage"}).AddRow(2, 10))
+		expires := time.Now()
+		user := User{GroupID: 4, PreviousGroupID: 2, GroupExpires: &expires}
+		asserts.NoError(user.DowngradeGroup())
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(2, user.GroupID)
+		asserts.EqualValues(10, user.Group.MaxStorage)
+		asserts.Nil(user.GroupExpires)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		user := User{GroupID: 4, PreviousGroupID: 2}
+		asserts.Error(user.DowngradeGroup())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestUser_UpdateOveruseStatus(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("pack_size_6", uint64(0), 0)
+	grace := time.Hour
+	started := time.Now().Add(-2 * time.Hour)
+
+	// 未超出，无需处理
+	{
+		user := User{Status: Active, Storage: 10}
+		user.ID = 6
+		user.Group.MaxStorage = 10
+		notify, err := user.UpdateOveruseStatus(grace, OveruseActionReadOnly)
+		asserts.NoError(err)
+		asserts.False(notify)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 被管理员封禁的用户不受影响
+	{
+		user := User{Status: Baned, Storage: 11}
+		user.ID = 6
+		user.Group.MaxStorage = 10
+		notify, err := user.UpdateOveruseStatus(grace, OveruseActionBan)
+		asserts.NoError(err)
+		asserts.False(notify)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(Baned, user.Status)
+	}
+
+	// 超出，开始宽限期
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)overuse_since(.+)").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		user := User{Status: Active, Storage: 11}
+		user.ID = 6
+		user.Group.MaxStorage = 10
+		notify, err := user.UpdateOveruseStatus(grace, OveruseActionReadOnly)
+		asserts.NoError(err)
+		asserts.True(notify)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(user.OveruseSince)
+		asserts.False(user.ReadOnly)
+	}
+
+	// 开始宽限期出错
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		user := User{Status: Active, Storage: 11}
+		user.ID = 6
+		user.Group.MaxStorage = 10
+		notify, err := user.UpdateOveruseStatus(grace, OveruseActionReadOnly)
+		asserts.Error(err)
+		asserts.False(notify)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 宽限期内
+	{
+		since := time.Now()
+		user := User{Status: Active, Storage: 11, OveruseSince: &since}
+		user.ID = 6
+		user.Group.MaxStorage = 10
+		notify, err := user.UpdateOveruseStatus(grace, OveruseActionReadOnly)
+		asserts.NoError(err)
+		asserts.False(notify)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.False(user.ReadOnly)
+	}
+
+	// 宽限期结束，设为只读
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)read_only(.+)").WithArgs(true, sqlmock.AnyArg(), 6).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		user := User{Status: Active, Storage: 11, OveruseSince: &started}
+		user.ID = 6
+		user.Group.MaxStorage = 10
+		notify, err := user.UpdateOveruseStatus(grace, OveruseActionReadOnly)
+		asserts.NoError(err)
+		asserts.False(notify)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(user.ReadOnly)
+		asserts.Equal(Active, user.Status)
+	}
+
+	// 已设为只读，无需重复处理
+	{
+		user := User{Status: Active, Storage: 11, OveruseSince: &started, ReadOnly: true}
+		user.ID = 6
+		user.Group.MaxStorage = 10
+		_, err := user.UpdateOveruseStatus(grace, OveruseActionReadOnly)
+		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 宽限期结束，封禁
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)status(.+)").WithArgs(OveruseBaned, sqlmock.AnyArg(), 6).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		user := User{Status: Active, Storage: 11, OveruseSince: &started}
+		user.ID = 6
+		user.Group.MaxStorage = 10
+		notify, err := user.UpdateOveruseStatus(grace, OveruseActionBan)
+		asserts.NoError(err)
+		asserts.False(notify)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(OveruseBaned, user.Status)
+	}
+
+	// 处理出错
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		user := User{Status: Active, Storage: 11, OveruseSince: &started}
+		user.ID = 6
+		user.Group.MaxStorage = 10
+		_, err := user.UpdateOveruseStatus(grace, OveruseActionBan)
+		asserts.Error(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 容量恢复，解除限制
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		user := User{Status: OveruseBaned, Storage: 10, OveruseSince: &started, ReadOnly: true}
+		user.ID = 6
+		user.Group.MaxStorage = 10
+		notify, err := user.UpdateOveruseStatus(grace, OveruseActionBan)
+		asserts.NoError(err)
+		asserts.False(notify)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(Active, user.Status)
+		asserts.False(user.ReadOnly)
+		asserts.Nil(user.OveruseSince)
+	}
+
+	// 解除限制出错
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		user := User{Status: Active, Storage: 10, ReadOnly: true}
+		user.ID = 6
+		user.Group.MaxStorage = 10
+		_, err := user.UpdateOveruseStatus(grace, OveruseActionBan)
+		asserts.Error(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetOveruseCandidates(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)groups(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "max_storage"}).AddRow(1, 10).AddRow(2, 20))
+	mock.ExpectQuery("SELECT(.+)users(.+)overuse_since(.+)").
+		WithArgs(true, OveruseBaned, 1, 10, 2, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "group_id"}).AddRow(1, 1))
+	mock.ExpectQuery("SELECT(.+)groups(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	users := GetOveruseCandidates()
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(users, 1)
+	asserts.EqualValues(1, users[0].Group.ID)
+}
+
+func TestGetGroupExpiredUsers(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)users(.+)group_expires(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	asserts.Len(GetGroupExpiredUsers(), 1)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
@@ -1,16 +1,36 @@
 package model
 
 import (
+	"net"
+	"strings"
+	"time"
+
 	"github.com/jinzhu/gorm"
 )
 
+// WebDAV 账号权限限制
+const (
+	// WebdavReadOnly 只读
+	WebdavReadOnly = "readonly"
+	// WebdavNoDelete 禁止删除及覆盖已有对象
+	WebdavNoDelete = "nodelete"
+	// WebdavUploadOnly 仅上传，禁止下载及修改已有对象
+	WebdavUploadOnly = "uploadonly"
+)
+
 // Webdav 应用账户
 type Webdav struct {
 	gorm.Model
-	Name     string // 应用名称
-	Password string `gorm:"unique_index:password_only_on"` // 应用密码
-	UserID   uint   `gorm:"unique_index:password_only_on"` // 用户ID
-	Root     string `gorm:"type:text"`                     // 根目录
+	Name           string     // 应用名称
+	Password       string     `gorm:"unique_index:password_only_on"` // 应用密码
+	UserID         uint       `gorm:"unique_index:password_only_on"` // 用户ID
+	Root           string     `gorm:"type:text"`                     // 根目录
+	Permissions    string     // 权限限制，以逗号分隔，为空表示不限制
+	ExpiresAt      *time.Time // 过期时间，为空表示永不过期
+	AllowedIPs     string     `gorm:"type:text"` // 允许访问的 IP 或 CIDR，以逗号分隔，为空表示不限制
+	LastUsedAt     *time.Time // 最后使用时间
+	LastUsedIP     string     // 最后使用 IP
+	LastUsedClient string     `gorm:"type:text"` // 最后使用的客户端
 }
 
 // Create 创建账户
@@ -21,6 +41,66 @@ func (webdav *Webdav) Create() (uint, error) {
 	return webdav.ID, nil
 }
 
+// HasPermission 账户是否设有给定权限限制
+func (webdav *Webdav) HasPermission(permission string) bool {
+	for _, p := range strings.Split(webdav.Permissions, ",") {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired 账户是否已过期
+func (webdav *Webdav) Expired() bool {
+	return webdav.ExpiresAt != nil && webdav.ExpiresAt.Before(time.Now())
+}
+
+// AllowsIP 是否允许从给定 IP 访问
+func (webdav *Webdav) AllowsIP(ip string) bool {
+	if webdav.AllowedIPs == "" {
+		return true
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, rule := range strings.Split(webdav.AllowedIPs, ",") {
+		if _, network, err := net.ParseCIDR(rule); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+		} else if allowed := net.ParseIP(rule); allowed != nil && allowed.Equal(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Touch 记录账户使用情况
+func (webdav *Webdav) Touch(ip, client string) error {
+	now := time.Now()
+	webdav.LastUsedAt = &now
+	webdav.LastUsedIP = ip
+	webdav.LastUsedClient = client
+	return DB.Model(webdav).UpdateColumns(map[string]interface{}{
+		"last_used_at":     now,
+		"last_used_ip":     ip,
+		"last_used_client": client,
+	}).Error
+}
+
+// IsValidWebdavIPRule 返回 rule 是否为合法的 IP 或 CIDR
+func IsValidWebdavIPRule(rule string) bool {
+	if _, _, err := net.ParseCIDR(rule); err == nil {
+		return true
+	}
+	return net.ParseIP(rule) != nil
+}
+
 // GetWebdavByPassword 根据密码和用户查找Webdav应用
 func GetWebdavByPassword(password string, uid uint) (*Webdav, error) {
 	webdav := &Webdav{}
@@ -0,0 +1,120 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// WebdavLock WebDAV 锁，保存在数据库中以便在多个实例间共享并在重启后保留
+type WebdavLock struct {
+	Token     string `gorm:"primary_key;size:64"`
+	UserID    uint   `gorm:"index:webdav_lock_user_id"`
+	Root      string `gorm:"type:text"` // 锁定对象在用户文件系统中的完整路径
+	ZeroDepth bool
+	OwnerXML  string        `gorm:"type:text"`
+	Duration  time.Duration // 锁的有效时长，负数表示永不过期
+	ExpiresAt *time.Time    `gorm:"index:webdav_lock_expires_at"`
+	// HeldUntil 锁正被某个请求使用时的占用期限，实例异常退出后占用在期限后自动失效
+	HeldUntil *time.Time
+	CreatedAt time.Time
+}
+
+// Covers 返回锁是否作用于给定完整路径的对象
+func (lock *WebdavLock) Covers(name string) bool {
+	return lock.Root == name || (!lock.ZeroDepth && isSubPath(name, lock.Root))
+}
+
+// Within 返回锁定的对象是否位于给定完整路径的目录之下
+func (lock *WebdavLock) Within(dir string) bool {
+	return isSubPath(lock.Root, dir)
+}
+
+// IsExpired 返回锁是否已过期
+func (lock *WebdavLock) IsExpired(now time.Time) bool {
+	return lock.ExpiresAt != nil && !now.Before(*lock.ExpiresAt)
+}
+
+// IsHeld 返回锁是否正被某个请求占用
+func (lock *WebdavLock) IsHeld(now time.Time) bool {
+	return lock.HeldUntil != nil && now.Before(*lock.HeldUntil)
+}
+
+// Hold 占用锁直至 until，锁已被占用时返回 false
+func (lock *WebdavLock) Hold(now, until time.Time) (bool, error) {
+	res := DB.Model(&WebdavLock{}).
+		Where("token = ? and (held_until is null or held_until <= ?)", lock.Token, now).
+		Update("held_until", until)
+	if res.Error != nil {
+		return false, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return false, nil
+	}
+
+	lock.HeldUntil = &until
+	return true, nil
+}
+
+// Unhold 解除对锁的占用
+func (lock *WebdavLock) Unhold() error {
+	lock.HeldUntil = nil
+	return DB.Model(&WebdavLock{}).Where("token = ?", lock.Token).Update("held_until", nil).Error
+}
+
+// Refresh 以新的有效时长刷新锁
+func (lock *WebdavLock) Refresh(now time.Time, duration time.Duration) error {
+	lock.Duration = duration
+	lock.ExpiresAt = nil
+	if duration >= 0 {
+		expires := now.Add(duration)
+		lock.ExpiresAt = &expires
+	}
+
+	return DB.Model(&WebdavLock{}).Where("token = ?", lock.Token).
+		Updates(map[string]interface{}{"duration": lock.Duration, "expires_at": lock.ExpiresAt}).Error
+}
+
+// Create 创建锁
+func (lock *WebdavLock) Create() error {
+	return DB.Create(lock).Error
+}
+
+// Delete 删除锁
+func (lock *WebdavLock) Delete() error {
+	return DB.Where("token = ?", lock.Token).Delete(&WebdavLock{}).Error
+}
+
+// GetWebdavLockByToken 根据令牌查找用户未过期的锁
+func GetWebdavLockByToken(token string, uid uint, now time.Time) (*WebdavLock, error) {
+	var lock WebdavLock
+	err := DB.Where("token = ? and user_id = ? and (expires_at is null or expires_at > ?)", token, uid, now).
+		First(&lock).Error
+	return &lock, err
+}
+
+// GetWebdavLocks 列出用户所有未过期的锁
+func GetWebdavLocks(uid uint, now time.Time) ([]WebdavLock, error) {
+	var locks []WebdavLock
+	err := DB.Where("user_id = ? and (expires_at is null or expires_at > ?)", uid, now).Find(&locks).Error
+	return locks, err
+}
+
+// DeleteExpiredWebdavLocks 清理已过期的锁，uid 为 0 时清理所有用户的锁
+func DeleteExpiredWebdavLocks(uid uint, now time.Time) error {
+	tx := DB.Where("expires_at <= ?", now)
+	if uid > 0 {
+		tx = tx.Where("user_id = ?", uid)
+	}
+
+	return tx.Delete(&WebdavLock{}).Error
+}
+
+// isSubPath 返回 name 是否为 dir 之下的路径
+func isSubPath(name, dir string) bool {
+	if dir == "/" {
+		return name != "/"
+	}
+
+	return strings.HasPrefix(name, dir+"/")
+}
@@ -0,0 +1,178 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebdavLock_Covers(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 无限深度
+	{
+		lock := WebdavLock{Root: "/a"}
+		asserts.True(lock.Covers("/a"))
+		asserts.True(lock.Covers("/a/b/c"))
+		asserts.False(lock.Covers("/ab"))
+		asserts.False(lock.Covers("/"))
+	}
+
+	// 零深度
+	{
+		lock := WebdavLock{Root: "/a", ZeroDepth: true}
+		asserts.True(lock.Covers("/a"))
+		asserts.False(lock.Covers("/a/b"))
+	}
+
+	// 根目录
+	{
+		lock := WebdavLock{Root: "/"}
+		asserts.True(lock.Covers("/"))
+		asserts.True(lock.Covers("/a"))
+	}
+}
+
+func TestWebdavLock_Within(t *testing.T) {
+	asserts := assert.New(t)
+	lock := WebdavLock{Root: "/a/b"}
+	asserts.True(lock.Within("/a"))
+	asserts.True(lock.Within("/"))
+	asserts.False(lock.Within("/a/b"))
+	asserts.False(lock.Within("/a/bc"))
+}
+
+func TestWebdavLock_State(t *testing.T) {
+	asserts := assert.New(t)
+	now := time.Now()
+	past, future := now.Add(-time.Second), now.Add(time.Second)
+
+	asserts.False((&WebdavLock{}).IsExpired(now))
+	asserts.True((&WebdavLock{ExpiresAt: &past}).IsExpired(now))
+	asserts.False((&WebdavLock{ExpiresAt: &future}).IsExpired(now))
+
+	asserts.False((&WebdavLock{}).IsHeld(now))
+	asserts.False((&WebdavLock{HeldUntil: &past}).IsHeld(now))
+	asserts.True((&WebdavLock{HeldUntil: &future}).IsHeld(now))
+}
+
+func TestWebdavLock_Hold(t *testing.T) {
+	asserts := assert.New(t)
+	now := time.Now()
+	until := now.Add(time.Minute)
+
+	// 成功
+	{
+		lock := &WebdavLock{Token: "t"}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)held_until(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		ok, err := lock.Hold(now, until)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.True(ok)
+		asserts.True(lock.IsHeld(now))
+	}
+
+	// 已被占用
+	{
+		lock := &WebdavLock{Token: "t"}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)held_until(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		ok, err := lock.Hold(now, until)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.False(ok)
+	}
+
+	// 失败
+	{
+		lock := &WebdavLock{Token: "t"}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)held_until(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		ok, err := lock.Hold(now, until)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.False(ok)
+	}
+}
+
+func TestWebdavLock_Refresh(t *testing.T) {
+	asserts := assert.New(t)
+	now := time.Now()
+
+	// 有限时长
+	{
+		lock := &WebdavLock{Token: "t"}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(lock.Refresh(now, time.Minute))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(now.Add(time.Minute), *lock.ExpiresAt)
+	}
+
+	// 永不过期
+	{
+		lock := &WebdavLock{Token: "t"}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(lock.Refresh(now, -1))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(lock.ExpiresAt)
+	}
+}
+
+func TestGetWebdavLocks(t *testing.T) {
+	asserts := assert.New(t)
+	now := time.Now()
+
+	// 根据令牌查找
+	{
+		mock.ExpectQuery("SELECT(.+)token(.+)user_id(.+)expires_at(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"token", "root"}).AddRow("t", "/a"))
+		lock, err := GetWebdavLockByToken("t", 1, now)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal("/a", lock.Root)
+	}
+
+	// 列出用户的锁
+	{
+		mock.ExpectQuery("SELECT(.+)user_id(.+)expires_at(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"token"}).AddRow("t1").AddRow("t2"))
+		locks, err := GetWebdavLocks(1, now)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(locks, 2)
+	}
+}
+
+func TestDeleteExpiredWebdavLocks(t *testing.T) {
+	asserts := assert.New(t)
+	now := time.Now()
+
+	// 指定用户
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)expires_at(.+)user_id(.+)").WithArgs(now, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(DeleteExpiredWebdavLocks(1, now))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 所有用户
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)expires_at(.+)").WithArgs(now).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(DeleteExpiredWebdavLocks(0, now))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
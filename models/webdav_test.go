@@ -5,6 +5,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestWebdav_Create(t *testing.T) {
@@ -58,3 +59,56 @@ func TestDeleteWebDAVAccountByID(t *testing.T) {
 	DeleteWebDAVAccountByID(1, 1)
 	asserts.NoError(mock.ExpectationsWereMet())
 }
+
+func TestWebdav_HasPermission(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.False((&Webdav{}).HasPermission(WebdavReadOnly))
+	account := Webdav{Permissions: WebdavNoDelete + "," + WebdavUploadOnly}
+	asserts.True(account.HasPermission(WebdavNoDelete))
+	asserts.True(account.HasPermission(WebdavUploadOnly))
+	asserts.False(account.HasPermission(WebdavReadOnly))
+}
+
+func TestWebdav_Expired(t *testing.T) {
+	asserts := assert.New(t)
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	asserts.False((&Webdav{}).Expired())
+	asserts.True((&Webdav{ExpiresAt: &past}).Expired())
+	asserts.False((&Webdav{ExpiresAt: &future}).Expired())
+}
+
+func TestWebdav_AllowsIP(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 不限制
+	asserts.True((&Webdav{}).AllowsIP("1.2.3.4"))
+
+	account := Webdav{AllowedIPs: "192.168.1.0/24,10.0.0.1,fd00::/8"}
+	asserts.True(account.AllowsIP("192.168.1.20"))
+	asserts.True(account.AllowsIP("10.0.0.1"))
+	asserts.True(account.AllowsIP("fd00::1"))
+	asserts.False(account.AllowsIP("10.0.0.2"))
+	asserts.False(account.AllowsIP("invalid"))
+}
+
+func TestWebdav_Touch(t *testing.T) {
+	asserts := assert.New(t)
+	account := Webdav{}
+	account.ID = 1
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)webdavs(.+)last_used_at(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	asserts.NoError(account.Touch("1.2.3.4", "client"))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NotNil(account.LastUsedAt)
+	asserts.Equal("client", account.LastUsedClient)
+}
+
+func TestIsValidWebdavIPRule(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.True(IsValidWebdavIPRule("10.0.0.1"))
+	asserts.True(IsValidWebdavIPRule("10.0.0.0/8"))
+	asserts.True(IsValidWebdavIPRule("::1"))
+	asserts.False(IsValidWebdavIPRule("10.0.0.0/33"))
+	asserts.False(IsValidWebdavIPRule("example.com"))
+}
@@ -0,0 +1,185 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Webhook 投递状态
+const (
+	// WebhookDeliveryPending 等待投递
+	WebhookDeliveryPending = iota
+	// WebhookDeliverySuccess 投递成功
+	WebhookDeliverySuccess
+	// WebhookDeliveryFailed 投递失败且不再重试
+	WebhookDeliveryFailed
+)
+
+// Webhook 事件订阅
+type Webhook struct {
+	gorm.Model
+	UserID  uint   `gorm:"index:webhook_user_id"` // 创建者UID
+	Global  bool   // 是否为管理员订阅，接收所有用户的事件
+	URL     string `gorm:"type:text"` // 投递地址
+	Secret  string `json:"-"`         // 签名密钥
+	Events  string `gorm:"type:text"` // 订阅的事件，以逗号分隔，为空表示订阅所有事件
+	Enabled bool   // 是否启用
+}
+
+// WebhookDelivery Webhook 投递记录
+type WebhookDelivery struct {
+	gorm.Model
+	WebhookID    uint      `gorm:"index:webhook_delivery_webhook_id"`
+	Event        string    // 事件类型
+	Payload      string    `gorm:"type:text"` // 投递正文
+	Status       int       // 投递状态
+	Attempts     int       // 已尝试次数
+	ResponseCode int       // 最后一次投递的响应状态码
+	Error        string    `gorm:"type:text"` // 最后一次投递的错误信息
+	NextRetry    time.Time // 下次重试时间
+}
+
+// Create 创建订阅
+func (webhook *Webhook) Create() (uint, error) {
+	if err := DB.Create(webhook).Error; err != nil {
+		return 0, err
+	}
+	return webhook.ID, nil
+}
+
+// Update 更新订阅属性
+func (webhook *Webhook) Update(props map[string]interface{}) error {
+	return DB.Model(webhook).Updates(props).Error
+}
+
+// Subscribed 是否订阅了给定事件
+func (webhook *Webhook) Subscribed(event string) bool {
+	if webhook.Events == "" {
+		return true
+	}
+
+	for _, subscribed := range strings.Split(webhook.Events, ",") {
+		if subscribed == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetWebhookByID 根据ID和UID查找订阅，uid 为 0 时不限制创建者
+func GetWebhookByID(id, uid uint) (*Webhook, error) {
+	webhook := &Webhook{}
+	dbChain := DB.Where("id = ?", id)
+	if uid > 0 {
+		dbChain = dbChain.Where("user_id = ?", uid)
+	}
+	res := dbChain.First(webhook)
+	return webhook, res.Error
+}
+
+// ListWebhooks 列出用户创建的订阅
+func ListWebhooks(uid uint) []Webhook {
+	var webhooks []Webhook
+	DB.Where("user_id = ? and global = ?", uid, false).Order("created_at desc").Find(&webhooks)
+	return webhooks
+}
+
+// ListGlobalWebhooks 列出所有管理员订阅
+func ListGlobalWebhooks() []Webhook {
+	var webhooks []Webhook
+	DB.Where("global = ?", true).Order("created_at desc").Find(&webhooks)
+	return webhooks
+}
+
+// GetSubscribedWebhooks 获取应接收给定用户事件的已启用订阅
+func GetSubscribedWebhooks(uid uint) []Webhook {
+	var webhooks []Webhook
+	DB.Where("enabled = ? and (user_id = ? or global = ?)", true, uid, true).Find(&webhooks)
+	return webhooks
+}
+
+// DeleteWebhookByID 根据ID和UID删除订阅及其投递记录，uid 为 0 时不限制创建者
+func DeleteWebhookByID(id, uid uint) error {
+	webhook, err := GetWebhookByID(id, uid)
+	if err != nil {
+		return err
+	}
+
+	if err := DB.Where("webhook_id = ?", webhook.ID).Delete(&WebhookDelivery{}).Error; err != nil {
+		return err
+	}
+
+	return DB.Delete(webhook).Error
+}
+
+// Create 创建投递记录
+func (delivery *WebhookDelivery) Create() (uint, error) {
+	if err := DB.Create(delivery).Error; err != nil {
+		return 0, err
+	}
+	return delivery.ID, nil
+}
+
+// Save 保存投递结果
+func (delivery *WebhookDelivery) Save() error {
+	return DB.Save(delivery).Error
+}
+
+// Claim 领取本次投递尝试并增加尝试次数，多个实例同时处理同一投递时仅有一个成功
+func (delivery *WebhookDelivery) Claim() (bool, error) {
+	res := DB.Model(&WebhookDelivery{}).
+		Where("id = ? and status = ? and attempts = ?", delivery.ID, WebhookDeliveryPending, delivery.Attempts).
+		UpdateColumn("attempts", gorm.Expr("attempts + ?", 1))
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false, res.Error
+	}
+
+	delivery.Attempts++
+	return true, nil
+}
+
+// GetWebhookDeliveryByID 根据ID查找投递记录
+func GetWebhookDeliveryByID(id uint) (*WebhookDelivery, error) {
+	delivery := &WebhookDelivery{}
+	res := DB.Where("id = ?", id).First(delivery)
+	return delivery, res.Error
+}
+
+// GetPendingWebhookDeliveries 获取所有等待投递的记录
+func GetPendingWebhookDeliveries() []WebhookDelivery {
+	var deliveries []WebhookDelivery
+	DB.Where("status = ?", WebhookDeliveryPending).Find(&deliveries)
+	return deliveries
+}
+
+// ListWebhookDeliveries 分页列出订阅的投递记录
+func ListWebhookDeliveries(webhookID uint, page, pageSize int) ([]WebhookDelivery, int) {
+	var (
+		deliveries []WebhookDelivery
+		total      int
+	)
+	dbChain := DB.Where("webhook_id = ?", webhookID)
+
+	// 计算总数用于分页
+	dbChain.Model(&WebhookDelivery{}).Count(&total)
+
+	// 查询记录
+	dbChain.Limit(pageSize).Offset((page - 1) * pageSize).Order("id desc").Find(&deliveries)
+
+	return deliveries, total
+}
+
+// PruneWebhookDeliveries 仅保留订阅最近的 keep 条投递记录
+func PruneWebhookDeliveries(webhookID uint, keep int) error {
+	var deliveries []WebhookDelivery
+	if err := DB.Select("id").Where("webhook_id = ?", webhookID).Order("id desc").
+		Offset(keep).Limit(1).Find(&deliveries).Error; err != nil || len(deliveries) == 0 {
+		return err
+	}
+
+	return DB.Unscoped().Where("webhook_id = ? and id <= ?", webhookID, deliveries[0].ID).
+		Delete(&WebhookDelivery{}).Error
+}
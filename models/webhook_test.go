@@ -0,0 +1,170 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhook_Create(t *testing.T) {
+	asserts := assert.New(t)
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		hook := Webhook{URL: "http://example.com"}
+		id, err := hook.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(1, id)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		hook := Webhook{URL: "http://example.com"}
+		id, err := hook.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.EqualValues(0, id)
+	}
+}
+
+func TestWebhook_Subscribed(t *testing.T) {
+	asserts := assert.New(t)
+
+	hook := Webhook{}
+	asserts.True(hook.Subscribed("file.uploaded"))
+
+	hook.Events = "file.uploaded,share.created"
+	asserts.True(hook.Subscribed("file.uploaded"))
+	asserts.True(hook.Subscribed("share.created"))
+	asserts.False(hook.Subscribed("file.deleted"))
+}
+
+func TestGetWebhookByID(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 限制创建者
+	{
+		mock.ExpectQuery("SELECT(.+)user_id(.+)").WithArgs(1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		res, err := GetWebhookByID(1, 2)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(1, res.ID)
+	}
+
+	// 不限制创建者
+	{
+		mock.ExpectQuery("SELECT(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		_, err := GetWebhookByID(1, 0)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+}
+
+func TestGetSubscribedWebhooks(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)").WithArgs(true, 1, true).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	res := GetSubscribedWebhooks(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(res, 2)
+}
+
+func TestDeleteWebhookByID(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)webhook_deliveries(.+)").WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)webhooks(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(DeleteWebhookByID(1, 1))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 不存在
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		asserts.Error(DeleteWebhookByID(1, 1))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+	res, total := ListWebhookDeliveries(1, 1, 10)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(5, total)
+	asserts.Len(res, 1)
+}
+
+func TestPruneWebhookDeliveries(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 无需清理
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		asserts.NoError(PruneWebhookDeliveries(1, 10))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 清理
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)").WithArgs(1, 5).WillReturnResult(sqlmock.NewResult(0, 5))
+		mock.ExpectCommit()
+		asserts.NoError(PruneWebhookDeliveries(1, 10))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestWebhookDelivery_Claim(t *testing.T) {
+	asserts := assert.New(t)
+	delivery := WebhookDelivery{Attempts: 1}
+	delivery.ID = 2
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)attempts(.+)").WithArgs(1, 2, WebhookDeliveryPending, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		ok, err := delivery.Claim()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.True(ok)
+		asserts.Equal(2, delivery.Attempts)
+	}
+
+	// 已被其他实例领取
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)attempts(.+)").WithArgs(1, 2, WebhookDeliveryPending, 2).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		ok, err := delivery.Claim()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.False(ok)
+		asserts.Equal(2, delivery.Attempts)
+	}
+}
@@ -0,0 +1,135 @@
+package activity
+
+import (
+	"path"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// 客户端类型
+const (
+	// ClientWeb 网页端
+	ClientWeb = "web"
+	// ClientWebDAV WebDAV 应用
+	ClientWebDAV = "webdav"
+	// ClientAPI 第三方 API 调用
+	ClientAPI = "api"
+)
+
+// webhookEvents 操作类型对应的 Webhook 事件
+var webhookEvents = map[int]string{
+	model.ActivityUpload:        webhook.EventFileUploaded,
+	model.ActivityDelete:        webhook.EventFileDeleted,
+	model.ActivityMove:          webhook.EventFileMoved,
+	model.ActivityRename:        webhook.EventFileMoved,
+	model.ActivityShareCreate:   webhook.EventShareCreated,
+	model.ActivityShareDownload: webhook.EventShareDownloaded,
+}
+
+// Actor 操作发起者
+type Actor struct {
+	UserID uint
+	Client string
+	App    string
+	IP     string
+}
+
+// ActorFromContext 从请求上下文中解析操作发起者
+func ActorFromContext(c *gin.Context) Actor {
+	actor := Actor{
+		Client: ClientWeb,
+		IP:     c.ClientIP(),
+	}
+
+	if user, ok := c.Get("user"); ok {
+		if u, ok := user.(*model.User); ok {
+			actor.UserID = u.ID
+		}
+	}
+
+	if token, ok := c.Get("access_token"); ok {
+		if accessToken, ok := token.(*model.AccessToken); ok {
+			actor.Client = ClientAPI
+			actor.App = accessToken.Name
+		}
+	}
+
+	if webdav, ok := c.Get("webdav"); ok {
+		if application, ok := webdav.(*model.Webdav); ok {
+			actor.Client = ClientWebDAV
+			actor.App = application.Name
+		}
+	}
+
+	return actor
+}
+
+// Record 记录一条操作，owner 为被操作对象所属用户
+func Record(actor Actor, owner uint, activityType int, src, dst string) {
+	activity := model.Activity{
+		UserID:  owner,
+		ActorID: actor.UserID,
+		Type:    activityType,
+		Client:  actor.Client,
+		App:     actor.App,
+		IP:      actor.IP,
+		Src:     src,
+		Dst:     dst,
+	}
+
+	// 记录失败不影响原操作
+	activity.Create()
+
+	if event, ok := webhookEvents[activityType]; ok {
+		webhook.Trigger(owner, event, map[string]interface{}{
+			"src":    src,
+			"dst":    dst,
+			"client": actor.Client,
+			"app":    actor.App,
+		})
+	}
+}
+
+// RecordPaths 为多个对象路径分别记录操作，dstDir 不为空时目标路径为 dstDir 下的同名对象
+func RecordPaths(actor Actor, owner uint, activityType int, paths []string, dstDir string) {
+	for _, src := range paths {
+		dst := ""
+		if dstDir != "" {
+			dst = path.Join(dstDir, path.Base(src))
+		}
+		Record(actor, owner, activityType, src, dst)
+	}
+}
+
+// ResolvePaths 根据目录和文件 ID 查找其在用户文件系统中的完整路径
+func ResolvePaths(uid uint, dirs, files []uint) []string {
+	paths := make([]string, 0, len(dirs)+len(files))
+
+	if len(dirs) > 0 {
+		folders, _ := model.GetFoldersByIDs(dirs, uid)
+		for _, folder := range folders {
+			folder.TraceRoot()
+			paths = append(paths, path.Join("/", folder.Position, folder.Name))
+		}
+	}
+
+	if len(files) > 0 {
+		fileList, _ := model.GetFilesByIDs(files, uid)
+		parents := make(map[uint]string)
+		for _, file := range fileList {
+			parent, ok := parents[file.FolderID]
+			if !ok {
+				if folders, err := model.GetFoldersByIDs([]uint{file.FolderID}, uid); err == nil && len(folders) > 0 {
+					folders[0].TraceRoot()
+					parent = path.Join("/", folders[0].Position, folders[0].Name)
+				}
+				parents[file.FolderID] = parent
+			}
+			paths = append(paths, path.Join("/", parent, file.Name))
+		}
+	}
+
+	return paths
+}
@@ -0,0 +1,194 @@
+package activity
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+
+	cache.Set("setting_siteURL", "http://cloudreve.org", 0)
+	cache.Set("setting_siteID", "site", 0)
+	m.Run()
+}
+
+func TestActorFromContext(t *testing.T) {
+	asserts := assert.New(t)
+	user := &model.User{}
+	user.ID = 1
+
+	testCases := []struct {
+		name     string
+		values   map[string]interface{}
+		expected Actor
+	}{
+		{
+			name:     "匿名访客",
+			expected: Actor{Client: ClientWeb, IP: "192.168.1.2"},
+		},
+		{
+			name:     "网页端",
+			values:   map[string]interface{}{"user": user},
+			expected: Actor{UserID: 1, Client: ClientWeb, IP: "192.168.1.2"},
+		},
+		{
+			name: "访问令牌",
+			values: map[string]interface{}{
+				"user":         user,
+				"access_token": &model.AccessToken{Name: "sync"},
+			},
+			expected: Actor{UserID: 1, Client: ClientAPI, App: "sync", IP: "192.168.1.2"},
+		},
+		{
+			name: "WebDAV",
+			values: map[string]interface{}{
+				"user":   user,
+				"webdav": &model.Webdav{Name: "nas"},
+			},
+			expected: Actor{UserID: 1, Client: ClientWebDAV, App: "nas", IP: "192.168.1.2"},
+		},
+		{
+			name:     "无效的上下文值",
+			values:   map[string]interface{}{"user": "user", "webdav": "webdav"},
+			expected: Actor{Client: ClientWeb, IP: "192.168.1.2"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		c.Request.RemoteAddr = "192.168.1.2:1234"
+		for key, value := range testCase.values {
+			c.Set(key, value)
+		}
+		asserts.Equal(testCase.expected, ActorFromContext(c), testCase.name)
+	}
+}
+
+func TestResolvePaths(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 根目录及其下的 docs 目录
+	expectFolder := func() {
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id", "owner_id"}).AddRow(2, "docs", 1, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_id"}).AddRow(1, "/", 1))
+	}
+
+	testCases := []struct {
+		name     string
+		dirs     []uint
+		files    []uint
+		mock     func()
+		expected []string
+	}{
+		{
+			name:     "空列表",
+			mock:     func() {},
+			expected: []string{},
+		},
+		{
+			name:     "目录",
+			dirs:     []uint{2},
+			mock:     expectFolder,
+			expected: []string{"/docs"},
+		},
+		{
+			name:  "同一目录下的文件仅查找一次父目录",
+			files: []uint{3, 4},
+			mock: func() {
+				mock.ExpectQuery("SELECT(.+)files(.+)").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "folder_id"}).
+						AddRow(3, "a.txt", 2).
+						AddRow(4, "b.txt", 2))
+				expectFolder()
+			},
+			expected: []string{"/docs/a.txt", "/docs/b.txt"},
+		},
+		{
+			name:  "父目录不存在",
+			files: []uint{3},
+			mock: func() {
+				mock.ExpectQuery("SELECT(.+)files(.+)").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "folder_id"}).AddRow(3, "a.txt", 5))
+				mock.ExpectQuery("SELECT(.+)folders(.+)").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			},
+			expected: []string{"/a.txt"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase.mock()
+		asserts.Equal(testCase.expected, ResolvePaths(1, testCase.dirs, testCase.files), testCase.name)
+		asserts.NoError(mock.ExpectationsWereMet(), testCase.name)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	asserts := assert.New(t)
+	actor := Actor{UserID: 2, Client: ClientAPI, App: "sync", IP: "192.168.1.2"}
+
+	// 未初始化 Webhook 投递器
+	{
+		webhook.Default = nil
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)activities(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		Record(actor, 1, model.ActivityUpload, "/a.txt", "")
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 记录操作并触发 Webhook
+	{
+		webhook.Default = webhook.NewDispatcher(0, 1, time.Second, time.Second, 0)
+		defer func() { webhook.Default = nil }()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)activities(.+)").
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 1, 2, model.ActivityUpload, ClientAPI, "sync", "192.168.1.2", "/a.txt", "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)webhooks(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "events"}).AddRow(1, ""))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)webhook_deliveries(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		Record(actor, 1, model.ActivityUpload, "/a.txt", "")
+		asserts.Eventually(func() bool {
+			return mock.ExpectationsWereMet() == nil
+		}, time.Second, 10*time.Millisecond)
+	}
+
+	// 没有对应 Webhook 事件的操作
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)activities(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		Record(actor, 1, model.ActivityCopy, "/a.txt", "/b.txt")
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
+	"github.com/cloudreve/Cloudreve/v3/pkg/leader"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
 	"net/url"
 	"sync"
@@ -14,8 +15,16 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/monitor"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/rpc"
 	"github.com/cloudreve/Cloudreve/v3/pkg/balancer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
+// MonitorTopic 非主节点实例请求主节点监控新建下载任务的消息主题
+const MonitorTopic = "aria2_monitor"
+
+// rescanInterval 主节点扫描未被监控的下载任务的间隔。未配置 Redis 时消息队列不在实例间共享，
+// 非主节点实例新建的任务只能由此被主节点接管
+const rescanInterval = 10 * time.Second
+
 // Instance 默认使用的Aria2处理实例
 var Instance common.Aria2 = &common.DummyAria2{}
 
@@ -25,6 +34,12 @@ var LB balancer.Balancer
 // Lock Instance的读写锁
 var Lock sync.RWMutex
 
+var (
+	// rescanStop 用于停止主节点的扫描循环，为 nil 时表示未在扫描
+	rescanStop chan struct{}
+	rescanLock sync.Mutex
+)
+
 // GetLoadBalancer 返回供Aria2使用的负载均衡器
 func GetLoadBalancer() balancer.Balancer {
 	Lock.RLock()
@@ -39,14 +54,89 @@ func Init(isReload bool, pool cluster.Pool, mqClient mq.MQ) {
 	Lock.Unlock()
 
 	if !isReload {
-		// 从数据库中读取未完成任务，创建监控
-		unfinished := model.GetDownloadsByStatus(common.Ready, common.Paused, common.Downloading)
+		// 多实例部署时，下载任务监控仅由主节点负责
+		leader.OnElected(func() {
+			watchUnfinished(pool, mqClient)
+			startRescan(pool, mqClient)
+		})
+		leader.OnRevoked(func() {
+			stopRescan()
+			monitor.StopAll()
+		})
+
+		mqClient.SubscribeCallback(MonitorTopic, func(msg mq.Message) {
+			id, ok := msg.Content.(uint)
+			if !ok || !leader.IsLeader() {
+				return
+			}
+
+			task, err := model.GetDownloadByID(id)
+			if err != nil {
+				util.Log().Warning("无法监控下载任务 [%d]，%s", id, err)
+				return
+			}
+
+			monitor.NewMonitor(task, pool, mqClient)
+		})
+	}
+}
+
+// watchUnfinished 从数据库中读取未完成任务，为尚未监控的任务创建监控
+func watchUnfinished(pool cluster.Pool, mqClient mq.MQ) {
+	unfinished := model.GetDownloadsByStatus(common.Ready, common.Paused, common.Downloading)
+
+	for i := 0; i < len(unfinished); i++ {
+		// 同一任务在本实例中只会存在一个监控
+		monitor.NewMonitor(&unfinished[i], pool, mqClient)
+	}
+}
+
+// startRescan 成为主节点后定期扫描未被监控的下载任务
+func startRescan(pool cluster.Pool, mqClient mq.MQ) {
+	rescanLock.Lock()
+	defer rescanLock.Unlock()
+
+	if rescanStop != nil {
+		return
+	}
+
+	stop := make(chan struct{})
+	rescanStop = stop
+	go func() {
+		ticker := time.NewTicker(rescanInterval)
+		defer ticker.Stop()
 
-		for i := 0; i < len(unfinished); i++ {
-			// 创建任务监控
-			monitor.NewMonitor(&unfinished[i], pool, mqClient)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				watchUnfinished(pool, mqClient)
+			}
 		}
+	}()
+}
+
+// stopRescan 失去主节点身份后停止扫描
+func stopRescan() {
+	rescanLock.Lock()
+	defer rescanLock.Unlock()
+
+	if rescanStop != nil {
+		close(rescanStop)
+		rescanStop = nil
+	}
+}
+
+// Watch 为新建的下载任务创建监控，当前实例不是主节点时交由主节点监控。
+// 消息队列不在实例间共享时消息无法送达主节点，任务将在主节点下一次扫描时被接管
+func Watch(task *model.Download, pool cluster.Pool, mqClient mq.MQ) {
+	if leader.IsLeader() {
+		monitor.NewMonitor(task, pool, mqClient)
+		return
 	}
+
+	mqClient.Publish(MonitorTopic, mq.Message{Content: task.ID})
 }
 
 // TestRPCConnection 发送测试用的 RPC 请求，测试服务连通性
@@ -2,11 +2,13 @@ package aria2
 
 import (
 	"database/sql"
+	"github.com/cloudreve/Cloudreve/v3/pkg/leader"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mocks"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
 	"github.com/stretchr/testify/assert"
 	testMock "github.com/stretchr/testify/mock"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	model "github.com/cloudreve/Cloudreve/v3/models"
@@ -36,10 +38,83 @@ func TestInit(t *testing.T) {
 
 	mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	Init(false, mockPool, mockQueue)
+	defer stopRescan()
 	a.NoError(mock.ExpectationsWereMet())
 	mockPool.AssertExpectations(t)
 }
 
+func TestRescan(t *testing.T) {
+	a := assert.New(t)
+	mockPool := &mocks.NodePoolMock{}
+	mockQueue := mq.NewMQ()
+
+	startRescan(mockPool, mockQueue)
+	stop := rescanStop
+	a.NotNil(stop)
+
+	// 重复启动不会创建新的扫描
+	startRescan(mockPool, mockQueue)
+	a.Equal(stop, rescanStop)
+
+	stopRescan()
+	a.Nil(rescanStop)
+	_, open := <-stop
+	a.False(open)
+
+	// 重复停止
+	stopRescan()
+	a.Nil(rescanStop)
+}
+
+func TestWatch(t *testing.T) {
+	a := assert.New(t)
+	mockQueue := mq.NewMQ()
+
+	// 当前为主节点，直接监控
+	{
+		mockPool := &mocks.NodePoolMock{}
+		mockPool.On("GetNodeByID", testMock.Anything).Return(nil)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		Watch(&model.Download{Model: gorm.Model{ID: 1}}, mockPool, mockQueue)
+		a.NoError(mock.ExpectationsWereMet())
+		mockPool.AssertExpectations(t)
+	}
+
+	// 非主节点，交由主节点监控
+	{
+		leader.Default = leader.NewElector(leader.LeaseName, "a", nil, time.Minute)
+		defer func() { leader.Default = nil }()
+		notifier := mockQueue.Subscribe(MonitorTopic, 1)
+		Watch(&model.Download{Model: gorm.Model{ID: 2}}, &mocks.NodePoolMock{}, mockQueue)
+		msg := <-notifier
+		a.Equal(uint(2), msg.Content)
+	}
+}
+
+func TestMonitorTopic(t *testing.T) {
+	a := assert.New(t)
+	mockPool := &mocks.NodePoolMock{}
+	mockPool.On("GetNodeByID", testMock.Anything).Return(nil)
+	mockQueue := mq.NewMQ()
+
+	mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	Init(false, mockPool, mockQueue)
+	defer stopRescan()
+	a.NoError(mock.ExpectationsWereMet())
+
+	// 收到监控请求
+	mock.ExpectQuery("SELECT(.+)downloads(.+)").WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mockQueue.Publish(MonitorTopic, mq.Message{Content: uint(3)})
+	a.Eventually(func() bool {
+		return mock.ExpectationsWereMet() == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
 func TestTestRPCConnection(t *testing.T) {
 	a := assert.New(t)
 
@@ -0,0 +1,312 @@
+package downloader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/common"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/rpc"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/juju/ratelimit"
+)
+
+// Version 测试连接时返回的版本信息
+const Version = "builtin"
+
+const (
+	// tempDirName 临时下载目录下存放内置下载器任务的子目录
+	tempDirName = "builtin"
+	// finishedJobTTL 已结束的任务在内存中的保留时长，之后从控制文件中恢复
+	finishedJobTTL = time.Hour
+	// deleteTempFileDuration 删除临时目录前的等待时长，等待下载协程退出
+	deleteTempFileDuration = 5 * time.Second
+)
+
+var (
+	// ErrUnsupportedSource 内置下载器不支持的下载地址
+	ErrUnsupportedSource = errors.New("builtin downloader only supports HTTP(S) and FTP URLs")
+	// ErrTaskNotFound 下载任务不存在
+	ErrTaskNotFound = errors.New("download task not found")
+)
+
+var (
+	// jobs 本进程中的全部下载任务，以 GID 为键。节点配置变更后任务继续运行
+	jobs     = make(map[string]*job)
+	jobsLock sync.Mutex
+)
+
+// Downloader 内置的 HTTP/FTP 下载器，实现 common.Aria2 接口
+type Downloader struct {
+	option   model.Aria2Option
+	options  map[string]interface{}
+	notifier rpc.Notifier
+	overall  *ratelimit.Bucket
+
+	deletePaddingDuration time.Duration
+}
+
+// New 根据节点离线下载配置创建下载器，notifier 用于推送任务状态变更
+func New(option model.Aria2Option, notifier rpc.Notifier) *Downloader {
+	if notifier == nil {
+		notifier = nopNotifier{}
+	}
+
+	return &Downloader{
+		option:                option,
+		notifier:              notifier,
+		deletePaddingDuration: deleteTempFileDuration,
+	}
+}
+
+// Init 加载附加下载配置并创建临时下载目录
+func (d *Downloader) Init() error {
+	d.options = nil
+	if d.option.Options != "" {
+		if err := json.Unmarshal([]byte(d.option.Options), &d.options); err != nil {
+			util.Log().Warning("无法解析内置下载器配置，%s", err)
+			return err
+		}
+	}
+
+	// 节点全局限速
+	d.overall = newBucket(optionSize(d.options, "max-overall-download-limit", 0))
+
+	return os.MkdirAll(filepath.Join(d.option.TempPath, tempDirName), 0744)
+}
+
+// CreateTask 创建下载任务，下载配置兼容 aria2 的同名选项
+func (d *Downloader) CreateTask(task *model.Download, groupOptions map[string]interface{}) (string, error) {
+	if task.Type == common.TorrentTask {
+		return "", ErrUnsupportedSource
+	}
+
+	u, err := url.Parse(task.Source)
+	if err != nil || !isSupportedScheme(u.Scheme) {
+		return "", ErrUnsupportedSource
+	}
+
+	options := make(map[string]interface{}, len(d.options)+len(groupOptions))
+	for k, v := range d.options {
+		options[k] = v
+	}
+	for k, v := range groupOptions {
+		options[k] = v
+	}
+
+	gid, err := newGID()
+	if err != nil {
+		return "", err
+	}
+
+	dir := d.dir(gid)
+	if err := os.MkdirAll(dir, 0744); err != nil {
+		return "", err
+	}
+
+	j := &job{
+		state: state{
+			GID:      gid,
+			Source:   task.Source,
+			Settings: parseSettings(options),
+			Total:    -1,
+		},
+		dir:     dir,
+		status:  "waiting",
+		overall: d.overall,
+	}
+	if err := j.save(); err != nil {
+		return "", err
+	}
+
+	d.start(j)
+	return gid, nil
+}
+
+// Status 返回与 aria2 兼容的任务状态，进程重启后从控制文件恢复任务
+func (d *Downloader) Status(task *model.Download) (rpc.StatusInfo, error) {
+	j, err := d.lookup(task.GID)
+	if err != nil {
+		return rpc.StatusInfo{}, err
+	}
+
+	return j.statusInfo(), nil
+}
+
+// Cancel 取消下载任务
+func (d *Downloader) Cancel(task *model.Download) error {
+	j, err := d.lookup(task.GID)
+	if err != nil {
+		return err
+	}
+
+	if j.stop() {
+		d.notifier.OnDownloadStop(events(j.GID))
+	}
+	return nil
+}
+
+// Select 内置下载器的任务仅包含一个文件，无需选择
+func (d *Downloader) Select(task *model.Download, files []int) error {
+	if _, err := d.lookup(task.GID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetConfig 返回节点离线下载配置
+func (d *Downloader) GetConfig() model.Aria2Option {
+	return d.option
+}
+
+// DeleteTempFile 停止任务并删除临时下载目录
+func (d *Downloader) DeleteTempFile(task *model.Download) error {
+	jobsLock.Lock()
+	if j, ok := jobs[task.GID]; ok {
+		j.stop()
+		delete(jobs, task.GID)
+	}
+	jobsLock.Unlock()
+
+	// 等待下载协程退出后异步删除
+	go func(d time.Duration, src string) {
+		time.Sleep(d)
+		if err := os.RemoveAll(src); err != nil {
+			util.Log().Warning("无法删除离线下载临时目录[%s], %s", src, err)
+		}
+	}(d.deletePaddingDuration, d.dir(task.GID))
+
+	return nil
+}
+
+// dir 返回任务的下载目录
+func (d *Downloader) dir(gid string) string {
+	return filepath.Join(d.option.TempPath, tempDirName, filepath.Base(gid))
+}
+
+// lookup 查找任务，不在内存中时从下载目录的控制文件恢复，未完成的任务将继续下载
+func (d *Downloader) lookup(gid string) (*job, error) {
+	jobsLock.Lock()
+	defer jobsLock.Unlock()
+
+	if j, ok := jobs[gid]; ok {
+		return j, nil
+	}
+
+	if gid == "" {
+		return nil, ErrTaskNotFound
+	}
+
+	j, err := loadJob(d.dir(gid))
+	if err != nil {
+		return nil, ErrTaskNotFound
+	}
+
+	j.overall = d.overall
+	jobs[gid] = j
+	if j.status == "waiting" {
+		util.Log().Info("继续离线下载任务[%s]", gid)
+		go d.run(j)
+	} else {
+		d.forgetLater(j)
+	}
+
+	return j, nil
+}
+
+// start 登记并开始任务
+func (d *Downloader) start(j *job) {
+	jobsLock.Lock()
+	jobs[j.GID] = j
+	jobsLock.Unlock()
+
+	go d.run(j)
+}
+
+// run 执行下载并推送状态变更
+func (d *Downloader) run(j *job) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if !j.begin(cancel) {
+		cancel()
+		return
+	}
+	d.notifier.OnDownloadStart(events(j.GID))
+
+	err := j.download(ctx)
+	cancel()
+
+	switch j.finish(err) {
+	case "complete":
+		d.notifier.OnDownloadComplete(events(j.GID))
+	case "error":
+		util.Log().Warning("离线下载任务[%s]失败，%s", j.GID, err)
+		d.notifier.OnDownloadError(events(j.GID))
+	}
+
+	d.forgetLater(j)
+}
+
+// forgetLater 一段时间后从内存中移除已结束的任务
+func (d *Downloader) forgetLater(j *job) {
+	time.AfterFunc(finishedJobTTL, func() {
+		jobsLock.Lock()
+		if jobs[j.GID] == j {
+			delete(jobs, j.GID)
+		}
+		jobsLock.Unlock()
+	})
+}
+
+// events 返回推送给订阅者的任务事件
+func events(gid string) []rpc.Event {
+	return []rpc.Event{{Gid: gid}}
+}
+
+// TestTempPath 测试临时下载目录是否可写
+func TestTempPath(tempPath string) error {
+	dir := filepath.Join(tempPath, tempDirName)
+	if err := os.MkdirAll(dir, 0744); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, "test")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+func isSupportedScheme(scheme string) bool {
+	switch scheme {
+	case "http", "https", "ftp":
+		return true
+	}
+	return false
+}
+
+// newGID 生成与 aria2 格式相同的 16 位十六进制任务 ID
+func newGID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type nopNotifier struct{}
+
+func (nopNotifier) OnDownloadStart([]rpc.Event)      {}
+func (nopNotifier) OnDownloadPause([]rpc.Event)      {}
+func (nopNotifier) OnDownloadStop([]rpc.Event)       {}
+func (nopNotifier) OnDownloadComplete([]rpc.Event)   {}
+func (nopNotifier) OnDownloadError([]rpc.Event)      {}
+func (nopNotifier) OnBtDownloadComplete([]rpc.Event) {}
@@ -0,0 +1,436 @@
+package downloader
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/common"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func testContent(size int) []byte {
+	content := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(content)
+	return content
+}
+
+func newTestDownloader(t *testing.T, options string) *Downloader {
+	d := New(model.Aria2Option{TempPath: t.TempDir(), Options: options}, nil)
+	d.deletePaddingDuration = 0
+	assert.NoError(t, d.Init())
+	return d
+}
+
+// waitStatus 等待任务进入给定状态
+func waitStatus(t *testing.T, d *Downloader, gid string, status string) rpc.StatusInfo {
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		res, err := d.Status(&model.Download{GID: gid})
+		assert.NoError(t, err)
+		if res.Status == status || time.Now().After(deadline) {
+			return res
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestParseSettings(t *testing.T) {
+	a := assert.New(t)
+
+	res := parseSettings(map[string]interface{}{})
+	a.Equal(defaultSplit, res.Split)
+	a.EqualValues(defaultMinSplitSize, res.MinSplitSize)
+	a.Equal(defaultMaxTries, res.MaxTries)
+
+	res = parseSettings(map[string]interface{}{
+		"split":              float64(8),
+		"min-split-size":     "1M",
+		"max-download-limit": "512K",
+		"checksum":           "sha-256=abc",
+		"header":             []interface{}{"Cookie: a=b"},
+		"out":                "file.bin",
+	})
+	a.Equal(8, res.Split)
+	a.EqualValues(1<<20, res.MinSplitSize)
+	a.EqualValues(512<<10, res.SpeedLimit)
+	a.Equal("sha-256=abc", res.Checksum)
+	a.Equal([]string{"Cookie: a=b"}, res.Headers)
+	a.Equal("file.bin", res.Out)
+
+	res = parseSettings(map[string]interface{}{"split": "0", "header": "Cookie: a=b"})
+	a.Equal(1, res.Split)
+	a.Equal([]string{"Cookie: a=b"}, res.Headers)
+}
+
+func TestParseSize(t *testing.T) {
+	a := assert.New(t)
+
+	for input, expected := range map[string]int64{"10": 10, "2K": 2 << 10, "3m": 3 << 20, "1G": 1 << 30} {
+		res, err := parseSize(input)
+		a.NoError(err)
+		a.Equal(expected, res)
+	}
+
+	_, err := parseSize("abc")
+	a.Error(err)
+}
+
+func TestPlanSegments(t *testing.T) {
+	a := assert.New(t)
+
+	// 不支持分段
+	a.Equal([]*segment{{Start: 0, End: -1}}, planSegments(100, false, 5, 10))
+	a.Equal([]*segment{{Start: 0, End: -1}}, planSegments(-1, true, 5, 10))
+
+	// 受最小分段大小限制
+	res := planSegments(25, true, 5, 10)
+	a.Equal([]*segment{{Start: 0, End: 12}, {Start: 12, End: 25}}, res)
+
+	// 小于最小分段大小
+	a.Equal([]*segment{{Start: 0, End: 5}}, planSegments(5, true, 5, 10))
+
+	// 平均划分
+	res = planSegments(100, true, 3, 0)
+	a.Len(res, 3)
+	a.EqualValues(0, res[0].Start)
+	a.EqualValues(res[0].End, res[1].Start)
+	a.EqualValues(100, res[2].End)
+}
+
+func TestSanitizeName(t *testing.T) {
+	a := assert.New(t)
+	a.Equal("a.txt", sanitizeName("a.txt"))
+	a.Equal("passwd", sanitizeName("../../etc/passwd"))
+	a.Equal("b.txt", sanitizeName("..\\a\\b.txt"))
+	a.Equal("download", sanitizeName(""))
+	a.Equal("download", sanitizeName(".."))
+	a.Equal("download", sanitizeName(controlFileName))
+}
+
+func TestParseDigestHeader(t *testing.T) {
+	a := assert.New(t)
+	sum := sha256.Sum256([]byte("test"))
+
+	a.Equal("", parseDigestHeader(""))
+	a.Equal("sha-256="+hex.EncodeToString(sum[:]),
+		parseDigestHeader("MD5=CY9rzUYh03PK3k6DJie09g==, SHA-256="+base64.StdEncoding.EncodeToString(sum[:])))
+	a.Equal("md5=098f6bcd4621d373cade4e832627b4f6", parseDigestHeader("md5=CY9rzUYh03PK3k6DJie09g==,unknown=abc"))
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	a := assert.New(t)
+	path := filepath.Join(t.TempDir(), "file")
+	a.NoError(ioutil.WriteFile(path, []byte("test"), 0644))
+	sum := sha256.Sum256([]byte("test"))
+
+	a.NoError(verifyChecksum(path, ""))
+	a.NoError(verifyChecksum(path, "sha-256="+hex.EncodeToString(sum[:])))
+	a.NoError(verifyChecksum(path, "md5=098F6BCD4621D373CADE4E832627B4F6"))
+	a.Error(verifyChecksum(path, "sha-1=abc"))
+	a.Error(verifyChecksum(path, "crc32=abc"))
+	a.Error(verifyChecksum(path, "invalid"))
+}
+
+func TestDownloader_CreateTask(t *testing.T) {
+	a := assert.New(t)
+	d := newTestDownloader(t, "")
+
+	// 不支持的地址
+	_, err := d.CreateTask(&model.Download{Source: "magnet:?xt=urn:btih:abc"}, nil)
+	a.Equal(ErrUnsupportedSource, err)
+	_, err = d.CreateTask(&model.Download{Source: "http://example.com/a.torrent", Type: common.TorrentTask}, nil)
+	a.Equal(ErrUnsupportedSource, err)
+
+	// 附加配置无效
+	d = New(model.Aria2Option{TempPath: t.TempDir(), Options: "{"}, nil)
+	a.Error(d.Init())
+}
+
+func TestDownloader_HTTP(t *testing.T) {
+	a := assert.New(t)
+	content := testContent(64 << 10)
+	sum := sha256.Sum256(content)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.Equal("test", r.Header.Get("X-Test"))
+		http.ServeContent(w, r, "file.bin", time.Now(), bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	// 分段下载
+	{
+		d := newTestDownloader(t, `{"split":"4","min-split-size":"1K","header":["X-Test: test"]}`)
+		gid, err := d.CreateTask(&model.Download{Source: server.URL + "/path/file.bin"}, map[string]interface{}{
+			"checksum": "sha-256=" + hex.EncodeToString(sum[:]),
+		})
+		a.NoError(err)
+		a.Len(gid, 16)
+
+		res := waitStatus(t, d, gid, "complete")
+		a.Equal("complete", res.Status)
+		a.Equal(strconv.Itoa(len(content)), res.TotalLength)
+		a.Equal(strconv.Itoa(len(content)), res.CompletedLength)
+		a.Len(res.Files, 1)
+		a.Equal("file.bin", filepath.Base(res.Files[0].Path))
+		a.Equal("true", res.Files[0].Selected)
+
+		downloaded, err := ioutil.ReadFile(res.Files[0].Path)
+		a.NoError(err)
+		a.Equal(content, downloaded)
+
+		// 从控制文件恢复已完成的任务
+		jobsLock.Lock()
+		delete(jobs, gid)
+		jobsLock.Unlock()
+		res, err = d.Status(&model.Download{GID: gid})
+		a.NoError(err)
+		a.Equal("complete", res.Status)
+
+		// 删除临时目录
+		a.NoError(d.DeleteTempFile(&model.Download{GID: gid}))
+		time.Sleep(100 * time.Millisecond)
+		_, err = d.Status(&model.Download{GID: gid})
+		a.Equal(ErrTaskNotFound, err)
+	}
+
+	// 校验失败
+	{
+		d := newTestDownloader(t, `{"header":"X-Test: test"}`)
+		gid, err := d.CreateTask(&model.Download{Source: server.URL + "/file.bin"}, map[string]interface{}{
+			"checksum": "sha-256=0000",
+		})
+		a.NoError(err)
+
+		res := waitStatus(t, d, gid, "error")
+		a.Equal("error", res.Status)
+		a.Contains(res.ErrorMessage, "checksum mismatch")
+	}
+}
+
+func TestDownloader_HTTPWithoutRange(t *testing.T) {
+	a := assert.New(t)
+	content := testContent(10 << 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="named.bin"`)
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	d := newTestDownloader(t, `{"min-split-size":"1K"}`)
+	gid, err := d.CreateTask(&model.Download{Source: server.URL + "/"}, nil)
+	a.NoError(err)
+
+	res := waitStatus(t, d, gid, "complete")
+	a.Equal("complete", res.Status)
+	a.Equal("named.bin", filepath.Base(res.Files[0].Path))
+	downloaded, err := ioutil.ReadFile(res.Files[0].Path)
+	a.NoError(err)
+	a.Equal(content, downloaded)
+}
+
+func TestDownloader_HTTPError(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	d := newTestDownloader(t, "")
+	gid, err := d.CreateTask(&model.Download{Source: server.URL + "/missing"}, nil)
+	a.NoError(err)
+
+	res := waitStatus(t, d, gid, "error")
+	a.Equal("error", res.Status)
+	a.Contains(res.ErrorMessage, "404")
+}
+
+func TestDownloader_Resume(t *testing.T) {
+	a := assert.New(t)
+	content := testContent(8 << 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file.bin", time.Now(), bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	// 模拟进程重启前已下载了部分内容
+	d := newTestDownloader(t, "")
+	dir := d.dir("0123456789abcdef")
+	a.NoError(os.MkdirAll(dir, 0744))
+	a.NoError(ioutil.WriteFile(filepath.Join(dir, "file.bin"), content[:1000], 0644))
+	j := &job{dir: dir, state: state{
+		GID:       "0123456789abcdef",
+		Source:    server.URL + "/file.bin",
+		Settings:  parseSettings(nil),
+		Name:      "file.bin",
+		Total:     int64(len(content)),
+		Resumable: true,
+		Segments:  []*segment{{Start: 0, End: int64(len(content)), Done: 1000}},
+	}}
+	a.NoError(j.save())
+
+	res := waitStatus(t, d, "0123456789abcdef", "complete")
+	a.Equal("complete", res.Status)
+	downloaded, err := ioutil.ReadFile(filepath.Join(dir, "file.bin"))
+	a.NoError(err)
+	a.Equal(content, downloaded)
+}
+
+func TestDownloader_Cancel(t *testing.T) {
+	a := assert.New(t)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	d := newTestDownloader(t, "")
+	gid, err := d.CreateTask(&model.Download{Source: server.URL + "/file.bin"}, nil)
+	a.NoError(err)
+	a.NoError(d.Select(&model.Download{GID: gid}, []int{1}))
+
+	a.NoError(d.Cancel(&model.Download{GID: gid}))
+	res, err := d.Status(&model.Download{GID: gid})
+	a.NoError(err)
+	a.Equal("removed", res.Status)
+
+	// 不存在的任务
+	a.Equal(ErrTaskNotFound, d.Cancel(&model.Download{GID: "notexist"}))
+	a.Equal(ErrTaskNotFound, d.Select(&model.Download{GID: ""}, nil))
+}
+
+func TestTestTempPath(t *testing.T) {
+	a := assert.New(t)
+	a.NoError(TestTempPath(t.TempDir()))
+
+	file := filepath.Join(t.TempDir(), "file")
+	a.NoError(ioutil.WriteFile(file, nil, 0644))
+	a.Error(TestTempPath(file))
+}
+
+// serveFTP 仅支持下载单个文件的 FTP 服务端
+func serveFTP(t *testing.T, name string, content []byte) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	handle := func(conn net.Conn) {
+		defer conn.Close()
+		var (
+			data   net.Listener
+			offset int
+		)
+		defer func() {
+			if data != nil {
+				data.Close()
+			}
+		}()
+
+		reader := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 ready\r\n")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd, arg, _ := cut(strings.TrimSpace(line), " ")
+			switch cmd {
+			case "USER":
+				fmt.Fprint(conn, "331 password required\r\n")
+			case "PASS":
+				fmt.Fprint(conn, "230 logged in\r\n")
+			case "TYPE":
+				fmt.Fprint(conn, "200 ok\r\n")
+			case "SIZE":
+				if arg != name {
+					fmt.Fprint(conn, "550 not found\r\n")
+					continue
+				}
+				fmt.Fprintf(conn, "213 %d\r\n", len(content))
+			case "REST":
+				offset, _ = strconv.Atoi(arg)
+				fmt.Fprint(conn, "350 restarting\r\n")
+			case "EPSV":
+				data, _ = net.Listen("tcp", "127.0.0.1:0")
+				fmt.Fprintf(conn, "229 Entering Extended Passive Mode (|||%d|)\r\n", data.Addr().(*net.TCPAddr).Port)
+			case "RETR":
+				if arg != name {
+					fmt.Fprint(conn, "550 not found\r\n")
+					continue
+				}
+				fmt.Fprint(conn, "150 opening\r\n")
+				dataConn, err := data.Accept()
+				if err != nil {
+					return
+				}
+				dataConn.Write(content[offset:])
+				dataConn.Close()
+				fmt.Fprint(conn, "226 done\r\n")
+			default:
+				fmt.Fprint(conn, "502 not implemented\r\n")
+			}
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go handle(conn)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestDownloader_FTP(t *testing.T) {
+	a := assert.New(t)
+	content := testContent(16 << 10)
+	addr := serveFTP(t, "/pub/file.bin", content)
+
+	// 分段下载
+	{
+		d := newTestDownloader(t, `{"split":"3","min-split-size":"1K"}`)
+		gid, err := d.CreateTask(&model.Download{Source: "ftp://user:pass@" + addr + "/pub/file.bin"}, nil)
+		a.NoError(err)
+
+		res := waitStatus(t, d, gid, "complete")
+		a.Equal("complete", res.Status)
+		a.Equal("file.bin", filepath.Base(res.Files[0].Path))
+		downloaded, err := ioutil.ReadFile(res.Files[0].Path)
+		a.NoError(err)
+		a.Equal(content, downloaded)
+	}
+
+	// 文件不存在
+	{
+		d := newTestDownloader(t, `{"max-tries":"1"}`)
+		gid, err := d.CreateTask(&model.Download{Source: "ftp://" + addr + "/missing"}, nil)
+		a.NoError(err)
+
+		res := waitStatus(t, d, gid, "error")
+		a.Equal("error", res.Status)
+		a.Contains(res.ErrorMessage, "550")
+	}
+}
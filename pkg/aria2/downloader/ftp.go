@@ -0,0 +1,217 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ftpDialTimeout = 30 * time.Second
+
+// ftpSource FTP 下载源，以被动模式传输，每次读取使用独立的连接
+type ftpSource struct {
+	url *url.URL
+}
+
+// ftpConn FTP 控制连接
+type ftpConn struct {
+	conn net.Conn
+	text *textproto.Conn
+	host string
+	stop chan struct{}
+}
+
+// dial 建立控制连接并登录，上下文取消时关闭连接
+func (s *ftpSource) dial(ctx context.Context) (*ftpConn, error) {
+	addr := s.url.Host
+	if s.url.Port() == "" {
+		addr = net.JoinHostPort(s.url.Hostname(), "21")
+	}
+
+	dialer := net.Dialer{Timeout: ftpDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &ftpConn{conn: conn, text: textproto.NewConn(conn), host: s.url.Hostname(), stop: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-c.stop:
+		}
+	}()
+
+	if _, _, err := c.text.ReadResponse(220); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	user, password := "anonymous", "anonymous@"
+	if s.url.User != nil {
+		user = s.url.User.Username()
+		if p, ok := s.url.User.Password(); ok {
+			password = p
+		}
+	}
+
+	code, _, err := c.cmd(0, "USER %s", user)
+	if err == nil && code == 331 {
+		code, _, err = c.cmd(0, "PASS %s", password)
+	}
+	if err == nil && code != 230 {
+		err = permanent("ftp login failed with code %d", code)
+	}
+	if err == nil {
+		_, _, err = c.cmd(200, "TYPE I")
+	}
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// cmd 发送命令并读取响应，expectCode 为 0 时不检查响应码
+func (c *ftpConn) cmd(expectCode int, format string, args ...interface{}) (int, string, error) {
+	if _, err := c.text.Cmd(format, args...); err != nil {
+		return 0, "", err
+	}
+	return c.text.ReadResponse(expectCode)
+}
+
+// passive 进入被动模式并建立数据连接，忽略服务端返回的地址以兼容 NAT 后的服务器
+func (c *ftpConn) passive(ctx context.Context) (net.Conn, error) {
+	var port int
+	if _, msg, err := c.cmd(229, "EPSV"); err == nil {
+		// Entering Extended Passive Mode (|||port|)
+		start, end := strings.Index(msg, "(|||"), strings.LastIndex(msg, "|)")
+		if start < 0 || end < start {
+			return nil, fmt.Errorf("invalid EPSV response %q", msg)
+		}
+		if port, err = strconv.Atoi(msg[start+4 : end]); err != nil {
+			return nil, err
+		}
+	} else {
+		_, msg, err := c.cmd(227, "PASV")
+		if err != nil {
+			return nil, err
+		}
+
+		// Entering Passive Mode (h1,h2,h3,h4,p1,p2)
+		start, end := strings.Index(msg, "("), strings.LastIndex(msg, ")")
+		if start < 0 || end < start {
+			return nil, fmt.Errorf("invalid PASV response %q", msg)
+		}
+		fields := strings.Split(msg[start+1:end], ",")
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("invalid PASV response %q", msg)
+		}
+		p1, err1 := strconv.Atoi(strings.TrimSpace(fields[4]))
+		p2, err2 := strconv.Atoi(strings.TrimSpace(fields[5]))
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid PASV response %q", msg)
+		}
+		port = p1<<8 | p2
+	}
+
+	dialer := net.Dialer{Timeout: ftpDialTimeout}
+	return dialer.DialContext(ctx, "tcp", net.JoinHostPort(c.host, strconv.Itoa(port)))
+}
+
+// Close 关闭控制连接
+func (c *ftpConn) Close() error {
+	close(c.stop)
+	return c.text.Close()
+}
+
+func (s *ftpSource) probe(ctx context.Context) (*remoteFile, error) {
+	c, err := s.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	info := &remoteFile{Name: path.Base(s.url.Path), Size: -1}
+	if _, msg, err := c.cmd(213, "SIZE %s", s.url.Path); err == nil {
+		if size, err := strconv.ParseInt(strings.TrimSpace(msg), 10, 64); err == nil {
+			info.Size = size
+		}
+	}
+
+	// 支持 REST 时可分段下载
+	if info.Size > 0 {
+		if _, _, err := c.cmd(350, "REST 0"); err == nil {
+			info.Ranges = true
+		}
+	}
+
+	return info, nil
+}
+
+func (s *ftpSource) open(ctx context.Context, start, end int64) (io.ReadCloser, error) {
+	c, err := s.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := c.passive(ctx)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			data.Close()
+		case <-c.stop:
+		}
+	}()
+
+	if start > 0 {
+		if _, _, err := c.cmd(350, "REST %d", start); err != nil {
+			data.Close()
+			c.Close()
+			return nil, permanent("ftp server does not support resuming: %s", err)
+		}
+	}
+
+	code, msg, err := c.cmd(0, "RETR %s", s.url.Path)
+	if err == nil && code/100 != 1 {
+		err = fmt.Errorf("ftp server returns %d %s", code, msg)
+		if code/100 == 5 {
+			err = permanentError{err: err}
+		}
+	}
+	if err != nil {
+		data.Close()
+		c.Close()
+		return nil, err
+	}
+
+	return &ftpReader{data: data, control: c}, nil
+}
+
+// ftpReader 读取数据连接，关闭时一并关闭控制连接。分段读取完成后直接断开，不等待传输结束的响应
+type ftpReader struct {
+	data    net.Conn
+	control *ftpConn
+}
+
+func (r *ftpReader) Read(p []byte) (int, error) {
+	return r.data.Read(p)
+}
+
+func (r *ftpReader) Close() error {
+	r.data.Close()
+	return r.control.Close()
+}
@@ -0,0 +1,484 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/rpc"
+	"github.com/juju/ratelimit"
+)
+
+const (
+	// controlFileName 下载目录中记录任务进度的控制文件
+	controlFileName = ".cloudreve_download"
+	// saveInterval 下载过程中保存进度的间隔
+	saveInterval = 2 * time.Second
+	// idleTimeout 连接无数据传输超过该时长时重新连接
+	idleTimeout = 60 * time.Second
+)
+
+var errShortRead = errors.New("connection closed before segment completed")
+
+// segment 分段下载的一段，End 为 -1 表示读取至末尾
+type segment struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	Done  int64 `json:"done"`
+}
+
+// state 持久化在控制文件中的任务状态，用于断点续传
+type state struct {
+	GID       string     `json:"gid"`
+	Source    string     `json:"source"`
+	Settings  settings   `json:"settings"`
+	Name      string     `json:"name,omitempty"`
+	Total     int64      `json:"total"`
+	Resumable bool       `json:"resumable"`
+	Checksum  string     `json:"checksum,omitempty"`
+	Segments  []*segment `json:"segments,omitempty"`
+	Complete  bool       `json:"complete,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// job 下载任务
+type job struct {
+	completed int64 // 已下载字节数
+	speed     int64 // 下载速度，字节每秒
+	active    int32 // 活动连接数
+
+	state
+	dir     string
+	overall *ratelimit.Bucket
+
+	mu     sync.Mutex
+	status string // 与 aria2 相同：waiting、active、complete、error、removed
+	cancel context.CancelFunc
+}
+
+// loadJob 从下载目录的控制文件恢复任务
+func loadJob(dir string) (*job, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, controlFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	j := &job{dir: dir, status: "waiting"}
+	if err := json.Unmarshal(content, &j.state); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case j.Complete:
+		j.status = "complete"
+	case j.Error != "":
+		j.status = "error"
+	}
+
+	for _, seg := range j.Segments {
+		j.completed += seg.Done
+	}
+	return j, nil
+}
+
+// save 将任务状态写入控制文件
+func (j *job) save() error {
+	j.mu.Lock()
+	snapshot := j.state
+	snapshot.Segments = make([]*segment, len(j.Segments))
+	for i, seg := range j.Segments {
+		snapshot.Segments[i] = &segment{Start: seg.Start, End: seg.End, Done: atomic.LoadInt64(&seg.Done)}
+	}
+	j.mu.Unlock()
+
+	content, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	// 先写入临时文件再替换，避免中断时控制文件损坏
+	tmp := filepath.Join(j.dir, controlFileName+".tmp")
+	if err := ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(j.dir, controlFileName))
+}
+
+// begin 将等待中的任务标记为下载中，任务已被取消时返回 false
+func (j *job) begin(cancel context.CancelFunc) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.status != "waiting" {
+		return false
+	}
+	j.status = "active"
+	j.cancel = cancel
+	return true
+}
+
+// finish 根据下载结果更新任务状态并返回新状态
+func (j *job) finish(err error) string {
+	j.mu.Lock()
+	switch {
+	case j.status == "removed":
+	case err != nil:
+		j.status = "error"
+		j.Error = err.Error()
+	default:
+		j.status = "complete"
+		j.Complete = true
+	}
+	status := j.status
+	j.mu.Unlock()
+
+	atomic.StoreInt64(&j.speed, 0)
+	if status != "removed" {
+		j.save()
+	}
+	return status
+}
+
+// stop 停止未结束的任务，返回任务是否被停止
+func (j *job) stop() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.status != "waiting" && j.status != "active" {
+		return false
+	}
+
+	j.status = "removed"
+	if j.cancel != nil {
+		j.cancel()
+	}
+	return true
+}
+
+// path 返回下载文件的路径
+func (j *job) path() string {
+	return filepath.Join(j.dir, j.Name)
+}
+
+// statusInfo 返回与 aria2 tellStatus 兼容的任务状态
+func (j *job) statusInfo() rpc.StatusInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	completed := strconv.FormatInt(atomic.LoadInt64(&j.completed), 10)
+	total := "0"
+	if j.Total >= 0 {
+		total = strconv.FormatInt(j.Total, 10)
+	}
+
+	status := rpc.StatusInfo{
+		Gid:             j.GID,
+		Status:          j.status,
+		TotalLength:     total,
+		CompletedLength: completed,
+		DownloadSpeed:   strconv.FormatInt(atomic.LoadInt64(&j.speed), 10),
+		Connections:     strconv.Itoa(int(atomic.LoadInt32(&j.active))),
+		ErrorMessage:    j.Error,
+		Dir:             j.dir,
+	}
+
+	// 获取到文件信息前不返回文件列表
+	if j.Name != "" {
+		status.Files = []rpc.FileInfo{{
+			Index:           "1",
+			Path:            j.path(),
+			Length:          total,
+			CompletedLength: completed,
+			Selected:        "true",
+			URIs:            []rpc.URIInfo{{URI: j.Source, Status: "used"}},
+		}}
+	}
+
+	return status
+}
+
+// download 下载文件，已有进度时从断点继续
+func (j *job) download(ctx context.Context) error {
+	src, err := newSource(j.Source, j.Settings)
+	if err != nil {
+		return err
+	}
+
+	if j.Segments == nil {
+		if err := j.prepare(ctx, src); err != nil {
+			return err
+		}
+	} else if !j.Resumable {
+		// 不支持断点续传时重新下载
+		for _, seg := range j.Segments {
+			seg.Done = 0
+		}
+		atomic.StoreInt64(&j.completed, 0)
+	}
+
+	file, err := os.OpenFile(j.path(), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 任务限速由各分段共享
+	var bucket *ratelimit.Bucket
+	if j.Settings.SpeedLimit > 0 {
+		bucket = newBucket(j.Settings.SpeedLimit)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for _, seg := range j.Segments {
+		wg.Add(1)
+		go func(seg *segment) {
+			defer wg.Done()
+			if err := j.fetchSegment(ctx, src, file, seg, bucket); err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(seg)
+	}
+
+	done := make(chan struct{})
+	go j.monitor(done)
+	wg.Wait()
+	close(done)
+
+	if err := j.save(); err != nil {
+		return err
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+
+	if j.Total < 0 {
+		j.mu.Lock()
+		j.Total = atomic.LoadInt64(&j.completed)
+		j.mu.Unlock()
+	}
+
+	return verifyChecksum(j.path(), j.Checksum)
+}
+
+// prepare 获取文件信息并划分下载分段
+func (j *job) prepare(ctx context.Context, src source) error {
+	var (
+		info *remoteFile
+		err  error
+	)
+	err = j.retry(ctx, func() error {
+		info, err = src.probe(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	name := j.Settings.Out
+	if name == "" {
+		name = info.Name
+	}
+
+	checksum := j.Settings.Checksum
+	if checksum == "" {
+		checksum = info.Digest
+	}
+
+	j.mu.Lock()
+	j.Name = sanitizeName(name)
+	j.Total = info.Size
+	j.Resumable = info.Ranges && info.Size > 0
+	j.Checksum = checksum
+	j.Segments = planSegments(info.Size, j.Resumable, j.Settings.Split, j.Settings.MinSplitSize)
+	j.mu.Unlock()
+
+	return j.save()
+}
+
+// planSegments 将文件平均划分为若干段，每段不小于 minSize
+func planSegments(size int64, resumable bool, split int, minSize int64) []*segment {
+	if !resumable || size <= 0 {
+		return []*segment{{Start: 0, End: -1}}
+	}
+
+	n := int64(split)
+	if minSize > 0 && size/minSize < n {
+		n = size / minSize
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	segments := make([]*segment, 0, n)
+	length := size / n
+	for i := int64(0); i < n; i++ {
+		seg := &segment{Start: i * length, End: (i + 1) * length}
+		if i == n-1 {
+			seg.End = size
+		}
+		segments = append(segments, seg)
+	}
+	return segments
+}
+
+// monitor 定时计算下载速度并保存进度，直至 done 关闭
+func (j *job) monitor(done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	last, lastSaved := atomic.LoadInt64(&j.completed), time.Now()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			completed := atomic.LoadInt64(&j.completed)
+			atomic.StoreInt64(&j.speed, completed-last)
+			last = completed
+
+			if now.Sub(lastSaved) >= saveInterval {
+				j.save()
+				lastSaved = now
+			}
+		}
+	}
+}
+
+// fetchSegment 下载一个分段，出错后从已下载的位置重试
+func (j *job) fetchSegment(ctx context.Context, src source, file *os.File, seg *segment, bucket *ratelimit.Bucket) error {
+	return j.retry(ctx, func() error {
+		err := j.fetchOnce(ctx, src, file, seg, bucket)
+		if err != nil && !j.Resumable {
+			// 无法续传时丢弃已下载的部分
+			atomic.AddInt64(&j.completed, -atomic.SwapInt64(&seg.Done, 0))
+		}
+		return err
+	})
+}
+
+func (j *job) fetchOnce(ctx context.Context, src source, file *os.File, seg *segment, bucket *ratelimit.Bucket) error {
+	start := seg.Start + atomic.LoadInt64(&seg.Done)
+	if seg.End >= 0 && start >= seg.End {
+		return nil
+	}
+
+	// 连接长时间没有数据传输时断开重连
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	watchdog := time.AfterFunc(idleTimeout, cancel)
+	defer watchdog.Stop()
+
+	body, err := src.open(ctx, start, seg.End)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	atomic.AddInt32(&j.active, 1)
+	defer atomic.AddInt32(&j.active, -1)
+
+	var reader io.Reader = body
+	if seg.End >= 0 {
+		reader = io.LimitReader(reader, seg.End-start)
+	}
+	if bucket != nil {
+		reader = ratelimit.Reader(reader, bucket)
+	}
+	if j.overall != nil {
+		reader = ratelimit.Reader(reader, j.overall)
+	}
+
+	buf := make([]byte, 32*1024)
+	offset := start
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			watchdog.Reset(idleTimeout)
+			if _, err := file.WriteAt(buf[:n], offset); err != nil {
+				return err
+			}
+			offset += int64(n)
+			atomic.AddInt64(&seg.Done, int64(n))
+			atomic.AddInt64(&j.completed, int64(n))
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if seg.End >= 0 && offset < seg.End {
+		return errShortRead
+	}
+	return nil
+}
+
+// retry 重试操作直至成功、被取消或达到最大尝试次数
+func (j *job) retry(ctx context.Context, fn func() error) error {
+	for try := 1; ; try++ {
+		err := fn()
+		if err == nil || ctx.Err() != nil || isPermanent(err) || (j.Settings.MaxTries > 0 && try >= j.Settings.MaxTries) {
+			if ctx.Err() != nil && err != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(j.Settings.RetryWait) * time.Second):
+		}
+	}
+}
+
+// sanitizeName 去除文件名中的路径，避免写入下载目录之外
+func sanitizeName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	switch name {
+	case "", ".", "..", "/", controlFileName, controlFileName + ".tmp":
+		return "download"
+	}
+	return name
+}
+
+// permanentError 重试也无法成功的错误
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func isPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+func permanent(format string, a ...interface{}) error {
+	return permanentError{err: fmt.Errorf(format, a...)}
+}
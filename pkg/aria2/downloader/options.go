@@ -0,0 +1,174 @@
+package downloader
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/juju/ratelimit"
+)
+
+// 未设定时使用的下载配置，与 aria2 的默认值相同
+const (
+	defaultSplit        = 5
+	defaultMinSplitSize = 20 << 20
+	defaultMaxTries     = 5
+	defaultRetryWait    = 3
+)
+
+// settings 单个任务的下载配置
+type settings struct {
+	Split        int      `json:"split"`                 // 最大分段数
+	MinSplitSize int64    `json:"min_split_size"`        // 最小分段大小
+	SpeedLimit   int64    `json:"speed_limit,omitempty"` // 任务限速，字节每秒
+	MaxTries     int      `json:"max_tries"`             // 每段最大尝试次数，0 为不限制
+	RetryWait    int      `json:"retry_wait"`            // 重试间隔，单位为秒
+	Checksum     string   `json:"checksum,omitempty"`    // 校验值，格式为 算法=十六进制摘要
+	UserAgent    string   `json:"user_agent,omitempty"`
+	Headers      []string `json:"headers,omitempty"`
+	Out          string   `json:"out,omitempty"` // 保存的文件名
+}
+
+// parseSettings 从 aria2 格式的下载选项中读取内置下载器支持的部分
+func parseSettings(options map[string]interface{}) settings {
+	res := settings{
+		Split:        optionInt(options, "split", defaultSplit),
+		MinSplitSize: optionSize(options, "min-split-size", defaultMinSplitSize),
+		SpeedLimit:   optionSize(options, "max-download-limit", 0),
+		MaxTries:     optionInt(options, "max-tries", defaultMaxTries),
+		RetryWait:    optionInt(options, "retry-wait", defaultRetryWait),
+		Checksum:     optionString(options, "checksum"),
+		UserAgent:    optionString(options, "user-agent"),
+		Out:          optionString(options, "out"),
+	}
+
+	switch header := options["header"].(type) {
+	case string:
+		res.Headers = []string{header}
+	case []interface{}:
+		for _, h := range header {
+			res.Headers = append(res.Headers, fmt.Sprint(h))
+		}
+	}
+
+	if res.Split < 1 {
+		res.Split = 1
+	}
+	return res
+}
+
+func optionString(options map[string]interface{}, key string) string {
+	switch v := options[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func optionInt(options map[string]interface{}, key string, def int) int {
+	if v, err := strconv.Atoi(optionString(options, key)); err == nil {
+		return v
+	}
+	return def
+}
+
+// optionSize 读取大小选项，支持 K、M、G 后缀
+func optionSize(options map[string]interface{}, key string, def int64) int64 {
+	if v, err := parseSize(optionString(options, key)); err == nil {
+		return v
+	}
+	return def
+}
+
+func parseSize(s string) (int64, error) {
+	unit := int64(1)
+	switch {
+	case strings.HasSuffix(strings.ToUpper(s), "K"):
+		unit = 1 << 10
+	case strings.HasSuffix(strings.ToUpper(s), "M"):
+		unit = 1 << 20
+	case strings.HasSuffix(strings.ToUpper(s), "G"):
+		unit = 1 << 30
+	}
+	if unit > 1 {
+		s = s[:len(s)-1]
+	}
+
+	v, err := strconv.ParseInt(s, 10, 64)
+	return v * unit, err
+}
+
+// newBucket 创建限速令牌桶，rate 不大于 0 时不限速
+func newBucket(rate int64) *ratelimit.Bucket {
+	if rate <= 0 {
+		return nil
+	}
+	return ratelimit.NewBucketWithRate(float64(rate), rate)
+}
+
+// newHash 根据 aria2 的算法名称创建摘要
+func newHash(algorithm string) hash.Hash {
+	switch strings.ToLower(algorithm) {
+	case "md5":
+		return md5.New()
+	case "sha-1", "sha1":
+		return sha1.New()
+	case "sha-224", "sha224":
+		return sha256.New224()
+	case "sha-256", "sha256":
+		return sha256.New()
+	case "sha-384", "sha384":
+		return sha512.New384()
+	case "sha-512", "sha512":
+		return sha512.New()
+	}
+	return nil
+}
+
+// verifyChecksum 校验下载完成的文件，checksum 为空时不校验
+func verifyChecksum(path, checksum string) error {
+	if checksum == "" {
+		return nil
+	}
+
+	algorithm, expected, ok := cut(checksum, "=")
+	h := newHash(algorithm)
+	if !ok || h == nil {
+		return fmt.Errorf("unsupported checksum %q", checksum)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, strings.TrimSpace(expected)) {
+		return fmt.Errorf("checksum mismatch, expected %s but got %s", expected, actual)
+	}
+	return nil
+}
+
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,182 @@
+package downloader
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+)
+
+// remoteFile 探测到的远程文件信息
+type remoteFile struct {
+	Name   string
+	Size   int64  // 为 -1 表示未知
+	Ranges bool   // 是否支持从指定位置读取
+	Digest string // 服务端提供的校验值，格式同 settings.Checksum
+}
+
+// source 下载源
+type source interface {
+	// probe 获取远程文件信息
+	probe(ctx context.Context) (*remoteFile, error)
+	// open 读取 [start, end) 范围内的数据，end 为 -1 时读取至末尾
+	open(ctx context.Context, start, end int64) (io.ReadCloser, error)
+}
+
+// newSource 根据下载地址创建下载源
+func newSource(rawURL string, s settings) (source, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, permanentError{err: err}
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		// 避免传输层自动解压导致长度与探测结果不符
+		header := http.Header{"Accept-Encoding": []string{"identity"}}
+		for _, h := range s.Headers {
+			if name, value, ok := cut(h, ":"); ok {
+				header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+			}
+		}
+		if s.UserAgent != "" {
+			header.Set("User-Agent", s.UserAgent)
+		}
+		return &httpSource{url: u.String(), header: header}, nil
+	case "ftp":
+		return &ftpSource{url: u}, nil
+	}
+
+	return nil, permanentError{err: ErrUnsupportedSource}
+}
+
+// httpSource HTTP(S) 下载源
+type httpSource struct {
+	url    string
+	header http.Header
+}
+
+func (s *httpSource) request(ctx context.Context, header http.Header) (*http.Response, error) {
+	// 客户端的请求头会被请求选项修改，每次请求使用新的客户端
+	resp := request.NewClient(request.WithTimeout(0)).Request(
+		"GET",
+		s.url,
+		nil,
+		request.WithContext(ctx),
+		request.WithHeader(header),
+	)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+
+	if resp.Response.StatusCode >= 400 {
+		resp.Response.Body.Close()
+		err := fmt.Errorf("server returns %s", resp.Response.Status)
+		if resp.Response.StatusCode < 500 && resp.Response.StatusCode != http.StatusTooManyRequests {
+			return nil, permanentError{err: err}
+		}
+		return nil, err
+	}
+
+	return resp.Response, nil
+}
+
+func (s *httpSource) probe(ctx context.Context) (*remoteFile, error) {
+	header := s.header.Clone()
+	header.Set("Range", "bytes=0-")
+	resp, err := s.request(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	info := &remoteFile{
+		Name:   httpFileName(resp),
+		Size:   resp.ContentLength,
+		Digest: parseDigestHeader(resp.Header.Get("Digest")),
+	}
+
+	if resp.StatusCode == http.StatusPartialContent {
+		// Content-Range: bytes 0-N/total
+		if _, total, ok := cut(resp.Header.Get("Content-Range"), "/"); ok {
+			if size, err := strconv.ParseInt(total, 10, 64); err == nil {
+				info.Size = size
+				info.Ranges = true
+			}
+		}
+	}
+
+	return info, nil
+}
+
+func (s *httpSource) open(ctx context.Context, start, end int64) (io.ReadCloser, error) {
+	header := s.header.Clone()
+	if start > 0 || end >= 0 {
+		rangeEnd := ""
+		if end >= 0 {
+			rangeEnd = strconv.FormatInt(end-1, 10)
+		}
+		header.Set("Range", fmt.Sprintf("bytes=%d-%s", start, rangeEnd))
+	}
+
+	resp, err := s.request(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+
+	// 服务端忽略 Range 时无法从中间位置读取
+	if start > 0 && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, permanent("server does not support range requests")
+	}
+
+	return resp.Body, nil
+}
+
+// httpFileName 从 Content-Disposition 或最终地址中获取文件名
+func httpFileName(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if name := params["filename"]; name != "" {
+			return name
+		}
+	}
+
+	if name := path.Base(resp.Request.URL.Path); name != "/" && name != "." {
+		return name
+	}
+	return "index.html"
+}
+
+// parseDigestHeader 解析 RFC 3230 的 Digest 响应头，返回支持的最强校验值
+func parseDigestHeader(value string) string {
+	res, rank := "", 0
+	ranks := map[string]int{"md5": 1, "sha": 2, "sha-256": 3, "sha-512": 4}
+	for _, digest := range strings.Split(value, ",") {
+		algorithm, encoded, ok := cut(strings.TrimSpace(digest), "=")
+		algorithm = strings.ToLower(algorithm)
+		if !ok || ranks[algorithm] <= rank {
+			continue
+		}
+		algorithmRank := ranks[algorithm]
+
+		sum, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+
+		if algorithm == "sha" {
+			algorithm = "sha-1"
+		}
+		res, rank = algorithm+"="+hex.EncodeToString(sum), algorithmRank
+	}
+	return res
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"path/filepath"
 	"strconv"
+	"sync"
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
@@ -15,8 +16,10 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/cloudreve/Cloudreve/v3/pkg/progress"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/cloudreve/Cloudreve/v3/pkg/webhook"
 )
 
 // Monitor 离线下载状态监控
@@ -27,19 +30,35 @@ type Monitor struct {
 	notifier <-chan mq.Message
 	node     cluster.Node
 	retried  int
+	stop     chan struct{}
 }
 
 var MAX_RETRY = 10
 
-// NewMonitor 新建离线下载状态监控
+var (
+	// monitors 本实例正在运行的监控，以下载记录ID为键
+	monitors     = make(map[uint]*Monitor)
+	monitorsLock sync.Mutex
+)
+
+// NewMonitor 新建离线下载状态监控，同一下载任务在本实例中只会存在一个监控
 func NewMonitor(task *model.Download, pool cluster.Pool, mqClient mq.MQ) {
 	monitor := &Monitor{
 		Task:     task,
 		notifier: make(chan mq.Message),
 		node:     pool.GetNodeByID(task.GetNodeID()),
+		stop:     make(chan struct{}),
 	}
 
 	if monitor.node != nil {
+		monitorsLock.Lock()
+		if _, ok := monitors[task.ID]; ok {
+			monitorsLock.Unlock()
+			return
+		}
+		monitors[task.ID] = monitor
+		monitorsLock.Unlock()
+
 		monitor.Interval = time.Duration(monitor.node.GetAria2Instance().GetConfig().Interval) * time.Second
 		go monitor.Loop(mqClient)
 
@@ -49,15 +68,29 @@ func NewMonitor(task *model.Download, pool cluster.Pool, mqClient mq.MQ) {
 	}
 }
 
+// StopAll 停止本实例中的所有监控，用于失去主节点身份后交由新的主节点接管
+func StopAll() {
+	monitorsLock.Lock()
+	defer monitorsLock.Unlock()
+
+	for id, monitor := range monitors {
+		close(monitor.stop)
+		delete(monitors, id)
+	}
+}
+
 // Loop 开启监控循环
 func (monitor *Monitor) Loop(mqClient mq.MQ) {
 	defer mqClient.Unsubscribe(monitor.Task.GID, monitor.notifier)
+	defer monitor.unregister()
 
 	// 首次循环立即更新
 	interval := 50 * time.Millisecond
 
 	for {
 		select {
+		case <-monitor.stop:
+			return
 		case <-monitor.notifier:
 			if monitor.Update() {
 				return
@@ -71,6 +104,16 @@ func (monitor *Monitor) Loop(mqClient mq.MQ) {
 	}
 }
 
+// unregister 从正在运行的监控中移除
+func (monitor *Monitor) unregister() {
+	monitorsLock.Lock()
+	defer monitorsLock.Unlock()
+
+	if monitors[monitor.Task.ID] == monitor {
+		delete(monitors, monitor.Task.ID)
+	}
+}
+
 // Update 更新状态，返回值表示是否退出监控
 func (monitor *Monitor) Update() bool {
 	status, err := monitor.node.GetAria2Instance().Status(monitor.Task)
@@ -109,6 +152,13 @@ func (monitor *Monitor) Update() bool {
 
 	util.Log().Debug("离线下载[%s]更新状态[%s]", status.Gid, status.Status)
 
+	switch status.Status {
+	case "complete", "error", "removed":
+		monitor.publishProgress(status, true)
+	default:
+		monitor.publishProgress(status, false)
+	}
+
 	switch status.Status {
 	case "complete":
 		return monitor.Complete(task.TaskPoll)
@@ -272,8 +322,50 @@ func (monitor *Monitor) Complete(pool task.Pool) bool {
 	return true
 }
 
+// publishProgress 推送下载进度，done 表示下载已结束
+func (monitor *Monitor) publishProgress(status rpc.StatusInfo, done bool) {
+	event := progress.Event{
+		Kind:      progress.KindDownload,
+		ID:        monitor.Task.ID,
+		UserID:    monitor.Task.UserID,
+		Phase:     monitor.Task.Status,
+		Total:     monitor.Task.TotalSize,
+		Processed: monitor.Task.DownloadedSize,
+		Speed:     uint64(monitor.Task.Speed),
+		ETA:       -1,
+		Done:      done,
+	}
+
+	if status.BitTorrent.Info.Name != "" {
+		event.File = status.BitTorrent.Info.Name
+	} else if len(status.Files) > 0 {
+		event.File = filepath.Base(status.Files[0].Path)
+	}
+
+	if event.Speed > 0 && event.Total >= event.Processed {
+		event.ETA = int64((event.Total - event.Processed) / event.Speed)
+	}
+
+	progress.Publish(event)
+}
+
 func (monitor *Monitor) setErrorStatus(err error) {
 	monitor.Task.Status = common.Error
 	monitor.Task.Error = err.Error()
 	monitor.Task.Save()
+
+	progress.Publish(progress.Event{
+		Kind:   progress.KindDownload,
+		ID:     monitor.Task.ID,
+		UserID: monitor.Task.UserID,
+		Phase:  common.Error,
+		ETA:    -1,
+		Done:   true,
+	})
+
+	webhook.Trigger(monitor.Task.UserID, webhook.EventTaskFailed, map[string]interface{}{
+		"download": monitor.Task.ID,
+		"source":   monitor.Task.Source,
+		"error":    err.Error(),
+	})
 }
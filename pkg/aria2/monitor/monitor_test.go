@@ -69,6 +69,50 @@ func TestNewMonitor(t *testing.T) {
 
 }
 
+func TestNewMonitorDuplicated(t *testing.T) {
+	a := assert.New(t)
+	mockNode := &mocks.NodeMock{}
+	mockPool := &mocks.NodePoolMock{}
+	mockPool.On("GetNodeByID", uint(1)).Return(mockNode)
+
+	existed := &Monitor{Task: &model.Download{Model: gorm.Model{ID: 10}}, stop: make(chan struct{})}
+	monitorsLock.Lock()
+	monitors[10] = existed
+	monitorsLock.Unlock()
+
+	// 已存在监控，不再创建
+	NewMonitor(&model.Download{Model: gorm.Model{ID: 10}}, mockPool, mq.NewMQ())
+	mockNode.AssertNotCalled(t, "GetAria2Instance")
+
+	// 全部停止
+	StopAll()
+	_, ok := <-existed.stop
+	a.False(ok)
+	monitorsLock.Lock()
+	a.Empty(monitors)
+	monitorsLock.Unlock()
+}
+
+func TestMonitor_LoopStopped(t *testing.T) {
+	a := assert.New(t)
+	mockMQ := mq.NewMQ()
+	m := &Monitor{
+		Task:     &model.Download{Model: gorm.Model{ID: 11}},
+		notifier: mockMQ.Subscribe("test", 1),
+		stop:     make(chan struct{}),
+	}
+	monitorsLock.Lock()
+	monitors[11] = m
+	monitorsLock.Unlock()
+
+	close(m.stop)
+	m.Loop(mockMQ)
+	monitorsLock.Lock()
+	_, ok := monitors[11]
+	monitorsLock.Unlock()
+	a.False(ok)
+}
+
 func TestMonitor_Loop(t *testing.T) {
 	a := assert.New(t)
 	mockMQ := mq.NewMQ()
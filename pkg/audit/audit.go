@@ -0,0 +1,187 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/gin-gonic/gin"
+)
+
+// 管理员操作类型
+const (
+	ActionSettingUpdate   = "setting.update"
+	ActionServiceReload   = "service.reload"
+	ActionUserSave        = "user.save"
+	ActionUserDelete      = "user.delete"
+	ActionUserBan         = "user.ban"
+	ActionUserUnban       = "user.unban"
+	ActionGroupSave       = "group.save"
+	ActionGroupDelete     = "group.delete"
+	ActionPolicySave      = "policy.save"
+	ActionPolicyDelete    = "policy.delete"
+	ActionNodeSave        = "node.save"
+	ActionNodeEnable      = "node.enable"
+	ActionNodeDisable     = "node.disable"
+	ActionNodeDelete      = "node.delete"
+	ActionFileDelete      = "file.delete"
+	ActionShareDelete     = "share.delete"
+	ActionDownloadDelete  = "download.delete"
+	ActionTaskDelete      = "task.delete"
+	ActionTaskImport      = "task.import"
+	ActionTaskCancel      = "task.cancel"
+	ActionTaskPriority    = "task.priority"
+	ActionWebhookSave     = "webhook.save"
+	ActionWebhookDelete   = "webhook.delete"
+	ActionRateLimitUnlock = "ratelimit.unlock"
+	ActionRedeemGenerate  = "redeem.generate"
+	ActionRedeemDelete    = "redeem.delete"
+)
+
+// maskedValue 敏感字段变更后记录的占位值
+const maskedValue = "******"
+
+// sensitiveFields 不记录原始值的字段及站点设置名称，嵌套对象中的同名字段同样遮盖
+var sensitiveFields = map[string]bool{
+	// 用户
+	"TwoFactor": true,
+	"Authn":     true,
+	// 节点，Aria2Options 中包含 RPC 密钥
+	"SlaveKey":     true,
+	"MasterKey":    true,
+	"Aria2Options": true,
+	// 存储策略，Options 中包含又拍云 Token
+	"AccessKey": true,
+	"SecretKey": true,
+	"Options":   true,
+	// 存储策略及离线下载配置中的 Token
+	"token": true,
+	// Webhook 签名密钥
+	"Secret": true,
+	"secret": true,
+	// 站点设置
+	"secret_key":                    true,
+	"smtpPass":                      true,
+	"oidc_client_secret":            true,
+	"ldap_bind_password":            true,
+	"captcha_ReCaptchaSecret":       true,
+	"captcha_TCaptcha_SecretId":     true,
+	"captcha_TCaptcha_SecretKey":    true,
+	"captcha_TCaptcha_AppSecretKey": true,
+}
+
+// Change 单个字段的变更
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Record 记录一条管理员操作，diff 为 nil 时不记录差异
+func Record(c *gin.Context, action string, targets []uint, diff map[string]Change) {
+	log := model.AuditLog{
+		Action: action,
+		IP:     c.ClientIP(),
+	}
+
+	if user, ok := c.Get("user"); ok {
+		if u, ok := user.(*model.User); ok {
+			log.AdminID = u.ID
+		}
+	}
+
+	if targets == nil {
+		targets = []uint{}
+	}
+	targetsRaw, _ := json.Marshal(targets)
+	log.Targets = string(targetsRaw)
+
+	if diff != nil {
+		diffRaw, _ := json.Marshal(diff)
+		log.Diff = string(diffRaw)
+	}
+
+	// 写入失败不影响原操作
+	if _, err := log.Create(); err == nil {
+		forward(&log)
+	}
+}
+
+// Diff 比较两个对象 JSON 序列化后的字段差异，before 或 after 为 nil 时视为空对象
+func Diff(before, after interface{}) map[string]Change {
+	beforeFields := toFields(before)
+	afterFields := toFields(after)
+	res := make(map[string]Change)
+
+	for k, v := range beforeFields {
+		if k == "UpdatedAt" {
+			continue
+		}
+		if newValue, ok := afterFields[k]; !ok || !reflect.DeepEqual(v, newValue) {
+			res[k] = Change{Before: v, After: afterFields[k]}
+		}
+	}
+
+	for k, v := range afterFields {
+		if _, ok := beforeFields[k]; !ok && k != "UpdatedAt" {
+			res[k] = Change{After: v}
+		}
+	}
+
+	for k, change := range res {
+		if sensitiveFields[k] {
+			res[k] = Change{Before: mask(change.Before), After: mask(change.After)}
+		} else {
+			res[k] = Change{Before: maskNested(change.Before), After: maskNested(change.After)}
+		}
+	}
+
+	return res
+}
+
+// toFields 将对象转换为字段映射
+func toFields(obj interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if obj == nil {
+		return fields
+	}
+
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return fields
+	}
+
+	json.Unmarshal(raw, &fields)
+	return fields
+}
+
+// maskNested 遮盖嵌套对象中的敏感字段
+func maskNested(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for k, field := range v {
+			if sensitiveFields[k] {
+				res[k] = mask(field)
+			} else {
+				res[k] = maskNested(field)
+			}
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			res[i] = maskNested(item)
+		}
+		return res
+	}
+	return value
+}
+
+// mask 遮盖敏感字段的值
+func mask(value interface{}) interface{} {
+	if value == nil || fmt.Sprint(value) == "" {
+		return value
+	}
+	return maskedValue
+}
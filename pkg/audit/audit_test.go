@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 修改
+	{
+		res := Diff(
+			map[string]string{"siteName": "a", "siteTitle": "b", "smtpPass": "old"},
+			map[string]string{"siteName": "c", "siteTitle": "b", "smtpPass": "new"},
+		)
+		asserts.Len(res, 2)
+		asserts.Equal(Change{Before: "a", After: "c"}, res["siteName"])
+		asserts.Equal(Change{Before: maskedValue, After: maskedValue}, res["smtpPass"])
+	}
+
+	// 新建
+	{
+		res := Diff(nil, struct{ Name string }{Name: "a"})
+		asserts.Len(res, 1)
+		asserts.Equal(Change{After: "a"}, res["Name"])
+	}
+
+	// 删除
+	{
+		res := Diff(struct{ Name string }{Name: "a"}, nil)
+		asserts.Len(res, 1)
+		asserts.Equal(Change{Before: "a"}, res["Name"])
+	}
+
+	// 忽略更新时间
+	{
+		res := Diff(map[string]string{"UpdatedAt": "1"}, map[string]string{"UpdatedAt": "2"})
+		asserts.Empty(res)
+	}
+}
+
+func TestDiff_Sensitive(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 用户二步验证密钥及 WebAuthn 凭证
+	{
+		res := Diff(model.User{Email: "a", TwoFactor: "old"}, model.User{Email: "b", TwoFactor: "new", Authn: "[]"})
+		asserts.Equal(Change{Before: "a", After: "b"}, res["Email"])
+		asserts.Equal(Change{Before: maskedValue, After: maskedValue}, res["TwoFactor"])
+		asserts.Equal(Change{Before: "", After: maskedValue}, res["Authn"])
+	}
+
+	// 节点通信密钥及离线下载 RPC 密钥
+	{
+		before := model.Node{SlaveKey: "a", MasterKey: "b", Aria2Options: `{"token":"c"}`}
+		before.Aria2OptionsSerialized.Token = "c"
+		before.Aria2OptionsSerialized.Server = "http://127.0.0.1"
+		after := model.Node{SlaveKey: "d", MasterKey: "e", Aria2Options: `{"token":"f"}`}
+		after.Aria2OptionsSerialized.Token = "f"
+		after.Aria2OptionsSerialized.Server = "http://127.0.0.2"
+		res := Diff(before, after)
+		for _, k := range []string{"SlaveKey", "MasterKey", "Aria2Options"} {
+			asserts.Equal(Change{Before: maskedValue, After: maskedValue}, res[k], k)
+		}
+		asserts.Equal(
+			Change{
+				Before: map[string]interface{}{"server": "http://127.0.0.1", "token": maskedValue},
+				After:  map[string]interface{}{"server": "http://127.0.0.2", "token": maskedValue},
+			},
+			res["Aria2OptionsSerialized"],
+		)
+	}
+
+	// 存储策略密钥
+	{
+		before := model.Policy{AccessKey: "a", SecretKey: "b", Options: `{"token":"c"}`}
+		before.OptionsSerialized.Token = "c"
+		after := model.Policy{AccessKey: "d", SecretKey: "e", Options: `{"token":"f"}`}
+		after.OptionsSerialized.Token = "f"
+		res := Diff(before, after)
+		for _, k := range []string{"AccessKey", "SecretKey", "Options"} {
+			asserts.Equal(Change{Before: maskedValue, After: maskedValue}, res[k], k)
+		}
+		asserts.Equal(maskedValue, res["OptionsSerialized"].Before.(map[string]interface{})["token"])
+		asserts.Equal(maskedValue, res["OptionsSerialized"].After.(map[string]interface{})["token"])
+	}
+
+	// Webhook 签名密钥
+	{
+		res := Diff(nil, map[string]string{"url": "http://a", "secret": "b"})
+		asserts.Equal(Change{After: "http://a"}, res["url"])
+		asserts.Equal(Change{After: maskedValue}, res["secret"])
+	}
+
+	// 站点设置
+	{
+		res := Diff(
+			map[string]string{"siteKeywords": "a", "oidc_client_secret": "b", "ldap_bind_password": "c"},
+			map[string]string{"siteKeywords": "d", "oidc_client_secret": "e", "ldap_bind_password": "f"},
+		)
+		asserts.Equal(Change{Before: "a", After: "d"}, res["siteKeywords"])
+		asserts.Equal(Change{Before: maskedValue, After: maskedValue}, res["oidc_client_secret"])
+		asserts.Equal(Change{Before: maskedValue, After: maskedValue}, res["ldap_bind_password"])
+	}
+}
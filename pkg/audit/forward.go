@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"fmt"
+	"sync"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// Forwarder 审计日志外部转发器
+type Forwarder interface {
+	Forward(line string) error
+	Close()
+}
+
+// Client 当前使用的转发器，为 nil 时不转发
+var Client Forwarder
+
+// Lock 读写锁
+var Lock sync.RWMutex
+
+// Init 根据站点设置初始化审计日志转发
+func Init() {
+	util.Log().Debug("审计日志转发初始化")
+	Lock.Lock()
+	defer Lock.Unlock()
+
+	if Client != nil {
+		Client.Close()
+		Client = nil
+	}
+
+	options := model.GetSettingByNames(
+		"audit_syslog",
+		"audit_syslog_network",
+		"audit_syslog_addr",
+		"audit_syslog_tag",
+	)
+	if options["audit_syslog"] != "1" {
+		return
+	}
+
+	client, err := NewSyslogForwarder(options["audit_syslog_network"], options["audit_syslog_addr"], options["audit_syslog_tag"])
+	if err != nil {
+		util.Log().Warning("无法连接到 Syslog 服务器，审计日志将不会被转发, %s", err)
+		return
+	}
+
+	Client = client
+}
+
+// forward 转发审计日志
+func forward(log *model.AuditLog) {
+	Lock.RLock()
+	defer Lock.RUnlock()
+
+	if Client == nil {
+		return
+	}
+
+	line := fmt.Sprintf("admin=%d action=%s targets=%s ip=%s diff=%s",
+		log.AdminID, log.Action, log.Targets, log.IP, log.Diff)
+	if err := Client.Forward(line); err != nil {
+		util.Log().Warning("无法转发审计日志, %s", err)
+	}
+}
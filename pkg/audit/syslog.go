@@ -0,0 +1,37 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package audit
+
+import (
+	"log/syslog"
+)
+
+// SyslogForwarder 转发至 Syslog 服务器
+type SyslogForwarder struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogForwarder 新建 Syslog 转发器，addr 为空时使用本机 Syslog 服务
+func NewSyslogForwarder(network, addr, tag string) (*SyslogForwarder, error) {
+	if addr == "" {
+		network = ""
+	}
+
+	writer, err := syslog.Dial(network, addr, syslog.LOG_NOTICE|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SyslogForwarder{writer: writer}, nil
+}
+
+// Forward 发送一条日志
+func (forwarder *SyslogForwarder) Forward(line string) error {
+	return forwarder.writer.Notice(line)
+}
+
+// Close 关闭连接
+func (forwarder *SyslogForwarder) Close() {
+	forwarder.writer.Close()
+}
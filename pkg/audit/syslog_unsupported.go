@@ -0,0 +1,13 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package audit
+
+import (
+	"errors"
+)
+
+// NewSyslogForwarder 当前平台不支持 Syslog
+func NewSyslogForwarder(network, addr, tag string) (Forwarder, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
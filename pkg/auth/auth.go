@@ -14,6 +14,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
 )
 
 var (
@@ -143,3 +144,16 @@ func Init() {
 		SecretKey: []byte(secretKey),
 	}
 }
+
+// ConnectingIP 返回用于访问控制的客户端地址。未配置可信代理时请求头中的地址可被客户端伪造，
+// 此时使用连接的对端地址
+func ConnectingIP(c *gin.Context) string {
+	if len(conf.SystemConfig.TrustedProxies) > 0 || conf.UnixConfig.Listen != "" {
+		return c.ClientIP()
+	}
+
+	if ip, ok := c.RemoteIP(); ok {
+		return ip.String()
+	}
+	return ""
+}
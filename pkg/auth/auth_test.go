@@ -3,10 +3,13 @@ package auth
 import (
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -134,3 +137,19 @@ func TestCheckRequest(t *testing.T) {
 		asserts.Error(err)
 	}
 }
+
+func TestConnectingIP(t *testing.T) {
+	asserts := assert.New(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	c.Request.RemoteAddr = "192.168.1.2:1234"
+	c.Request.Header.Set("X-Forwarded-For", "10.0.0.1")
+
+	// 未配置可信代理时使用对端地址
+	asserts.Equal("192.168.1.2", ConnectingIP(c))
+
+	// 配置可信代理时使用请求头中的地址
+	conf.SystemConfig.TrustedProxies = []string{"192.168.1.2"}
+	defer func() { conf.SystemConfig.TrustedProxies = nil }()
+	asserts.Equal("10.0.0.1", ConnectingIP(c))
+}
@@ -0,0 +1,73 @@
+package changes
+
+import (
+	"encoding/gob"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+)
+
+// Topic 发布目录变更使用的消息主题
+const Topic = "changes"
+
+const (
+	// ActionCreate 新建文件或目录
+	ActionCreate = "create"
+	// ActionUpdate 更新文件内容
+	ActionUpdate = "update"
+	// ActionDelete 删除文件或目录
+	ActionDelete = "delete"
+	// ActionMove 移动文件或目录
+	ActionMove = "move"
+	// ActionRename 重命名文件或目录
+	ActionRename = "rename"
+)
+
+// Event 用户文件系统中的一次变更
+type Event struct {
+	UserID uint
+	Action string
+	// 发生变更的目录，移动时为目标目录
+	Folder uint
+	// 移动前所在的目录，仅移动时有效
+	From uint
+	// 发生变更的文件及目录，复制等无法确定对象时为空
+	Files []uint
+	Dirs  []uint
+	// 变更后的名称，仅新建或重命名单个对象时有效
+	Name string
+	Time time.Time
+}
+
+func init() {
+	gob.Register(Event{})
+}
+
+// Publish 发布变更，经由消息队列送达各实例的订阅者
+func Publish(event Event) {
+	if event.UserID == 0 {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	mq.GlobalMQ.Publish(Topic, mq.Message{
+		TriggeredBy: event.Action,
+		Content:     event,
+	})
+}
+
+// Folders 返回与变更相关的全部目录，订阅其中任一目录的客户端都会收到此变更
+func (event *Event) Folders() []uint {
+	folders := make([]uint, 0, len(event.Dirs)+2)
+	if event.Folder > 0 {
+		folders = append(folders, event.Folder)
+	}
+	if event.From > 0 {
+		folders = append(folders, event.From)
+	}
+
+	return append(folders, event.Dirs...)
+}
@@ -0,0 +1,101 @@
+package changes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvent_Folders(t *testing.T) {
+	a := assert.New(t)
+
+	event := Event{Folder: 1, From: 2, Dirs: []uint{3, 4}}
+	a.Equal([]uint{1, 2, 3, 4}, event.Folders())
+
+	event = Event{Folder: 1, Files: []uint{5}}
+	a.Equal([]uint{1}, event.Folders())
+}
+
+func TestPublish(t *testing.T) {
+	a := assert.New(t)
+	notifier := mq.GlobalMQ.Subscribe(Topic, 1)
+	defer mq.GlobalMQ.Unsubscribe(Topic, notifier)
+
+	// 未指定用户
+	Publish(Event{Action: ActionCreate})
+	select {
+	case <-notifier:
+		t.Fatal("unexpected event")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	Publish(Event{UserID: 1, Action: ActionCreate, Folder: 2})
+	select {
+	case msg := <-notifier:
+		event := msg.Content.(Event)
+		a.Equal(ActionCreate, event.Action)
+		a.False(event.Time.IsZero())
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestHub(t *testing.T) {
+	a := assert.New(t)
+	hub := NewHub()
+
+	sub := hub.Subscribe(1)
+	other := hub.Subscribe(2)
+	other.Watch([]uint{1})
+
+	// 未订阅任何目录
+	hub.Dispatch(Event{UserID: 1, Folder: 1})
+	a.Len(sub.C, 0)
+
+	// 订阅的目录发生变更，不分发给其他用户
+	sub.Watch([]uint{1, 2})
+	hub.Dispatch(Event{UserID: 1, Folder: 1})
+	a.Len(sub.C, 1)
+	a.Len(other.C, 0)
+	<-sub.C
+
+	// 移出订阅的目录
+	hub.Dispatch(Event{UserID: 1, Action: ActionMove, Folder: 3, From: 2})
+	a.Equal(ActionMove, (<-sub.C).Action)
+
+	// 订阅的目录自身被删除
+	sub.Watch([]uint{4})
+	hub.Dispatch(Event{UserID: 1, Action: ActionDelete, Folder: 3, Dirs: []uint{4}})
+	a.Equal(ActionDelete, (<-sub.C).Action)
+
+	// 替换订阅后不再收到原目录的变更
+	hub.Dispatch(Event{UserID: 1, Folder: 1})
+	a.Len(sub.C, 0)
+
+	// 取消订阅
+	hub.Unsubscribe(1, sub)
+	hub.Dispatch(Event{UserID: 1, Folder: 4})
+	a.Len(sub.C, 0)
+	a.NotContains(hub.subscribers, uint(1))
+}
+
+func TestInit(t *testing.T) {
+	a := assert.New(t)
+	Init()
+
+	sub := Default.Subscribe(3)
+	defer Default.Unsubscribe(3, sub)
+	sub.Watch([]uint{5})
+
+	Publish(Event{UserID: 3, Action: ActionRename, Folder: 5, Name: "new"})
+	select {
+	case event := <-sub.C:
+		a.Equal("new", event.Name)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	mq.GlobalMQ.Publish(Topic, mq.Message{Content: "invalid"})
+}
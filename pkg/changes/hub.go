@@ -0,0 +1,117 @@
+package changes
+
+import (
+	"sync"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+)
+
+// subscriberBuffer 每个订阅者的缓冲区大小，订阅者消费过慢时丢弃新的变更
+const subscriberBuffer = 64
+
+// Default 本实例使用的变更分发中心
+var Default = NewHub()
+
+var initOnce sync.Once
+
+// Subscriber 订阅某个用户部分目录变更的客户端
+type Subscriber struct {
+	C <-chan Event
+
+	ch      chan Event
+	mu      sync.Mutex
+	folders map[uint]bool
+}
+
+// Watch 替换订阅的目录
+func (s *Subscriber) Watch(folders []uint) {
+	set := make(map[uint]bool, len(folders))
+	for _, folder := range folders {
+		set[folder] = true
+	}
+
+	s.mu.Lock()
+	s.folders = set
+	s.mu.Unlock()
+}
+
+// watching 返回是否订阅了变更相关的目录
+func (s *Subscriber) watching(event *Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, folder := range event.Folders() {
+		if s.folders[folder] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Hub 将变更分发给订阅了相关目录的客户端
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[uint]map[*Subscriber]struct{}
+}
+
+// NewHub 新建变更分发中心
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[uint]map[*Subscriber]struct{}),
+	}
+}
+
+// Init 订阅消息队列中的目录变更。配置 Redis 时，各实例均可收到其他实例发布的变更
+func Init() {
+	initOnce.Do(func() {
+		mq.GlobalMQ.SubscribeCallback(Topic, func(msg mq.Message) {
+			if event, ok := msg.Content.(Event); ok {
+				Default.Dispatch(event)
+			}
+		})
+	})
+}
+
+// Subscribe 新建用户的订阅者，初始时未订阅任何目录
+func (h *Hub) Subscribe(uid uint) *Subscriber {
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscriber{C: ch, ch: ch, folders: make(map[uint]bool)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[uid]; !ok {
+		h.subscribers[uid] = make(map[*Subscriber]struct{})
+	}
+	h.subscribers[uid][sub] = struct{}{}
+
+	return sub
+}
+
+// Unsubscribe 移除订阅者
+func (h *Hub) Unsubscribe(uid uint, sub *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subscribers[uid], sub)
+	if len(h.subscribers[uid]) == 0 {
+		delete(h.subscribers, uid)
+	}
+}
+
+// Dispatch 将变更分发给订阅了相关目录的订阅者
+func (h *Hub) Dispatch(event Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscribers[event.UserID] {
+		if !sub.watching(&event) {
+			continue
+		}
+
+		select {
+		case sub.ch <- event:
+		default:
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/common"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/downloader"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/rpc"
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
@@ -28,6 +29,7 @@ const (
 type MasterNode struct {
 	Model    *model.Node
 	aria2RPC rpcService
+	builtin  *downloader.Downloader // 使用内置下载器时的下载器实例
 	lock     sync.RWMutex
 }
 
@@ -53,12 +55,27 @@ func (node *MasterNode) Init(nodeModel *model.Node) {
 	node.aria2RPC.parent = node
 	node.aria2RPC.retryDuration = statusRetryDuration
 	node.aria2RPC.deletePaddingDuration = deleteTempFileDuration
+	node.builtin = nil
+	if nodeModel.Aria2OptionsSerialized.UseBuiltinDownloader() {
+		node.builtin = downloader.New(nodeModel.Aria2OptionsSerialized, mq.GlobalMQ)
+	}
 	node.lock.Unlock()
 
 	node.lock.RLock()
 	if node.Model.Aria2Enabled {
+		builtin := node.builtin
 		node.lock.RUnlock()
-		node.aria2RPC.Init()
+
+		if builtin == nil {
+			node.aria2RPC.Init()
+			return
+		}
+
+		// 切换至内置下载器后关闭先前的 RPC 连接
+		node.Kill()
+		if err := builtin.Init(); err != nil {
+			util.Log().Warning("无法初始化内置下载器，%s", err)
+		}
 		return
 	}
 	node.lock.RUnlock()
@@ -111,7 +128,7 @@ func (node *MasterNode) IsActive() bool {
 	return true
 }
 
-// Kill 结束aria2请求
+// Kill 结束aria2请求，内置下载器的任务不受影响
 func (node *MasterNode) Kill() {
 	if node.aria2RPC.Caller != nil {
 		node.aria2RPC.Caller.Close()
@@ -127,6 +144,11 @@ func (node *MasterNode) GetAria2Instance() common.Aria2 {
 		return &common.DummyAria2{}
 	}
 
+	if node.builtin != nil {
+		defer node.lock.RUnlock()
+		return node.builtin
+	}
+
 	if !node.aria2RPC.Initialized {
 		node.lock.RUnlock()
 		node.aria2RPC.Init()
@@ -3,6 +3,7 @@ package cluster
 import (
 	"context"
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/downloader"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/rpc"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
@@ -89,6 +90,28 @@ func TestMasterNode_GetAria2Instance(t *testing.T) {
 	a.NotNil(m.GetAria2Instance())
 }
 
+func TestMasterNode_BuiltinDownloader(t *testing.T) {
+	a := assert.New(t)
+	m := &MasterNode{}
+	m.Init(&model.Node{
+		Aria2Enabled: true,
+		Aria2OptionsSerialized: model.Aria2Option{
+			Downloader: model.DownloaderBuiltin,
+			TempPath:   t.TempDir(),
+		},
+	})
+
+	a.NotNil(m.builtin)
+	a.Equal(m.builtin, m.GetAria2Instance())
+	a.Equal(model.DownloaderBuiltin, m.GetAria2Instance().GetConfig().Downloader)
+
+	// 切换回 aria2
+	m.Init(&model.Node{Aria2Enabled: true})
+	a.Nil(m.builtin)
+	_, ok := m.GetAria2Instance().(*downloader.Downloader)
+	a.False(ok)
+}
+
 func TestRpcService_Init(t *testing.T) {
 	a := assert.New(t)
 	m := &MasterNode{
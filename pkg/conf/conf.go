@@ -27,6 +27,8 @@ type system struct {
 	SessionSecret string
 	HashIDSalt    string
 	GracePeriod   int `validate:"gte=0"`
+	// 可信的反向代理地址或网段，为空时不信任请求头中的客户端地址
+	TrustedProxies []string
 }
 
 type ssl struct {
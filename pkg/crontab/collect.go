@@ -10,6 +10,7 @@ import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ldap"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
@@ -95,5 +96,81 @@ func uploadSessionCollect() {
 		fs.Recycle()
 	}
 
+	// 释放过期的访客上传会话预留的分享累计上传大小
+	for _, reservation := range model.GetShareUploadReservations() {
+		if _, sessionExist := cache.Get(filesystem.UploadSessionCachePrefix + reservation.SessionID); sessionExist {
+			continue
+		}
+
+		if err := reservation.Release(); err != nil {
+			util.Log().Warning("无法释放上传分享的预留大小, %s", err)
+		}
+	}
+
 	util.Log().Info("定时任务 [cron_recycle_upload_session] 执行完毕")
 }
+
+func activityCollect() {
+	days := model.GetIntSetting("activity_retention_days", 90)
+	if days <= 0 {
+		return
+	}
+
+	before := time.Now().AddDate(0, 0, -days)
+	if err := model.DeleteActivitiesBefore(before); err != nil {
+		util.Log().Warning("无法清理过期操作记录, %s", err)
+	}
+
+	util.Log().Info("定时任务 [cron_collect_activity] 执行完毕")
+}
+
+func changeJournalCollect() {
+	days := model.GetIntSetting("change_journal_retention_days", 30)
+	if days <= 0 {
+		return
+	}
+
+	before := time.Now().AddDate(0, 0, -days)
+	if err := model.DeleteChangesBefore(before); err != nil {
+		util.Log().Warning("无法清理过期变更日志, %s", err)
+	}
+
+	util.Log().Info("定时任务 [cron_collect_change_journal] 执行完毕")
+}
+
+func webdavLockCollect() {
+	if err := model.DeleteExpiredWebdavLocks(0, time.Now()); err != nil {
+		util.Log().Warning("无法清理过期的 WebDAV 锁, %s", err)
+	}
+
+	util.Log().Info("定时任务 [cron_collect_webdav_lock] 执行完毕")
+}
+
+func ldapSync() {
+	if err := ldap.Sync(); err != nil {
+		if err != ldap.ErrNotEnabled {
+			util.Log().Warning("无法同步 LDAP 目录用户, %s", err)
+		}
+		return
+	}
+
+	util.Log().Info("定时任务 [cron_ldap_sync] 执行完毕")
+}
+
+// storageExpire 清理过期的容量包并降级限时用户组到期的用户，
+// 由此超出可用容量的用户由 overuseCheck 处理
+func storageExpire() {
+	// 删除过期的容量包
+	if err := model.DeleteStoragePacks(model.GetExpiredStoragePacks()); err != nil {
+		util.Log().Warning("无法删除过期容量包, %s", err)
+	}
+
+	// 限时用户组到期降级
+	for _, user := range model.GetGroupExpiredUsers() {
+		if err := user.DowngradeGroup(); err != nil {
+			util.Log().Warning("无法恢复用户 %d 的用户组, %s", user.ID, err)
+		}
+	}
+
+	util.Log().Info("定时任务 [cron_storage_expire] 执行完毕")
+}
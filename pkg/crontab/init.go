@@ -1,7 +1,10 @@
 package crontab
 
 import (
+	"sync"
+
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/leader"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/robfig/cron/v3"
 )
@@ -9,23 +12,51 @@ import (
 // Cron 定时任务
 var Cron *cron.Cron
 
-// Reload 重新启动定时任务
+var cronLock sync.Mutex
+
+// Reload 重新启动定时任务，仅主节点上的定时任务会被启动
 func Reload() {
+	if leader.IsLeader() {
+		stop()
+		start()
+	}
+}
+
+// Init 初始化定时任务，多实例部署时仅在主节点上运行
+func Init() {
+	leader.OnElected(start)
+	leader.OnRevoked(stop)
+}
+
+// stop 停止定时任务
+func stop() {
+	cronLock.Lock()
+	defer cronLock.Unlock()
+
 	if Cron != nil {
 		Cron.Stop()
+		Cron = nil
 	}
-	Init()
 }
 
-// Init 初始化定时任务
-func Init() {
+// start 启动定时任务
+func start() {
+	cronLock.Lock()
+	defer cronLock.Unlock()
+
 	util.Log().Info("初始化定时任务...")
 	// 读取cron日程设置
 	options := model.GetSettingByNames(
 		"cron_garbage_collect",
 		"cron_recycle_upload_session",
+		"cron_collect_activity",
+		"cron_ldap_sync",
+		"cron_storage_expire",
+		"cron_overuse_check",
+		"cron_collect_change_journal",
+		"cron_collect_webdav_lock",
 	)
-	Cron := cron.New()
+	Cron = cron.New()
 	for k, v := range options {
 		var handler func()
 		switch k {
@@ -33,6 +64,18 @@ func Init() {
 			handler = garbageCollect
 		case "cron_recycle_upload_session":
 			handler = uploadSessionCollect
+		case "cron_collect_activity":
+			handler = activityCollect
+		case "cron_ldap_sync":
+			handler = ldapSync
+		case "cron_storage_expire":
+			handler = storageExpire
+		case "cron_overuse_check":
+			handler = overuseCheck
+		case "cron_collect_change_journal":
+			handler = changeJournalCollect
+		case "cron_collect_webdav_lock":
+			handler = webdavLockCollect
 		default:
 			util.Log().Warning("未知定时任务类型 [%s]，跳过", k)
 			continue
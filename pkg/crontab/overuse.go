@@ -0,0 +1,38 @@
+package crontab
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// overuseCheck 检查超出可用容量的用户，宽限期结束后限制账户，容量恢复后自动解除
+func overuseCheck() {
+	grace := time.Duration(model.GetIntSetting("overuse_grace_period", 604800)) * time.Second
+	action := model.GetSettingByName("overuse_action")
+
+	for _, user := range model.GetOveruseCandidates() {
+		started, err := user.UpdateOveruseStatus(grace, action)
+		if err != nil {
+			util.Log().Warning("无法更新用户 %d 的超额使用状态, %s", user.ID, err)
+			continue
+		}
+
+		if started {
+			title, body := email.NewOveruseEmail(
+				user.Nick,
+				user.Storage,
+				user.GetTotalCapacity(),
+				user.OveruseSince.Add(grace),
+				action,
+			)
+			if err := email.Send(user.Email, title, body); err != nil {
+				util.Log().Warning("无法发送超额使用警告邮件, %s", err)
+			}
+		}
+	}
+
+	util.Log().Info("定时任务 [cron_overuse_check] 执行完毕")
+}
@@ -2,6 +2,7 @@ package email
 
 import (
 	"fmt"
+	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
@@ -34,3 +35,57 @@ func NewResetEmail(userName, resetURL string) (string, string) {
 	return fmt.Sprintf("【%s】密码重置", options["siteName"]),
 		util.Replace(replace, options["mail_reset_pwd_template"])
 }
+
+// NewShareUploadEmail 新建上传分享收到文件的通知邮件
+func NewShareUploadEmail(userName, shareName, fileName, shareURL string) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL", "siteTitle", "mail_share_upload_template")
+	replace := map[string]string{
+		"{siteTitle}":    options["siteName"],
+		"{userName}":     userName,
+		"{shareName}":    shareName,
+		"{fileName}":     fileName,
+		"{shareUrl}":     shareURL,
+		"{siteUrl}":      options["siteURL"],
+		"{siteSecTitle}": options["siteTitle"],
+	}
+	return fmt.Sprintf("【%s】上传分享收到新文件", options["siteName"]),
+		util.Replace(replace, options["mail_share_upload_template"])
+}
+
+// NewOveruseEmail 新建超出可用容量的警告邮件，deadline 为宽限期结束时间
+func NewOveruseEmail(userName string, used, total uint64, deadline time.Time, action string) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL", "siteTitle", "mail_overuse_template")
+	actionName := "限制为只读"
+	if action == model.OveruseActionBan {
+		actionName = "封禁"
+	}
+
+	replace := map[string]string{
+		"{siteTitle}":    options["siteName"],
+		"{userName}":     userName,
+		"{used}":         formatSize(used),
+		"{total}":        formatSize(total),
+		"{deadline}":     deadline.Format("2006-01-02 15:04"),
+		"{action}":       actionName,
+		"{siteUrl}":      options["siteURL"],
+		"{siteSecTitle}": options["siteTitle"],
+	}
+	return fmt.Sprintf("【%s】存储容量超出限制", options["siteName"]),
+		util.Replace(replace, options["mail_overuse_template"])
+}
+
+// formatSize 将字节数格式化为便于阅读的大小
+func formatSize(size uint64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB", "PB"}
+	value := float64(size)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+
+	if i == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.2f %s", value, units[i])
+}
@@ -14,6 +14,7 @@ import (
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/progress"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
 	"github.com/mholt/archiver/v4"
@@ -143,7 +144,11 @@ func (fs *FileSystem) doCompress(ctx context.Context, file *model.File, folder *
 			return
 		}
 
-		_, err = io.Copy(writer, fileToZip)
+		tracker := progress.FromContext(ctx)
+		tracker.BeginFile(header.Name, file.Size)
+		defer tracker.EndFile()
+
+		_, err = io.Copy(writer, progress.NewReader(fileToZip, tracker))
 	} else if folder != nil {
 		// 对象是目录
 		// 获取子文件
@@ -203,8 +208,12 @@ func (fs *FileSystem) Decompress(ctx context.Context, src, dst, encoding string)
 	}
 	defer zipFile.Close()
 
+	// 下载或流式解压时，按读取的压缩包字节数计算进度
+	tracker := progress.FromContext(ctx)
+	tracker.Reset(fs.FileTarget[0].Size)
+
 	// 下载前先判断是否是可解压的格式
-	format, readStream, err := archiver.Identify(fs.FileTarget[0].SourceName, fileStream)
+	format, readStream, err := archiver.Identify(fs.FileTarget[0].SourceName, progress.NewReader(fileStream, tracker))
 	if err != nil {
 		util.Log().Warning("无法识别文件格式 %s , %s", fs.FileTarget[0].SourceName, err)
 		return err
@@ -237,6 +246,17 @@ func (fs *FileSystem) Decompress(ctx context.Context, src, dst, encoding string)
 		// 设置文件偏移量
 		zipFile.Seek(0, io.SeekStart)
 		reader = zipFile
+
+		// zip 格式解压时按解压出的字节数计算进度
+		var total uint64
+		if stat, err := zipFile.Stat(); err == nil {
+			if zipReader, err := zip.NewReader(zipFile, stat.Size()); err == nil {
+				for _, f := range zipReader.File {
+					total += f.UncompressedSize64
+				}
+			}
+		}
+		tracker.Reset(total)
 	}
 
 	// 重设存储策略
@@ -301,6 +321,11 @@ func (fs *FileSystem) Decompress(ctx context.Context, src, dst, encoding string)
 			return nil
 		}
 
+		tracker.SetFile(rawPath)
+		if isZip {
+			fileStream = progress.NewReader(fileStream, tracker)
+		}
+
 		if !isZip {
 			uploadFunc(fileStream, f.FileInfo.Size(), savePath, rawPath)
 		} else {
@@ -17,6 +17,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
 	"github.com/cloudreve/Cloudreve/v3/pkg/request"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/traffic"
 )
 
 // Driver 远程存储策略适配器
@@ -120,8 +121,8 @@ func (handler *Driver) Get(ctx context.Context, path string) (response.RSCloser,
 		speedLimit = user.Group.SpeedLimit
 	}
 
-	// 获取文件源地址
-	downloadURL, err := handler.Source(ctx, path, url.URL{}, 0, true, speedLimit)
+	// 获取文件源地址，经由主机中转的流量由主机计量
+	downloadURL, err := handler.signSource(ctx, path, 0, true, speedLimit, false)
 	if err != nil {
 		return nil, err
 	}
@@ -232,10 +233,17 @@ func (handler *Driver) Source(
 	isDownload bool,
 	speed int,
 ) (string, error) {
+	return handler.signSource(ctx, path, ttl, isDownload, speed, true)
+}
+
+// signSource 签名从机文件地址，report 为 true 时附带流量标记，由从机在传输后回报流量
+func (handler *Driver) signSource(ctx context.Context, path string, ttl int64, isDownload bool, speed int, report bool) (string, error) {
 	// 尝试从上下文获取文件名
 	fileName := "file"
+	var owner uint
 	if file, ok := ctx.Value(fsctx.FileModelCtx).(model.File); ok {
 		fileName = file.Name
+		owner = file.UserID
 	}
 
 	serverURL, err := url.Parse(handler.Policy.Server)
@@ -272,9 +280,14 @@ func (handler *Driver) Source(
 		return "", serializer.NewError(serializer.CodeEncryptError, "无法对URL进行签名", err)
 	}
 
+	if report && owner > 0 {
+		queries := signedURI.Query()
+		queries.Set("traffic", traffic.Tag(handler.AuthInstance, path, owner))
+		signedURI.RawQuery = queries.Encode()
+	}
+
 	finalURL := serverURL.ResolveReference(signedURI).String()
 	return finalURL, nil
-
 }
 
 // Token 获取上传策略和认证Token
@@ -114,6 +114,24 @@ func TestHandler_Source(t *testing.T) {
 		res, err := handler.Source(ctx, "", url.URL{}, 10, false, 0)
 		asserts.NoError(err)
 		asserts.Contains(res, "api/v3/slave/source/0")
+		asserts.NotContains(res, "traffic=")
+	}
+
+	// 成功 附带流量标记
+	{
+		cache.Set("setting_siteID", "site", 0)
+		handler := Driver{
+			Policy:       &model.Policy{Server: "/"},
+			AuthInstance: auth.HMACAuth{},
+		}
+		file := model.File{
+			SourceName: "1.txt",
+			UserID:     1,
+		}
+		ctx := context.WithValue(context.Background(), fsctx.FileModelCtx, file)
+		res, err := handler.Source(ctx, "1.txt", url.URL{}, 10, true, 0)
+		asserts.NoError(err)
+		asserts.Contains(res, "traffic=site%2C1%2C")
 	}
 }
 
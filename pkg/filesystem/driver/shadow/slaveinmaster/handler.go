@@ -11,6 +11,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/cloudreve/Cloudreve/v3/pkg/progress"
 	"github.com/cloudreve/Cloudreve/v3/pkg/request"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"net/url"
@@ -79,18 +80,25 @@ func (d *Driver) Put(ctx context.Context, file fsctx.FileHeader) error {
 		return serializer.NewErrorFromResponse(res)
 	}
 
-	// 等待转存结果或者超时
+	// 等待转存结果或者超时，期间将从机上报的进度转交给进度跟踪器
 	waitTimeout := model.GetIntSetting("slave_transfer_timeout", 172800)
-	select {
-	case <-time.After(time.Duration(waitTimeout) * time.Second):
-		return ErrWaitResultTimeout
-	case msg := <-resChan:
-		if msg.Event != serializer.SlaveTransferSuccess {
-			return errors.New(msg.Content.(serializer.SlaveTransferResult).Error)
+	timeout := time.After(time.Duration(waitTimeout) * time.Second)
+	tracker := progress.FromContext(ctx)
+	for {
+		select {
+		case <-timeout:
+			return ErrWaitResultTimeout
+		case msg := <-resChan:
+			switch msg.Event {
+			case serializer.SlaveTransferProgress:
+				tracker.Set(msg.Content.(serializer.SlaveTransferResult).Processed)
+			case serializer.SlaveTransferSuccess:
+				return nil
+			default:
+				return errors.New(msg.Content.(serializer.SlaveTransferResult).Error)
+			}
 		}
 	}
-
-	return nil
 }
 
 func (d *Driver) Delete(ctx context.Context, files []string) ([]string, error) {
@@ -23,4 +23,5 @@ var (
 	ErrDBListObjects            = serializer.NewError(serializer.CodeDBError, "Failed to list object records", nil)
 	ErrDBDeleteObjects          = serializer.NewError(serializer.CodeDBError, "Failed to delete object records", nil)
 	ErrOneObjectOnly            = serializer.ParamErr("You can only copy one object at the same time", nil)
+	ErrObjectLocked             = serializer.NewError(serializer.CodeObjectLocked, "Object is locked by a WebDAV client", nil)
 )
@@ -7,10 +7,12 @@ import (
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/changes"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/traffic"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/jinzhu/gorm"
 	"github.com/juju/ratelimit"
@@ -79,6 +81,16 @@ func (fs *FileSystem) AddFile(ctx context.Context, parent *model.Folder, file fs
 	}
 
 	fs.User.Storage += newFile.Size
+	// 占位文件在上传完成后才计入变更日志
+	if newFile.UploadSessionID == nil {
+		fs.journal(model.NewFileChange(model.ChangeCreate, &newFile, uploadInfo.VirtualPath))
+	}
+	fs.publishChange(changes.Event{
+		Action: changes.ActionCreate,
+		Folder: parent.ID,
+		Files:  []uint{newFile.ID},
+		Name:   newFile.Name,
+	})
 	return &newFile, nil
 }
 
@@ -118,6 +130,13 @@ func (fs *FileSystem) AddFileTransaction(ctx context.Context, parent *model.Fold
 		return nil, ErrFileExisted.WithError(err)
 	}
 
+	fs.journalTransaction(tx, model.NewFileChange(model.ChangeCreate, &newFile, folderFullPath(parent)))
+	fs.publishChange(changes.Event{
+		Action: changes.ActionCreate,
+		Folder: parent.ID,
+		Files:  []uint{newFile.ID},
+		Name:   newFile.Name,
+	})
 	return &newFile, nil
 }
 
@@ -334,6 +353,11 @@ func (fs *FileSystem) SignURL(ctx context.Context, file *model.File, ttl int64,
 		return "", err
 	}
 
+	// 流量计入文件所有者，检查其本月配额
+	if err := traffic.Check(file.UserID); err != nil {
+		return "", err
+	}
+
 	// 签名最终URL
 	// 生成外链地址
 	siteURL := model.GetSiteURL()
@@ -342,6 +366,12 @@ func (fs *FileSystem) SignURL(ctx context.Context, file *model.File, ttl int64,
 		return "", serializer.NewError(serializer.CodeNotSet, "无法获取外链", err)
 	}
 
+	// 本机和从机存储在实际传输后计量，其他存储无法得知实际传输量，
+	// 在签发临时地址时按文件大小计入
+	if ttl > 0 && fs.Policy.Type != "local" && fs.Policy.Type != "remote" {
+		traffic.Record(file.UserID, file.Size)
+	}
+
 	return source, nil
 }
 
@@ -9,6 +9,7 @@ import (
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/changes"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/local"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
@@ -240,6 +241,14 @@ func GenericAfterUpdate(ctx context.Context, fs *FileSystem, newFile fsctx.FileH
 		return err
 	}
 
+	fs.journal(model.NewFileChange(model.ChangeModify, &originFile, newFile.Info().VirtualPath))
+
+	fs.publishChange(changes.Event{
+		Action: changes.ActionUpdate,
+		Folder: originFile.FolderID,
+		Files:  []uint{originFile.ID},
+	})
+
 	return nil
 }
 
@@ -406,7 +415,12 @@ func HookPopPlaceholderToFile(picInfo string) Hook {
 			picInfo = "1,1"
 		}
 
-		return fileModel.PopChunkToFile(fileInfo.LastModified, picInfo)
+		if err := fileModel.PopChunkToFile(fileInfo.LastModified, picInfo); err != nil {
+			return err
+		}
+
+		fs.journal(model.NewFileChange(model.ChangeCreate, fileModel, fileInfo.VirtualPath))
+		return nil
 	}
 }
 
@@ -0,0 +1,185 @@
+package filesystem
+
+import (
+	"path"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+// journal 记录当前用户文件系统的变更日志，供同步客户端增量拉取。
+// 变更已经生效，记录失败时仅输出警告
+func (fs *FileSystem) journal(changes ...model.Change) {
+	if fs.User == nil || len(changes) == 0 {
+		return
+	}
+
+	for i := range changes {
+		changes[i].UserID = fs.User.ID
+	}
+
+	if err := model.RecordChanges(changes); err != nil {
+		util.Log().Warning("无法记录变更日志, %s", err)
+	}
+}
+
+// journalTransaction 在事务中记录变更日志，事务回滚时变更日志一并撤销
+func (fs *FileSystem) journalTransaction(tx *gorm.DB, changes ...model.Change) {
+	if fs.User == nil || len(changes) == 0 {
+		return
+	}
+
+	for i := range changes {
+		changes[i].UserID = fs.User.ID
+	}
+
+	if err := model.RecordChangesTransaction(changes, tx); err != nil {
+		util.Log().Warning("无法记录变更日志, %s", err)
+	}
+}
+
+// folderFullPath 返回目录的完整路径
+func folderFullPath(folder *model.Folder) string {
+	if folder.ParentID != nil && folder.Position == "" {
+		folder.TraceRoot()
+	}
+
+	return path.Join(folder.Position, folder.Name)
+}
+
+// folderPathByID 返回当前用户给定ID目录的完整路径
+func (fs *FileSystem) folderPathByID(id uint) string {
+	folders, err := model.GetFoldersByIDs([]uint{id}, fs.User.ID)
+	if err != nil || len(folders) == 0 {
+		return ""
+	}
+
+	return folderFullPath(&folders[0])
+}
+
+// subtreeChanges 为目录及其下所有子目录、文件构建变更记录，dir 为目录所在父目录的完整路径
+func subtreeChanges(action string, root *model.Folder, dir string) []model.Change {
+	res := []model.Change{model.NewFolderChange(action, root, dir)}
+
+	folders, err := model.GetRecursiveChildFolder([]uint{root.ID}, root.OwnerID, false)
+	if err != nil {
+		return res
+	}
+
+	// 子目录按层级顺序返回，父目录的路径总是先于子目录确定
+	paths := map[uint]string{root.ID: path.Join(dir, root.Name)}
+	for i := range folders {
+		parent := paths[*folders[i].ParentID]
+		paths[folders[i].ID] = path.Join(parent, folders[i].Name)
+		res = append(res, model.NewFolderChange(action, &folders[i], parent))
+	}
+
+	folders = append(folders, *root)
+	files, err := model.GetChildFilesOfFolders(&folders)
+	if err != nil {
+		return res
+	}
+
+	for i := range files {
+		res = append(res, model.NewFileChange(action, &files[i], paths[files[i].FolderID]))
+	}
+
+	return res
+}
+
+// copiedChanges 为复制到 dstFolder 下的对象构建变更记录，复制出的对象按名称查找
+func (fs *FileSystem) copiedChanges(dirs, files []uint, dstFolder *model.Folder, dst string) []model.Change {
+	res := make([]model.Change, 0, len(dirs)+len(files))
+
+	if len(dirs) > 0 {
+		if origin, err := model.GetFoldersByIDs(dirs[:1], fs.User.ID); err == nil && len(origin) > 0 {
+			if folder, err := dstFolder.GetChild(origin[0].Name); err == nil {
+				res = append(res, subtreeChanges(model.ChangeCreate, folder, dst)...)
+			}
+		}
+	}
+
+	if len(files) > 0 {
+		origin, _ := model.GetFilesByIDs(files, fs.User.ID)
+		for i := range origin {
+			if file, err := dstFolder.GetChildFile(origin[i].Name); err == nil {
+				res = append(res, model.NewFileChange(model.ChangeCreate, file, dst))
+			}
+		}
+	}
+
+	return res
+}
+
+// movedChanges 为从 src 移动到 dst 的顶层对象构建变更记录
+func (fs *FileSystem) movedChanges(dirs, files []uint, dstFolder *model.Folder, src, dst string) []model.Change {
+	res := make([]model.Change, 0, len(dirs)+len(files))
+
+	if len(dirs) > 0 {
+		folders, _ := model.GetFoldersByIDs(dirs, fs.User.ID)
+		for i := range folders {
+			if folders[i].ParentID != nil && *folders[i].ParentID == dstFolder.ID {
+				change := model.NewFolderChange(model.ChangeMove, &folders[i], dst)
+				change.OldPath = path.Join(src, folders[i].Name)
+				res = append(res, change)
+			}
+		}
+	}
+
+	if len(files) > 0 {
+		moved, _ := model.GetFilesByIDs(files, fs.User.ID)
+		for i := range moved {
+			if moved[i].FolderID == dstFolder.ID {
+				change := model.NewFileChange(model.ChangeMove, &moved[i], dst)
+				change.OldPath = path.Join(src, moved[i].Name)
+				res = append(res, change)
+			}
+		}
+	}
+
+	return res
+}
+
+// deletedChanges 为待删除的顶层对象构建变更记录，需在列出待删除对象之后、删除记录之前调用
+func (fs *FileSystem) deletedChanges(dirs, files []uint) []model.Change {
+	res := make([]model.Change, 0, len(dirs)+len(files))
+	paths := make(map[uint]string)
+
+	for i := range fs.FileTarget {
+		file := &fs.FileTarget[i]
+		if !util.ContainsUint(files, file.ID) {
+			continue
+		}
+
+		if _, ok := paths[file.FolderID]; !ok {
+			paths[file.FolderID] = fs.folderPathByID(file.FolderID)
+		}
+		res = append(res, model.NewFileChange(model.ChangeDelete, file, paths[file.FolderID]))
+	}
+
+	for i := range fs.DirTarget {
+		folder := &fs.DirTarget[i]
+		if !util.ContainsUint(dirs, folder.ID) || folder.ParentID == nil {
+			continue
+		}
+
+		change := model.NewFolderChange(model.ChangeDelete, folder, "")
+		change.Path = folderFullPath(folder)
+		res = append(res, change)
+	}
+
+	return res
+}
+
+// filterDeletedChanges 仅保留实际被删除的对象的变更记录
+func filterDeletedChanges(changes []model.Change, deletedFiles []uint, foldersDeleted bool) []model.Change {
+	res := make([]model.Change, 0, len(changes))
+	for _, change := range changes {
+		if (change.IsDir && foldersDeleted) || (!change.IsDir && util.ContainsUint(deletedFiles, change.ObjectID)) {
+			res = append(res, change)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,32 @@
+package filesystem
+
+import (
+	"path"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// CheckNotLocked 检查给定完整路径的对象及其下的对象均未被 WebDAV 客户端锁定，
+// 供不携带锁令牌的网页端及 API 写入操作使用
+func (fs *FileSystem) CheckNotLocked(paths ...string) error {
+	if fs.User == nil || len(paths) == 0 {
+		return nil
+	}
+
+	locks, err := model.GetWebdavLocks(fs.User.ID, time.Now())
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	for _, p := range paths {
+		p = path.Join("/", p)
+		for i := range locks {
+			if locks[i].Covers(p) || locks[i].Within(p) {
+				return ErrObjectLocked
+			}
+		}
+	}
+
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/changes"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
@@ -37,10 +38,23 @@ func (fs *FileSystem) Rename(ctx context.Context, dir, file []uint, new string)
 			return ErrPathNotExist
 		}
 
+		dirPath := fs.folderPathByID(fileObject[0].FolderID)
+		change := model.NewFileChange(model.ChangeMove, &fileObject[0], dirPath)
+		change.OldPath, change.Path = change.Path, path.Join(dirPath, new)
+
 		err = fileObject[0].Rename(new)
 		if err != nil {
 			return ErrFileExisted
 		}
+
+		fs.journal(change)
+
+		fs.publishChange(changes.Event{
+			Action: changes.ActionRename,
+			Folder: fileObject[0].FolderID,
+			Files:  []uint{fileObject[0].ID},
+			Name:   new,
+		})
 		return nil
 	}
 
@@ -50,10 +64,29 @@ func (fs *FileSystem) Rename(ctx context.Context, dir, file []uint, new string)
 			return ErrPathNotExist
 		}
 
+		var dirPath string
+		if folderObject[0].ParentID != nil {
+			dirPath = fs.folderPathByID(*folderObject[0].ParentID)
+		}
+		change := model.NewFolderChange(model.ChangeMove, &folderObject[0], dirPath)
+		change.OldPath, change.Path = change.Path, path.Join(dirPath, new)
+
 		err = folderObject[0].Rename(new)
 		if err != nil {
 			return ErrFileExisted
 		}
+
+		fs.journal(change)
+
+		event := changes.Event{
+			Action: changes.ActionRename,
+			Dirs:   []uint{folderObject[0].ID},
+			Name:   new,
+		}
+		if folderObject[0].ParentID != nil {
+			event.Folder = *folderObject[0].ParentID
+		}
+		fs.publishChange(event)
 		return nil
 	}
 
@@ -92,9 +125,14 @@ func (fs *FileSystem) Copy(ctx context.Context, dirs, files []uint, src, dst str
 		newUsedStorage += subFileSizes
 	}
 
+	fs.journal(fs.copiedChanges(dirs, files, dstFolder, dst)...)
+
 	// 扣除容量
 	fs.User.IncreaseStorageWithoutCheck(newUsedStorage)
 
+	// 复制出的对象ID未知，仅通知目标目录
+	fs.publishChange(changes.Event{Action: changes.ActionCreate, Folder: dstFolder.ID})
+
 	return nil
 }
 
@@ -120,7 +158,15 @@ func (fs *FileSystem) Move(ctx context.Context, dirs, files []uint, src, dst str
 		return ErrFileExisted.WithError(err)
 	}
 
-	// 移动文件
+	fs.journal(fs.movedChanges(dirs, files, dstFolder, src, dst)...)
+
+	fs.publishChange(changes.Event{
+		Action: changes.ActionMove,
+		Folder: dstFolder.ID,
+		From:   srcFolder.ID,
+		Files:  files,
+		Dirs:   dirs,
+	})
 
 	return err
 }
@@ -150,6 +196,15 @@ func (fs *FileSystem) Delete(ctx context.Context, dirs, files []uint, force bool
 		}
 	}
 
+	// 记录删除前的父目录与路径，用于通知变更
+	deleted := fs.deleteEvents(dirs, files)
+	journal := fs.deletedChanges(dirs, files)
+	defer func() {
+		for _, event := range deleted {
+			fs.publishChange(event)
+		}
+	}()
+
 	// 去除待删除文件中包含软连接的部分
 	filesToBeDelete, err := model.RemoveFilesWithSoftLinks(fs.FileTarget)
 	if err != nil {
@@ -192,6 +247,7 @@ func (fs *FileSystem) Delete(ctx context.Context, dirs, files []uint, force bool
 	}
 
 	model.DeleteShareBySourceIDs(deletedFileIDs, false)
+	model.DeleteDeadPropertiesTransaction(false, deletedFileIDs, model.DB)
 
 	// 如果文件全部删除成功，继续删除目录
 	if len(deletedFiles) == len(allFiles) {
@@ -206,8 +262,11 @@ func (fs *FileSystem) Delete(ctx context.Context, dirs, files []uint, force bool
 
 		// 删除目录记录对应的分享记录
 		model.DeleteShareBySourceIDs(allFolderIDs, true)
+		model.DeleteDeadPropertiesTransaction(true, allFolderIDs, model.DB)
 	}
 
+	fs.journal(filterDeletedChanges(journal, deletedFileIDs, len(deletedFiles) == len(allFiles))...)
+
 	if notDeleted := len(fs.FileTarget) - len(deletedFiles); notDeleted > 0 {
 		return serializer.NewError(
 			serializer.CodeNotFullySuccess,
@@ -221,6 +280,14 @@ func (fs *FileSystem) Delete(ctx context.Context, dirs, files []uint, force bool
 
 // Delete 递归删除对象, force 为 true 时强制删除文件记录，忽略物理删除是否成功
 func (fs *FileSystem) DeleteTransaction(ctx context.Context, dirs, files []uint, force bool, tx *gorm.DB) error {
+	// 在事务提交后通知变更
+	var deleted []changes.Event
+	defer func() {
+		for _, event := range deleted {
+			fs.publishChange(event)
+		}
+	}()
+
 	// 事务初始化
 	if tx == nil {
 		tx = model.DB.Begin()
@@ -270,6 +337,9 @@ func (fs *FileSystem) DeleteTransaction(ctx context.Context, dirs, files []uint,
 		}
 	}
 
+	deleted = fs.deleteEvents(dirs, files)
+	journal := fs.deletedChanges(dirs, files)
+
 	// 去除待删除文件中包含软连接的部分
 	filesToBeDelete, err := model.RemoveFilesWithSoftLinksTransaction(fs.FileTarget, tx)
 	if err != nil {
@@ -309,6 +379,7 @@ func (fs *FileSystem) DeleteTransaction(ctx context.Context, dirs, files []uint,
 
 	// 删除文件记录对应的分享记录
 	model.DeleteShareBySourceIDsTransaction(deletedFileIDs, false, tx)
+	model.DeleteDeadPropertiesTransaction(false, deletedFileIDs, tx)
 
 	// 归还容量
 	var total uint64
@@ -330,8 +401,11 @@ func (fs *FileSystem) DeleteTransaction(ctx context.Context, dirs, files []uint,
 
 		// 删除目录记录对应的分享记录
 		model.DeleteShareBySourceIDsTransaction(allFolderIDs, true, tx)
+		model.DeleteDeadPropertiesTransaction(true, allFolderIDs, tx)
 	}
 
+	fs.journalTransaction(tx, filterDeletedChanges(journal, deletedFileIDs, len(deletedFileIDs) == len(allFileIDs))...)
+
 	if notDeleted := len(fs.FileTarget) - len(deletedFileIDs); notDeleted > 0 {
 		return serializer.NewError(
 			serializer.CodeNotFullySuccess,
@@ -612,6 +686,11 @@ func (fs *FileSystem) listObjects(ctx context.Context, parent string, files []mo
 	return objects
 }
 
+// ListObjects 将给定的文件和目录转换为列目录结果，parent 为这些对象所在的目录路径
+func (fs *FileSystem) ListObjects(ctx context.Context, parent string, files []model.File, folders []model.Folder) []serializer.Object {
+	return fs.listObjects(ctx, parent, files, folders, nil)
+}
+
 // CreateDirectory 根据给定的完整创建目录，支持递归创建。如果目录已存在，则直接
 // 返回已存在的目录。
 func (fs *FileSystem) CreateDirectory(ctx context.Context, fullPath string) (*model.Folder, error) {
@@ -666,6 +745,15 @@ func (fs *FileSystem) CreateDirectory(ctx context.Context, fullPath string) (*mo
 		return nil, fmt.Errorf("failed to create folder: %w", err)
 	}
 
+	fs.journal(model.NewFolderChange(model.ChangeCreate, &newFolder, base))
+
+	fs.publishChange(changes.Event{
+		Action: changes.ActionCreate,
+		Folder: parent.ID,
+		Dirs:   []uint{newFolder.ID},
+		Name:   newFolder.Name,
+	})
+
 	return &newFolder, nil
 }
 
@@ -720,6 +808,8 @@ func (fs *FileSystem) CreateDirectoryTransaction(ctx context.Context, fullPath s
 			return nil, ErrFolderExisted
 		}
 
+	} else {
+		fs.journalTransaction(tx, model.NewFolderChange(model.ChangeCreate, &newFolder, base))
 	}
 	return &newFolder, nil
 }
@@ -755,3 +845,44 @@ func (fs *FileSystem) SaveTo(ctx context.Context, path string) error {
 
 	return nil
 }
+
+// deleteEvents 按父目录整理待删除的顶层对象，需在列出待删除对象之后、删除记录之前调用
+func (fs *FileSystem) deleteEvents(dirs, files []uint) []changes.Event {
+	events := make(map[uint]*changes.Event)
+	eventOf := func(parent uint) *changes.Event {
+		if _, ok := events[parent]; !ok {
+			events[parent] = &changes.Event{Action: changes.ActionDelete, Folder: parent}
+		}
+		return events[parent]
+	}
+
+	for _, file := range fs.FileTarget {
+		if util.ContainsUint(files, file.ID) {
+			event := eventOf(file.FolderID)
+			event.Files = append(event.Files, file.ID)
+		}
+	}
+
+	for _, folder := range fs.DirTarget {
+		if util.ContainsUint(dirs, folder.ID) && folder.ParentID != nil {
+			event := eventOf(*folder.ParentID)
+			event.Dirs = append(event.Dirs, folder.ID)
+		}
+	}
+
+	res := make([]changes.Event, 0, len(events))
+	for _, event := range events {
+		res = append(res, *event)
+	}
+	return res
+}
+
+// publishChange 发布当前用户文件系统的变更
+func (fs *FileSystem) publishChange(event changes.Event) {
+	if fs.User == nil {
+		return
+	}
+
+	event.UserID = fs.User.ID
+	changes.Publish(event)
+}
@@ -11,6 +11,7 @@ import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/progress"
 	"github.com/cloudreve/Cloudreve/v3/pkg/request"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
@@ -323,5 +324,9 @@ func (fs *FileSystem) UploadFromPath(ctx context.Context, src, dst string, mode
 	size := fi.Size()
 
 	// 开始上传
-	return fs.UploadFromStream1(ctx, file, dst, uint64(size), util.RelativePath(src))
+	tracker := progress.FromContext(ctx)
+	tracker.BeginFile(path.Base(dst), uint64(size))
+	defer tracker.EndFile()
+
+	return fs.UploadFromStream1(ctx, progress.NewReader(file, tracker), dst, uint64(size), util.RelativePath(src))
 }
@@ -9,12 +9,13 @@ import (
 
 // ID类型
 const (
-	ShareID  = iota // 分享
-	UserID          // 用户
-	FileID          // 文件ID
-	FolderID        // 目录ID
-	TagID           // 标签ID
-	PolicyID        // 存储策略ID
+	ShareID         = iota // 分享
+	UserID                 // 用户
+	FileID                 // 文件ID
+	FolderID               // 目录ID
+	TagID                  // 标签ID
+	PolicyID               // 存储策略ID
+	InternalShareID        // 站内共享ID
 )
 
 var (
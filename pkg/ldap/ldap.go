@@ -0,0 +1,244 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+// timeout 连接与请求超时
+const timeout = 10 * time.Second
+
+var (
+	// ErrNotEnabled 未启用 LDAP 认证
+	ErrNotEnabled = errors.New("LDAP authentication is not enabled")
+	// ErrUserNotFound 目录中不存在该用户
+	ErrUserNotFound = errors.New("user not found in directory")
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = errors.New("invalid directory credentials")
+)
+
+// Config LDAP 服务器及属性映射设置
+type Config struct {
+	URL          string // ldap:// 或 ldaps:// 地址
+	StartTLS     bool   // 对 ldap:// 连接使用 StartTLS
+	SkipVerify   bool   // 跳过服务器证书校验
+	BindDN       string // 用于查找用户的服务账号，为空时匿名绑定
+	BindPassword string
+	BaseDN       string
+	UserFilter   string // 查找用户的过滤器，%s 替换为转义后的登录名
+	EmailAttr    string
+	NickAttr     string
+	GroupAttr    string
+	GroupMapping map[string]uint // 目录中的用户组 DN 到用户组 ID 的映射
+}
+
+// Entry 目录中的用户
+type Entry struct {
+	DN     string
+	Email  string
+	Nick   string
+	Groups []string
+}
+
+// NewConfigFromSetting 根据站点设置创建配置，未启用时返回 ErrNotEnabled
+func NewConfigFromSetting() (*Config, error) {
+	options := model.GetSettingByNames(
+		"ldap_enabled",
+		"ldap_url",
+		"ldap_start_tls",
+		"ldap_skip_verify",
+		"ldap_bind_dn",
+		"ldap_bind_password",
+		"ldap_base_dn",
+		"ldap_user_filter",
+		"ldap_email_attr",
+		"ldap_nick_attr",
+		"ldap_group_attr",
+		"ldap_group_mapping",
+	)
+	if !model.IsTrueVal(options["ldap_enabled"]) {
+		return nil, ErrNotEnabled
+	}
+
+	config := &Config{
+		URL:          options["ldap_url"],
+		StartTLS:     model.IsTrueVal(options["ldap_start_tls"]),
+		SkipVerify:   model.IsTrueVal(options["ldap_skip_verify"]),
+		BindDN:       options["ldap_bind_dn"],
+		BindPassword: options["ldap_bind_password"],
+		BaseDN:       options["ldap_base_dn"],
+		UserFilter:   options["ldap_user_filter"],
+		EmailAttr:    options["ldap_email_attr"],
+		NickAttr:     options["ldap_nick_attr"],
+		GroupAttr:    options["ldap_group_attr"],
+		GroupMapping: make(map[string]uint),
+	}
+
+	if options["ldap_group_mapping"] != "" {
+		if err := json.Unmarshal([]byte(options["ldap_group_mapping"]), &config.GroupMapping); err != nil {
+			util.Log().Warning("无法解析 LDAP 用户组映射, %s", err)
+		}
+	}
+
+	return config, nil
+}
+
+// dial 连接服务器并使用服务账号绑定
+func (config *Config) dial() (*goldap.Conn, error) {
+	target, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LDAP URL: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         target.Hostname(),
+		InsecureSkipVerify: config.SkipVerify,
+	}
+	conn, err := goldap.DialURL(
+		config.URL,
+		goldap.DialWithDialer(&net.Dialer{Timeout: timeout}),
+		goldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP server: %w", err)
+	}
+	conn.SetTimeout(timeout)
+
+	if config.StartTLS && target.Scheme == "ldap" {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	if config.BindDN != "" {
+		err = conn.Bind(config.BindDN, config.BindPassword)
+	} else {
+		err = conn.UnauthenticatedBind("")
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to bind service account: %w", err)
+	}
+
+	return conn, nil
+}
+
+// Filter 生成查找登录名对应用户的过滤器
+func (config *Config) Filter(username string) string {
+	return strings.ReplaceAll(config.UserFilter, "%s", goldap.EscapeFilter(username))
+}
+
+// attributes 需要读取的用户属性
+func (config *Config) attributes() []string {
+	attrs := []string{}
+	for _, attr := range []string{config.EmailAttr, config.NickAttr, config.GroupAttr} {
+		if attr != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
+
+// Authenticate 查找用户并以用户身份绑定来校验密码
+func (config *Config) Authenticate(username, password string) (*Entry, error) {
+	// 空密码会被服务器视为匿名绑定而成功
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := config.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	res, err := conn.Search(goldap.NewSearchRequest(
+		config.BaseDN,
+		goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 2, int(timeout.Seconds()), false,
+		config.Filter(username),
+		config.attributes(),
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search directory: %w", err)
+	}
+
+	if len(res.Entries) == 0 {
+		return nil, ErrUserNotFound
+	}
+	if len(res.Entries) > 1 {
+		return nil, fmt.Errorf("filter matches more than one user for %q", username)
+	}
+
+	entry := res.Entries[0]
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to bind user: %w", err)
+	}
+
+	return &Entry{
+		DN:     entry.DN,
+		Email:  entry.GetAttributeValue(config.EmailAttr),
+		Nick:   entry.GetAttributeValue(config.NickAttr),
+		Groups: entry.GetAttributeValues(config.GroupAttr),
+	}, nil
+}
+
+// Missing 返回给定 DN 中在目录里已不存在或不再匹配用户过滤器的部分
+func (config *Config) Missing(dns []string) ([]string, error) {
+	conn, err := config.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	filter := strings.ReplaceAll(config.UserFilter, "%s", "*")
+	missing := make([]string, 0)
+	for _, dn := range dns {
+		res, err := conn.Search(goldap.NewSearchRequest(
+			dn,
+			goldap.ScopeBaseObject, goldap.NeverDerefAliases, 1, int(timeout.Seconds()), false,
+			filter,
+			[]string{"dn"},
+			nil,
+		))
+		if err != nil {
+			if goldap.IsErrorWithCode(err, goldap.LDAPResultNoSuchObject) {
+				missing = append(missing, dn)
+				continue
+			}
+			return nil, fmt.Errorf("failed to search directory: %w", err)
+		}
+
+		if len(res.Entries) == 0 {
+			missing = append(missing, dn)
+		}
+	}
+
+	return missing, nil
+}
+
+// MapGroup 根据用户所属的目录用户组查找映射的用户组，未命中时返回 0
+func (config *Config) MapGroup(groups []string) uint {
+	for _, group := range groups {
+		for dn, id := range config.GroupMapping {
+			if strings.EqualFold(dn, group) {
+				return id
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,221 @@
+package ldap
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+func TestNewConfigFromSetting(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 未启用
+	{
+		cache.Set("setting_ldap_enabled", "0", 0)
+		_, err := NewConfigFromSetting()
+		asserts.Equal(ErrNotEnabled, err)
+	}
+
+	// 已启用
+	{
+		cache.SetSettings(map[string]string{
+			"ldap_enabled":       "1",
+			"ldap_url":           "ldaps://ldap.cloudreve.org",
+			"ldap_start_tls":     "0",
+			"ldap_skip_verify":   "1",
+			"ldap_bind_dn":       "",
+			"ldap_bind_password": "",
+			"ldap_base_dn":       "dc=cloudreve,dc=org",
+			"ldap_user_filter":   "(mail=%s)",
+			"ldap_email_attr":    "mail",
+			"ldap_nick_attr":     "cn",
+			"ldap_group_attr":    "memberOf",
+			"ldap_group_mapping": `{"cn=admins,dc=cloudreve,dc=org":1}`,
+		}, "setting_")
+		config, err := NewConfigFromSetting()
+		asserts.NoError(err)
+		asserts.Equal("ldaps://ldap.cloudreve.org", config.URL)
+		asserts.True(config.SkipVerify)
+		asserts.EqualValues(1, config.GroupMapping["cn=admins,dc=cloudreve,dc=org"])
+	}
+}
+
+func TestConfig_Filter(t *testing.T) {
+	asserts := assert.New(t)
+	config := &Config{UserFilter: "(&(objectClass=person)(mail=%s))"}
+	asserts.Equal("(&(objectClass=person)(mail=admin@cloudreve.org))", config.Filter("admin@cloudreve.org"))
+	asserts.Equal(`(&(objectClass=person)(mail=\2a\29\28uid=\2a))`, config.Filter("*)(uid=*"))
+}
+
+func TestConfig_MapGroup(t *testing.T) {
+	asserts := assert.New(t)
+	config := &Config{GroupMapping: map[string]uint{
+		"cn=admins,dc=cloudreve,dc=org": 1,
+		"cn=staff,dc=cloudreve,dc=org":  3,
+	}}
+	asserts.EqualValues(0, config.MapGroup(nil))
+	asserts.EqualValues(0, config.MapGroup([]string{"cn=others,dc=cloudreve,dc=org"}))
+	asserts.EqualValues(3, config.MapGroup([]string{"CN=Staff,DC=cloudreve,DC=org"}))
+}
+
+func TestConfig_Authenticate(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 空密码
+	{
+		config := &Config{URL: "ldap://127.0.0.1:1"}
+		_, err := config.Authenticate("admin@cloudreve.org", "")
+		asserts.Equal(ErrInvalidCredentials, err)
+	}
+
+	// 无法连接
+	{
+		config := &Config{URL: "ldap://127.0.0.1:1"}
+		_, err := config.Authenticate("admin@cloudreve.org", "password")
+		asserts.Error(err)
+	}
+}
+
+func TestConfig_provision(t *testing.T) {
+	asserts := assert.New(t)
+	config := &Config{GroupMapping: map[string]uint{"cn=staff,dc=cloudreve,dc=org": 3}}
+
+	// 缺少邮箱
+	{
+		_, err := config.provision(&Entry{DN: "uid=user"}, "user")
+		asserts.Error(err)
+	}
+
+	// 未开启自动创建
+	{
+		cache.Set("setting_ldap_auto_register", "0", 0)
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		_, err := config.provision(&Entry{DN: "uid=user", Email: "User@cloudreve.org"}, "user")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(ErrNotProvisioned, err)
+	}
+
+	// 自动创建
+	{
+		cache.Set("setting_ldap_auto_register", "1", 0)
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)users(.+)").WillReturnResult(sqlmock.NewResult(5, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)ldap_accounts(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		user, err := config.provision(&Entry{
+			DN:     "uid=user",
+			Groups: []string{"cn=staff,dc=cloudreve,dc=org"},
+		}, "User@cloudreve.org")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal("user@cloudreve.org", user.Email)
+		asserts.Equal("user", user.Nick)
+		asserts.EqualValues(3, user.GroupID)
+	}
+
+	// 已有用户，更新 DN
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "group_id", "options"}).AddRow(5, "user@cloudreve.org", 3, "{}"))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectQuery("SELECT(.+)ldap_accounts(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "dn"}).AddRow(1, 5, "uid=old"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)ldap_accounts(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		user, err := config.provision(&Entry{DN: "uid=user", Email: "user@cloudreve.org"}, "user")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(5, user.ID)
+	}
+
+	// 已有用户，未开启按邮箱绑定
+	{
+		cache.Set("setting_ldap_link_by_email", "0", 0)
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "group_id", "options"}).AddRow(5, "user@cloudreve.org", 3, "{}"))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectQuery("SELECT(.+)ldap_accounts(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		_, err := config.provision(&Entry{DN: "uid=user", Email: "user@cloudreve.org"}, "user")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(ErrNotLinked, err)
+	}
+
+	// 已开启按邮箱绑定，管理员不自动绑定
+	{
+		cache.Set("setting_ldap_link_by_email", "1", 0)
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "group_id", "options"}).AddRow(6, "admin@cloudreve.org", 1, "{}"))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("SELECT(.+)ldap_accounts(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		_, err := config.provision(&Entry{DN: "uid=admin", Email: "admin@cloudreve.org"}, "admin")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(ErrNotLinked, err)
+	}
+
+	// 已开启按邮箱绑定
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "group_id", "options"}).AddRow(5, "user@cloudreve.org", 3, "{}"))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectQuery("SELECT(.+)ldap_accounts(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)ldap_accounts(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		user, err := config.provision(&Entry{DN: "uid=user", Email: "user@cloudreve.org"}, "user")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(5, user.ID)
+		cache.Set("setting_ldap_link_by_email", "0", 0)
+	}
+
+	// 重新出现在目录中，解除同步任务的封禁
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "group_id", "status", "options"}).AddRow(5, "user@cloudreve.org", 3, model.Baned, "{}"))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectQuery("SELECT(.+)ldap_accounts(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "dn", "sync_baned"}).AddRow(1, 5, "uid=user", true))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)ldap_accounts(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		user, err := config.provision(&Entry{DN: "uid=user", Email: "user@cloudreve.org"}, "user")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal(model.Active, user.Status)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_ldap_enabled", "0", 0)
+	_, err := Login("user@cloudreve.org", "password")
+	asserts.Equal(ErrNotEnabled, err)
+	asserts.False(IsDirectoryUser(&model.User{}))
+}
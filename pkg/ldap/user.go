@@ -0,0 +1,221 @@
+package ldap
+
+import (
+	"errors"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+var (
+	// ErrNotProvisioned 未开启自动创建用户，且本地不存在同邮箱的用户
+	ErrNotProvisioned = errors.New("directory user has no local account")
+	// ErrNotLinked 本地存在同邮箱的用户，但不允许自动绑定，需由用户手动绑定
+	ErrNotLinked = errors.New("directory user is not linked to the local account with the same email")
+	// ErrLinkedByOthers 目录用户已绑定其他本地用户
+	ErrLinkedByOthers = errors.New("directory user is linked to another account")
+)
+
+// Login 使用目录校验登录名和密码并返回对应的用户。
+// 首次登录时根据设置绑定同邮箱的已有用户，不存在时根据设置自动创建
+func Login(username, password string) (*model.User, error) {
+	config, err := NewConfigFromSetting()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := config.Authenticate(username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.provision(entry, username)
+}
+
+// IsDirectoryUser 用户是否由目录认证，这类用户不能使用本地密码登录
+func IsDirectoryUser(user *model.User) bool {
+	if !model.IsTrueVal(model.GetSettingByName("ldap_enabled")) {
+		return false
+	}
+
+	_, err := model.GetLDAPAccountByUserID(user.ID)
+	return err == nil
+}
+
+// provision 查找或创建目录用户对应的本地用户，并同步用户组
+func (config *Config) provision(entry *Entry, username string) (*model.User, error) {
+	email := strings.ToLower(entry.Email)
+	if email == "" && strings.Contains(username, "@") {
+		email = strings.ToLower(username)
+	}
+	if email == "" {
+		return nil, errors.New("directory user has no email address")
+	}
+
+	group := config.MapGroup(entry.Groups)
+	var account *model.LDAPAccount
+	user, err := model.GetUserByEmail(email)
+	if err != nil {
+		if !model.IsTrueVal(model.GetSettingByName("ldap_auto_register")) {
+			return nil, ErrNotProvisioned
+		}
+
+		user = model.NewUser()
+		user.Email = email
+		user.Nick = entry.Nick
+		if user.Nick == "" {
+			user.Nick = strings.Split(email, "@")[0]
+		}
+		user.SetPassword(util.RandStringRunes(32))
+		user.Status = model.Active
+		user.GroupID = uint(model.GetIntSetting("ldap_default_group", 2))
+		if group != 0 {
+			user.GroupID = group
+		}
+
+		if err := model.DB.Create(&user).Error; err != nil {
+			return nil, err
+		}
+	} else {
+		if account, err = model.GetLDAPAccountByUserID(user.ID); err != nil {
+			// 绑定后本地密码将失效，仅在明确开启时绑定同邮箱的已有用户，管理员需手动绑定
+			if !model.IsTrueVal(model.GetSettingByName("ldap_link_by_email")) || user.ID == 1 || user.GroupID == 1 {
+				return nil, ErrNotLinked
+			}
+			account = nil
+		}
+
+		// 同步用户组，初始管理员不受影响
+		if group != 0 && group != user.GroupID && user.ID != 1 {
+			if err := user.Update(map[string]interface{}{"group_id": group}); err != nil {
+				return nil, err
+			}
+			user.GroupID = group
+		}
+	}
+
+	if account == nil {
+		account = &model.LDAPAccount{UserID: user.ID, DN: entry.DN}
+		if _, err := account.Create(); err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+
+	if account.DN != entry.DN {
+		if err := account.UpdateDN(entry.DN); err != nil {
+			return nil, err
+		}
+	}
+
+	// 用户重新出现在目录中，解除同步任务的封禁
+	if account.SyncBaned {
+		if err := unban(&user, account); err != nil {
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+// Link 使用目录校验登录名和密码，并将目录用户绑定到已登录的本地用户
+func Link(user *model.User, username, password string) error {
+	config, err := NewConfigFromSetting()
+	if err != nil {
+		return err
+	}
+
+	entry, err := config.Authenticate(username, password)
+	if err != nil {
+		return err
+	}
+
+	if account, err := model.GetLDAPAccountByDN(entry.DN); err == nil {
+		if account.UserID != user.ID {
+			return ErrLinkedByOthers
+		}
+		return nil
+	}
+
+	if account, err := model.GetLDAPAccountByUserID(user.ID); err == nil {
+		return account.UpdateDN(entry.DN)
+	}
+
+	account := &model.LDAPAccount{UserID: user.ID, DN: entry.DN}
+	_, err = account.Create()
+	return err
+}
+
+// unban 解除同步任务对用户的封禁，管理员手动设定的状态不受影响
+func unban(user *model.User, account *model.LDAPAccount) error {
+	if user.Status == model.Baned {
+		user.SetStatus(model.Active)
+		user.Status = model.Active
+		util.Log().Info("用户 [%s] 已重新出现在目录中，解除封禁", user.Email)
+	}
+	return account.SetSyncBaned(false)
+}
+
+// Sync 封禁已从目录中移除的用户，并解除重新出现在目录中的用户的封禁。
+// 仅解除由同步任务产生的封禁，管理员手动封禁的用户不受影响
+func Sync() error {
+	config, err := NewConfigFromSetting()
+	if err != nil {
+		return err
+	}
+
+	accounts := model.ListLDAPAccounts()
+	if len(accounts) == 0 {
+		return nil
+	}
+
+	dns := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		dns = append(dns, account.DN)
+	}
+
+	missingDNs, err := config.Missing(dns)
+	if err != nil {
+		return err
+	}
+
+	missing := make(map[string]bool, len(missingDNs))
+	for _, dn := range missingDNs {
+		missing[dn] = true
+	}
+
+	for i := range accounts {
+		account := &accounts[i]
+		// 初始管理员不受影响
+		if account.UserID == 1 || missing[account.DN] == account.SyncBaned {
+			continue
+		}
+
+		user, err := model.GetUserByID(account.UserID)
+		if err != nil {
+			continue
+		}
+
+		if !missing[account.DN] {
+			if err := unban(&user, account); err != nil {
+				util.Log().Warning("无法解除用户 [%s] 的封禁, %s", user.Email, err)
+			}
+			continue
+		}
+
+		// 已被管理员封禁的用户无需处理
+		if user.Status != model.Active {
+			continue
+		}
+
+		if err := account.SetSyncBaned(true); err != nil {
+			util.Log().Warning("无法记录用户 [%s] 的封禁, %s", user.Email, err)
+			continue
+		}
+		user.SetStatus(model.Baned)
+		util.Log().Info("目录中已不存在用户 [%s]，已封禁", user.Email)
+	}
+
+	return nil
+}
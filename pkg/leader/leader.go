@@ -0,0 +1,211 @@
+package leader
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// LeaseName 主节点租约名称
+	LeaseName = "master"
+	// LeaseTTL 租约有效期，主节点失联超过此时长后由其他实例接管
+	LeaseTTL = 15 * time.Second
+)
+
+// Default 当前实例使用的选举器，未初始化时视为单实例部署，始终为主节点
+var Default *Elector
+
+// Backend 租约存储
+type Backend interface {
+	// Acquire 获取或续期租约，返回 holder 是否持有租约
+	Acquire(name, holder string, ttl time.Duration) (bool, error)
+	// Release 释放 holder 持有的租约
+	Release(name, holder string) error
+}
+
+// dbBackend 基于数据库的租约存储
+type dbBackend struct{}
+
+func (dbBackend) Acquire(name, holder string, ttl time.Duration) (bool, error) {
+	return model.AcquireLease(name, holder, ttl)
+}
+
+func (dbBackend) Release(name, holder string) error {
+	return model.ReleaseLease(name, holder)
+}
+
+// Elector 基于租约的主节点选举器。持有租约的实例为主节点，需在租约到期前不断续期
+type Elector struct {
+	name    string
+	id      string
+	backend Backend
+	ttl     time.Duration
+
+	mu        sync.Mutex
+	leader    bool
+	renewedAt time.Time
+	elected   []func()
+	revoked   []func()
+	stop      chan struct{}
+}
+
+// NewElector 新建选举器，id 为当前实例的唯一标识
+func NewElector(name, id string, backend Backend, ttl time.Duration) *Elector {
+	return &Elector{
+		name:    name,
+		id:      id,
+		backend: backend,
+		ttl:     ttl,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Init 初始化主节点选举，配置了 Redis 时使用 Redis 存储租约，否则使用数据库
+func Init() {
+	var backend Backend = dbBackend{}
+	if conf.RedisConfig.Server != "" && gin.Mode() != gin.TestMode {
+		backend = NewRedisBackend(
+			conf.RedisConfig.Network,
+			conf.RedisConfig.Server,
+			conf.RedisConfig.Password,
+			conf.RedisConfig.DB,
+		)
+	}
+
+	hostname, _ := os.Hostname()
+	Default = NewElector(LeaseName, hostname+"-"+util.RandStringRunes(8), backend, LeaseTTL)
+
+	// 首次竞选同步进行，以便单实例部署时后续组件可立即以主节点身份启动
+	Default.Campaign()
+	go Default.Run()
+}
+
+// IsLeader 返回当前实例是否为主节点
+func IsLeader() bool {
+	if Default == nil {
+		return true
+	}
+	return Default.IsLeader()
+}
+
+// OnElected 注册成为主节点时执行的回调，当前已是主节点时立即执行
+func OnElected(f func()) {
+	if Default == nil {
+		f()
+		return
+	}
+	Default.OnElected(f)
+}
+
+// OnRevoked 注册失去主节点身份时执行的回调
+func OnRevoked(f func()) {
+	if Default != nil {
+		Default.OnRevoked(f)
+	}
+}
+
+// ID 返回当前实例的唯一标识
+func (e *Elector) ID() string {
+	return e.id
+}
+
+// IsLeader 返回当前实例是否为主节点
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// OnElected 注册成为主节点时执行的回调，当前已是主节点时立即执行
+func (e *Elector) OnElected(f func()) {
+	e.mu.Lock()
+	e.elected = append(e.elected, f)
+	leader := e.leader
+	e.mu.Unlock()
+
+	if leader {
+		f()
+	}
+}
+
+// OnRevoked 注册失去主节点身份时执行的回调
+func (e *Elector) OnRevoked(f func()) {
+	e.mu.Lock()
+	e.revoked = append(e.revoked, f)
+	e.mu.Unlock()
+}
+
+// Campaign 进行一轮竞选或续期，并在身份变化时执行回调
+func (e *Elector) Campaign() {
+	ok, err := e.backend.Acquire(e.name, e.id, e.ttl)
+	now := time.Now()
+
+	e.mu.Lock()
+	wasLeader := e.leader
+	if err != nil {
+		util.Log().Warning("无法获取主节点租约，%s", err)
+		// 无法续期时，在租约到期前仍保持主节点身份
+		ok = wasLeader && now.Sub(e.renewedAt) < e.ttl
+	} else if ok {
+		e.renewedAt = now
+	}
+	e.leader = ok
+
+	var callbacks []func()
+	if ok && !wasLeader {
+		util.Log().Info("当前实例 [%s] 已成为主节点", e.id)
+		callbacks = append(callbacks, e.elected...)
+	} else if !ok && wasLeader {
+		util.Log().Warning("当前实例 [%s] 已失去主节点身份", e.id)
+		callbacks = append(callbacks, e.revoked...)
+	}
+	e.mu.Unlock()
+
+	for _, f := range callbacks {
+		f()
+	}
+}
+
+// Run 定期竞选或续期租约，直到 Stop 被调用
+func (e *Elector) Run() {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.Campaign()
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// Stop 停止竞选并释放租约，以便其他实例立即接管
+func (e *Elector) Stop() {
+	close(e.stop)
+
+	e.mu.Lock()
+	wasLeader := e.leader
+	e.leader = false
+	revoked := append([]func(){}, e.revoked...)
+	e.mu.Unlock()
+
+	if !wasLeader {
+		return
+	}
+
+	for _, f := range revoked {
+		f()
+	}
+
+	if err := e.backend.Release(e.name, e.id); err != nil {
+		util.Log().Warning("无法释放主节点租约，%s", err)
+	}
+}
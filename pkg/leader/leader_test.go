@@ -0,0 +1,183 @@
+package leader
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+type backendMock struct {
+	sync.Mutex
+	ok       bool
+	err      error
+	released bool
+}
+
+func (b *backendMock) Acquire(name, holder string, ttl time.Duration) (bool, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.ok, b.err
+}
+
+func (b *backendMock) Release(name, holder string) error {
+	b.Lock()
+	defer b.Unlock()
+	b.released = true
+	return b.err
+}
+
+func (b *backendMock) set(ok bool, err error) {
+	b.Lock()
+	defer b.Unlock()
+	b.ok, b.err = ok, err
+}
+
+func TestDefault(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 未初始化时视为主节点
+	{
+		Default = nil
+		asserts.True(IsLeader())
+		called := false
+		OnElected(func() { called = true })
+		OnRevoked(func() {})
+		asserts.True(called)
+	}
+
+	// 已初始化
+	{
+		backend := &backendMock{ok: true}
+		Default = NewElector(LeaseName, "a", backend, time.Minute)
+		defer func() { Default = nil }()
+		asserts.False(IsLeader())
+
+		elected, revoked := 0, 0
+		OnElected(func() { elected++ })
+		OnRevoked(func() { revoked++ })
+		asserts.Equal(0, elected)
+
+		Default.Campaign()
+		asserts.True(IsLeader())
+		asserts.Equal(1, elected)
+		asserts.Equal(0, revoked)
+	}
+}
+
+func TestElector_Campaign(t *testing.T) {
+	asserts := assert.New(t)
+	backend := &backendMock{}
+	elector := NewElector(LeaseName, "a", backend, time.Minute)
+	asserts.Equal("a", elector.ID())
+
+	elected, revoked := 0, 0
+	elector.OnElected(func() { elected++ })
+	elector.OnRevoked(func() { revoked++ })
+
+	// 未获得租约
+	elector.Campaign()
+	asserts.False(elector.IsLeader())
+	asserts.Equal(0, elected)
+
+	// 获得租约
+	backend.set(true, nil)
+	elector.Campaign()
+	asserts.True(elector.IsLeader())
+	asserts.Equal(1, elected)
+
+	// 续期不重复触发回调
+	elector.Campaign()
+	asserts.Equal(1, elected)
+
+	// 当前已是主节点，注册时立即执行
+	called := false
+	elector.OnElected(func() { called = true })
+	asserts.True(called)
+
+	// 续期出错，租约到期前保持身份
+	backend.set(false, errors.New("error"))
+	elector.Campaign()
+	asserts.True(elector.IsLeader())
+	asserts.Equal(0, revoked)
+
+	// 租约已到期
+	elector.renewedAt = time.Now().Add(-2 * time.Minute)
+	elector.Campaign()
+	asserts.False(elector.IsLeader())
+	asserts.Equal(1, revoked)
+
+	// 租约被其他实例持有
+	backend.set(true, nil)
+	elector.Campaign()
+	asserts.True(elector.IsLeader())
+	backend.set(false, nil)
+	elector.Campaign()
+	asserts.False(elector.IsLeader())
+	asserts.Equal(2, revoked)
+}
+
+func TestElector_RunAndStop(t *testing.T) {
+	asserts := assert.New(t)
+	backend := &backendMock{}
+	elector := NewElector(LeaseName, "a", backend, 30*time.Millisecond)
+
+	revoked := false
+	elector.OnRevoked(func() { revoked = true })
+
+	go elector.Run()
+	backend.set(true, nil)
+	asserts.Eventually(elector.IsLeader, time.Second, 10*time.Millisecond)
+
+	elector.Stop()
+	asserts.False(elector.IsLeader())
+	asserts.True(revoked)
+	asserts.True(backend.released)
+}
+
+func TestElector_StopNotLeader(t *testing.T) {
+	asserts := assert.New(t)
+	backend := &backendMock{}
+	elector := NewElector(LeaseName, "a", backend, time.Minute)
+	elector.Stop()
+	asserts.False(backend.released)
+}
+
+func TestDBBackend(t *testing.T) {
+	asserts := assert.New(t)
+	backend := dbBackend{}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)leases(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	ok, err := backend.Acquire(LeaseName, "a", time.Minute)
+	asserts.NoError(err)
+	asserts.True(ok)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)leases(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	asserts.NoError(backend.Release(LeaseName, "a"))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,82 @@
+package leader
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// redisKeyPrefix 租约在 Redis 中的键前缀
+const redisKeyPrefix = "cloudreve_leader_"
+
+// acquireScript 租约不存在或由自身持有时，写入并设定过期时间
+var acquireScript = redis.NewScript(1, `
+local holder = redis.call('GET', KEYS[1])
+if holder == false or holder == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// releaseScript 仅删除由自身持有的租约
+var releaseScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// RedisBackend 基于 Redis 的租约存储
+type RedisBackend struct {
+	pool *redis.Pool
+}
+
+// NewRedisBackend 创建基于 Redis 的租约存储
+func NewRedisBackend(network, address, password, database string) *RedisBackend {
+	return &RedisBackend{
+		pool: &redis.Pool{
+			MaxIdle:     2,
+			IdleTimeout: 240 * time.Second,
+			TestOnBorrow: func(c redis.Conn, t time.Time) error {
+				_, err := c.Do("PING")
+				return err
+			},
+			Dial: func() (redis.Conn, error) {
+				db, err := strconv.Atoi(database)
+				if err != nil {
+					return nil, err
+				}
+
+				return redis.Dial(
+					network,
+					address,
+					redis.DialDatabase(db),
+					redis.DialPassword(password),
+				)
+			},
+		},
+	}
+}
+
+// Acquire 获取或续期租约
+func (store *RedisBackend) Acquire(name, holder string, ttl time.Duration) (bool, error) {
+	rc := store.pool.Get()
+	defer rc.Close()
+
+	res, err := redis.Int(acquireScript.Do(rc, redisKeyPrefix+name, holder, ttl.Milliseconds()))
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// Release 释放租约
+func (store *RedisBackend) Release(name, holder string) error {
+	rc := store.pool.Get()
+	defer rc.Close()
+
+	_, err := releaseScript.Do(rc, redisKeyPrefix+name, holder)
+	return err
+}
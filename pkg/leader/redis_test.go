@@ -0,0 +1,78 @@
+package leader
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRedisBackend(t *testing.T) {
+	asserts := assert.New(t)
+
+	store := NewRedisBackend("tcp", "", "", "0")
+	asserts.NotNil(store)
+
+	conn, err := store.pool.Dial()
+	asserts.Nil(conn)
+	asserts.Error(err)
+
+	testConn := redigomock.NewConn()
+	testConn.Command("PING").Expect("PONG")
+	asserts.NoError(store.pool.TestOnBorrow(testConn, time.Now()))
+}
+
+func TestRedisBackend_Acquire(t *testing.T) {
+	asserts := assert.New(t)
+	conn := redigomock.NewConn()
+	store := &RedisBackend{pool: &redis.Pool{
+		Dial:    func() (redis.Conn, error) { return conn, nil },
+		MaxIdle: 10,
+	}}
+
+	// 获得租约
+	{
+		conn.Clear()
+		conn.GenericCommand("EVALSHA").Expect(int64(1))
+		ok, err := store.Acquire(LeaseName, "a", time.Minute)
+		asserts.NoError(err)
+		asserts.True(ok)
+	}
+
+	// 由其他实例持有
+	{
+		conn.Clear()
+		conn.GenericCommand("EVALSHA").Expect(int64(0))
+		ok, err := store.Acquire(LeaseName, "a", time.Minute)
+		asserts.NoError(err)
+		asserts.False(ok)
+	}
+
+	// 出错
+	{
+		conn.Clear()
+		conn.GenericCommand("EVALSHA").ExpectError(errors.New("error"))
+		ok, err := store.Acquire(LeaseName, "a", time.Minute)
+		asserts.Error(err)
+		asserts.False(ok)
+	}
+}
+
+func TestRedisBackend_Release(t *testing.T) {
+	asserts := assert.New(t)
+	conn := redigomock.NewConn()
+	store := &RedisBackend{pool: &redis.Pool{
+		Dial:    func() (redis.Conn, error) { return conn, nil },
+		MaxIdle: 10,
+	}}
+
+	conn.GenericCommand("EVALSHA").Expect(int64(1))
+	asserts.NoError(store.Release(LeaseName, "a"))
+
+	conn.Clear()
+	conn.GenericCommand("EVALSHA").ExpectError(errors.New("error"))
+	asserts.Error(store.Release(LeaseName, "a"))
+}
@@ -0,0 +1,373 @@
+package mq
+
+import (
+	"bytes"
+	"encoding/gob"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/common"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/rpc"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// redisStream 用于在实例间同步消息的 Redis Stream
+	redisStream = "cloudreve_mq_stream"
+	// redisStreamLen Stream 中保留的最大消息数量
+	redisStreamLen = 10000
+	// redisReadBatch 每次读取的消息数量
+	redisReadBatch = 100
+	// redisBlockTimeout 等待新消息的最长时间
+	redisBlockTimeout = 5 * time.Second
+)
+
+// Init 配置了 Redis 时，使用基于 Redis 的消息队列在多个主机实例间同步消息
+func Init() {
+	if conf.RedisConfig.Server != "" && gin.Mode() != gin.TestMode {
+		GlobalMQ = NewRedisMQ(
+			conf.RedisConfig.Network,
+			conf.RedisConfig.Server,
+			conf.RedisConfig.Password,
+			conf.RedisConfig.DB,
+		)
+	}
+}
+
+// redisEnvelope 在 Redis 中传输的消息
+type redisEnvelope struct {
+	// Stream 中的消息ID，读取时由 Redis 返回
+	ID      string
+	Topic   string
+	Message Message
+}
+
+// redisMQ 基于 Redis 的消息队列。消息写入 Stream，各实例按 Stream 中的顺序读取并分发，
+// 断线重连后从最后一条已分发的消息继续读取。订阅者仍保存在本地
+type redisMQ struct {
+	pool  *redis.Pool
+	local *inMemoryMQ
+
+	// 最后一条已分发消息在 Stream 中的ID
+	lastID string
+	mu     sync.Mutex
+}
+
+// NewRedisMQ 创建基于 Redis 的消息队列，并开始监听其他实例发布的消息
+func NewRedisMQ(network, address, password, database string) MQ {
+	r := newRedisMQ(&redis.Pool{
+		MaxIdle:     10,
+		IdleTimeout: 240 * time.Second,
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+		Dial: func() (redis.Conn, error) {
+			db, err := strconv.Atoi(database)
+			if err != nil {
+				return nil, err
+			}
+
+			return redis.Dial(
+				network,
+				address,
+				redis.DialDatabase(db),
+				redis.DialPassword(password),
+			)
+		},
+	})
+
+	go r.listen()
+	return r
+}
+
+func newRedisMQ(pool *redis.Pool) *redisMQ {
+	return &redisMQ{
+		pool:  pool,
+		local: NewMQ().(*inMemoryMQ),
+	}
+}
+
+func encodeEnvelope(envelope redisEnvelope) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(envelope); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func decodeEnvelope(data []byte) (redisEnvelope, error) {
+	var envelope redisEnvelope
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&envelope)
+	return envelope, err
+}
+
+// Publish 发布消息，Redis 不可用时退化为仅向本实例的订阅者发布
+func (r *redisMQ) Publish(topic string, message Message) {
+	if err := r.publish(topic, message); err != nil {
+		util.Log().Warning("无法通过 Redis 发布消息 [%s]，仅投递给本实例, %s", topic, err)
+		r.local.Publish(topic, message)
+	}
+}
+
+func (r *redisMQ) publish(topic string, message Message) error {
+	data, err := encodeEnvelope(redisEnvelope{Topic: topic, Message: message})
+	if err != nil {
+		return err
+	}
+
+	rc := r.pool.Get()
+	defer rc.Close()
+	if rc.Err() != nil {
+		return rc.Err()
+	}
+
+	_, err = rc.Do("XADD", redisStream, "MAXLEN", "~", redisStreamLen, "*", "data", data)
+	return err
+}
+
+// Subscribe 订阅一个消息主题
+func (r *redisMQ) Subscribe(topic string, buffer int) <-chan Message {
+	return r.local.Subscribe(topic, buffer)
+}
+
+// SubscribeCallback 订阅一个消息主题，注册触发回调函数
+func (r *redisMQ) SubscribeCallback(topic string, callbackFunc CallbackFunc) {
+	r.local.SubscribeCallback(topic, callbackFunc)
+}
+
+// Unsubscribe 取消订阅一个消息主题
+func (r *redisMQ) Unsubscribe(topic string, sub <-chan Message) {
+	r.local.Unsubscribe(topic, sub)
+}
+
+// listen 持续读取 Stream，连接断开后重试
+func (r *redisMQ) listen() {
+	for {
+		if err := r.receive(); err != nil {
+			util.Log().Warning("Redis 消息队列连接中断，将在 1 秒后重试, %s", err)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// receive 确定读取位置后持续读取并分发 Stream 中的消息
+func (r *redisMQ) receive() error {
+	rc := r.pool.Get()
+	defer rc.Close()
+
+	if err := r.seek(rc); err != nil {
+		return err
+	}
+
+	for {
+		if err := r.read(rc); err != nil {
+			return err
+		}
+	}
+}
+
+// seek 首次连接时将读取位置设为 Stream 的末尾，之后从最后一条已分发的消息继续读取
+func (r *redisMQ) seek(rc redis.Conn) error {
+	r.mu.Lock()
+	lastID := r.lastID
+	r.mu.Unlock()
+	if lastID != "" {
+		return nil
+	}
+
+	entries, err := redis.Values(rc.Do("XREVRANGE", redisStream, "+", "-", "COUNT", 1))
+	if err != nil {
+		return err
+	}
+
+	lastID = "0-0"
+	if len(entries) > 0 {
+		if entry, err := redis.Values(entries[0], nil); err == nil && len(entry) > 0 {
+			lastID, _ = redis.String(entry[0], nil)
+		}
+	}
+
+	r.mu.Lock()
+	if r.lastID == "" {
+		r.lastID = lastID
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+// read 读取并分发最后一条已分发消息之后的消息，没有新消息时最多等待 redisBlockTimeout
+func (r *redisMQ) read(rc redis.Conn) error {
+	r.mu.Lock()
+	lastID := r.lastID
+	r.mu.Unlock()
+
+	reply, err := rc.Do("XREAD", "COUNT", redisReadBatch, "BLOCK", redisBlockTimeout.Milliseconds(),
+		"STREAMS", redisStream, lastID)
+	if err != nil {
+		return err
+	}
+
+	envelopes, readID, err := parseStreamReply(reply)
+	if err != nil {
+		return err
+	}
+
+	for _, envelope := range envelopes {
+		r.dispatch(envelope)
+	}
+
+	// 跳过无法解析的消息，以免重复读取
+	r.mu.Lock()
+	if readID != "" && streamIDAfter(readID, r.lastID) {
+		r.lastID = readID
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+// dispatch 将消息分发给本实例的订阅者。消息按 Stream 中的顺序读取，已分发过的消息将被忽略
+func (r *redisMQ) dispatch(envelope redisEnvelope) {
+	r.mu.Lock()
+	if !streamIDAfter(envelope.ID, r.lastID) {
+		r.mu.Unlock()
+		return
+	}
+	r.lastID = envelope.ID
+	r.mu.Unlock()
+
+	r.local.Publish(envelope.Topic, envelope.Message)
+}
+
+// parseStreamReply 解析 XREAD 命令的返回值，同时返回读取到的最后一条消息的ID，包括无法解析的消息
+func parseStreamReply(reply interface{}) ([]redisEnvelope, string, error) {
+	if reply == nil {
+		return nil, "", nil
+	}
+
+	streams, err := redis.Values(reply, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var (
+		res    []redisEnvelope
+		lastID string
+	)
+	for _, stream := range streams {
+		// [流名称, [[消息ID, [字段, 值, ...]], ...]]
+		pair, err := redis.Values(stream, nil)
+		if err != nil || len(pair) != 2 {
+			continue
+		}
+
+		entries, err := redis.Values(pair[1], nil)
+		if err != nil {
+			return nil, "", err
+		}
+
+		for _, entry := range entries {
+			fields, err := redis.Values(entry, nil)
+			if err != nil || len(fields) != 2 {
+				continue
+			}
+
+			id, err := redis.String(fields[0], nil)
+			if err != nil {
+				continue
+			}
+			lastID = id
+
+			values, err := redis.ByteSlices(fields[1], nil)
+			if err != nil {
+				continue
+			}
+
+			for i := 0; i+1 < len(values); i += 2 {
+				if string(values[i]) != "data" {
+					continue
+				}
+
+				envelope, err := decodeEnvelope(values[i+1])
+				if err != nil {
+					util.Log().Warning("无法解析 Redis 消息 [%s], %s", id, err)
+					break
+				}
+
+				envelope.ID = id
+				res = append(res, envelope)
+			}
+		}
+	}
+
+	return res, lastID, nil
+}
+
+// streamIDAfter 判断 Stream 消息ID a 是否在 b 之后，b 为空时始终成立
+func streamIDAfter(a, b string) bool {
+	if b == "" {
+		return true
+	}
+
+	aTime, aSeq := splitStreamID(a)
+	bTime, bSeq := splitStreamID(b)
+	if aTime != bTime {
+		return aTime > bTime
+	}
+	return aSeq > bSeq
+}
+
+func splitStreamID(id string) (uint64, uint64) {
+	parts := strings.SplitN(id, "-", 2)
+	ms, _ := strconv.ParseUint(parts[0], 10, 64)
+	var seq uint64
+	if len(parts) == 2 {
+		seq, _ = strconv.ParseUint(parts[1], 10, 64)
+	}
+	return ms, seq
+}
+
+func (r *redisMQ) Aria2Notify(events []rpc.Event, status int) {
+	for _, event := range events {
+		r.Publish(event.Gid, Message{
+			TriggeredBy: event.Gid,
+			Event:       strconv.FormatInt(int64(status), 10),
+			Content:     events,
+		})
+	}
+}
+
+// OnDownloadStart 下载开始
+func (r *redisMQ) OnDownloadStart(events []rpc.Event) {
+	r.Aria2Notify(events, common.Downloading)
+}
+
+// OnDownloadPause 下载暂停
+func (r *redisMQ) OnDownloadPause(events []rpc.Event) {
+	r.Aria2Notify(events, common.Paused)
+}
+
+// OnDownloadStop 下载停止
+func (r *redisMQ) OnDownloadStop(events []rpc.Event) {
+	r.Aria2Notify(events, common.Canceled)
+}
+
+// OnDownloadComplete 下载完成
+func (r *redisMQ) OnDownloadComplete(events []rpc.Event) {
+	r.Aria2Notify(events, common.Complete)
+}
+
+// OnDownloadError 下载出错
+func (r *redisMQ) OnDownloadError(events []rpc.Event) {
+	r.Aria2Notify(events, common.Error)
+}
+
+// OnBtDownloadComplete BT下载完成
+func (r *redisMQ) OnBtDownloadComplete(events []rpc.Event) {
+	r.Aria2Notify(events, common.Complete)
+}
@@ -0,0 +1,253 @@
+package mq
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/common"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/rpc"
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMockRedisMQ() (*redisMQ, *redigomock.Conn) {
+	conn := redigomock.NewConn()
+	pool := &redis.Pool{
+		Dial:    func() (redis.Conn, error) { return conn, nil },
+		MaxIdle: 10,
+	}
+	return newRedisMQ(pool), conn
+}
+
+func streamEntry(id string, topic string, msg Message) []interface{} {
+	data, _ := encodeEnvelope(redisEnvelope{Topic: topic, Message: msg})
+	return []interface{}{[]byte(id), []interface{}{[]byte("data"), data}}
+}
+
+func TestNewRedisMQ(t *testing.T) {
+	asserts := assert.New(t)
+	mq := NewRedisMQ("tcp", "", "", "0")
+	asserts.NotNil(mq)
+
+	r := mq.(*redisMQ)
+	conn, err := r.pool.Dial()
+	asserts.Nil(conn)
+	asserts.Error(err)
+}
+
+func TestRedisMQ_Publish(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 正常
+	{
+		r, conn := newMockRedisMQ()
+		xadd := conn.Command("XADD", redisStream, "MAXLEN", "~", redisStreamLen, "*", "data", redigomock.NewAnyData()).
+			Expect("1-0")
+		notifier := r.Subscribe("topic", 1)
+		r.Publish("topic", Message{TriggeredBy: "Tester"})
+		asserts.Equal(1, conn.Stats(xadd))
+
+		// 从 Stream 中读取前，不会直接投递
+		select {
+		case <-notifier:
+			asserts.Fail("message should not be delivered locally")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	// Redis 出错，仅投递给本实例
+	{
+		r, conn := newMockRedisMQ()
+		conn.Command("XADD", redisStream, "MAXLEN", "~", redisStreamLen, "*", "data", redigomock.NewAnyData()).
+			ExpectError(errors.New("error"))
+		notifier := r.Subscribe("topic", 1)
+		r.Publish("topic", Message{TriggeredBy: "Tester"})
+		select {
+		case msg := <-notifier:
+			asserts.Equal("Tester", msg.TriggeredBy)
+		case <-time.After(time.Second):
+			asserts.Fail("message not delivered")
+		}
+	}
+}
+
+func TestRedisMQ_Dispatch(t *testing.T) {
+	asserts := assert.New(t)
+	r, _ := newMockRedisMQ()
+	notifier := r.Subscribe("topic", 3)
+
+	r.dispatch(redisEnvelope{ID: "1-1", Topic: "topic", Message: Message{TriggeredBy: "1"}})
+	// 重复或更早的消息被忽略
+	r.dispatch(redisEnvelope{ID: "1-1", Topic: "topic", Message: Message{TriggeredBy: "2"}})
+	r.dispatch(redisEnvelope{ID: "1-0", Topic: "topic", Message: Message{TriggeredBy: "3"}})
+	r.dispatch(redisEnvelope{ID: "2-0", Topic: "topic", Message: Message{TriggeredBy: "4"}})
+
+	asserts.ElementsMatch([]string{"1", "4"}, []string{(<-notifier).TriggeredBy, (<-notifier).TriggeredBy})
+	asserts.Equal("2-0", r.lastID)
+}
+
+func TestRedisMQ_Seek(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 首次连接，记录当前位置
+	{
+		r, conn := newMockRedisMQ()
+		conn.Command("XREVRANGE", redisStream, "+", "-", "COUNT", 1).
+			Expect([]interface{}{streamEntry("5-0", "topic", Message{})})
+		asserts.NoError(r.seek(conn))
+		asserts.Equal("5-0", r.lastID)
+	}
+
+	// 首次连接，Stream 为空
+	{
+		r, conn := newMockRedisMQ()
+		conn.Command("XREVRANGE", redisStream, "+", "-", "COUNT", 1).Expect([]interface{}{})
+		asserts.NoError(r.seek(conn))
+		asserts.Equal("0-0", r.lastID)
+	}
+
+	// 首次连接出错
+	{
+		r, conn := newMockRedisMQ()
+		conn.Command("XREVRANGE", redisStream, "+", "-", "COUNT", 1).ExpectError(errors.New("error"))
+		asserts.Error(r.seek(conn))
+		asserts.Equal("", r.lastID)
+	}
+
+	// 重新连接，从最后一条已分发的消息继续
+	{
+		r, conn := newMockRedisMQ()
+		r.lastID = "1-0"
+		asserts.NoError(r.seek(conn))
+		asserts.Equal("1-0", r.lastID)
+	}
+}
+
+func TestRedisMQ_Read(t *testing.T) {
+	asserts := assert.New(t)
+	block := redisBlockTimeout.Milliseconds()
+
+	// 按 Stream 中的顺序分发，跳过无法解析的消息
+	{
+		r, conn := newMockRedisMQ()
+		r.lastID = "1-0"
+		notifier := r.Subscribe("topic", 2)
+		conn.Command("XREAD", "COUNT", redisReadBatch, "BLOCK", block, "STREAMS", redisStream, "1-0").
+			Expect([]interface{}{[]interface{}{[]byte(redisStream), []interface{}{
+				streamEntry("2-0", "topic", Message{TriggeredBy: "2"}),
+				streamEntry("3-0", "topic", Message{TriggeredBy: "3"}),
+				[]interface{}{[]byte("4-0"), []interface{}{[]byte("data"), []byte("invalid")}},
+			}}})
+		asserts.NoError(r.read(conn))
+		asserts.Equal("4-0", r.lastID)
+		asserts.ElementsMatch([]string{"2", "3"}, []string{(<-notifier).TriggeredBy, (<-notifier).TriggeredBy})
+	}
+
+	// 没有新消息
+	{
+		r, conn := newMockRedisMQ()
+		r.lastID = "1-0"
+		conn.Command("XREAD", "COUNT", redisReadBatch, "BLOCK", block, "STREAMS", redisStream, "1-0").Expect(nil)
+		asserts.NoError(r.read(conn))
+		asserts.Equal("1-0", r.lastID)
+	}
+
+	// 读取出错
+	{
+		r, conn := newMockRedisMQ()
+		r.lastID = "1-0"
+		conn.Command("XREAD", "COUNT", redisReadBatch, "BLOCK", block, "STREAMS", redisStream, "1-0").
+			ExpectError(errors.New("error"))
+		asserts.Error(r.read(conn))
+	}
+}
+
+func TestRedisMQ_Receive(t *testing.T) {
+	asserts := assert.New(t)
+	r, conn := newMockRedisMQ()
+	notifier := r.Subscribe("topic", 1)
+	block := redisBlockTimeout.Milliseconds()
+
+	conn.Command("XREVRANGE", redisStream, "+", "-", "COUNT", 1).
+		Expect([]interface{}{streamEntry("1-0", "topic", Message{})})
+	conn.Command("XREAD", "COUNT", redisReadBatch, "BLOCK", block, "STREAMS", redisStream, "1-0").
+		Expect([]interface{}{[]interface{}{[]byte(redisStream), []interface{}{
+			streamEntry("2-0", "topic", Message{TriggeredBy: "Tester"}),
+		}}})
+	conn.Command("XREAD", "COUNT", redisReadBatch, "BLOCK", block, "STREAMS", redisStream, "2-0").
+		ExpectError(errors.New("error"))
+
+	asserts.Error(r.receive())
+	asserts.Equal("Tester", (<-notifier).TriggeredBy)
+	asserts.Equal("2-0", r.lastID)
+}
+
+func TestParseStreamReply(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 空
+	res, lastID, err := parseStreamReply(nil)
+	asserts.NoError(err)
+	asserts.Empty(res)
+	asserts.Empty(lastID)
+
+	// 格式错误
+	_, _, err = parseStreamReply("invalid")
+	asserts.Error(err)
+
+	// 跳过无法解析的消息
+	res, lastID, err = parseStreamReply([]interface{}{[]interface{}{[]byte(redisStream), []interface{}{
+		[]interface{}{[]byte("1-0"), []interface{}{[]byte("data"), []byte("invalid")}},
+		[]interface{}{[]byte("1-1"), []interface{}{[]byte("other"), []byte("value")}},
+		streamEntry("2-0", "topic", Message{TriggeredBy: "Tester"}),
+	}}})
+	asserts.NoError(err)
+	asserts.Equal("2-0", lastID)
+	asserts.Len(res, 1)
+	asserts.Equal("2-0", res[0].ID)
+	asserts.Equal("topic", res[0].Topic)
+	asserts.Equal("Tester", res[0].Message.TriggeredBy)
+}
+
+func TestStreamIDAfter(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.True(streamIDAfter("1-0", ""))
+	asserts.True(streamIDAfter("2-0", "1-5"))
+	asserts.True(streamIDAfter("1-6", "1-5"))
+	asserts.True(streamIDAfter("10-0", "9-0"))
+	asserts.False(streamIDAfter("1-5", "1-5"))
+	asserts.False(streamIDAfter("1-4", "1-5"))
+}
+
+func TestRedisMQ_Aria2Notify(t *testing.T) {
+	asserts := assert.New(t)
+	r, conn := newMockRedisMQ()
+	xadd := conn.Command("XADD", redisStream, "MAXLEN", "~", redisStreamLen, "*", "data", redigomock.NewAnyData()).
+		ExpectError(errors.New("error"))
+
+	notifier := r.Subscribe("gid", 6)
+	events := []rpc.Event{{Gid: "gid"}}
+	r.OnDownloadStart(events)
+	r.OnDownloadPause(events)
+	r.OnDownloadStop(events)
+	r.OnDownloadComplete(events)
+	r.OnDownloadError(events)
+	r.OnBtDownloadComplete(events)
+	asserts.Equal(6, conn.Stats(xadd))
+
+	statuses := make(map[string]bool)
+	for i := 0; i < 6; i++ {
+		select {
+		case msg := <-notifier:
+			statuses[msg.Event] = true
+		case <-time.After(time.Second):
+			asserts.Fail("message not delivered")
+		}
+	}
+	for _, status := range []int{common.Downloading, common.Paused, common.Canceled, common.Complete, common.Error} {
+		asserts.True(statuses[strconv.Itoa(status)])
+	}
+}
@@ -0,0 +1,120 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+)
+
+// supportedAlgorithms 支持的 ID Token 签名算法
+var supportedAlgorithms = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// jsonWebKey 身份提供方公布的签名公钥
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// publicKey 查找 kid 对应的签名公钥，缓存中找不到时重新获取一次，以适应身份提供方轮换密钥
+func (p *Provider) publicKey(jwksURI, kid string) (interface{}, error) {
+	if cached, ok := cache.Get("oidc_jwks_" + jwksURI); ok {
+		if key, err := findKey(cached.(string), kid); err == nil {
+			return key, nil
+		}
+	}
+
+	raw, err := p.client.Request("GET", jwksURI, nil).CheckHTTPResponse(200).GetResponse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	key, err := findKey(raw, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.Set("oidc_jwks_"+jwksURI, raw, discoveryTTL)
+	return key, nil
+}
+
+// findKey 从 JWKS 文档中解析 kid 对应的公钥，kid 为空时使用第一个签名公钥
+func findKey(raw, kid string) (interface{}, error) {
+	var set jsonWebKeySet
+	if err := json.Unmarshal([]byte(raw), &set); err != nil {
+		return nil, fmt.Errorf("failed to parse signing keys: %w", err)
+	}
+
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		if kid == "" || key.Kid == kid {
+			return key.publicKey()
+		}
+	}
+
+	return nil, fmt.Errorf("signing key %q not found", kid)
+}
+
+func (key *jsonWebKey) publicKey() (interface{}, error) {
+	switch key.Kty {
+	case "RSA":
+		n, err := decodeBigInt(key.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(key.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch key.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", key.Crv)
+		}
+		x, err := decodeBigInt(key.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(key.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", key.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key parameter: %w", err)
+	}
+	return new(big.Int).SetBytes(buf), nil
+}
@@ -0,0 +1,294 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// discoveryTTL 发现文档与签名公钥的缓存时间，秒
+const discoveryTTL = 3600
+
+var (
+	// ErrNotConfigured 未配置身份提供方
+	ErrNotConfigured = errors.New("OpenID Connect provider is not configured")
+	// ErrNoIDToken 令牌响应中不包含 ID Token
+	ErrNoIDToken = errors.New("no id_token in token response")
+	// ErrNonceMismatch ID Token 中的 nonce 不匹配
+	ErrNonceMismatch = errors.New("nonce in id_token does not match")
+	// ErrInvalidClaims ID Token 中的签发者、受众或有效期无效
+	ErrInvalidClaims = errors.New("invalid issuer, audience or expiry in id_token")
+)
+
+// Discovery 身份提供方的发现文档
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// TokenResponse 令牌端点的响应
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// Provider OpenID Connect 身份提供方
+type Provider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	client request.Client
+}
+
+// NewProvider 新建身份提供方
+func NewProvider(issuer, clientID, clientSecret, redirectURL string, scopes []string) *Provider {
+	return &Provider{
+		Issuer:       strings.TrimSuffix(issuer, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       scopes,
+		client:       request.NewClient(request.WithTimeout(10 * time.Second)),
+	}
+}
+
+// NewProviderFromSetting 根据站点设置新建身份提供方，回调地址为站点下的 /login/oidc
+func NewProviderFromSetting() (*Provider, error) {
+	options := model.GetSettingByNames("oidc_issuer", "oidc_client_id", "oidc_client_secret", "oidc_scopes")
+	if options["oidc_issuer"] == "" || options["oidc_client_id"] == "" {
+		return nil, ErrNotConfigured
+	}
+
+	controller, _ := url.Parse("/login/oidc")
+	return NewProvider(
+		options["oidc_issuer"],
+		options["oidc_client_id"],
+		options["oidc_client_secret"],
+		model.GetSiteURL().ResolveReference(controller).String(),
+		strings.Fields(options["oidc_scopes"]),
+	), nil
+}
+
+// NewPKCE 生成 PKCE 校验码及其 S256 摘要
+func NewPKCE() (verifier, challenge string, err error) {
+	verifier, err = RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// RandomString 生成 n 字节随机数据的 URL 安全编码，用于 state、nonce 等
+func RandomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Discover 获取并缓存发现文档
+func (p *Provider) Discover() (*Discovery, error) {
+	var raw string
+	if cached, ok := cache.Get("oidc_discovery_" + p.Issuer); ok {
+		raw = cached.(string)
+	} else {
+		res, err := p.client.Request("GET", p.Issuer+"/.well-known/openid-configuration", nil).
+			CheckHTTPResponse(200).GetResponse()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch discovery document: %w", err)
+		}
+		raw = res
+	}
+
+	discovery := &Discovery{}
+	if err := json.Unmarshal([]byte(raw), discovery); err != nil {
+		return nil, fmt.Errorf("failed to parse discovery document: %w", err)
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.Issuer {
+		return nil, fmt.Errorf("issuer %q in discovery document does not match %q", discovery.Issuer, p.Issuer)
+	}
+
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JwksURI == "" {
+		return nil, errors.New("discovery document is missing required endpoints")
+	}
+
+	cache.Set("oidc_discovery_"+p.Issuer, raw, discoveryTTL)
+	return discovery, nil
+}
+
+// AuthCodeURL 生成授权请求地址
+func (p *Provider) AuthCodeURL(state, nonce, challenge string) (string, error) {
+	discovery, err := p.Discover()
+	if err != nil {
+		return "", err
+	}
+
+	target, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := p.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid"}
+	}
+
+	queries := target.Query()
+	queries.Set("response_type", "code")
+	queries.Set("client_id", p.ClientID)
+	queries.Set("redirect_uri", p.RedirectURL)
+	queries.Set("scope", strings.Join(scopes, " "))
+	queries.Set("state", state)
+	queries.Set("nonce", nonce)
+	queries.Set("code_challenge", challenge)
+	queries.Set("code_challenge_method", "S256")
+	target.RawQuery = queries.Encode()
+
+	return target.String(), nil
+}
+
+// Exchange 使用授权码和 PKCE 校验码换取令牌
+func (p *Provider) Exchange(code, verifier string) (*TokenResponse, error) {
+	discovery, err := p.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("client_id", p.ClientID)
+	form.Set("code_verifier", verifier)
+	if p.ClientSecret != "" {
+		form.Set("client_secret", p.ClientSecret)
+	}
+	body := form.Encode()
+
+	res, err := p.client.Request(
+		"POST",
+		discovery.TokenEndpoint,
+		strings.NewReader(body),
+		request.WithContentLength(int64(len(body))),
+		request.WithHeader(http.Header{
+			"Content-Type": {"application/x-www-form-urlencoded"},
+			"Accept":       {"application/json"},
+		}),
+	).GetResponse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to request token endpoint: %w", err)
+	}
+
+	token := &TokenResponse{}
+	if err := json.Unmarshal([]byte(res), token); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+
+	if token.Error != "" {
+		return nil, fmt.Errorf("token endpoint returned error %q: %s", token.Error, token.Description)
+	}
+
+	if token.IDToken == "" {
+		return nil, ErrNoIDToken
+	}
+
+	return token, nil
+}
+
+// VerifyIDToken 校验 ID Token 的签名、签发者、受众、有效期和 nonce，返回其中的声明
+func (p *Provider) VerifyIDToken(raw, nonce string) (Claims, error) {
+	discovery, err := p.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &jwt.Parser{ValidMethods: supportedAlgorithms}
+	claims := jwt.MapClaims{}
+	if _, err := parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(discovery.JwksURI, kid)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to verify id_token: %w", err)
+	}
+
+	if !claims.VerifyIssuer(discovery.Issuer, true) ||
+		!claims.VerifyAudience(p.ClientID, true) ||
+		!claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, ErrInvalidClaims
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+
+	return Claims(claims), nil
+}
+
+// Claims ID Token 中的声明
+type Claims map[string]interface{}
+
+// Subject 身份提供方中的用户唯一标识
+func (claims Claims) Subject() string {
+	return claims.String("sub")
+}
+
+// String 获取字符串类型的声明，不存在时返回空字符串
+func (claims Claims) String(name string) string {
+	if val, ok := claims[name].(string); ok {
+		return val
+	}
+	return ""
+}
+
+// Bool 获取布尔类型的声明，兼容以字符串表示的布尔值
+func (claims Claims) Bool(name string) bool {
+	switch val := claims[name].(type) {
+	case bool:
+		return val
+	case string:
+		return val == "true"
+	}
+	return false
+}
+
+// Strings 获取字符串列表类型的声明，单个字符串视为只有一个元素的列表
+func (claims Claims) Strings(name string) []string {
+	switch val := claims[name].(type) {
+	case string:
+		return []string{val}
+	case []interface{}:
+		res := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	return nil
+}
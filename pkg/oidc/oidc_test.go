@@ -0,0 +1,217 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// testProvider 模拟的身份提供方
+type testProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims
+	form   url.Values
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &testProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Discovery{
+			Issuer:                p.server.URL,
+			AuthorizationEndpoint: p.server.URL + "/auth",
+			TokenEndpoint:         p.server.URL + "/token",
+			JwksURI:               p.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jsonWebKeySet{Keys: []jsonWebKey{{
+			Kid: "key1",
+			Kty: "RSA",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		p.form = r.PostForm
+		if r.PostForm.Get("code") != "code" {
+			json.NewEncoder(w).Encode(TokenResponse{Error: "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(TokenResponse{AccessToken: "access", IDToken: p.sign(p.claims)})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	p.claims = jwt.MapClaims{
+		"iss":   p.server.URL,
+		"aud":   "client",
+		"sub":   "user1",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": "nonce",
+		"email": "user@cloudreve.org",
+	}
+	return p
+}
+
+func (p *testProvider) sign(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "key1"
+	res, _ := token.SignedString(p.key)
+	return res
+}
+
+func TestNewPKCE(t *testing.T) {
+	asserts := assert.New(t)
+	verifier, challenge, err := NewPKCE()
+	asserts.NoError(err)
+	asserts.NotEmpty(verifier)
+	asserts.NotEqual(verifier, challenge)
+	asserts.Len(challenge, 43)
+}
+
+func TestProvider_AuthCodeURL(t *testing.T) {
+	asserts := assert.New(t)
+	p := newTestProvider(t)
+	provider := NewProvider(p.server.URL+"/", "client", "secret", "http://cloudreve.org/login/oidc", []string{"openid", "email"})
+
+	res, err := provider.AuthCodeURL("state", "nonce", "challenge")
+	asserts.NoError(err)
+	target, _ := url.Parse(res)
+	asserts.Equal("/auth", target.Path)
+	asserts.Equal("client", target.Query().Get("client_id"))
+	asserts.Equal("openid email", target.Query().Get("scope"))
+	asserts.Equal("S256", target.Query().Get("code_challenge_method"))
+	asserts.Equal("challenge", target.Query().Get("code_challenge"))
+	asserts.Equal("nonce", target.Query().Get("nonce"))
+}
+
+func TestProvider_Discover(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 签发者不一致
+	{
+		p := newTestProvider(t)
+		cache.Set("oidc_discovery_"+p.server.URL+"/other", `{"issuer":"`+p.server.URL+`"}`, 0)
+		provider := NewProvider(p.server.URL+"/other", "client", "", "", nil)
+		_, err := provider.Discover()
+		asserts.Error(err)
+	}
+
+	// 无法访问
+	{
+		provider := NewProvider("http://127.0.0.1:0", "client", "", "", nil)
+		_, err := provider.Discover()
+		asserts.Error(err)
+	}
+}
+
+func TestProvider_ExchangeAndVerify(t *testing.T) {
+	asserts := assert.New(t)
+	p := newTestProvider(t)
+	provider := NewProvider(p.server.URL, "client", "secret", "http://cloudreve.org/login/oidc", nil)
+
+	// 授权码无效
+	{
+		_, err := provider.Exchange("invalid", "verifier")
+		asserts.Error(err)
+	}
+
+	// 成功
+	{
+		token, err := provider.Exchange("code", "verifier")
+		asserts.NoError(err)
+		asserts.Equal("verifier", p.form.Get("code_verifier"))
+		asserts.Equal("secret", p.form.Get("client_secret"))
+
+		claims, err := provider.VerifyIDToken(token.IDToken, "nonce")
+		asserts.NoError(err)
+		asserts.Equal("user1", claims.Subject())
+		asserts.Equal("user@cloudreve.org", claims.String("email"))
+	}
+
+	// nonce 不匹配
+	{
+		_, err := provider.VerifyIDToken(p.sign(p.claims), "other")
+		asserts.Equal(ErrNonceMismatch, err)
+	}
+
+	// 受众不匹配
+	{
+		claims := jwt.MapClaims{}
+		for k, v := range p.claims {
+			claims[k] = v
+		}
+		claims["aud"] = "other"
+		_, err := provider.VerifyIDToken(p.sign(claims), "nonce")
+		asserts.Equal(ErrInvalidClaims, err)
+	}
+
+	// 已过期
+	{
+		claims := jwt.MapClaims{}
+		for k, v := range p.claims {
+			claims[k] = v
+		}
+		claims["exp"] = time.Now().Add(-time.Hour).Unix()
+		_, err := provider.VerifyIDToken(p.sign(claims), "nonce")
+		asserts.Error(err)
+	}
+
+	// 签名无效
+	{
+		other, _ := rsa.GenerateKey(rand.Reader, 2048)
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, p.claims)
+		token.Header["kid"] = "key1"
+		raw, _ := token.SignedString(other)
+		_, err := provider.VerifyIDToken(raw, "nonce")
+		asserts.Error(err)
+	}
+
+	// 不支持的签名算法
+	{
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, p.claims)
+		raw, _ := token.SignedString([]byte("client"))
+		_, err := provider.VerifyIDToken(raw, "nonce")
+		asserts.Error(err)
+	}
+}
+
+func TestClaims(t *testing.T) {
+	asserts := assert.New(t)
+	claims := Claims{
+		"sub":            "1",
+		"email_verified": true,
+		"verified":       "true",
+		"groups":         []interface{}{"a", "b", 1},
+		"group":          "c",
+	}
+
+	asserts.Equal("1", claims.Subject())
+	asserts.Equal("", claims.String("missing"))
+	asserts.True(claims.Bool("email_verified"))
+	asserts.True(claims.Bool("verified"))
+	asserts.False(claims.Bool("missing"))
+	asserts.Equal([]string{"a", "b"}, claims.Strings("groups"))
+	asserts.Equal([]string{"c"}, claims.Strings("group"))
+	asserts.Nil(claims.Strings("missing"))
+}
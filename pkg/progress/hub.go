@@ -0,0 +1,127 @@
+package progress
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+)
+
+// subscriberBuffer 每个订阅者的缓冲区大小，订阅者消费过慢时丢弃新的进度
+const subscriberBuffer = 32
+
+const (
+	// doneRetention 已结束任务的进度保留时长
+	doneRetention = time.Minute
+	// staleRetention 长时间未更新的任务视为已中断，不再保留其进度
+	staleRetention = 10 * time.Minute
+)
+
+// Default 本实例使用的进度分发中心
+var Default = NewHub()
+
+var initOnce sync.Once
+
+// Hub 将进度更新分发给对应用户的订阅者，并保留进行中任务的最新进度
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[uint]map[chan Event]struct{}
+	latest      map[string]Event
+}
+
+// NewHub 新建进度分发中心
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[uint]map[chan Event]struct{}),
+		latest:      make(map[string]Event),
+	}
+}
+
+// Init 订阅消息队列中的进度更新
+func Init() {
+	initOnce.Do(func() {
+		mq.GlobalMQ.SubscribeCallback(Topic, func(msg mq.Message) {
+			if event, ok := msg.Content.(Event); ok {
+				Default.Broadcast(event)
+			}
+		})
+	})
+}
+
+func eventKey(event Event) string {
+	return fmt.Sprintf("%s_%d", event.Kind, event.ID)
+}
+
+// Subscribe 订阅用户的进度更新
+func (h *Hub) Subscribe(uid uint) chan Event {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[uid]; !ok {
+		h.subscribers[uid] = make(map[chan Event]struct{})
+	}
+	h.subscribers[uid][ch] = struct{}{}
+
+	return ch
+}
+
+// Unsubscribe 取消订阅
+func (h *Hub) Unsubscribe(uid uint, ch chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subscribers[uid], ch)
+	if len(h.subscribers[uid]) == 0 {
+		delete(h.subscribers, uid)
+	}
+}
+
+// Broadcast 分发进度更新。消息队列不保证顺序，早于已记录进度的更新将被丢弃
+func (h *Hub) Broadcast(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := eventKey(event)
+	if last, ok := h.latest[key]; ok && event.UpdatedAt.Before(last.UpdatedAt) {
+		return
+	}
+
+	h.latest[key] = event
+	if event.Done {
+		h.prune(event.UpdatedAt)
+	}
+
+	for ch := range h.subscribers[event.UserID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// prune 清理已结束或已中断较久的任务。已结束的任务保留一段时间，以便丢弃晚到的进度
+func (h *Hub) prune(now time.Time) {
+	for key, event := range h.latest {
+		age := now.Sub(event.UpdatedAt)
+		if (event.Done && age > doneRetention) || age > staleRetention {
+			delete(h.latest, key)
+		}
+	}
+}
+
+// Snapshot 列出用户进行中任务的最新进度
+func (h *Hub) Snapshot(uid uint) []Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	res := make([]Event, 0)
+	for _, event := range h.latest {
+		if event.UserID == uid && !event.Done {
+			res = append(res, event)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,65 @@
+package progress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub(t *testing.T) {
+	a := assert.New(t)
+	hub := NewHub()
+	now := time.Now()
+
+	ch := hub.Subscribe(1)
+	other := hub.Subscribe(2)
+
+	// 仅分发给对应用户
+	hub.Broadcast(Event{Kind: KindTask, ID: 1, UserID: 1, Processed: 10, UpdatedAt: now})
+	a.EqualValues(10, (<-ch).Processed)
+	a.Len(other, 0)
+	a.Len(hub.Snapshot(1), 1)
+	a.Len(hub.Snapshot(2), 0)
+
+	// 丢弃晚到的旧进度
+	hub.Broadcast(Event{Kind: KindTask, ID: 1, UserID: 1, Processed: 5, UpdatedAt: now.Add(-time.Second)})
+	a.Len(ch, 0)
+	a.EqualValues(10, hub.Snapshot(1)[0].Processed)
+
+	// 结束后不再出现在快照中
+	hub.Broadcast(Event{Kind: KindTask, ID: 1, UserID: 1, Done: true, UpdatedAt: now.Add(time.Second)})
+	a.True((<-ch).Done)
+	a.Len(hub.Snapshot(1), 0)
+
+	// 清理已结束较久的任务
+	hub.Broadcast(Event{Kind: KindDownload, ID: 1, UserID: 1, Done: true, UpdatedAt: now.Add(2 * doneRetention)})
+	a.True((<-ch).Done)
+	a.Len(hub.latest, 1)
+
+	// 取消订阅
+	hub.Unsubscribe(1, ch)
+	hub.Broadcast(Event{Kind: KindTask, ID: 2, UserID: 1, UpdatedAt: now})
+	a.Len(ch, 0)
+	a.NotContains(hub.subscribers, uint(1))
+}
+
+func TestInit(t *testing.T) {
+	a := assert.New(t)
+	Init()
+
+	ch := Default.Subscribe(3)
+	defer Default.Unsubscribe(3, ch)
+
+	Publish(Event{Kind: KindTask, ID: 3, UserID: 3})
+	select {
+	case event := <-ch:
+		a.EqualValues(3, event.ID)
+		a.False(event.UpdatedAt.IsZero())
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	mq.GlobalMQ.Publish(Topic, mq.Message{Content: "invalid"})
+}
@@ -0,0 +1,267 @@
+package progress
+
+import (
+	"context"
+	"encoding/gob"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+)
+
+// Topic 发布实时进度使用的消息主题
+const Topic = "progress"
+
+const (
+	// KindTask 常规任务
+	KindTask = "task"
+	// KindDownload 离线下载
+	KindDownload = "download"
+)
+
+// publishInterval 同一进度两次发布之间的最小间隔
+const publishInterval = 500 * time.Millisecond
+
+// Event 任务或离线下载的一次进度更新
+type Event struct {
+	Kind      string    `json:"kind"`
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"-"`
+	Phase     int       `json:"phase"`     // 任务阶段或离线下载状态
+	File      string    `json:"file"`      // 当前处理的文件
+	Total     uint64    `json:"total"`     // 当前阶段的总字节数，0 表示未知
+	Processed uint64    `json:"processed"` // 当前阶段已处理的字节数
+	Speed     uint64    `json:"speed"`     // 处理速度，字节/秒
+	ETA       int64     `json:"eta"`       // 预计剩余秒数，-1 表示未知
+	Done      bool      `json:"done"`      // 任务已结束
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func init() {
+	gob.Register(Event{})
+}
+
+// Publish 发布进度更新，经由消息队列送达各实例的订阅者
+func Publish(event Event) {
+	if event.UpdatedAt.IsZero() {
+		event.UpdatedAt = time.Now()
+	}
+
+	mq.GlobalMQ.Publish(Topic, mq.Message{
+		TriggeredBy: event.Kind,
+		Content:     event,
+	})
+}
+
+// Tracker 累计任务的字节级进度，并按固定间隔发布。所有方法在 Tracker 为 nil 时不做任何事
+type Tracker struct {
+	mu    sync.Mutex
+	event Event
+
+	// 当前阶段开始的时间，用于计算速度
+	start time.Time
+	// 已完成文件的字节数
+	base uint64
+	// 当前文件的大小与已处理字节数
+	size    uint64
+	current uint64
+
+	lastPublish time.Time
+}
+
+// NewTracker 新建进度跟踪器
+func NewTracker(kind string, id, uid uint) *Tracker {
+	return &Tracker{
+		event: Event{Kind: kind, ID: id, UserID: uid},
+		start: time.Now(),
+	}
+}
+
+// SetPhase 进入新阶段，重置已处理的字节数并设定新阶段的总字节数
+func (t *Tracker) SetPhase(phase int, total uint64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.event.Phase = phase
+	t.mu.Unlock()
+
+	t.Reset(total)
+}
+
+// Reset 在当前阶段内重新开始计数，total 为 0 表示总量未知
+func (t *Tracker) Reset(total uint64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.event.Total = total
+	t.event.File = ""
+	t.base, t.size, t.current = 0, 0, 0
+	t.start = time.Now()
+	t.mu.Unlock()
+
+	t.publish(true)
+}
+
+// SetFile 设定当前处理的文件名，不影响已处理的字节数。适用于多个文件并行处理的场景
+func (t *Tracker) SetFile(name string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.event.File = name
+	t.mu.Unlock()
+
+	t.publish(false)
+}
+
+// BeginFile 开始处理新文件
+func (t *Tracker) BeginFile(name string, size uint64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.event.File = name
+	t.size, t.current = size, 0
+	t.mu.Unlock()
+
+	t.publish(true)
+}
+
+// Add 增加当前文件已处理的字节数
+func (t *Tracker) Add(n uint64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.current += n
+	t.mu.Unlock()
+
+	t.publish(false)
+}
+
+// Set 设定当前文件已处理的字节数，用于由其他节点上报进度的场景
+func (t *Tracker) Set(processed uint64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.current = processed
+	t.mu.Unlock()
+
+	t.publish(false)
+}
+
+// EndFile 结束当前文件，无论实际读取了多少字节均按文件大小计入
+func (t *Tracker) EndFile() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	if t.size > t.current {
+		t.current = t.size
+	}
+	t.base += t.current
+	t.size, t.current = 0, 0
+	t.mu.Unlock()
+
+	t.publish(false)
+}
+
+// Done 标记任务结束并立即发布
+func (t *Tracker) Done() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.event.Done = true
+	t.mu.Unlock()
+
+	t.publish(true)
+}
+
+// Snapshot 返回当前进度
+func (t *Tracker) Snapshot() Event {
+	if t == nil {
+		return Event{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.snapshot(time.Now())
+}
+
+func (t *Tracker) snapshot(now time.Time) Event {
+	event := t.event
+	event.Processed = t.base + t.current
+	event.UpdatedAt = now
+	event.ETA = -1
+
+	if elapsed := now.Sub(t.start).Seconds(); elapsed > 0 {
+		event.Speed = uint64(float64(event.Processed) / elapsed)
+	}
+
+	if event.Speed > 0 && event.Total >= event.Processed {
+		event.ETA = int64((event.Total - event.Processed) / event.Speed)
+	}
+
+	return event
+}
+
+// publish 发布当前进度，force 为 false 时距上次发布不足间隔则跳过
+func (t *Tracker) publish(force bool) {
+	now := time.Now()
+
+	t.mu.Lock()
+	if !force && now.Sub(t.lastPublish) < publishInterval {
+		t.mu.Unlock()
+		return
+	}
+	t.lastPublish = now
+	event := t.snapshot(now)
+	t.mu.Unlock()
+
+	Publish(event)
+}
+
+type trackerKey struct{}
+
+// WithTracker 将进度跟踪器存入上下文，供文件系统等下层组件上报进度
+func WithTracker(ctx context.Context, t *Tracker) context.Context {
+	return context.WithValue(ctx, trackerKey{}, t)
+}
+
+// FromContext 从上下文中取得进度跟踪器，不存在时返回 nil
+func FromContext(ctx context.Context) *Tracker {
+	t, _ := ctx.Value(trackerKey{}).(*Tracker)
+	return t
+}
+
+type reader struct {
+	io.ReadCloser
+	tracker *Tracker
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.tracker.Add(uint64(n))
+	return n, err
+}
+
+// NewReader 包装 rc，读取时将字节数计入 t。t 为 nil 时原样返回
+func NewReader(rc io.ReadCloser, t *Tracker) io.ReadCloser {
+	if t == nil {
+		return rc
+	}
+	return &reader{ReadCloser: rc, tracker: t}
+}
@@ -0,0 +1,128 @@
+package progress
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	a := assert.New(t)
+	tracker := NewTracker(KindTask, 1, 2)
+
+	// 按文件累计进度
+	tracker.SetPhase(3, 100)
+	tracker.BeginFile("a.txt", 40)
+	tracker.Add(10)
+	a.EqualValues(10, tracker.Snapshot().Processed)
+	a.Equal("a.txt", tracker.Snapshot().File)
+
+	// 结束时按文件大小计入
+	tracker.EndFile()
+	a.EqualValues(40, tracker.Snapshot().Processed)
+
+	// 其他节点上报的进度
+	tracker.BeginFile("b.txt", 60)
+	tracker.Set(30)
+	event := tracker.Snapshot()
+	a.EqualValues(70, event.Processed)
+	a.EqualValues(100, event.Total)
+	a.Equal(3, event.Phase)
+	a.EqualValues(1, event.ID)
+	a.EqualValues(2, event.UserID)
+
+	// 进入新阶段
+	tracker.SetPhase(4, 0)
+	event = tracker.Snapshot()
+	a.EqualValues(0, event.Processed)
+	a.EqualValues(-1, event.ETA)
+	a.Equal(4, event.Phase)
+}
+
+func TestTracker_ETA(t *testing.T) {
+	a := assert.New(t)
+	tracker := NewTracker(KindTask, 1, 2)
+	tracker.Reset(100)
+	tracker.start = time.Now().Add(-10 * time.Second)
+	tracker.Add(60)
+
+	event := tracker.Snapshot()
+	a.EqualValues(5, event.Speed)
+	a.EqualValues(8, event.ETA)
+}
+
+func TestTracker_Nil(t *testing.T) {
+	a := assert.New(t)
+	var tracker *Tracker
+	a.NotPanics(func() {
+		tracker.SetPhase(1, 1)
+		tracker.Reset(1)
+		tracker.SetFile("a")
+		tracker.BeginFile("a", 1)
+		tracker.Add(1)
+		tracker.Set(1)
+		tracker.EndFile()
+		tracker.Done()
+		tracker.Snapshot()
+	})
+}
+
+func TestTracker_Publish(t *testing.T) {
+	a := assert.New(t)
+	notifier := mq.GlobalMQ.Subscribe(Topic, 10)
+	defer mq.GlobalMQ.Unsubscribe(Topic, notifier)
+
+	tracker := NewTracker(KindTask, 1, 2)
+	tracker.Add(1)
+	tracker.Add(1)
+	tracker.Done()
+
+	events := make([]Event, 0)
+	timeout := time.After(time.Second)
+	for len(events) < 2 {
+		select {
+		case msg := <-notifier:
+			events = append(events, msg.Content.(Event))
+		case <-timeout:
+			t.Fatal("timeout")
+		}
+	}
+
+	// 间隔内的更新被合并，结束时立即发布
+	select {
+	case <-notifier:
+		t.Fatal("unexpected event")
+	case <-time.After(100 * time.Millisecond):
+	}
+	a.Condition(func() bool {
+		return events[0].Done || events[1].Done
+	})
+}
+
+func TestContext(t *testing.T) {
+	a := assert.New(t)
+	a.Nil(FromContext(context.Background()))
+
+	tracker := NewTracker(KindTask, 1, 2)
+	a.Equal(tracker, FromContext(WithTracker(context.Background(), tracker)))
+}
+
+func TestNewReader(t *testing.T) {
+	a := assert.New(t)
+	src := ioutil.NopCloser(strings.NewReader("hello"))
+
+	// 无跟踪器
+	a.Equal(src, NewReader(src, nil))
+
+	tracker := NewTracker(KindTask, 1, 2)
+	tracker.BeginFile("a", 5)
+	content, err := ioutil.ReadAll(NewReader(src, tracker))
+	a.NoError(err)
+	a.Equal("hello", string(content))
+	a.EqualValues(5, tracker.Snapshot().Processed)
+}
@@ -0,0 +1,274 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+)
+
+// 内置的限流规则，对应设置项 ratelimit_<规则>_limit/window/lockout
+const (
+	// RuleLogin 登录及二步验证失败
+	RuleLogin = "login"
+	// RuleShare 分享密码错误
+	RuleShare = "share"
+	// RuleUpload 上传请求
+	RuleUpload = "upload"
+	// RuleAria2 创建离线下载任务
+	RuleAria2 = "aria2"
+)
+
+const (
+	counterPrefix = "ratelimit_"
+	lockPrefix    = "ratelimit_lock_"
+	lockIndexKey  = "ratelimit_locks"
+)
+
+// indexLock 保护本机对锁定索引的读改写
+var indexLock sync.Mutex
+
+// Rule 限流规则
+type Rule struct {
+	Limit    int           // 滑动窗口内允许的次数，0 为不限制
+	Window   int           // 滑动窗口长度，单位为秒
+	Lockout  int           // 超出限制后的锁定时长，单位为秒
+	Delay    time.Duration // 每次失败后递增的响应延迟
+	MaxDelay time.Duration // 响应延迟的上限
+}
+
+// Limiter 基于缓存的滑动窗口限流器。计数以读改写的方式存放于 cache.Store，
+// 多节点共用 Redis 时并发请求可能会少计个别次数
+type Limiter struct {
+	Name string
+	Rule
+}
+
+// Lock 一条锁定记录
+type Lock struct {
+	Rule    string    `json:"rule"`
+	Key     string    `json:"key"`
+	Expires time.Time `json:"expires"`
+}
+
+// New 根据站点设置创建给定规则的限流器
+func New(name string) *Limiter {
+	prefix := "ratelimit_" + name
+	options := model.GetSettingByNames(
+		prefix+"_limit",
+		prefix+"_window",
+		prefix+"_lockout",
+		"ratelimit_delay",
+		"ratelimit_max_delay",
+	)
+
+	return &Limiter{
+		Name: name,
+		Rule: Rule{
+			Limit:    atoi(options[prefix+"_limit"], 0),
+			Window:   atoi(options[prefix+"_window"], 60),
+			Lockout:  atoi(options[prefix+"_lockout"], 60),
+			Delay:    time.Duration(atoi(options["ratelimit_delay"], 0)) * time.Millisecond,
+			MaxDelay: time.Duration(atoi(options["ratelimit_max_delay"], 0)) * time.Millisecond,
+		},
+	}
+}
+
+func atoi(s string, defaultVal int) int {
+	if res, err := strconv.Atoi(s); err == nil {
+		return res
+	}
+	return defaultVal
+}
+
+// IPKey 按客户端 IP 计数的键
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// UserKey 按用户计数的键，name 可以是 UID 或登录名
+func UserKey(name interface{}) string {
+	return fmt.Sprintf("user:%v", name)
+}
+
+// UserIPKey 按用户和客户端 IP 计数的键，避免他人通过错误尝试锁定账号
+func UserIPKey(name interface{}, ip string) string {
+	return fmt.Sprintf("user:%v,ip:%s", name, ip)
+}
+
+// ShareKey 按分享和客户端 IP 计数的键，避免他人通过错误尝试锁定分享
+func ShareKey(id uint, ip string) string {
+	return fmt.Sprintf("share:%d,ip:%s", id, ip)
+}
+
+// Locked 返回给定键中最长的剩余锁定时间，未锁定时返回 0
+func (limiter *Limiter) Locked(keys ...string) time.Duration {
+	var wait time.Duration
+	now := time.Now()
+	for _, key := range keys {
+		expires, ok := cache.Get(lockPrefix + limiter.Name + "_" + key)
+		if !ok {
+			continue
+		}
+		if remain := time.Unix(expires.(int64), 0).Sub(now); remain > wait {
+			wait = remain
+		}
+	}
+	return wait
+}
+
+// Hit 为给定的键各记录一次，任一键在窗口内的次数超出限制时将其锁定。
+// 返回按失败次数递增的响应延迟，调用方可据此拖慢暴力尝试
+func (limiter *Limiter) Hit(keys ...string) time.Duration {
+	if limiter.Limit <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	windowStart := now.Add(-time.Duration(limiter.Window) * time.Second).UnixNano()
+	maxCount := 0
+	for _, key := range keys {
+		counterKey := counterPrefix + limiter.Name + "_" + key
+
+		// 丢弃窗口外的记录
+		hits := []int64{now.UnixNano()}
+		if raw, ok := cache.Get(counterKey); ok {
+			for _, hit := range raw.([]int64) {
+				if hit > windowStart {
+					hits = append(hits, hit)
+				}
+			}
+		}
+
+		if len(hits) > limiter.Limit {
+			limiter.lock(key, now)
+			cache.Deletes([]string{counterKey}, "")
+		} else {
+			cache.Set(counterKey, hits, limiter.Window)
+		}
+
+		if len(hits) > maxCount {
+			maxCount = len(hits)
+		}
+	}
+
+	delay := time.Duration(maxCount-1) * limiter.Delay
+	if limiter.MaxDelay > 0 && delay > limiter.MaxDelay {
+		delay = limiter.MaxDelay
+	}
+	return delay
+}
+
+// Fail 记录一次失败并等待递增的延迟
+func (limiter *Limiter) Fail(keys ...string) {
+	if delay := limiter.Hit(keys...); delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// Reset 清除给定键的计数，不影响已有的锁定
+func (limiter *Limiter) Reset(keys ...string) {
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		names = append(names, limiter.Name+"_"+key)
+	}
+	cache.Deletes(names, counterPrefix)
+}
+
+// lock 锁定给定的键，并加入锁定索引以便管理员查看
+func (limiter *Limiter) lock(key string, now time.Time) {
+	if limiter.Lockout <= 0 {
+		return
+	}
+
+	expires := now.Add(time.Duration(limiter.Lockout) * time.Second).Unix()
+	cache.Set(lockPrefix+limiter.Name+"_"+key, expires, limiter.Lockout)
+
+	indexLock.Lock()
+	defer indexLock.Unlock()
+
+	entry := limiter.Name + "|" + key
+	index := lockIndex()
+	for _, existed := range index {
+		if existed == entry {
+			return
+		}
+	}
+	cache.Set(lockIndexKey, append(index, entry), 0)
+}
+
+// LockedErr 构建请求被限流时的错误响应
+func LockedErr(wait time.Duration) serializer.Response {
+	seconds := int(wait.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	return serializer.Err(serializer.CodeTooManyRequests,
+		fmt.Sprintf("Too many attempts, please retry after %d seconds", seconds), nil)
+}
+
+func lockIndex() []string {
+	if raw, ok := cache.Get(lockIndexKey); ok {
+		return raw.([]string)
+	}
+	return []string{}
+}
+
+// Locks 列出当前仍有效的锁定，并清理索引中已过期的记录
+func Locks() []Lock {
+	indexLock.Lock()
+	defer indexLock.Unlock()
+
+	index := lockIndex()
+	res := make([]Lock, 0, len(index))
+	remain := make([]string, 0, len(index))
+	for _, entry := range index {
+		parts := strings.SplitN(entry, "|", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		expires, ok := cache.Get(lockPrefix + parts[0] + "_" + parts[1])
+		if !ok {
+			continue
+		}
+
+		remain = append(remain, entry)
+		res = append(res, Lock{
+			Rule:    parts[0],
+			Key:     parts[1],
+			Expires: time.Unix(expires.(int64), 0),
+		})
+	}
+
+	if len(remain) != len(index) {
+		cache.Set(lockIndexKey, remain, 0)
+	}
+
+	return res
+}
+
+// Unlock 解除给定规则下键的锁定，并清除其计数
+func Unlock(rule, key string) error {
+	name := rule + "_" + key
+	if err := cache.Deletes([]string{lockPrefix + name, counterPrefix + name}, ""); err != nil {
+		return err
+	}
+
+	indexLock.Lock()
+	defer indexLock.Unlock()
+
+	index := lockIndex()
+	remain := make([]string, 0, len(index))
+	for _, entry := range index {
+		if entry != rule+"|"+key {
+			remain = append(remain, entry)
+		}
+	}
+	return cache.Set(lockIndexKey, remain, 0)
+}
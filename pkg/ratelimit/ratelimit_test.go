@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Hit(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Store = cache.NewMemoStore()
+	limiter := &Limiter{
+		Name: "test",
+		Rule: Rule{Limit: 2, Window: 60, Lockout: 60, Delay: time.Millisecond, MaxDelay: 2 * time.Millisecond},
+	}
+
+	// 未超出限制
+	asserts.EqualValues(0, limiter.Hit(IPKey("127.0.0.1")))
+	asserts.EqualValues(time.Millisecond, limiter.Hit(IPKey("127.0.0.1"), UserKey(1)))
+	asserts.EqualValues(0, limiter.Locked(IPKey("127.0.0.1"), UserKey(1)))
+
+	// 超出限制后锁定，延迟不超过上限
+	asserts.EqualValues(2*time.Millisecond, limiter.Hit(IPKey("127.0.0.1")))
+	asserts.True(limiter.Locked(IPKey("127.0.0.1")) > 0)
+	asserts.True(limiter.Locked(UserKey(1), IPKey("127.0.0.1")) > 0)
+	asserts.EqualValues(0, limiter.Locked(UserKey(1)))
+
+	locks := Locks()
+	asserts.Len(locks, 1)
+	asserts.Equal("test", locks[0].Rule)
+	asserts.Equal(IPKey("127.0.0.1"), locks[0].Key)
+
+	// 解除锁定
+	asserts.NoError(Unlock("test", IPKey("127.0.0.1")))
+	asserts.EqualValues(0, limiter.Locked(IPKey("127.0.0.1")))
+	asserts.Len(Locks(), 0)
+}
+
+func TestLimiter_Unlimited(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Store = cache.NewMemoStore()
+	limiter := &Limiter{Name: "test"}
+
+	for i := 0; i < 10; i++ {
+		asserts.EqualValues(0, limiter.Hit(IPKey("127.0.0.1")))
+	}
+	asserts.EqualValues(0, limiter.Locked(IPKey("127.0.0.1")))
+}
+
+func TestLimiter_Reset(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Store = cache.NewMemoStore()
+	limiter := &Limiter{Name: "test", Rule: Rule{Limit: 1, Window: 60, Lockout: 60}}
+
+	keys := []string{IPKey("127.0.0.1"), UserKey("a@b.com")}
+	limiter.Hit(keys...)
+	limiter.Reset(keys[1])
+	asserts.Equal(UserKey("a@b.com"), keys[1])
+
+	// 账号计数已清除，IP 计数保留
+	limiter.Hit(keys...)
+	asserts.True(limiter.Locked(keys[0]) > 0)
+	asserts.EqualValues(0, limiter.Locked(keys[1]))
+}
+
+func TestKeys(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Store = cache.NewMemoStore()
+	limiter := &Limiter{Name: "test", Rule: Rule{Limit: 1, Window: 60, Lockout: 60}}
+
+	// 其他 IP 的失败尝试不会锁定当前 IP 下的账号和分享
+	limiter.Hit(UserIPKey("a@b.com", "1.1.1.1"), ShareKey(1, "1.1.1.1"))
+	limiter.Hit(UserIPKey("a@b.com", "1.1.1.1"), ShareKey(1, "1.1.1.1"))
+	asserts.True(limiter.Locked(UserIPKey("a@b.com", "1.1.1.1")) > 0)
+	asserts.True(limiter.Locked(ShareKey(1, "1.1.1.1")) > 0)
+	asserts.EqualValues(0, limiter.Locked(UserIPKey("a@b.com", "2.2.2.2")))
+	asserts.EqualValues(0, limiter.Locked(ShareKey(1, "2.2.2.2")))
+}
+
+func TestLockedErr(t *testing.T) {
+	asserts := assert.New(t)
+	res := LockedErr(10 * time.Second)
+	asserts.Equal(serializer.CodeTooManyRequests, res.Code)
+	asserts.Contains(res.Msg, "10 seconds")
+}
@@ -27,6 +27,7 @@ type options struct {
 	tpsLimiterToken string
 	tps             float64
 	tpsBurst        int
+	transport       http.RoundTripper
 }
 
 type optionFunc func(*options)
@@ -118,6 +119,13 @@ func WithEndpoint(endpoint string) Option {
 	})
 }
 
+// WithTransport 使用指定的 Transport 发送请求
+func WithTransport(transport http.RoundTripper) Option {
+	return optionFunc(func(o *options) {
+		o.transport = transport
+	})
+}
+
 // WithTPSLimit 请求时使用全局流量限制
 func WithTPSLimit(token string, tps float64, burst int) Option {
 	return optionFunc(func(o *options) {
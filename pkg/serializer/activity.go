@@ -0,0 +1,46 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+)
+
+type activity struct {
+	Type       int       `json:"type"`
+	Actor      string    `json:"actor"`
+	Client     string    `json:"client"`
+	App        string    `json:"app,omitempty"`
+	IP         string    `json:"ip"`
+	Src        string    `json:"src"`
+	Dst        string    `json:"dst,omitempty"`
+	CreateDate time.Time `json:"create_date"`
+}
+
+// BuildActivityList 构建操作记录列表响应
+func BuildActivityList(activities []model.Activity, total int) Response {
+	res := make([]activity, 0, len(activities))
+	for _, a := range activities {
+		actor := ""
+		if a.ActorID > 0 {
+			actor = hashid.HashID(a.ActorID, hashid.UserID)
+		}
+
+		res = append(res, activity{
+			Type:       a.Type,
+			Actor:      actor,
+			Client:     a.Client,
+			App:        a.App,
+			IP:         a.IP,
+			Src:        a.Src,
+			Dst:        a.Dst,
+			CreateDate: a.CreatedAt,
+		})
+	}
+
+	return Response{Data: map[string]interface{}{
+		"total":      total,
+		"activities": res,
+	}}
+}
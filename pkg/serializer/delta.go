@@ -0,0 +1,58 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+)
+
+type deltaChange struct {
+	Cursor  uint      `json:"cursor"`
+	Action  string    `json:"action"`
+	Type    string    `json:"type"`
+	ID      string    `json:"id"`
+	Parent  string    `json:"parent,omitempty"`
+	Path    string    `json:"path"`
+	OldPath string    `json:"old_path,omitempty"`
+	Size    uint64    `json:"size"`
+	Hash    string    `json:"hash,omitempty"`
+	Date    time.Time `json:"date"`
+}
+
+// BuildDelta 构建增量变更响应，cursor 为下次请求使用的游标。
+// reset 为 true 时客户端需重新列出全部文件，再从 cursor 开始增量同步
+func BuildDelta(changes []model.Change, cursor uint, hasMore, reset bool) Response {
+	res := make([]deltaChange, 0, len(changes))
+	for _, change := range changes {
+		item := deltaChange{
+			Cursor:  change.ID,
+			Action:  change.Action,
+			Type:    "file",
+			ID:      hashid.HashID(change.ObjectID, hashid.FileID),
+			Path:    change.Path,
+			OldPath: change.OldPath,
+			Size:    change.Size,
+			Hash:    change.Hash,
+			Date:    change.CreatedAt,
+		}
+
+		if change.IsDir {
+			item.Type = "dir"
+			item.ID = hashid.HashID(change.ObjectID, hashid.FolderID)
+		}
+
+		if change.ParentID > 0 {
+			item.Parent = hashid.HashID(change.ParentID, hashid.FolderID)
+		}
+
+		res = append(res, item)
+	}
+
+	return Response{Data: map[string]interface{}{
+		"changes":  res,
+		"cursor":   cursor,
+		"has_more": hasMore,
+		"reset":    reset,
+	}}
+}
@@ -56,6 +56,8 @@ const (
 	CodeNotFound = 404
 	// CodeConflict 资源冲突
 	CodeConflict = 409
+	// CodeTooManyRequests 请求过于频繁
+	CodeTooManyRequests = 429
 	// CodeUploadFailed 上传出错
 	CodeUploadFailed = 40002
 	// CodeCreateFolderFailed 目录创建失败
@@ -172,6 +174,36 @@ const (
 	CodeSlavePingMaster = 40060
 	// Cloudreve 版本不一致
 	CodeVersionMismatch = 40061
+	// Webhook 不存在
+	CodeWebhookNotFound = 40062
+	// 不支持的 Webhook 事件
+	CodeInvalidWebhookEvent = 40063
+	// OpenID Connect 登录失败
+	CodeOIDCLoginFailed = 40064
+	// OpenID Connect 身份未绑定账号
+	CodeOIDCNotLinked = 40065
+	// OpenID Connect 身份已被其他账号绑定
+	CodeOIDCLinkedByOthers = 40066
+	// 站内共享不存在
+	CodeInternalShareNotFound = 40067
+	// 站内共享为只读
+	CodeInternalShareReadOnly = 40068
+	// 站内共享的接收者无效
+	CodeInvalidShareTarget = 40069
+	// 上传分享的累计上传大小超出限制
+	CodeShareUploadCapacityExceeded = 40070
+	// 本月下载流量已用尽
+	CodeTrafficQuotaExceeded = 40071
+	// 兑换码无效或已被使用
+	CodeInvalidRedeemCode = 40072
+	// 超额使用，账户已被限制为只读
+	CodeOveruseReadOnly = 40073
+	// 对象已被 WebDAV 客户端锁定
+	CodeObjectLocked = 40074
+	// 目录用户未绑定同邮箱的本地账号
+	CodeLDAPNotLinked = 40075
+	// 目录用户已被其他账号绑定
+	CodeLDAPLinkedByOthers = 40076
 	// CodeDBError 数据库操作失败
 	CodeDBError = 50001
 	// CodeEncryptError 加密失败
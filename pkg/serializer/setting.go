@@ -22,14 +22,18 @@ type SiteConfig struct {
 	CaptchaType          string `json:"captcha_type"`
 	TCaptchaCaptchaAppId string `json:"tcaptcha_captcha_app_id"`
 	RegisterEnabled      bool   `json:"registerEnabled"`
+	OIDC                 bool   `json:"oidc"`
 }
 
 type task struct {
+	ID         uint      `json:"id"`
 	Status     int       `json:"status"`
 	Type       int       `json:"type"`
 	CreateDate time.Time `json:"create_date"`
 	Progress   int       `json:"progress"`
 	Error      string    `json:"error"`
+	Priority   int       `json:"priority"`
+	Attempts   int       `json:"attempts"`
 }
 
 // BuildTaskList 构建任务列表响应
@@ -37,11 +41,14 @@ func BuildTaskList(tasks []model.Task, total int) Response {
 	res := make([]task, 0, len(tasks))
 	for _, t := range tasks {
 		res = append(res, task{
+			ID:         t.ID,
 			Status:     t.Status,
 			Type:       t.Type,
 			CreateDate: t.CreatedAt,
 			Progress:   t.Progress,
 			Error:      t.Error,
+			Priority:   t.Priority,
+			Attempts:   t.Attempts,
 		})
 	}
 
@@ -83,6 +90,7 @@ func BuildSiteConfig(settings map[string]string, user *model.User) Response {
 			CaptchaType:          checkSettingValue(settings, "captcha_type"),
 			TCaptchaCaptchaAppId: checkSettingValue(settings, "captcha_TCaptcha_CaptchaAppId"),
 			RegisterEnabled:      model.IsTrueVal(checkSettingValue(settings, "register_enabled")),
+			OIDC:                 model.IsTrueVal(checkSettingValue(settings, "oidc_enabled")),
 		}}
 	return res
 }
@@ -1,6 +1,7 @@
 package serializer
 
 import (
+	"strings"
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
@@ -12,6 +13,7 @@ type Share struct {
 	Key        string        `json:"key"`
 	Locked     bool          `json:"locked"`
 	IsDir      bool          `json:"is_dir"`
+	IsMulti    bool          `json:"is_multi"`
 	CreateDate time.Time     `json:"create_date,omitempty"`
 	Downloads  int           `json:"downloads"`
 	Views      int           `json:"views"`
@@ -19,6 +21,7 @@ type Share struct {
 	Preview    bool          `json:"preview"`
 	Creator    *shareCreator `json:"creator,omitempty"`
 	Source     *shareSource  `json:"source,omitempty"`
+	Upload     *shareUpload  `json:"upload,omitempty"`
 }
 
 type shareCreator struct {
@@ -32,10 +35,18 @@ type shareSource struct {
 	Size uint64 `json:"size"`
 }
 
+// shareUpload 上传分享的限制
+type shareUpload struct {
+	MaxSize    uint64   `json:"max_size"`
+	Extensions []string `json:"extensions"`
+	Remain     int64    `json:"remain"` // 剩余可上传大小，-1 为不限制
+}
+
 // myShareItem 我的分享列表条目
 type myShareItem struct {
 	Key             string       `json:"key"`
 	IsDir           bool         `json:"is_dir"`
+	IsMulti         bool         `json:"is_multi"`
 	Password        string       `json:"password"`
 	CreateDate      time.Time    `json:"create_date,omitempty"`
 	Downloads       int          `json:"downloads"`
@@ -43,6 +54,7 @@ type myShareItem struct {
 	Views           int          `json:"views"`
 	Expire          int64        `json:"expire"`
 	Preview         bool         `json:"preview"`
+	Upload          bool         `json:"upload"`
 	Source          *shareSource `json:"source,omitempty"`
 }
 
@@ -54,6 +66,7 @@ func BuildShareList(shares []model.Share, total int) Response {
 		item := myShareItem{
 			Key:             hashid.HashID(shares[i].ID, hashid.ShareID),
 			IsDir:           shares[i].IsDir,
+			IsMulti:         shares[i].IsMulti,
 			Password:        shares[i].Password,
 			CreateDate:      shares[i].CreatedAt,
 			Downloads:       shares[i].Downloads,
@@ -61,6 +74,7 @@ func BuildShareList(shares []model.Share, total int) Response {
 			Preview:         shares[i].PreviewEnabled,
 			Expire:          -1,
 			RemainDownloads: shares[i].RemainDownloads,
+			Upload:          shares[i].UploadEnabled,
 		}
 		if shares[i].Expires != nil {
 			item.Expire = shares[i].Expires.Unix() - now
@@ -68,7 +82,11 @@ func BuildShareList(shares []model.Share, total int) Response {
 				item.Expire = 0
 			}
 		}
-		if shares[i].File.ID != 0 {
+		if shares[i].IsMulti {
+			item.Source = &shareSource{
+				Name: shares[i].SourceName,
+			}
+		} else if shares[i].File.ID != 0 {
 			item.Source = &shareSource{
 				Name: shares[i].File.Name,
 				Size: shares[i].File.Size,
@@ -108,6 +126,7 @@ func BuildShareResponse(share *model.Share, unlocked bool) Share {
 	}
 
 	resp.IsDir = share.IsDir
+	resp.IsMulti = share.IsMulti
 	resp.Downloads = share.Downloads
 	resp.Views = share.Views
 	resp.Preview = share.PreviewEnabled
@@ -116,12 +135,34 @@ func BuildShareResponse(share *model.Share, unlocked bool) Share {
 		resp.Expire = share.Expires.Unix() - time.Now().Unix()
 	}
 
-	if share.IsDir {
+	if share.IsMulti {
+		resp.Source = &shareSource{
+			Name: share.SourceName,
+			Size: 0,
+		}
+	} else if share.IsDir {
 		source := share.SourceFolder()
 		resp.Source = &shareSource{
 			Name: source.Name,
 			Size: 0,
 		}
+
+		if share.UploadEnabled {
+			resp.Upload = &shareUpload{
+				MaxSize:    share.UploadMaxSize,
+				Extensions: []string{},
+				Remain:     -1,
+			}
+			if share.UploadExtensions != "" {
+				resp.Upload.Extensions = strings.Split(share.UploadExtensions, ",")
+			}
+			if share.UploadCapacity > 0 {
+				resp.Upload.Remain = int64(share.UploadCapacity) - int64(share.UploadedSize)
+				if resp.Upload.Remain < 0 {
+					resp.Upload.Remain = 0
+				}
+			}
+		}
 	} else {
 		source := share.SourceFile()
 		resp.Source = &shareSource{
@@ -133,3 +174,88 @@ func BuildShareResponse(share *model.Share, unlocked bool) Share {
 	return resp
 
 }
+
+// InternalShare 站内共享信息序列化
+type InternalShare struct {
+	Key         string        `json:"key"`
+	Name        string        `json:"name"`
+	Writable    bool          `json:"writable"`
+	CreateDate  time.Time     `json:"create_date"`
+	Creator     *shareCreator `json:"creator,omitempty"`
+	TargetUser  string        `json:"target_user,omitempty"`
+	TargetGroup string        `json:"target_group,omitempty"`
+}
+
+// BuildInternalShareList 构建站内共享列表响应，received 为 true 时
+// 返回共享者信息，否则返回接收者信息
+func BuildInternalShareList(shares []model.InternalShare, received bool) Response {
+	res := make([]InternalShare, 0, len(shares))
+	for i := 0; i < len(shares); i++ {
+		if received && !shares[i].IsAvailable() {
+			continue
+		}
+
+		item := InternalShare{
+			Key:        hashid.HashID(shares[i].ID, hashid.InternalShareID),
+			Name:       shares[i].SourceFolder().Name,
+			Writable:   shares[i].Writable,
+			CreateDate: shares[i].CreatedAt,
+		}
+
+		if received {
+			creator := shares[i].Creator()
+			item.Creator = &shareCreator{
+				Key:       hashid.HashID(creator.ID, hashid.UserID),
+				Nick:      creator.Nick,
+				GroupName: creator.Group.Name,
+			}
+		} else if shares[i].TargetUserID != 0 {
+			if target, err := model.GetUserByID(shares[i].TargetUserID); err == nil {
+				item.TargetUser = target.Email
+			}
+		} else if group, err := model.GetGroupByID(shares[i].TargetGroupID); err == nil {
+			item.TargetGroup = group.Name
+		}
+
+		res = append(res, item)
+	}
+
+	return Response{Data: res}
+}
+
+// shareEvent 分享访问记录
+type shareEvent struct {
+	Type       int       `json:"type"`
+	Path       string    `json:"path,omitempty"`
+	User       string    `json:"user,omitempty"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	Referer    string    `json:"referer,omitempty"`
+	CreateDate time.Time `json:"create_date"`
+}
+
+// BuildShareEventList 构建分享访问记录列表响应
+func BuildShareEventList(events []model.ShareEvent, total int) Response {
+	res := make([]shareEvent, 0, len(events))
+	for _, e := range events {
+		user := ""
+		if e.UserID > 0 {
+			user = hashid.HashID(e.UserID, hashid.UserID)
+		}
+
+		res = append(res, shareEvent{
+			Type:       e.Type,
+			Path:       e.Path,
+			User:       user,
+			IP:         e.IP,
+			UserAgent:  e.UserAgent,
+			Referer:    e.Referer,
+			CreateDate: e.CreatedAt,
+		})
+	}
+
+	return Response{Data: map[string]interface{}{
+		"total":  total,
+		"events": res,
+	}}
+}
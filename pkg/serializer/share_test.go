@@ -82,4 +82,34 @@ func TestBuildShareResponse(t *testing.T) {
 		asserts.NotEmpty(res.Expire)
 		asserts.NotNil(res.Creator)
 	}
+	// 上传分享
+	{
+		share := &model.Share{
+			User: model.User{Model: gorm.Model{ID: 1}},
+			Folder: model.Folder{
+				Model: gorm.Model{ID: 1},
+			},
+			IsDir:            true,
+			UploadEnabled:    true,
+			UploadExtensions: "jpg,png",
+			UploadCapacity:   10,
+			UploadedSize:     4,
+		}
+		res := BuildShareResponse(share, true)
+		asserts.NotNil(res.Upload)
+		asserts.Equal([]string{"jpg", "png"}, res.Upload.Extensions)
+		asserts.EqualValues(6, res.Upload.Remain)
+	}
+	// 多对象分享
+	{
+		share := &model.Share{
+			User:       model.User{Model: gorm.Model{ID: 1}},
+			IsDir:      true,
+			IsMulti:    true,
+			SourceName: "a.txt docs",
+		}
+		res := BuildShareResponse(share, true)
+		asserts.True(res.IsMulti)
+		asserts.Equal("a.txt docs", res.Source.Name)
+	}
 }
@@ -54,12 +54,14 @@ func (s *SlaveTransferReq) Hash(id string) string {
 }
 
 const (
-	SlaveTransferSuccess = "success"
-	SlaveTransferFailed  = "failed"
+	SlaveTransferSuccess  = "success"
+	SlaveTransferFailed   = "failed"
+	SlaveTransferProgress = "progress"
 )
 
 type SlaveTransferResult struct {
-	Error string
+	Error     string
+	Processed uint64 // 已上传的字节数，仅用于进度通知
 }
 
 func init() {
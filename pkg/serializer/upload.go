@@ -36,6 +36,8 @@ type UploadCredential struct {
 type UploadSession struct {
 	Key            string     // 上传会话 GUID
 	UID            uint       // 发起者
+	Delegate       uint       // 经由站内共享代为上传的用户，为 0 时仅发起者可上传
+	ShareID        uint       // 经由上传分享由访客上传时的分享ID
 	VirtualPath    string     // 用户文件路径，不含文件名
 	Name           string     // 文件名
 	Size           uint64     // 文件大小
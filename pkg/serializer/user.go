@@ -19,16 +19,19 @@ func CheckLogin() Response {
 
 // User 用户序列化器
 type User struct {
-	ID             string    `json:"id"`
-	Email          string    `json:"user_name"`
-	Nickname       string    `json:"nickname"`
-	Status         int       `json:"status"`
-	Avatar         string    `json:"avatar"`
-	CreatedAt      time.Time `json:"created_at"`
-	PreferredTheme string    `json:"preferred_theme"`
-	Anonymous      bool      `json:"anonymous"`
-	Group          group     `json:"group"`
-	Tags           []tag     `json:"tags"`
+	ID             string     `json:"id"`
+	Email          string     `json:"user_name"`
+	Nickname       string     `json:"nickname"`
+	Status         int        `json:"status"`
+	Avatar         string     `json:"avatar"`
+	CreatedAt      time.Time  `json:"created_at"`
+	PreferredTheme string     `json:"preferred_theme"`
+	Anonymous      bool       `json:"anonymous"`
+	Group          group      `json:"group"`
+	GroupExpires   *time.Time `json:"group_expires,omitempty"`
+	OveruseSince   *time.Time `json:"overuse_since,omitempty"`
+	ReadOnly       bool       `json:"read_only,omitempty"`
+	Tags           []tag      `json:"tags"`
 }
 
 type group struct {
@@ -58,6 +61,25 @@ type storage struct {
 	Total uint64 `json:"total"`
 }
 
+type storagePack struct {
+	Name        string    `json:"name"`
+	Size        uint64    `json:"size"`
+	ActiveTime  time.Time `json:"activate_date"`
+	ExpiredTime time.Time `json:"expired_date"`
+}
+
+type traffic struct {
+	Month   string         `json:"month"`
+	Used    uint64         `json:"used"`
+	Quota   uint64         `json:"quota"`
+	History []trafficMonth `json:"history"`
+}
+
+type trafficMonth struct {
+	Month string `json:"month"`
+	Used  uint64 `json:"used"`
+}
+
 // WebAuthnCredentials 外部验证器凭证
 type WebAuthnCredentials struct {
 	ID          []byte `json:"id"`
@@ -90,6 +112,9 @@ func BuildUser(user model.User) User {
 		CreatedAt:      user.CreatedAt,
 		PreferredTheme: user.OptionsSerialized.PreferredTheme,
 		Anonymous:      user.IsAnonymous(),
+		GroupExpires:   user.GroupExpires,
+		OveruseSince:   user.OveruseSince,
+		ReadOnly:       user.ReadOnly,
 		Group: group{
 			ID:                   user.GroupID,
 			Name:                 user.Group.Name,
@@ -114,7 +139,7 @@ func BuildUserResponse(user model.User) Response {
 
 // BuildUserStorageResponse 序列化用户存储概况响应
 func BuildUserStorageResponse(user model.User) Response {
-	total := user.Group.MaxStorage
+	total := user.GetTotalCapacity()
 	storageResp := storage{
 		Used:  user.Storage,
 		Free:  total - user.Storage,
@@ -130,6 +155,43 @@ func BuildUserStorageResponse(user model.User) Response {
 	}
 }
 
+// BuildStoragePackList 序列化用户的容量包列表
+func BuildStoragePackList(packs []model.StoragePack) Response {
+	res := make([]storagePack, 0, len(packs))
+	for _, pack := range packs {
+		res = append(res, storagePack{
+			Name:        pack.Name,
+			Size:        pack.Size,
+			ActiveTime:  pack.ActiveTime,
+			ExpiredTime: pack.ExpiredTime,
+		})
+	}
+
+	return Response{
+		Data: res,
+	}
+}
+
+// BuildUserTrafficResponse 序列化用户本月流量及历史记录，history 按月份倒序排列
+func BuildUserTrafficResponse(user model.User, month string, history []model.Traffic) Response {
+	res := traffic{
+		Month:   month,
+		Quota:   user.Group.OptionsSerialized.TrafficQuota,
+		History: make([]trafficMonth, 0, len(history)),
+	}
+
+	for _, record := range history {
+		if record.Month == month {
+			res.Used = record.Used
+		}
+		res.History = append(res.History, trafficMonth{Month: record.Month, Used: record.Used})
+	}
+
+	return Response{
+		Data: res,
+	}
+}
+
 // buildTagRes 构建标签列表
 func buildTagRes(tags []model.Tag) []tag {
 	res := make([]tag, 0, len(tags))
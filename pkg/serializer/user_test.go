@@ -114,3 +114,34 @@ func TestBuildWebAuthnList(t *testing.T) {
 	res := BuildWebAuthnList(credentials)
 	asserts.Len(res, 1)
 }
+
+func TestBuildUserTrafficResponse(t *testing.T) {
+	asserts := assert.New(t)
+	user := model.User{}
+	user.Group.OptionsSerialized.TrafficQuota = 100
+
+	// 本月无记录
+	{
+		res := BuildUserTrafficResponse(user, "2021-03", []model.Traffic{{Month: "2021-02", Used: 20}})
+		asserts.EqualValues(0, res.Data.(traffic).Used)
+		asserts.EqualValues(100, res.Data.(traffic).Quota)
+		asserts.Len(res.Data.(traffic).History, 1)
+	}
+
+	// 本月有记录
+	{
+		res := BuildUserTrafficResponse(user, "2021-03", []model.Traffic{{Month: "2021-03", Used: 30}, {Month: "2021-02", Used: 20}})
+		asserts.EqualValues(30, res.Data.(traffic).Used)
+		asserts.Equal("2021-03", res.Data.(traffic).Month)
+		asserts.Len(res.Data.(traffic).History, 2)
+	}
+}
+
+func TestBuildStoragePackList(t *testing.T) {
+	asserts := assert.New(t)
+
+	res := BuildStoragePackList([]model.StoragePack{{Name: "pack", Size: 10}})
+	asserts.Len(res.Data.([]storagePack), 1)
+	asserts.Equal("pack", res.Data.([]storagePack)[0].Name)
+	asserts.EqualValues(10, res.Data.([]storagePack)[0].Size)
+}
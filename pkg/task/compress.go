@@ -10,6 +10,7 @@ import (
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/progress"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
@@ -85,16 +86,18 @@ func (job *CompressTask) GetError() *JobError {
 }
 
 // Do 开始执行任务
-func (job *CompressTask) Do() {
+func (job *CompressTask) Do(ctx context.Context) {
 	// 创建文件系统
 	fs, err := filesystem.NewFileSystem(job.User)
 	if err != nil {
-		job.SetErrorMsg(err.Error())
+		job.SetError(NewJobError(err.Error(), err))
 		return
 	}
 
 	util.Log().Debug("开始压缩文件")
 	job.TaskModel.SetProgress(CompressingProgress)
+	tracker := progress.FromContext(ctx)
+	tracker.SetPhase(CompressingProgress, 0)
 
 	// 创建临时压缩文件
 	saveFolder := "compress"
@@ -106,29 +109,34 @@ func (job *CompressTask) Do() {
 	zipFile, err := util.CreatNestedFile(zipFilePath)
 	if err != nil {
 		util.Log().Warning("%s", err)
-		job.SetErrorMsg(err.Error())
+		job.SetError(NewJobError(err.Error(), err))
 		return
 	}
 
+	// 每次执行都会生成新的压缩文件，失败时由 SetError 删除
+	job.zipPath = zipFilePath
 	defer zipFile.Close()
 
 	// 开始压缩
-	ctx := context.Background()
 	err = fs.Compress(ctx, zipFile, job.TaskProps.Dirs, job.TaskProps.Files, false)
 	if err != nil {
-		job.SetErrorMsg(err.Error())
+		job.SetError(NewJobError(err.Error(), err))
 		return
 	}
 
-	job.zipPath = zipFilePath
+	var zipSize uint64
+	if stat, err := zipFile.Stat(); err == nil {
+		zipSize = uint64(stat.Size())
+	}
 	zipFile.Close()
 	util.Log().Debug("压缩文件存放至%s，开始上传", zipFilePath)
 	job.TaskModel.SetProgress(TransferringProgress)
+	tracker.SetPhase(TransferringProgress, zipSize)
 
 	// 上传文件
 	err = fs.UploadFromPath(ctx, zipFilePath, job.TaskProps.Dst, 0)
 	if err != nil {
-		job.SetErrorMsg(err.Error())
+		job.SetError(NewJobError(err.Error(), err))
 		return
 	}
 
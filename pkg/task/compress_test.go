@@ -1,6 +1,7 @@
 package task
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -79,7 +80,7 @@ func TestCompressTask_Do(t *testing.T) {
 		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1,
 			1))
 		mock.ExpectCommit()
-		task.Do()
+		task.Do(context.Background())
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NotEmpty(task.GetError().Msg)
 	}
@@ -104,7 +105,7 @@ func TestCompressTask_Do(t *testing.T) {
 		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1,
 			1))
 		mock.ExpectCommit()
-		task.Do()
+		task.Do(context.Background())
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NotEmpty(task.GetError().Msg)
 	}
@@ -141,7 +142,7 @@ func TestCompressTask_Do(t *testing.T) {
 		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1,
 			1))
 		mock.ExpectCommit()
-		task.Do()
+		task.Do(context.Background())
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NotEmpty(task.GetError().Msg)
 		asserts.True(util.IsEmpty(util.RelativePath("test/compress")))
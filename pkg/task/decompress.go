@@ -6,6 +6,7 @@ import (
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/progress"
 )
 
 // DecompressTask 文件压缩任务
@@ -60,11 +61,7 @@ func (job *DecompressTask) SetError(err *JobError) {
 
 // SetErrorMsg 设定任务失败信息
 func (job *DecompressTask) SetErrorMsg(msg string, err error) {
-	jobErr := &JobError{Msg: msg}
-	if err != nil {
-		jobErr.Error = err.Error()
-	}
-	job.SetError(jobErr)
+	job.SetError(NewJobError(msg, err))
 }
 
 // GetError 返回任务失败信息
@@ -73,7 +70,7 @@ func (job *DecompressTask) GetError() *JobError {
 }
 
 // Do 开始执行任务
-func (job *DecompressTask) Do() {
+func (job *DecompressTask) Do(ctx context.Context) {
 	// 创建文件系统
 	fs, err := filesystem.NewFileSystem(job.User)
 	if err != nil {
@@ -82,8 +79,9 @@ func (job *DecompressTask) Do() {
 	}
 
 	job.TaskModel.SetProgress(DecompressingProgress)
+	progress.FromContext(ctx).SetPhase(DecompressingProgress, 0)
 
-	err = fs.Decompress(context.Background(), job.TaskProps.Src, job.TaskProps.Dst, job.TaskProps.Encoding)
+	err = fs.Decompress(ctx, job.TaskProps.Src, job.TaskProps.Dst, job.TaskProps.Encoding)
 	if err != nil {
 		job.SetErrorMsg("解压缩失败", err)
 		return
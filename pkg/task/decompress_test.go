@@ -1,6 +1,7 @@
 package task
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -73,7 +74,7 @@ func TestDecompressTask_Do(t *testing.T) {
 		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1,
 			1))
 		mock.ExpectCommit()
-		task.Do()
+		task.Do(context.Background())
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NotEmpty(task.GetError().Msg)
 	}
@@ -86,7 +87,7 @@ func TestDecompressTask_Do(t *testing.T) {
 			},
 		}
 		task.TaskProps.Src = "test"
-		task.Do()
+		task.Do(context.Background())
 		asserts.NotEmpty(task.GetError().Msg)
 	}
 }
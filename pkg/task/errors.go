@@ -1,8 +1,43 @@
 package task
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+)
 
 var (
 	// ErrUnknownTaskType 未知任务类型
 	ErrUnknownTaskType = errors.New("未知任务类型")
 )
+
+// NewJobError 根据错误创建任务失败信息，并据错误类别判断任务是否可重试
+func NewJobError(msg string, err error) *JobError {
+	jobErr := &JobError{Msg: msg}
+	if err != nil {
+		jobErr.Error = err.Error()
+		jobErr.Retryable = IsRetryable(err)
+	}
+	return jobErr
+}
+
+// IsRetryable 判断错误是否为临时性错误。IO、数据库及网络错误重试后可能成功，
+// 参数、权限、容量等业务错误以及用户取消则无需重试
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var appErr serializer.AppError
+	if errors.As(err, &appErr) {
+		return appErr.Code == serializer.CodeIOFailed || appErr.Code == serializer.CodeDBError
+	}
+
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrExist) || errors.Is(err, os.ErrPermission) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,39 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryable(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.False(IsRetryable(nil))
+	asserts.False(IsRetryable(context.Canceled))
+	asserts.False(IsRetryable(fmt.Errorf("wrapped: %w", context.Canceled)))
+	asserts.False(IsRetryable(os.ErrNotExist))
+	asserts.False(IsRetryable(os.ErrPermission))
+	asserts.False(IsRetryable(serializer.NewError(serializer.CodeParamErr, "", nil)))
+	asserts.True(IsRetryable(serializer.NewError(serializer.CodeIOFailed, "", nil)))
+	asserts.True(IsRetryable(serializer.NewError(serializer.CodeDBError, "", nil)))
+	asserts.True(IsRetryable(errors.New("connection reset")))
+}
+
+func TestNewJobError(t *testing.T) {
+	asserts := assert.New(t)
+
+	res := NewJobError("msg", nil)
+	asserts.Equal("msg", res.Msg)
+	asserts.Empty(res.Error)
+	asserts.False(res.Retryable)
+
+	res = NewJobError("msg", errors.New("error"))
+	asserts.Equal("error", res.Error)
+	asserts.True(res.Retryable)
+}
@@ -63,11 +63,7 @@ func (job *ImportTask) SetError(err *JobError) {
 
 // SetErrorMsg 设定任务失败信息
 func (job *ImportTask) SetErrorMsg(msg string, err error) {
-	jobErr := &JobError{Msg: msg}
-	if err != nil {
-		jobErr.Error = err.Error()
-	}
-	job.SetError(jobErr)
+	job.SetError(NewJobError(msg, err))
 }
 
 // GetError 返回任务失败信息
@@ -200,8 +196,7 @@ func ImportDir(policyId uint, user *model.User, recursive bool, src string, dst
 }
 
 // Do 开始执行任务
-func (job *ImportTask) Do() {
-	ctx := context.Background()
+func (job *ImportTask) Do(ctx context.Context) {
 
 	// 事务
 	tx := model.DB.Begin()
@@ -243,7 +238,7 @@ func (job *ImportTask) Do() {
 	// 列取目录、对象
 
 	_ = job.TaskModel.SetProgressTransaction(ListingProgress, tx)
-	coxIgnoreConflict := context.WithValue(context.Background(), fsctx.IgnoreDirectoryConflictCtx, true)
+	coxIgnoreConflict := context.WithValue(ctx, fsctx.IgnoreDirectoryConflictCtx, true)
 	objects, err := fs.Handler.List(ctx, job.TaskProps.Src, job.TaskProps.Recursive)
 	if err != nil {
 		job.SetErrorMsg("无法列取文件", err)
@@ -272,6 +267,11 @@ func (job *ImportTask) Do() {
 
 	// 插入文件记录到用户文件系统
 	for _, object := range objects {
+		if ctx.Err() != nil {
+			tx.Rollback()
+			return
+		}
+
 		if !object.IsDir {
 			// 创建文件信息
 			virtualPath := path.Dir(path.Join(job.TaskProps.Dst, object.RelativePath))
@@ -291,7 +291,7 @@ func (job *ImportTask) Do() {
 				if exist {
 					parentFolder = folder
 				} else {
-					folder, err := fs.CreateDirectoryTransaction(context.Background(), virtualPath, tx)
+					folder, err := fs.CreateDirectoryTransaction(ctx, virtualPath, tx)
 					if err != nil {
 						util.Log().Warning("导入任务无法创建用户目录[%s], %s",
 							virtualPath, err)
@@ -332,7 +332,6 @@ func (job *ImportTask) Do() {
 
 	// 生成缩略图
 	if fs.User.Policy.IsThumbGenerateNeeded() {
-		ctx := context.Background()
 		fs.GenerateThumbnailsTransaction(ctx, nil)
 	}
 
@@ -1,6 +1,7 @@
 package task
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -80,7 +81,7 @@ func TestImportTask_Do(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
-		task.Do()
+		task.Do(context.Background())
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NotEmpty(task.Err.Error)
 		task.Err = nil
@@ -95,7 +96,7 @@ func TestImportTask_Do(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
-		task.Do()
+		task.Do(context.Background())
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NotEmpty(task.Err.Msg)
 		task.Err = nil
@@ -115,7 +116,7 @@ func TestImportTask_Do(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
-		task.Do()
+		task.Do(context.Background())
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Nil(task.Err)
 		task.Err = nil
@@ -149,7 +150,7 @@ func TestImportTask_Do(t *testing.T) {
 		mock.ExpectQuery("SELECT(.+)folders").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 		mock.ExpectQuery("SELECT(.+)folders").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 
-		task.Do()
+		task.Do(context.Background())
 
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Nil(task.Err)
@@ -187,7 +188,7 @@ func TestImportTask_Do(t *testing.T) {
 		mock.ExpectExec("UPDATE(.+)users(.+)storage(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
 		mock.ExpectCommit()
 
-		task.Do()
+		task.Do(context.Background())
 
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Nil(task.Err)
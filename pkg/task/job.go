@@ -1,8 +1,9 @@
 package task
 
 import (
+	"context"
+
 	model "github.com/cloudreve/Cloudreve/v3/models"
-	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
 // 任务类型
@@ -56,7 +57,7 @@ type Job interface {
 	Props() string       // 返回序列化后的任务属性
 	Model() *model.Task  // 返回对应的数据库模型
 	SetStatus(int)       // 设定任务状态
-	Do()                 // 开始执行任务
+	Do(context.Context)  // 开始执行任务，需在 context 被取消时尽快退出
 	SetError(*JobError)  // 设定任务失败信息
 	GetError() *JobError // 获取任务执行结果，返回nil表示成功完成执行
 }
@@ -65,6 +66,9 @@ type Job interface {
 type JobError struct {
 	Msg   string `json:"msg,omitempty"`
 	Error string `json:"error,omitempty"`
+
+	// 是否为可重试的临时性错误
+	Retryable bool `json:"-"`
 }
 
 // Record 将任务记录到数据库中
@@ -81,27 +85,6 @@ func Record(job Job) (*model.Task, error) {
 	return &record, err
 }
 
-// Resume 从数据库中恢复未完成任务
-func Resume(p Pool) {
-	tasks := model.GetTasksByStatus(Queued, Processing)
-	if len(tasks) == 0 {
-		return
-	}
-	util.Log().Info("从数据库中恢复 %d 个未完成任务", len(tasks))
-
-	for i := 0; i < len(tasks); i++ {
-		job, err := GetJobFromModel(&tasks[i])
-		if err != nil {
-			util.Log().Warning("无法恢复任务，%s", err)
-			continue
-		}
-
-		if job != nil {
-			p.Submit(job)
-		}
-	}
-}
-
 // GetJobFromModel 从数据库给定模型获取任务
 func GetJobFromModel(task *model.Task) (Job, error) {
 	switch task.Type {
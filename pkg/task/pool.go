@@ -1,9 +1,30 @@
 package task
 
 import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/leader"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// CancelTopic 取消任务的消息主题，用于通知执行任务的实例中止任务
+	CancelTopic = "task_cancel"
+
+	// pollInterval 调度器从数据库中领取任务的间隔
+	pollInterval = 5 * time.Second
+	// heartbeatInterval 执行中任务更新活跃时间的间隔
+	heartbeatInterval = 30 * time.Second
+	// staleTimeout 执行中任务超过此时长未活跃时，视为所在实例已退出
+	staleTimeout = 3 * heartbeatInterval
 )
 
 // TaskPoll 要使用的任务池
@@ -14,10 +35,18 @@ type Pool interface {
 	Submit(job Job)
 }
 
-// AsyncPool 带有最大配额的任务池
+// AsyncPool 带有最大配额的任务池。主机上有数据库记录的任务由调度器从数据库中领取执行，
+// 重启后不会丢失；没有记录的任务（如从机中转任务）直接在本实例中执行
 type AsyncPool struct {
 	// 容量
 	idleWorker chan int
+
+	// 唤醒调度器，为 nil 时不使用调度器
+	wake chan struct{}
+	// 领取任务时使用的实例标识
+	holder string
+	// 本实例中正在执行的任务的取消函数，以任务ID为键
+	running sync.Map
 }
 
 // Add 增加可用Worker数量
@@ -43,26 +72,196 @@ func (pool *AsyncPool) freeWorker() {
 
 // Submit 开始提交任务
 func (pool *AsyncPool) Submit(job Job) {
+	// 已记录到数据库的任务交由调度器领取
+	if record := job.Model(); record != nil && record.ID > 0 && pool.wake != nil {
+		pool.notify()
+		return
+	}
+
 	go func() {
 		util.Log().Debug("等待获取Worker")
 		worker := pool.obtainWorker()
 		util.Log().Debug("获取到Worker")
-		worker.Do(job)
+		worker.Do(context.Background(), job)
 		util.Log().Debug("释放Worker")
 		pool.freeWorker()
 	}()
 }
 
+// notify 唤醒调度器
+func (pool *AsyncPool) notify() {
+	select {
+	case pool.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run 持续从数据库中领取并执行排队中的任务
+func (pool *AsyncPool) Run() {
+	for {
+		pool.schedule()
+
+		select {
+		case <-pool.wake:
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// schedule 按优先级领取可执行的任务，直到没有空闲Worker。超出单用户并行上限的任务留待下次调度
+func (pool *AsyncPool) schedule() {
+	if leader.IsLeader() {
+		Resume()
+	}
+
+	free := len(pool.idleWorker)
+	if free == 0 {
+		return
+	}
+
+	limit := model.GetIntSetting("max_parallel_task_per_user", 0)
+	candidates := model.GetRunnableTasks(free * 4)
+	processing := make(map[uint]int)
+
+	for i := range candidates {
+		record := &candidates[i]
+		if limit > 0 && record.UserID > 0 {
+			if _, ok := processing[record.UserID]; !ok {
+				processing[record.UserID] = model.CountProcessingTasks(record.UserID)
+			}
+
+			if processing[record.UserID] >= limit {
+				continue
+			}
+		}
+
+		select {
+		case <-pool.idleWorker:
+		default:
+			return
+		}
+
+		ok, err := record.Claim(pool.holder)
+		if err != nil || !ok {
+			if err != nil {
+				util.Log().Warning("无法领取任务 [%d]，%s", record.ID, err)
+			}
+			pool.freeWorker()
+			continue
+		}
+
+		processing[record.UserID]++
+		go pool.run(record)
+	}
+}
+
+// run 执行已领取的任务
+func (pool *AsyncPool) run(record *model.Task) {
+	defer func() {
+		pool.freeWorker()
+		pool.notify()
+	}()
+
+	job, err := GetJobFromModel(record)
+	if err != nil {
+		util.Log().Warning("无法恢复任务 [%d]，%s", record.ID, err)
+		res, _ := json.Marshal(NewJobError("无法恢复任务", err))
+		record.SetError(string(res))
+		record.SetStatus(Error)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool.running.Store(record.ID, cancel)
+	defer pool.running.Delete(record.ID)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go heartbeat(record, stop)
+
+	worker := &GeneralWorker{}
+	worker.Do(ctx, job)
+}
+
+// cancel 中止本实例中正在执行的任务
+func (pool *AsyncPool) cancel(id uint) {
+	if cancel, ok := pool.running.Load(id); ok {
+		util.Log().Info("中止任务 [%d]", id)
+		cancel.(context.CancelFunc)()
+	}
+}
+
+// heartbeat 定期更新执行中任务的活跃时间，直到 stop 被关闭
+func heartbeat(record *model.Task, stop <-chan struct{}) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := record.Touch(); err != nil {
+				util.Log().Warning("无法更新任务 [%d] 的活跃时间，%s", record.ID, err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Resume 将因实例退出而中断的执行中任务重新排队
+func Resume() {
+	count, err := model.RequeueStaleTasks(time.Now().Add(-staleTimeout))
+	if err != nil {
+		util.Log().Warning("无法恢复中断的任务，%s", err)
+		return
+	}
+
+	if count > 0 {
+		util.Log().Info("重新排队 %d 个中断的任务", count)
+	}
+}
+
+// Cancel 取消排队中或执行中的任务，uid 不为 0 时仅能取消该用户的任务。
+// 执行中的任务将通过消息队列通知所在实例中止
+func Cancel(id, uid uint) (bool, error) {
+	ok, err := model.CancelTask(id, uid)
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	mq.GlobalMQ.Publish(CancelTopic, mq.Message{Content: id})
+	return true, nil
+}
+
 // Init 初始化任务池
 func Init() {
 	maxWorker := model.GetIntSetting("max_worker_num", 10)
-	TaskPoll = &AsyncPool{
+	pool := &AsyncPool{
 		idleWorker: make(chan int, maxWorker),
 	}
+	TaskPoll = pool
 	TaskPoll.Add(maxWorker)
 	util.Log().Info("初始化任务队列，WorkerNum = %d", maxWorker)
 
 	if conf.SystemConfig.Mode == "master" {
-		Resume(TaskPoll)
+		pool.holder, _ = os.Hostname()
+		if leader.Default != nil {
+			pool.holder = leader.Default.ID()
+		}
+
+		pool.wake = make(chan struct{}, 1)
+		mq.GlobalMQ.SubscribeCallback(CancelTopic, func(msg mq.Message) {
+			if id, ok := msg.Content.(uint); ok {
+				pool.cancel(id)
+			}
+		})
+
+		// 多实例部署时，仅由主节点恢复中断的任务
+		leader.OnElected(Resume)
+
+		if gin.Mode() != gin.TestMode {
+			go pool.Run()
+		}
 	}
 }
@@ -1,12 +1,15 @@
 package task
 
 import (
+	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 )
@@ -29,10 +32,13 @@ func TestMain(m *testing.M) {
 func TestInit(t *testing.T) {
 	asserts := assert.New(t)
 	cache.Set("setting_max_worker_num", "10", 0)
-	mock.ExpectQuery("SELECT(.+)").WithArgs(Queued, Processing).WillReturnRows(sqlmock.NewRows([]string{"type"}).AddRow(-1))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
 	Init()
 	asserts.NoError(mock.ExpectationsWereMet())
 	asserts.Len(TaskPoll.(*AsyncPool).idleWorker, 10)
+	asserts.NotNil(TaskPoll.(*AsyncPool).wake)
 }
 
 func TestPool_Submit(t *testing.T) {
@@ -49,4 +55,112 @@ func TestPool_Submit(t *testing.T) {
 	asserts.NotPanics(func() {
 		pool.Submit(job)
 	})
+
+	// 已记录的任务唤醒调度器
+	{
+		pool.wake = make(chan struct{}, 1)
+		pool.Submit(&MockJob{Record: &model.Task{Model: gorm.Model{ID: 1}}})
+		pool.Submit(&MockJob{Record: &model.Task{Model: gorm.Model{ID: 2}}})
+		asserts.Len(pool.wake, 1)
+	}
+}
+
+func TestPool_Schedule(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_max_parallel_task_per_user", "1", 0)
+	pool := &AsyncPool{
+		idleWorker: make(chan int, 3),
+		wake:       make(chan struct{}, 1),
+		holder:     "a",
+	}
+
+	// 没有空闲 Worker
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		pool.schedule()
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 领取任务，跳过超出并行上限的用户及已被其他实例领取的任务
+	{
+		pool.Add(3)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)tasks(.+)").WithArgs(Queued, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "type"}).
+				AddRow(1, 1, -1).AddRow(2, 1, -1).AddRow(3, 2, -1).AddRow(4, 3, -1))
+		// 用户1
+		mock.ExpectQuery("SELECT count(.+)tasks(.+)").WithArgs(1, Processing).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		// 用户2 已达到上限
+		// 用户3 已被领取
+		mock.ExpectQuery("SELECT count(.+)tasks(.+)").WithArgs(2, Processing).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery("SELECT count(.+)tasks(.+)").WithArgs(3, Processing).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		// 任务1 类型未知，执行失败
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		pool.schedule()
+
+		asserts.Eventually(func() bool {
+			return mock.ExpectationsWereMet() == nil && len(pool.idleWorker) == 3
+		}, time.Second, 10*time.Millisecond)
+	}
+}
+
+func TestPool_Cancel(t *testing.T) {
+	asserts := assert.New(t)
+	pool := &AsyncPool{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.running.Store(uint(1), cancel)
+
+	pool.cancel(2)
+	asserts.NoError(ctx.Err())
+
+	pool.cancel(1)
+	asserts.Error(ctx.Err())
+}
+
+func TestCancel(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 任务不存在或已结束
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		ok, err := Cancel(1, 2)
+		asserts.NoError(err)
+		asserts.False(ok)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功，通知执行任务的实例
+	{
+		notifier := mq.GlobalMQ.Subscribe(CancelTopic, 1)
+		defer mq.GlobalMQ.Unsubscribe(CancelTopic, notifier)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		ok, err := Cancel(1, 2)
+		asserts.NoError(err)
+		asserts.True(ok)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(uint(1), (<-notifier).Content)
+	}
 }
@@ -10,7 +10,10 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"io"
 	"os"
+	"sync/atomic"
+	"time"
 )
 
 // TransferTask 文件中转任务
@@ -78,7 +81,7 @@ func (job *TransferTask) GetError() *task.JobError {
 }
 
 // Do 开始执行任务
-func (job *TransferTask) Do() {
+func (job *TransferTask) Do(ctx context.Context) {
 	defer job.Recycle()
 
 	fs, err := filesystem.NewAnonymousFileSystem()
@@ -117,11 +120,17 @@ func (job *TransferTask) Do() {
 
 	size := fi.Size()
 
-	err = fs.Handler.Put(context.Background(), &fsctx.FileStream{
-		File:     file,
+	// 由独立的协程上报进度，避免阻塞上传
+	reader := &progressReader{ReadCloser: file}
+	stopReport := make(chan struct{})
+	go job.reportProgress(reader, stopReport)
+
+	err = fs.Handler.Put(ctx, &fsctx.FileStream{
+		File:     reader,
 		SavePath: job.Req.Dst,
 		Size:     uint64(size),
 	})
+	close(stopReport)
 	if err != nil {
 		job.SetErrorMsg("文件上传失败", err)
 		return
@@ -138,6 +147,53 @@ func (job *TransferTask) Do() {
 	}
 }
 
+// progressReportInterval 从机上报中转进度的间隔
+const progressReportInterval = time.Second
+
+// progressReader 读取源文件时记录已读取的字节数
+type progressReader struct {
+	io.ReadCloser
+	processed uint64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	atomic.AddUint64(&r.processed, uint64(n))
+	return n, err
+}
+
+// reportProgress 定期向主机上报已上传的字节数，直到 stop 被关闭
+func (job *TransferTask) reportProgress(reader *progressReader, stop <-chan struct{}) {
+	ticker := time.NewTicker(progressReportInterval)
+	defer ticker.Stop()
+
+	var reported uint64
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if processed := atomic.LoadUint64(&reader.processed); processed != reported {
+				reported = processed
+				job.notifyProgress(processed)
+			}
+		}
+	}
+}
+
+// notifyProgress 向主机上报中转进度，失败时忽略
+func (job *TransferTask) notifyProgress(processed uint64) {
+	msg := mq.Message{
+		TriggeredBy: job.MasterID,
+		Event:       serializer.SlaveTransferProgress,
+		Content:     serializer.SlaveTransferResult{Processed: processed},
+	}
+
+	if err := cluster.DefaultController.SendNotification(job.MasterID, job.Req.Hash(job.MasterID), msg); err != nil {
+		util.Log().Debug("无法发送转存进度通知到主机, %s", err)
+	}
+}
+
 // Recycle 回收临时文件
 func (job *TransferTask) Recycle() {
 	err := os.Remove(job.Req.Src)
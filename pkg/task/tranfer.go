@@ -12,6 +12,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/progress"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
@@ -73,11 +74,7 @@ func (job *TransferTask) SetError(err *JobError) {
 
 // SetErrorMsg 设定任务失败信息
 func (job *TransferTask) SetErrorMsg(msg string, err error) {
-	jobErr := &JobError{Msg: msg}
-	if err != nil {
-		jobErr.Error = err.Error()
-	}
-	job.SetError(jobErr)
+	job.SetError(NewJobError(msg, err))
 }
 
 // GetError 返回任务失败信息
@@ -86,9 +83,7 @@ func (job *TransferTask) GetError() *JobError {
 }
 
 // Do 开始执行任务
-func (job *TransferTask) Do() {
-	defer job.Recycle()
-
+func (job *TransferTask) Do(ctx context.Context) {
 	// 事务
 	tx := model.DB.Begin()
 
@@ -107,8 +102,16 @@ func (job *TransferTask) Do() {
 		fs.Tx = nil
 	}()
 
+	tracker := progress.FromContext(ctx)
+	tracker.SetPhase(TransferringProgress, job.totalSize())
+
 	successCount := 0
 	for index, file := range job.TaskProps.Src {
+		if ctx.Err() != nil {
+			tx.Rollback()
+			return
+		}
+
 		_ = job.TaskModel.SetProgressTransaction(index, tx)
 		dst := path.Join(job.TaskProps.Dst, filepath.Base(file))
 		if job.TaskProps.TrimPath {
@@ -127,18 +130,20 @@ func (job *TransferTask) Do() {
 				job.SetErrorMsg("从机节点不可用", nil)
 			}
 
-			// 切换为从机节点处理上传
+			// 切换为从机节点处理上传，进度由从机上报
 			fs.SwitchToSlaveHandler(node)
-			err = fs.UploadFromStream(context.Background(), &fsctx.FileStream{
+			tracker.BeginFile(path.Base(dst), job.TaskProps.SrcSizes[file])
+			err = fs.UploadFromStream(ctx, &fsctx.FileStream{
 				File:        nil,
 				Size:        job.TaskProps.SrcSizes[file],
 				Name:        path.Base(dst),
 				VirtualPath: path.Dir(dst),
 				Src:         file,
 			}, false)
+			tracker.EndFile()
 		} else {
 			// 主机节点中转
-			err = fs.UploadFromPath(context.Background(), file, dst, 0)
+			err = fs.UploadFromPath(ctx, file, dst, 0)
 		}
 
 		if err != nil {
@@ -168,12 +173,25 @@ func (job *TransferTask) Do() {
 	// 最后生成缩略图
 	// 需要生成缩略图
 	if fs.User.Policy.IsThumbGenerateNeeded() {
-		ctx := context.Background()
 		fs.GenerateThumbnailsTransaction(ctx, nil)
 	}
 }
 
-// Recycle 回收临时文件
+// totalSize 计算待中转文件的总大小，从机中转时使用从机上报的大小
+func (job *TransferTask) totalSize() uint64 {
+	var total uint64
+	for _, file := range job.TaskProps.Src {
+		if size, ok := job.TaskProps.SrcSizes[file]; ok {
+			total += size
+		} else if stat, err := os.Stat(util.RelativePath(file)); err == nil {
+			total += uint64(stat.Size())
+		}
+	}
+
+	return total
+}
+
+// Recycle 回收临时文件，由 Worker 在任务不再重试后调用
 func (job *TransferTask) Recycle() {
 	if job.TaskProps.NodeID == 1 {
 		err := os.RemoveAll(job.TaskProps.Parent)
@@ -1,6 +1,7 @@
 package task
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -74,7 +75,7 @@ func TestTransferTask_Do(t *testing.T) {
 		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1,
 			1))
 		mock.ExpectCommit()
-		task.Do()
+		task.Do(context.Background())
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NotEmpty(task.GetError().Msg)
 	}
@@ -93,7 +94,7 @@ func TestTransferTask_Do(t *testing.T) {
 		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1,
 			1))
 		mock.ExpectCommit()
-		task.Do()
+		task.Do(context.Background())
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NotEmpty(task.GetError().Msg)
 	}
@@ -113,7 +114,7 @@ func TestTransferTask_Do(t *testing.T) {
 		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1,
 			1))
 		mock.ExpectCommit()
-		task.Do()
+		task.Do(context.Background())
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NotEmpty(task.GetError().Msg)
 	}
@@ -1,13 +1,25 @@
 package task
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/progress"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/cloudreve/Cloudreve/v3/pkg/webhook"
 )
 
 // Worker 处理任务的对象
 type Worker interface {
-	Do(Job) // 执行任务
+	Do(context.Context, Job) // 执行任务
+}
+
+// Recycler 需要在执行结束后回收临时资源的任务，重新排队等待重试时不回收
+type Recycler interface {
+	Recycle()
 }
 
 // GeneralWorker 通用Worker
@@ -15,30 +27,121 @@ type GeneralWorker struct {
 }
 
 // Do 执行任务
-func (worker *GeneralWorker) Do(job Job) {
+func (worker *GeneralWorker) Do(ctx context.Context, job Job) {
 	util.Log().Debug("开始执行任务")
 	job.SetStatus(Processing)
 
+	// 已记录的任务向订阅者推送实时进度
+	if record := job.Model(); record != nil && record.ID > 0 {
+		tracker := progress.NewTracker(progress.KindTask, record.ID, record.UserID)
+		ctx = progress.WithTracker(ctx, tracker)
+		defer tracker.Done()
+	}
+
+	// 任务不再执行后回收临时资源
+	requeued := false
+	defer func() {
+		if recycler, ok := job.(Recycler); ok && !requeued {
+			recycler.Recycle()
+		}
+	}()
+
 	defer func() {
 		// 致命错误捕获
 		if err := recover(); err != nil {
 			util.Log().Debug("任务执行出错，%s", err)
 			job.SetError(&JobError{Msg: "致命错误", Error: fmt.Sprintf("%s", err)})
 			job.SetStatus(Error)
+			notifyWebhook(job, webhook.EventTaskFailed)
 		}
 	}()
 
 	// 开始执行任务
-	job.Do()
+	job.Do(ctx)
+
+	// 任务被取消，取消也可能在任务执行结束后才送达
+	if errors.Is(ctx.Err(), context.Canceled) || canceled(job) {
+		util.Log().Debug("任务已取消")
+		job.SetStatus(Canceled)
+		return
+	}
 
 	// 任务执行失败
 	if err := job.GetError(); err != nil {
+		if retry(job, err) {
+			requeued = true
+			return
+		}
+
 		util.Log().Debug("任务执行出错")
 		job.SetStatus(Error)
+		notifyWebhook(job, webhook.EventTaskFailed)
 		return
 	}
 
 	util.Log().Debug("任务执行完成")
 	// 执行完成
 	job.SetStatus(Complete)
+
+	// 清除重试前的失败信息
+	if record := job.Model(); record != nil && record.Attempts > 1 {
+		record.SetError("")
+	}
+
+	// 中转任务完成即离线下载完成
+	if _, ok := job.(*TransferTask); ok {
+		notifyWebhook(job, webhook.EventDownloadFinished)
+	}
+}
+
+// canceled 返回已记录的任务是否已在数据库中被取消
+func canceled(job Job) bool {
+	record := job.Model()
+	return record != nil && record.ID > 0 && record.IsCanceled()
+}
+
+// retry 可重试的错误在未超过最大重试次数时将任务重新排队，重试间隔随执行次数指数增长
+func retry(job Job, err *JobError) bool {
+	record := job.Model()
+	if record == nil || record.ID == 0 || !err.Retryable {
+		return false
+	}
+
+	if record.Attempts > model.GetIntSetting("task_max_retry", 3) {
+		return false
+	}
+
+	shift := record.Attempts - 1
+	if shift > 10 {
+		shift = 10
+	}
+	delay := time.Duration(model.GetIntSetting("task_retry_backoff", 60)) * time.Second << uint(shift)
+
+	if e := record.Retry(delay); e != nil {
+		util.Log().Warning("无法重新排队任务 [%d]，%s", record.ID, e)
+		return false
+	}
+
+	util.Log().Info("任务 [%d] 执行失败，将在 %s 后第 %d 次重试", record.ID, delay, record.Attempts)
+	return true
+}
+
+// notifyWebhook 触发任务相关的 Webhook 事件
+func notifyWebhook(job Job, event string) {
+	if webhook.Default == nil {
+		return
+	}
+
+	data := map[string]interface{}{
+		"type":  job.Type(),
+		"error": job.GetError(),
+	}
+	if record := job.Model(); record != nil {
+		data["task"] = record.ID
+	}
+	if transfer, ok := job.(*TransferTask); ok {
+		data["dst"] = transfer.TaskProps.Dst
+	}
+
+	webhook.Trigger(job.Creator(), event, data)
 }
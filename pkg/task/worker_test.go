@@ -1,16 +1,24 @@
 package task
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 )
 
 type MockJob struct {
-	Err    *JobError
-	Status int
-	DoFunc func()
+	Err      *JobError
+	Status   int
+	DoFunc   func()
+	Record   *model.Task
+	Recycled int
 }
 
 func (job *MockJob) Type() int {
@@ -26,14 +34,14 @@ func (job *MockJob) Props() string {
 }
 
 func (job *MockJob) Model() *model.Task {
-	panic("implement me")
+	return job.Record
 }
 
 func (job *MockJob) SetStatus(status int) {
 	job.Status = status
 }
 
-func (job *MockJob) Do() {
+func (job *MockJob) Do(ctx context.Context) {
 	job.DoFunc()
 }
 
@@ -44,6 +52,10 @@ func (job *MockJob) GetError() *JobError {
 	return job.Err
 }
 
+func (job *MockJob) Recycle() {
+	job.Recycled++
+}
+
 func TestGeneralWorker_Do(t *testing.T) {
 	asserts := assert.New(t)
 	worker := &GeneralWorker{}
@@ -53,7 +65,7 @@ func TestGeneralWorker_Do(t *testing.T) {
 	{
 		job.DoFunc = func() {
 		}
-		worker.Do(job)
+		worker.Do(context.Background(), job)
 		asserts.Equal(Complete, job.Status)
 	}
 
@@ -63,7 +75,7 @@ func TestGeneralWorker_Do(t *testing.T) {
 		}
 		job.Status = Queued
 		job.Err = &JobError{Msg: "error"}
-		worker.Do(job)
+		worker.Do(context.Background(), job)
 		asserts.Equal(Error, job.Status)
 	}
 
@@ -74,8 +86,175 @@ func TestGeneralWorker_Do(t *testing.T) {
 		}
 		job.Status = Queued
 		job.Err = nil
-		worker.Do(job)
+		worker.Do(context.Background(), job)
+		asserts.Equal(Error, job.Status)
+	}
+
+}
+
+func TestGeneralWorker_DoCanceled(t *testing.T) {
+	asserts := assert.New(t)
+	worker := &GeneralWorker{}
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &MockJob{
+		DoFunc: func() {
+			cancel()
+		},
+		Err: &JobError{Msg: "canceled"},
+	}
+
+	worker.Do(ctx, job)
+	asserts.Equal(Canceled, job.Status)
+}
+
+// expectTaskStatus 模拟任务执行结束后读取数据库中的任务状态
+func expectTaskStatus(status int) {
+	mock.ExpectQuery("SELECT status(.+)tasks(.+)").WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(status))
+}
+
+func TestGeneralWorker_DoCanceledAfterDone(t *testing.T) {
+	asserts := assert.New(t)
+	worker := &GeneralWorker{}
+
+	// 取消在任务执行结束后送达
+	job := &MockJob{
+		DoFunc: func() {},
+		Record: &model.Task{Model: gorm.Model{ID: 1}},
+	}
+	expectTaskStatus(Canceled)
+	worker.Do(context.Background(), job)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(Canceled, job.Status)
+}
+
+func TestGeneralWorker_DoRetry(t *testing.T) {
+	asserts := assert.New(t)
+	worker := &GeneralWorker{}
+	cache.Set("setting_task_max_retry", "2", 0)
+	cache.Set("setting_task_retry_backoff", "10", 0)
+
+	// 可重试，重新排队
+	{
+		job := &MockJob{
+			DoFunc: func() {},
+			Err:    &JobError{Msg: "error", Retryable: true},
+			Record: &model.Task{Model: gorm.Model{ID: 1}, Attempts: 2},
+		}
+		expectTaskStatus(Processing)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		worker.Do(context.Background(), job)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(Processing, job.Status)
+		asserts.Equal(Queued, job.Record.Status)
+		asserts.WithinDuration(time.Now().Add(20*time.Second), *job.Record.NextRunAt, time.Second)
+	}
+
+	// 超过最大重试次数
+	{
+		job := &MockJob{
+			DoFunc: func() {},
+			Err:    &JobError{Msg: "error", Retryable: true},
+			Record: &model.Task{Model: gorm.Model{ID: 1}, Attempts: 3},
+		}
+		expectTaskStatus(Processing)
+		worker.Do(context.Background(), job)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(Error, job.Status)
+		asserts.Equal(1, job.Recycled)
+	}
+
+	// 不可重试
+	{
+		job := &MockJob{
+			DoFunc: func() {},
+			Err:    &JobError{Msg: "error"},
+			Record: &model.Task{Model: gorm.Model{ID: 1}, Attempts: 1},
+		}
+		expectTaskStatus(Processing)
+		worker.Do(context.Background(), job)
+		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Equal(Error, job.Status)
 	}
 
+	// 重新排队失败
+	{
+		job := &MockJob{
+			DoFunc: func() {},
+			Err:    &JobError{Msg: "error", Retryable: true},
+			Record: &model.Task{Model: gorm.Model{ID: 1}, Attempts: 1},
+		}
+		expectTaskStatus(Processing)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		worker.Do(context.Background(), job)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(Error, job.Status)
+	}
+
+	// 重试后成功，清除失败信息
+	{
+		job := &MockJob{
+			DoFunc: func() {},
+			Record: &model.Task{Model: gorm.Model{ID: 1}, Attempts: 2, Error: "error"},
+		}
+		expectTaskStatus(Processing)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		worker.Do(context.Background(), job)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(Complete, job.Status)
+	}
+}
+
+func TestGeneralWorker_DoRecycle(t *testing.T) {
+	asserts := assert.New(t)
+	worker := &GeneralWorker{}
+	cache.Set("setting_task_max_retry", "2", 0)
+	cache.Set("setting_task_retry_backoff", "10", 0)
+
+	job := &MockJob{
+		DoFunc: func() {},
+		Err:    &JobError{Msg: "error", Retryable: true},
+		Record: &model.Task{Model: gorm.Model{ID: 1}, Attempts: 1},
+	}
+
+	// 重新排队时不回收临时资源
+	expectTaskStatus(Processing)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	worker.Do(context.Background(), job)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(Queued, job.Record.Status)
+	asserts.Equal(0, job.Recycled)
+
+	// 重试成功后回收
+	job.Err = nil
+	job.Record.Attempts++
+	expectTaskStatus(Processing)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	worker.Do(context.Background(), job)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(Complete, job.Status)
+	asserts.Equal(1, job.Recycled)
+
+	// 取消及致命错误时回收
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		job := &MockJob{DoFunc: func() { cancel() }}
+		worker.Do(ctx, job)
+		asserts.Equal(Canceled, job.Status)
+		asserts.Equal(1, job.Recycled)
+
+		job = &MockJob{DoFunc: func() { panic("mock fatal error") }}
+		worker.Do(context.Background(), job)
+		asserts.Equal(Error, job.Status)
+		asserts.Equal(1, job.Recycled)
+	}
 }
@@ -0,0 +1,110 @@
+package traffic
+
+import (
+	"encoding/gob"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// Topic 从机上报流量使用的消息主题
+const Topic = "traffic"
+
+// ErrQuotaExceeded 本月流量已用尽
+var ErrQuotaExceeded = serializer.NewError(serializer.CodeTrafficQuotaExceeded, "Monthly traffic quota exceeded", nil)
+
+var initOnce sync.Once
+
+// Report 从机上报的一次下载流量
+type Report struct {
+	UserID uint
+	Size   uint64
+}
+
+func init() {
+	gob.Register(Report{})
+}
+
+// Init 订阅从机上报的流量
+func Init() {
+	initOnce.Do(func() {
+		mq.GlobalMQ.SubscribeCallback(Topic, func(msg mq.Message) {
+			if report, ok := msg.Content.(Report); ok {
+				Record(report.UserID, report.Size)
+			}
+		})
+	})
+}
+
+// Check 检查用户本月流量是否已用尽，用户所在用户组未设置配额时不限制
+func Check(uid uint) error {
+	if uid == 0 {
+		return nil
+	}
+
+	user, err := model.GetUserByID(uid)
+	if err != nil {
+		return nil
+	}
+
+	quota := user.Group.OptionsSerialized.TrafficQuota
+	if quota > 0 && model.GetTraffic(uid, model.TrafficMonth(time.Now())) >= quota {
+		return ErrQuotaExceeded
+	}
+
+	return nil
+}
+
+// Record 为用户记录 size 字节的下载流量
+func Record(uid uint, size uint64) {
+	if uid == 0 || size == 0 {
+		return
+	}
+
+	if err := model.AddTraffic(uid, size); err != nil {
+		util.Log().Warning("无法记录用户 %d 的下载流量, %s", uid, err)
+	}
+}
+
+// Written 返回已向客户端写出的响应正文字节数
+func Written(w http.ResponseWriter) uint64 {
+	if sized, ok := w.(interface{ Size() int }); ok && sized.Size() > 0 {
+		return uint64(sized.Size())
+	}
+	return 0
+}
+
+// Tag 为从机下载地址生成流量归属标记，与文件路径绑定以防止被挪用到其他地址
+func Tag(instance auth.Auth, sourcePath string, uid uint) string {
+	siteID := model.GetSettingByName("siteID")
+	sign := instance.Sign(fmt.Sprintf("%s,%s,%d", sourcePath, siteID, uid), 0)
+	return fmt.Sprintf("%s,%d,%s", siteID, uid, sign)
+}
+
+// ParseTag 校验并解析从机下载地址中的流量归属标记，返回主机站点ID和用户ID
+func ParseTag(instance auth.Auth, sourcePath, tag string) (string, uint, bool) {
+	parts := strings.SplitN(tag, ",", 3)
+	if len(parts) != 3 {
+		return "", 0, false
+	}
+
+	uid, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return "", 0, false
+	}
+
+	if instance.Check(fmt.Sprintf("%s,%s,%d", sourcePath, parts[0], uid), parts[2]) != nil {
+		return "", 0, false
+	}
+
+	return parts[0], uint(uid), true
+}
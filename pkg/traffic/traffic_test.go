@@ -0,0 +1,159 @@
+package traffic
+
+import (
+	"database/sql"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+func TestCheck(t *testing.T) {
+	a := assert.New(t)
+	month := model.TrafficMonth(time.Now())
+
+	// 用户不存在
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		a.NoError(Check(1))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 未设置配额
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "group_id"}).AddRow(1, 1))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "options"}).AddRow(1, "{}"))
+		a.NoError(Check(1))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 未超出配额
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "group_id"}).AddRow(1, 1))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "options"}).AddRow(1, `{"traffic_quota":100}`))
+		mock.ExpectQuery("SELECT(.+)traffics(.+)").WithArgs(1, month).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "used"}).AddRow(1, 99))
+		a.NoError(Check(1))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 已超出配额
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "group_id"}).AddRow(1, 1))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "options"}).AddRow(1, `{"traffic_quota":100}`))
+		mock.ExpectQuery("SELECT(.+)traffics(.+)").WithArgs(1, month).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "used"}).AddRow(1, 100))
+		a.Equal(ErrQuotaExceeded, Check(1))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestRecord(t *testing.T) {
+	a := assert.New(t)
+
+	// 无需记录
+	{
+		Record(0, 10)
+		Record(1, 0)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 记录失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)traffics(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		Record(1, 10)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestInit(t *testing.T) {
+	a := assert.New(t)
+	Init()
+	Init()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)traffics(.+)").WithArgs(10, 1, model.TrafficMonth(time.Now())).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mq.GlobalMQ.Publish(Topic, mq.Message{Content: Report{UserID: 1, Size: 10}})
+
+	// 回调异步执行
+	a.Eventually(func() bool {
+		return mock.ExpectationsWereMet() == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWritten(t *testing.T) {
+	a := assert.New(t)
+
+	// 不支持统计
+	a.EqualValues(0, Written(httptest.NewRecorder()))
+
+	// 未写出
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	a.EqualValues(0, Written(c.Writer))
+
+	// 已写出
+	c.Writer.WriteString("hello")
+	a.EqualValues(5, Written(c.Writer))
+}
+
+func TestTag(t *testing.T) {
+	a := assert.New(t)
+	cache.Set("setting_siteID", "site", 0)
+	instance := auth.HMACAuth{SecretKey: []byte("secret")}
+
+	tag := Tag(instance, "/a.txt", 1)
+
+	// 正常
+	siteID, uid, ok := ParseTag(instance, "/a.txt", tag)
+	a.True(ok)
+	a.Equal("site", siteID)
+	a.EqualValues(1, uid)
+
+	// 路径不匹配
+	_, _, ok = ParseTag(instance, "/b.txt", tag)
+	a.False(ok)
+
+	// 格式错误
+	_, _, ok = ParseTag(instance, "/a.txt", "site,1")
+	a.False(ok)
+	_, _, ok = ParseTag(instance, "/a.txt", "site,x,sign")
+	a.False(ok)
+
+	// 篡改用户
+	_, _, ok = ParseTag(instance, "/a.txt", "site,2"+tag[len("site,1"):])
+	a.False(ok)
+}
@@ -91,6 +91,22 @@ func copyFiles(ctx context.Context, fs *filesystem.FileSystem, src FileInfo, dst
 		}
 	}
 
+	// 复制自定义属性
+	var copied FileInfo
+	target := path.Join(path.Dir(dst), src.GetName())
+	if src.IsDir() {
+		if exist, folder := fs.IsPathExist(target); exist {
+			copied = folder
+		}
+	} else if exist, file := fs.IsFileExist(target); exist {
+		copied = file
+	}
+	if copied != nil {
+		if err := copyDeadProps(fs, src, copied); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
 	return http.StatusNoContent, nil
 }
 
@@ -0,0 +1,985 @@
+package webdav
+
+import (
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// groupwareRoot CalDAV / CardDAV 资源在 WebDAV 根目录下的保留路径，同名目录无法通过 WebDAV 访问。
+// 该路径同时作为当前用户的主体地址
+const groupwareRoot = "/.groupware"
+
+// groupwareCompliance 提供 CalDAV / CardDAV 服务时的 DAV 响应头
+const groupwareCompliance = "1, 2, 3, calendar-access, addressbook, extended-mkcol"
+
+// maxGroupwareObjectSize 单个日历对象或名片的最大字节数
+const maxGroupwareObjectSize = 4 << 20
+
+// syncTokenPrefix 同步令牌前缀，令牌为集合版本
+const syncTokenPrefix = "http://cloudreve.org/ns/sync/"
+
+const (
+	defaultCollectionURI      = "default"
+	defaultCalendarComponents = "VEVENT,VTODO"
+	allCalendarComponents     = "VEVENT,VTODO,VJOURNAL"
+)
+
+// groupwareHomes 主体下日历及通讯录主目录的名称
+var groupwareHomes = map[string]string{
+	"calendars": model.DavCalendar,
+	"contacts":  model.DavAddressBook,
+}
+
+// 资源层级
+const (
+	resourcePrincipal = iota
+	resourceHome
+	resourceCollection
+	resourceObject
+)
+
+// groupwareResource CalDAV / CardDAV 资源
+type groupwareResource struct {
+	kind           int
+	collectionType string
+	collectionURI  string
+	objectURI      string
+	collection     *model.DavCollection
+	object         *model.DavObject
+}
+
+// parseGroupwareResource 根据保留路径下的相对路径解析资源
+func parseGroupwareResource(subPath string) (*groupwareResource, bool) {
+	parts := strings.Split(strings.Trim(subPath, "/"), "/")
+	if parts[0] == "" {
+		return &groupwareResource{kind: resourcePrincipal}, true
+	}
+
+	collectionType, ok := groupwareHomes[parts[0]]
+	if !ok || len(parts) > 3 {
+		return nil, false
+	}
+
+	res := &groupwareResource{kind: resourceHome + len(parts) - 1, collectionType: collectionType}
+	if len(parts) > 1 {
+		res.collectionURI = parts[1]
+	}
+	if len(parts) > 2 {
+		res.objectURI = parts[2]
+	}
+	return res, true
+}
+
+// load 读取资源对应的集合及对象，不存在时保持为空
+func (res *groupwareResource) load(uid uint) {
+	if res.kind < resourceCollection {
+		return
+	}
+
+	collection, err := model.GetDavCollection(uid, res.collectionType, res.collectionURI)
+	if err != nil {
+		return
+	}
+	res.collection = collection
+
+	if res.kind == resourceObject {
+		if object, err := model.GetDavObject(collection.ID, res.objectURI); err == nil {
+			res.object = object
+		}
+	}
+}
+
+// exists 返回资源是否存在
+func (res *groupwareResource) exists() bool {
+	switch res.kind {
+	case resourceCollection:
+		return res.collection != nil
+	case resourceObject:
+		return res.object != nil
+	}
+	return true
+}
+
+// child 返回集合中对象对应的资源
+func (res *groupwareResource) child(object *model.DavObject) *groupwareResource {
+	return &groupwareResource{
+		kind:           resourceObject,
+		collectionType: res.collectionType,
+		collectionURI:  res.collectionURI,
+		objectURI:      object.URI,
+		collection:     res.collection,
+		object:         object,
+	}
+}
+
+func (res *groupwareResource) isCalendar() bool {
+	return res.collectionType == model.DavCalendar
+}
+
+func (res *groupwareResource) etag() string {
+	return `"` + res.object.Etag + `"`
+}
+
+func (res *groupwareResource) contentType() string {
+	if res.isCalendar() {
+		return "text/calendar; charset=utf-8; component=" + strings.ToLower(res.object.Component)
+	}
+	return "text/vcard; charset=utf-8"
+}
+
+// components 返回日历支持的组件
+func (res *groupwareResource) components() []string {
+	if res.collection.Components == "" {
+		return strings.Split(allCalendarComponents, ",")
+	}
+	return strings.Split(res.collection.Components, ",")
+}
+
+// precondition 返回集合所属协议命名空间下的前置条件元素
+func (res *groupwareResource) precondition(name, innerXML string) string {
+	ns := nsCardDAV
+	if res.isCalendar() {
+		ns = nsCalDAV
+	}
+	return fmt.Sprintf(`<C:%s xmlns:C="%s">%s</C:%s>`, name, ns, innerXML, name)
+}
+
+// newObject 解析上传的日历对象或名片，数据不合法时返回未满足的前置条件
+func (res *groupwareResource) newObject(data []byte) (*model.DavObject, string) {
+	object := &model.DavObject{
+		URI:  res.objectURI,
+		Data: string(data),
+		Etag: fmt.Sprintf("%x", sha1.Sum(data)),
+	}
+
+	root, err := parseICal(object.Data)
+	if res.isCalendar() {
+		if err != nil {
+			return nil, res.precondition("valid-calendar-data", "")
+		}
+
+		info, err := inspectCalendar(root)
+		if err != nil {
+			return nil, res.precondition("valid-calendar-object-resource", "")
+		}
+
+		if !util.ContainsString(res.components(), info.Component) {
+			return nil, res.precondition("supported-calendar-component", "")
+		}
+
+		object.UID, object.Component, object.StartAt, object.EndAt = info.UID, info.Component, info.Start, info.End
+		return object, ""
+	}
+
+	if err != nil || root.Name != "VCARD" {
+		return nil, res.precondition("valid-address-data", "")
+	}
+
+	object.Component = "VCARD"
+	object.UID = res.objectURI
+	if uid := root.prop("UID"); uid != nil && uid.Value != "" {
+		object.UID = uid.Value
+	}
+	return object, ""
+}
+
+// groupwarePath 返回请求路径在 CalDAV / CardDAV 保留路径下的相对路径
+func (h *Handler) groupwarePath(p string) (string, bool) {
+	if !h.Groupware {
+		return "", false
+	}
+
+	reqPath, _, err := h.stripPrefix(p, 0)
+	if err != nil {
+		return "", false
+	}
+
+	if reqPath == groupwareRoot || strings.HasPrefix(reqPath, groupwareRoot+"/") {
+		return strings.TrimPrefix(reqPath, groupwareRoot), true
+	}
+	return "", false
+}
+
+// IsGroupwareRequest 返回请求是否由 CalDAV / CardDAV 服务处理
+func (h *Handler) IsGroupwareRequest(r *http.Request) bool {
+	_, ok := h.groupwarePath(r.URL.Path)
+	return ok
+}
+
+func (h *Handler) groupwareHref(elem ...string) string {
+	return path.Join(append([]string{h.Prefix, groupwareRoot}, elem...)...)
+}
+
+// PrincipalHref 返回当前用户的主体地址
+func (h *Handler) PrincipalHref() string {
+	return h.groupwareHref() + "/"
+}
+
+// homeHref 返回日历或通讯录主目录地址
+func (h *Handler) homeHref(collectionType string) string {
+	for name, t := range groupwareHomes {
+		if t == collectionType {
+			return h.groupwareHref(name) + "/"
+		}
+	}
+	return ""
+}
+
+// hrefOf 返回资源地址，集合地址以 / 结尾
+func (h *Handler) hrefOf(res *groupwareResource) string {
+	switch res.kind {
+	case resourcePrincipal:
+		return h.PrincipalHref()
+	case resourceHome:
+		return h.homeHref(res.collectionType)
+	case resourceCollection:
+		return h.homeHref(res.collectionType) + res.collectionURI + "/"
+	}
+	return h.homeHref(res.collectionType) + res.collectionURI + "/" + res.objectURI
+}
+
+func (h *Handler) serveGroupware(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, reqPath string) (int, error) {
+	defer fs.Recycle()
+
+	res, ok := parseGroupwareResource(reqPath)
+	if !ok {
+		return http.StatusNotFound, nil
+	}
+	res.load(fs.User.ID)
+
+	switch r.Method {
+	case "OPTIONS":
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, PROPPATCH, REPORT, MKCOL, MKCALENDAR")
+		w.Header().Set("DAV", groupwareCompliance)
+		return 0, nil
+	case "GET", "HEAD":
+		return h.handleGroupwareGet(w, r, res)
+	case "PUT":
+		return h.handleGroupwarePut(w, r, res)
+	case "DELETE":
+		return h.handleGroupwareDelete(w, r, res)
+	case "MKCOL", "MKCALENDAR":
+		return h.handleGroupwareMkcol(w, r, fs, res)
+	case "PROPFIND":
+		return h.handleGroupwarePropfind(w, r, fs, res)
+	case "PROPPATCH":
+		return h.handleGroupwareProppatch(w, r, res)
+	case "REPORT":
+		return h.handleGroupwareReport(w, r, fs, res)
+	}
+
+	return http.StatusMethodNotAllowed, errUnsupportedMethod
+}
+
+func (h *Handler) handleGroupwareGet(w http.ResponseWriter, r *http.Request, res *groupwareResource) (int, error) {
+	if res.kind != resourceObject {
+		return http.StatusMethodNotAllowed, nil
+	}
+	if !res.exists() {
+		return http.StatusNotFound, nil
+	}
+	if permissionFromContext(r.Context()).UploadOnly {
+		return http.StatusForbidden, errPermissionDenied
+	}
+
+	w.Header().Set("Content-Type", res.contentType())
+	w.Header().Set("Content-Length", strconv.Itoa(len(res.object.Data)))
+	w.Header().Set("ETag", res.etag())
+	w.Header().Set("Last-Modified", res.object.UpdatedAt.UTC().Format(http.TimeFormat))
+	if r.Method == "GET" {
+		io.WriteString(w, res.object.Data)
+	}
+	return 0, nil
+}
+
+func (h *Handler) handleGroupwarePut(w http.ResponseWriter, r *http.Request, res *groupwareResource) (int, error) {
+	if res.kind != resourceObject {
+		return http.StatusMethodNotAllowed, nil
+	}
+	if res.collection == nil {
+		return http.StatusConflict, nil
+	}
+
+	permission := permissionFromContext(r.Context())
+	if permission.ReadOnly || (res.object != nil && !permission.canOverwrite()) {
+		return http.StatusForbidden, errPermissionDenied
+	}
+	if status := checkConditionalHeaders(r, res.object); status != 0 {
+		return status, nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxGroupwareObjectSize+1))
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if len(body) > maxGroupwareObjectSize {
+		return http.StatusRequestEntityTooLarge, nil
+	}
+
+	object, condition := res.newObject(body)
+	if condition != "" {
+		writePrecondition(w, http.StatusForbidden, condition)
+		return 0, nil
+	}
+
+	// 同一集合中的 UID 不能重复
+	if other, err := model.GetDavObjectByUID(res.collection.ID, object.UID); err == nil && other.URI != object.URI {
+		href := `<D:href xmlns:D="DAV:">` + escape(h.hrefOf(res.child(other))) + `</D:href>`
+		writePrecondition(w, http.StatusForbidden, res.precondition("no-uid-conflict", href))
+		return 0, nil
+	}
+
+	if err := res.collection.PutObject(object); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	w.Header().Set("ETag", `"`+object.Etag+`"`)
+	if res.object != nil {
+		return http.StatusNoContent, nil
+	}
+	return http.StatusCreated, nil
+}
+
+func (h *Handler) handleGroupwareDelete(w http.ResponseWriter, r *http.Request, res *groupwareResource) (int, error) {
+	if res.kind < resourceCollection {
+		return http.StatusForbidden, errPermissionDenied
+	}
+	if !res.exists() {
+		return http.StatusNotFound, nil
+	}
+	if !permissionFromContext(r.Context()).canDelete() {
+		return http.StatusForbidden, errPermissionDenied
+	}
+
+	if res.kind == resourceCollection {
+		if err := res.collection.Delete(); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusNoContent, nil
+	}
+
+	if status := checkConditionalHeaders(r, res.object); status != 0 {
+		return status, nil
+	}
+	if err := res.collection.DeleteObject(res.object); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusNoContent, nil
+}
+
+func (h *Handler) handleGroupwareMkcol(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, res *groupwareResource) (int, error) {
+	if res.kind != resourceCollection || res.exists() {
+		return http.StatusMethodNotAllowed, nil
+	}
+	if permissionFromContext(r.Context()).ReadOnly {
+		return http.StatusForbidden, errPermissionDenied
+	}
+	if r.Method == "MKCALENDAR" && !res.isCalendar() {
+		return http.StatusForbidden, nil
+	}
+
+	props, status, err := readMkcol(r.Body)
+	if err != nil {
+		return status, err
+	}
+
+	collection := &model.DavCollection{
+		UserID:      fs.User.ID,
+		Type:        res.collectionType,
+		URI:         res.collectionURI,
+		DisplayName: res.collectionURI,
+	}
+	if res.isCalendar() {
+		collection.Components = defaultCalendarComponents
+	}
+
+	// 忽略无法设定的属性
+	for _, prop := range props {
+		switch collectionColumn(res, prop.XMLName) {
+		case "display_name":
+			collection.DisplayName = xmlText(prop.InnerXML)
+		case "description":
+			collection.Description = xmlText(prop.InnerXML)
+		case "color":
+			collection.Color = xmlText(prop.InnerXML)
+		case "components":
+			if components := parseComponentSet(prop.InnerXML); components != "" {
+				collection.Components = components
+			}
+		}
+	}
+
+	if err := collection.Create(); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusCreated, nil
+}
+
+func (h *Handler) handleGroupwareProppatch(w http.ResponseWriter, r *http.Request, res *groupwareResource) (int, error) {
+	if !res.exists() {
+		return http.StatusNotFound, nil
+	}
+	if res.kind != resourceCollection || permissionFromContext(r.Context()).ReadOnly {
+		return http.StatusForbidden, errPermissionDenied
+	}
+
+	patches, status, err := readProppatch(r.Body)
+	if err != nil {
+		return status, err
+	}
+
+	// 修改是原子的，任一属性无法修改时全部不生效
+	updates := make(map[string]interface{})
+	pstatOK := Propstat{Status: http.StatusOK}
+	pstatForbidden := Propstat{Status: http.StatusForbidden}
+	for _, patch := range patches {
+		for _, prop := range patch.Props {
+			column := collectionColumn(res, prop.XMLName)
+			if column == "" || column == "components" {
+				pstatForbidden.Props = append(pstatForbidden.Props, Property{XMLName: prop.XMLName})
+				continue
+			}
+
+			updates[column] = ""
+			if !patch.Remove {
+				updates[column] = xmlText(prop.InnerXML)
+			}
+			pstatOK.Props = append(pstatOK.Props, Property{XMLName: prop.XMLName})
+		}
+	}
+
+	if len(pstatForbidden.Props) > 0 {
+		pstatOK.Status = StatusFailedDependency
+	} else if len(updates) > 0 {
+		if err := res.collection.Update(updates); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
+	mw := multistatusWriter{w: w}
+	writeErr := mw.write(makePropstatResponse(h.hrefOf(res), makePropstats(pstatForbidden, pstatOK)))
+	closeErr := mw.close()
+	if writeErr != nil {
+		return http.StatusInternalServerError, writeErr
+	}
+	if closeErr != nil {
+		return http.StatusInternalServerError, closeErr
+	}
+	return 0, nil
+}
+
+func (h *Handler) handleGroupwarePropfind(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, res *groupwareResource) (int, error) {
+	if !res.exists() {
+		return http.StatusNotFound, nil
+	}
+
+	// 集合不包含子集合，无限深度按 1 处理
+	depth := infiniteDepth
+	if hdr := r.Header.Get("Depth"); hdr != "" {
+		depth = parseDepth(hdr)
+		if depth == invalidDepth {
+			return http.StatusBadRequest, errInvalidDepth
+		}
+	}
+
+	pf, status, err := readPropfind(r.Body)
+	if err != nil {
+		return status, err
+	}
+
+	if res.kind == resourceHome {
+		if err := ensureDefaultCollection(fs.User.ID, res.collectionType); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
+	resources := []*groupwareResource{res}
+	if depth != 0 {
+		children, err := groupwareChildren(fs.User.ID, res)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		resources = append(resources, children...)
+	}
+
+	mw := multistatusWriter{w: w}
+	var writeErr error
+	for _, resource := range resources {
+		pstats := groupwarePropstats(pf, h.groupwareProps(r, fs.User, resource))
+		if writeErr = mw.write(makePropstatResponse(h.hrefOf(resource), pstats)); writeErr != nil {
+			break
+		}
+	}
+
+	closeErr := mw.close()
+	if writeErr != nil {
+		return http.StatusInternalServerError, writeErr
+	}
+	if closeErr != nil {
+		return http.StatusInternalServerError, closeErr
+	}
+	return 0, nil
+}
+
+func (h *Handler) handleGroupwareReport(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, res *groupwareResource) (int, error) {
+	if !res.exists() {
+		return http.StatusNotFound, nil
+	}
+	if res.kind != resourceCollection {
+		writePrecondition(w, http.StatusForbidden, `<D:supported-report xmlns:D="DAV:"/>`)
+		return 0, nil
+	}
+	if permissionFromContext(r.Context()).UploadOnly {
+		return http.StatusForbidden, errPermissionDenied
+	}
+
+	rpt, status, err := readReport(r.Body)
+	if err != nil {
+		return status, err
+	}
+
+	limit := 0
+	if rpt.Limit != nil {
+		limit = rpt.Limit.NResults
+	}
+
+	mw := multistatusWriter{w: w}
+	var (
+		found     []model.DavObject
+		missing   []string
+		truncated bool
+	)
+	switch name := xml.Name(rpt.XMLName); {
+	case name == reportSyncCollection:
+		revision, ok := parseSyncToken(rpt.SyncToken)
+		if !ok || revision > res.collection.Revision {
+			writePrecondition(w, http.StatusForbidden, `<D:valid-sync-token xmlns:D="DAV:"/>`)
+			return 0, nil
+		}
+
+		changes, err := model.GetDavObjectsChangedSince(res.collection.ID, revision)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		latest := res.collection.Revision
+		if limit > 0 && len(changes) > limit {
+			changes, truncated = changes[:limit], true
+			latest = changes[limit-1].Revision
+		}
+		mw.syncToken = syncTokenPrefix + strconv.FormatUint(latest, 10)
+
+		for i := range changes {
+			if !changes[i].Deleted {
+				found = append(found, changes[i])
+			} else if revision > 0 {
+				// 首次同步时无需返回已删除的对象
+				missing = append(missing, (&url.URL{Path: h.hrefOf(res.child(&changes[i]))}).EscapedPath())
+			}
+		}
+	case res.isCalendar() && name == reportCalendarQuery:
+		found, err = queryCalendar(res.collection.ID, rpt.CalendarFilter)
+	case !res.isCalendar() && name == reportAddressQuery:
+		found, err = queryAddressBook(res.collection.ID, rpt.AddressFilter)
+		if limit > 0 && len(found) > limit {
+			found, truncated = found[:limit], true
+		}
+	case res.isCalendar() && name == reportCalendarMultiget, !res.isCalendar() && name == reportAddressMultiget:
+		found, missing, err = h.multiget(res, rpt.Hrefs)
+	default:
+		writePrecondition(w, http.StatusForbidden, `<D:supported-report xmlns:D="DAV:"/>`)
+		return 0, nil
+	}
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// 没有结果时同样返回空的 multistatus
+	if err := mw.writeHeader(); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	pf := rpt.propfind()
+	var writeErr error
+	for i := range found {
+		child := res.child(&found[i])
+		pstats := groupwarePropstats(pf, h.groupwareProps(r, fs.User, child))
+		if writeErr = mw.write(makePropstatResponse(h.hrefOf(child), pstats)); writeErr != nil {
+			break
+		}
+	}
+
+	for _, href := range missing {
+		if writeErr != nil {
+			break
+		}
+		writeErr = mw.write(&response{Href: []string{href}, Status: "HTTP/1.1 404 Not Found"})
+	}
+
+	// 结果被截断时对请求地址返回 507
+	if truncated && writeErr == nil {
+		writeErr = mw.write(&response{
+			Href:   []string{(&url.URL{Path: h.hrefOf(res)}).EscapedPath()},
+			Status: fmt.Sprintf("HTTP/1.1 %d %s", http.StatusInsufficientStorage, StatusText(http.StatusInsufficientStorage)),
+		})
+	}
+
+	closeErr := mw.close()
+	if writeErr != nil {
+		return http.StatusInternalServerError, writeErr
+	}
+	if closeErr != nil {
+		return http.StatusInternalServerError, closeErr
+	}
+	return 0, nil
+}
+
+// multiget 根据地址批量读取集合中的对象，返回找到的对象及不存在的地址
+func (h *Handler) multiget(res *groupwareResource, hrefs []string) ([]model.DavObject, []string, error) {
+	base := h.hrefOf(res)
+	requested := make(map[string]string, len(hrefs))
+	uris := make([]string, 0, len(hrefs))
+	var missing []string
+	for _, href := range hrefs {
+		href = strings.TrimSpace(href)
+		u, err := url.Parse(href)
+		if err != nil || !strings.HasPrefix(u.Path, base) || strings.Contains(strings.TrimPrefix(u.Path, base), "/") {
+			missing = append(missing, href)
+			continue
+		}
+
+		uri := strings.TrimPrefix(u.Path, base)
+		requested[uri] = href
+		uris = append(uris, uri)
+	}
+
+	objects, err := model.GetDavObjectsByURIs(res.collection.ID, uris)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, object := range objects {
+		delete(requested, object.URI)
+	}
+	for _, href := range requested {
+		missing = append(missing, href)
+	}
+
+	return objects, missing, nil
+}
+
+// queryCalendar 列出日历中满足查询条件的对象
+func queryCalendar(collectionID uint, filter *calendarFilter) ([]model.DavObject, error) {
+	if filter == nil {
+		return model.GetDavObjects(collectionID)
+	}
+
+	component, start, end := filter.prefilter()
+	objects, err := model.GetDavObjectsInRange(collectionID, component, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	res := objects[:0]
+	for _, object := range objects {
+		if root, err := parseICal(object.Data); err == nil && filter.match(root) {
+			res = append(res, object)
+		}
+	}
+	return res, nil
+}
+
+// queryAddressBook 列出通讯录中满足查询条件的名片
+func queryAddressBook(collectionID uint, filter *addressFilter) ([]model.DavObject, error) {
+	objects, err := model.GetDavObjects(collectionID)
+	if err != nil || filter == nil {
+		return objects, err
+	}
+
+	res := objects[:0]
+	for _, object := range objects {
+		if root, err := parseICal(object.Data); err == nil && filter.match(root) {
+			res = append(res, object)
+		}
+	}
+	return res, nil
+}
+
+// parseSyncToken 解析同步令牌，空令牌表示首次同步
+func parseSyncToken(token string) (uint64, bool) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return 0, true
+	}
+	if !strings.HasPrefix(token, syncTokenPrefix) {
+		return 0, false
+	}
+
+	revision, err := strconv.ParseUint(strings.TrimPrefix(token, syncTokenPrefix), 10, 64)
+	return revision, err == nil
+}
+
+// checkConditionalHeaders 检查 If-Match 及 If-None-Match 请求头，不满足时返回 412
+func checkConditionalHeaders(r *http.Request, object *model.DavObject) int {
+	if match := r.Header.Get("If-Match"); match != "" {
+		if object == nil || (strings.TrimSpace(match) != "*" && !etagListContains(match, object.Etag)) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if noneMatch := r.Header.Get("If-None-Match"); noneMatch != "" && object != nil {
+		if strings.TrimSpace(noneMatch) == "*" || etagListContains(noneMatch, object.Etag) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	return 0
+}
+
+func etagListContains(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if strings.Trim(tag, `"`) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writePrecondition 以 error 元素返回未满足的前置条件
+func writePrecondition(w http.ResponseWriter, status int, condition string) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><D:error xmlns:D="DAV:">%s</D:error>`, condition)
+}
+
+// ensureDefaultCollection 为尚无日历或通讯录的用户创建默认集合
+func ensureDefaultCollection(uid uint, collectionType string) error {
+	if collectionType == model.DavCalendar {
+		return model.EnsureDefaultDavCollection(uid, collectionType, defaultCollectionURI, "Calendar", defaultCalendarComponents)
+	}
+	return model.EnsureDefaultDavCollection(uid, collectionType, defaultCollectionURI, "Contacts", "")
+}
+
+// groupwareChildren 列出资源的下级资源
+func groupwareChildren(uid uint, res *groupwareResource) ([]*groupwareResource, error) {
+	var children []*groupwareResource
+	switch res.kind {
+	case resourcePrincipal:
+		children = append(children,
+			&groupwareResource{kind: resourceHome, collectionType: model.DavCalendar},
+			&groupwareResource{kind: resourceHome, collectionType: model.DavAddressBook},
+		)
+	case resourceHome:
+		collections, err := model.GetDavCollections(uid, res.collectionType)
+		if err != nil {
+			return nil, err
+		}
+		for i := range collections {
+			children = append(children, &groupwareResource{
+				kind:           resourceCollection,
+				collectionType: res.collectionType,
+				collectionURI:  collections[i].URI,
+				collection:     &collections[i],
+			})
+		}
+	case resourceCollection:
+		objects, err := model.GetDavObjects(res.collection.ID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range objects {
+			children = append(children, res.child(&objects[i]))
+		}
+	}
+
+	return children, nil
+}
+
+// collectionColumn 返回集合可修改属性对应的字段，不可修改时返回空
+func collectionColumn(res *groupwareResource, name xml.Name) string {
+	switch {
+	case name == xml.Name{Space: "DAV:", Local: "displayname"}:
+		return "display_name"
+	case res.isCalendar() && name == xml.Name{Space: nsCalDAV, Local: "calendar-description"},
+		!res.isCalendar() && name == xml.Name{Space: nsCardDAV, Local: "addressbook-description"}:
+		return "description"
+	case res.isCalendar() && name == xml.Name{Space: nsAppleICal, Local: "calendar-color"}:
+		return "color"
+	case res.isCalendar() && name == xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}:
+		return "components"
+	}
+	return ""
+}
+
+var componentNameRegex = regexp.MustCompile(`name="([A-Za-z]+)"`)
+
+// parseComponentSet 解析 supported-calendar-component-set 属性中的组件
+func parseComponentSet(innerXML []byte) string {
+	var components []string
+	for _, match := range componentNameRegex.FindAllStringSubmatch(string(innerXML), -1) {
+		component := strings.ToUpper(match[1])
+		if util.ContainsString(strings.Split(allCalendarComponents, ","), component) &&
+			!util.ContainsString(components, component) {
+			components = append(components, component)
+		}
+	}
+	return strings.Join(components, ",")
+}
+
+// expensiveGroupwareProps 仅在明确请求时返回的属性
+var expensiveGroupwareProps = map[xml.Name]bool{
+	{Space: nsCalDAV, Local: "calendar-data"}: true,
+	{Space: nsCardDAV, Local: "address-data"}: true,
+}
+
+// groupwarePropstats 根据 PROPFIND 请求从资源的全部属性中选取返回的属性
+func groupwarePropstats(pf propfind, available map[xml.Name]string) []Propstat {
+	pstatOK := Propstat{Status: http.StatusOK}
+	pstatNotFound := Propstat{Status: http.StatusNotFound}
+
+	switch {
+	case pf.Propname != nil:
+		for name := range available {
+			pstatOK.Props = append(pstatOK.Props, Property{XMLName: name})
+		}
+	case pf.Allprop != nil:
+		for name, value := range available {
+			if !expensiveGroupwareProps[name] || nameIncluded(pf.Include, name) {
+				pstatOK.Props = append(pstatOK.Props, Property{XMLName: name, InnerXML: []byte(value)})
+			}
+		}
+	default:
+		for _, name := range pf.Prop {
+			if value, ok := available[name]; ok {
+				pstatOK.Props = append(pstatOK.Props, Property{XMLName: name, InnerXML: []byte(value)})
+			} else {
+				pstatNotFound.Props = append(pstatNotFound.Props, Property{XMLName: name})
+			}
+		}
+	}
+
+	return makePropstats(pstatOK, pstatNotFound)
+}
+
+func nameIncluded(names []xml.Name, name xml.Name) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hrefProp(href string) string {
+	return `<D:href xmlns:D="DAV:">` + escape(href) + `</D:href>`
+}
+
+// privilegeSet 返回当前账号对资源拥有的权限
+func privilegeSet(permission Permission) string {
+	privileges := []string{"read", "read-current-user-privilege-set"}
+	if !permission.ReadOnly {
+		privileges = append(privileges, "write", "write-properties", "write-content", "bind")
+		if permission.canDelete() {
+			privileges = append(privileges, "unbind")
+		}
+	}
+
+	var b strings.Builder
+	for _, privilege := range privileges {
+		fmt.Fprintf(&b, `<D:privilege xmlns:D="DAV:"><D:%s/></D:privilege>`, privilege)
+	}
+	return b.String()
+}
+
+// supportedReportSet 返回集合支持的 REPORT
+func supportedReportSet(reports ...xml.Name) string {
+	var b strings.Builder
+	for _, report := range reports {
+		fmt.Fprintf(&b, `<D:supported-report xmlns:D="DAV:"><D:report><R:%s xmlns:R="%s"/></D:report></D:supported-report>`,
+			report.Local, report.Space)
+	}
+	return b.String()
+}
+
+// groupwareProps 返回资源的全部属性
+func (h *Handler) groupwareProps(r *http.Request, user *model.User, res *groupwareResource) map[xml.Name]string {
+	principal := hrefProp(h.PrincipalHref())
+	props := map[xml.Name]string{
+		{Space: "DAV:", Local: "current-user-principal"}:     principal,
+		{Space: "DAV:", Local: "owner"}:                      principal,
+		{Space: "DAV:", Local: "current-user-privilege-set"}: privilegeSet(permissionFromContext(r.Context())),
+	}
+
+	resourceType := xml.Name{Space: "DAV:", Local: "resourcetype"}
+	calendarHome := xml.Name{Space: nsCalDAV, Local: "calendar-home-set"}
+	addressBookHome := xml.Name{Space: nsCardDAV, Local: "addressbook-home-set"}
+
+	switch res.kind {
+	case resourcePrincipal:
+		props[resourceType] = `<D:collection xmlns:D="DAV:"/><D:principal xmlns:D="DAV:"/>`
+		props[xml.Name{Space: "DAV:", Local: "displayname"}] = escape(user.Nick)
+		props[xml.Name{Space: "DAV:", Local: "principal-URL"}] = principal
+		props[calendarHome] = hrefProp(h.homeHref(model.DavCalendar))
+		props[addressBookHome] = hrefProp(h.homeHref(model.DavAddressBook))
+		props[xml.Name{Space: nsCalDAV, Local: "calendar-user-address-set"}] = hrefProp("mailto:" + user.Email)
+	case resourceHome:
+		props[resourceType] = `<D:collection xmlns:D="DAV:"/>`
+		props[calendarHome] = hrefProp(h.homeHref(model.DavCalendar))
+		props[addressBookHome] = hrefProp(h.homeHref(model.DavAddressBook))
+	case resourceCollection:
+		collection := res.collection
+		revision := strconv.FormatUint(collection.Revision, 10)
+		props[xml.Name{Space: "DAV:", Local: "displayname"}] = escape(collection.DisplayName)
+		props[xml.Name{Space: nsCalendarServer, Local: "getctag"}] = revision
+		props[xml.Name{Space: "DAV:", Local: "sync-token"}] = escape(syncTokenPrefix + revision)
+
+		if res.isCalendar() {
+			props[resourceType] = `<D:collection xmlns:D="DAV:"/><C:calendar xmlns:C="` + nsCalDAV + `"/>`
+			props[xml.Name{Space: nsCalDAV, Local: "calendar-description"}] = escape(collection.Description)
+			if collection.Color != "" {
+				props[xml.Name{Space: nsAppleICal, Local: "calendar-color"}] = escape(collection.Color)
+			}
+
+			var components strings.Builder
+			for _, component := range res.components() {
+				fmt.Fprintf(&components, `<C:comp xmlns:C="%s" name="%s"/>`, nsCalDAV, component)
+			}
+			props[xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}] = components.String()
+			props[xml.Name{Space: "DAV:", Local: "supported-report-set"}] = supportedReportSet(
+				reportCalendarQuery, reportCalendarMultiget, reportSyncCollection)
+		} else {
+			props[resourceType] = `<D:collection xmlns:D="DAV:"/><C:addressbook xmlns:C="` + nsCardDAV + `"/>`
+			props[xml.Name{Space: nsCardDAV, Local: "addressbook-description"}] = escape(collection.Description)
+			props[xml.Name{Space: "DAV:", Local: "supported-report-set"}] = supportedReportSet(
+				reportAddressQuery, reportAddressMultiget, reportSyncCollection)
+		}
+	case resourceObject:
+		object := res.object
+		props[resourceType] = ""
+		props[xml.Name{Space: "DAV:", Local: "getetag"}] = escape(res.etag())
+		props[xml.Name{Space: "DAV:", Local: "getcontenttype"}] = escape(res.contentType())
+		props[xml.Name{Space: "DAV:", Local: "getcontentlength"}] = strconv.Itoa(len(object.Data))
+		props[xml.Name{Space: "DAV:", Local: "getlastmodified"}] = object.UpdatedAt.UTC().Format(http.TimeFormat)
+		if res.isCalendar() {
+			props[xml.Name{Space: nsCalDAV, Local: "calendar-data"}] = escape(object.Data)
+		} else {
+			props[xml.Name{Space: nsCardDAV, Local: "address-data"}] = escape(object.Data)
+		}
+	}
+
+	return props
+}
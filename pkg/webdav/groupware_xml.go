@@ -0,0 +1,333 @@
+package webdav
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	ixml "github.com/cloudreve/Cloudreve/v3/pkg/webdav/internal/xml"
+)
+
+// CalDAV (RFC 4791)、CardDAV (RFC 6352) 及 WebDAV 同步 (RFC 6578) 使用的 XML 元素
+
+const (
+	nsCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	nsCardDAV        = "urn:ietf:params:xml:ns:carddav"
+	nsCalendarServer = "http://calendarserver.org/ns/"
+	nsAppleICal      = "http://apple.com/ns/ical/"
+)
+
+var (
+	reportCalendarQuery    = xml.Name{Space: nsCalDAV, Local: "calendar-query"}
+	reportCalendarMultiget = xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}
+	reportAddressQuery     = xml.Name{Space: nsCardDAV, Local: "addressbook-query"}
+	reportAddressMultiget  = xml.Name{Space: nsCardDAV, Local: "addressbook-multiget"}
+	reportSyncCollection   = xml.Name{Space: "DAV:", Local: "sync-collection"}
+)
+
+// reportProps REPORT 请求中的属性名。与 PROPFIND 不同，calendar-data 等属性
+// 可包含指定返回内容的子元素，此处忽略子元素并返回完整内容
+type reportProps []xml.Name
+
+func (pn *reportProps) UnmarshalXML(d *ixml.Decoder, start ixml.StartElement) error {
+	for {
+		t, err := next(d)
+		if err != nil {
+			return err
+		}
+		switch elem := t.(type) {
+		case ixml.EndElement:
+			return nil
+		case ixml.StartElement:
+			*pn = append(*pn, xml.Name(elem.Name))
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// report REPORT 请求体
+type report struct {
+	XMLName        ixml.Name
+	Allprop        *struct{}       `xml:"DAV: allprop"`
+	Prop           reportProps     `xml:"DAV: prop"`
+	Hrefs          []string        `xml:"DAV: href"`
+	CalendarFilter *calendarFilter `xml:"urn:ietf:params:xml:ns:caldav filter"`
+	AddressFilter  *addressFilter  `xml:"urn:ietf:params:xml:ns:carddav filter"`
+	SyncToken      string          `xml:"DAV: sync-token"`
+	SyncLevel      string          `xml:"DAV: sync-level"`
+	Limit          *reportLimit    `xml:"limit"`
+}
+
+// reportLimit 返回结果数量上限
+type reportLimit struct {
+	NResults int `xml:"nresults"`
+}
+
+// propfind 将 REPORT 请求的属性转换为 PROPFIND 形式
+func (r *report) propfind() propfind {
+	if r.Allprop != nil || len(r.Prop) == 0 {
+		return propfind{Allprop: new(struct{})}
+	}
+	return propfind{Prop: propfindProps(r.Prop)}
+}
+
+func readReport(body io.Reader) (rpt report, status int, err error) {
+	if err = ixml.NewDecoder(body).Decode(&rpt); err != nil {
+		return report{}, http.StatusBadRequest, err
+	}
+	return rpt, 0, nil
+}
+
+// calendarFilter CalDAV 查询条件
+type calendarFilter struct {
+	CompFilter compFilter `xml:"comp-filter"`
+}
+
+// compFilter 组件过滤条件
+type compFilter struct {
+	Name         string       `xml:"name,attr"`
+	IsNotDefined *struct{}    `xml:"is-not-defined"`
+	TimeRange    *timeRange   `xml:"time-range"`
+	PropFilters  []propFilter `xml:"prop-filter"`
+	CompFilters  []compFilter `xml:"comp-filter"`
+}
+
+// timeRange 时间范围，起止时间为空表示不限制
+type timeRange struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+// addressFilter CardDAV 查询条件
+type addressFilter struct {
+	Test        string       `xml:"test,attr"`
+	PropFilters []propFilter `xml:"prop-filter"`
+}
+
+// propFilter 属性过滤条件
+type propFilter struct {
+	Name         string      `xml:"name,attr"`
+	Test         string      `xml:"test,attr"`
+	IsNotDefined *struct{}   `xml:"is-not-defined"`
+	TextMatches  []textMatch `xml:"text-match"`
+}
+
+// textMatch 文本匹配条件
+type textMatch struct {
+	Collation       string `xml:"collation,attr"`
+	NegateCondition string `xml:"negate-condition,attr"`
+	MatchType       string `xml:"match-type,attr"`
+	Value           string `xml:",chardata"`
+}
+
+// parse 返回时间范围的起止时间
+func (r *timeRange) parse() (start, end *time.Time, err error) {
+	if r.Start != "" {
+		t, err := time.Parse("20060102T150405Z", r.Start)
+		if err != nil {
+			return nil, nil, err
+		}
+		start = &t
+	}
+	if r.End != "" {
+		t, err := time.Parse("20060102T150405Z", r.End)
+		if err != nil {
+			return nil, nil, err
+		}
+		end = &t
+	}
+	return start, end, nil
+}
+
+// prefilter 返回查询条件中可在数据库中预先筛选的组件类型及时间范围
+func (f *calendarFilter) prefilter() (component string, start, end *time.Time) {
+	if len(f.CompFilter.CompFilters) != 1 {
+		return "", nil, nil
+	}
+
+	child := f.CompFilter.CompFilters[0]
+	if child.IsNotDefined != nil {
+		return "", nil, nil
+	}
+
+	if child.TimeRange != nil {
+		start, end, _ = child.TimeRange.parse()
+	}
+	return child.Name, start, end
+}
+
+// match 返回日历对象是否满足查询条件
+func (f *calendarFilter) match(root *icalComponent) bool {
+	if f.CompFilter.IsNotDefined != nil || root.Name != f.CompFilter.Name {
+		return false
+	}
+	return f.CompFilter.match(root)
+}
+
+// match 返回已按名称匹配的组件是否满足其余条件
+func (f *compFilter) match(component *icalComponent) bool {
+	if f.TimeRange != nil {
+		start, end, err := f.TimeRange.parse()
+		if err != nil || !overlaps(component, start, end) {
+			return false
+		}
+	}
+
+	for i := range f.PropFilters {
+		if !f.PropFilters[i].match(component) {
+			return false
+		}
+	}
+
+	for i := range f.CompFilters {
+		child := &f.CompFilters[i]
+		matched, defined := false, false
+		for _, c := range component.Components {
+			if c.Name != child.Name {
+				continue
+			}
+			defined = true
+			if child.IsNotDefined == nil && child.match(c) {
+				matched = true
+				break
+			}
+		}
+
+		if child.IsNotDefined != nil {
+			matched = !defined
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// overlaps 返回组件是否与时间范围重叠，无法确定组件时间时视为重叠
+func overlaps(component *icalComponent, start, end *time.Time) bool {
+	s, e := componentTimeRange(component)
+	if s == nil {
+		return true
+	}
+
+	if end != nil && !s.Before(*end) {
+		return false
+	}
+	// 无持续时间的组件起始于范围开始时刻时同样视为重叠
+	if start != nil && e != nil && !e.After(*start) && !(e.Equal(*s) && s.Equal(*start)) {
+		return false
+	}
+	return true
+}
+
+// match 返回名片是否满足查询条件，未指定条件时匹配任意名片
+func (f *addressFilter) match(card *icalComponent) bool {
+	if len(f.PropFilters) == 0 {
+		return true
+	}
+
+	allof := f.Test == "allof"
+	for i := range f.PropFilters {
+		matched := f.PropFilters[i].match(card)
+		if allof && !matched {
+			return false
+		}
+		if !allof && matched {
+			return true
+		}
+	}
+	return allof
+}
+
+// match 返回组件的属性是否满足条件，多个文本匹配条件默认满足任一即可
+func (f *propFilter) match(component *icalComponent) bool {
+	name := strings.ToUpper(f.Name)
+	var values []string
+	for _, prop := range component.Props {
+		if prop.Name == name {
+			values = append(values, prop.Value)
+		}
+	}
+
+	if f.IsNotDefined != nil {
+		return len(values) == 0
+	}
+	if len(values) == 0 {
+		return false
+	}
+	if len(f.TextMatches) == 0 {
+		return true
+	}
+
+	allof := f.Test == "allof"
+	for i := range f.TextMatches {
+		matched := false
+		for _, value := range values {
+			if f.TextMatches[i].match(value) {
+				matched = true
+				break
+			}
+		}
+		if allof && !matched {
+			return false
+		}
+		if !allof && matched {
+			return true
+		}
+	}
+	return allof
+}
+
+func (m *textMatch) match(value string) bool {
+	res := matchText(value, strings.TrimSpace(m.Value), m.Collation, m.MatchType)
+	if m.NegateCondition == "yes" {
+		return !res
+	}
+	return res
+}
+
+// mkcol 扩展 MKCOL (RFC 5689) 及 MKCALENDAR 的请求体
+type mkcol struct {
+	XMLName ixml.Name
+	Set     []setRemove `xml:"DAV: set"`
+}
+
+func readMkcol(body io.Reader) (props []Property, status int, err error) {
+	c := countingReader{r: body}
+	var req mkcol
+	if err = ixml.NewDecoder(&c).Decode(&req); err != nil {
+		if err == io.EOF && c.n == 0 {
+			// 请求体为空时不设定属性
+			return nil, 0, nil
+		}
+		return nil, http.StatusBadRequest, err
+	}
+
+	for _, set := range req.Set {
+		props = append(props, set.Prop...)
+	}
+	return props, 0, nil
+}
+
+// xmlText 返回属性值中的文本内容
+func xmlText(innerXML []byte) string {
+	var (
+		b strings.Builder
+		d = ixml.NewDecoder(strings.NewReader(string(innerXML)))
+	)
+	for {
+		t, err := d.Token()
+		if err != nil {
+			break
+		}
+		if data, ok := t.(ixml.CharData); ok {
+			b.Write(data)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,323 @@
+package webdav
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// iCalendar (RFC 5545) 与 vCard (RFC 6350) 共用的内容行格式，仅解析 CalDAV / CardDAV 查询需要的部分
+
+var errInvalidICal = errors.New("webdav: invalid calendar or vcard data")
+
+// icalProperty 内容行
+type icalProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// icalComponent 以 BEGIN / END 包围的组件
+type icalComponent struct {
+	Name       string
+	Props      []icalProperty
+	Components []*icalComponent
+}
+
+// prop 返回组件中第一个给定名称的属性
+func (c *icalComponent) prop(name string) *icalProperty {
+	for i := range c.Props {
+		if c.Props[i].Name == name {
+			return &c.Props[i]
+		}
+	}
+	return nil
+}
+
+// parseICal 解析日历或名片数据，返回顶层组件
+func parseICal(data string) (*icalComponent, error) {
+	// 展开折叠的内容行
+	data = strings.NewReplacer("\r\n ", "", "\r\n\t", "", "\n ", "", "\n\t", "").Replace(data)
+
+	var (
+		root  *icalComponent
+		stack []*icalComponent
+	)
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+
+		prop, ok := parseICalLine(line)
+		if !ok {
+			return nil, errInvalidICal
+		}
+
+		switch prop.Name {
+		case "BEGIN":
+			component := &icalComponent{Name: strings.ToUpper(prop.Value)}
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, errInvalidICal
+				}
+				root = component
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, component)
+			}
+			stack = append(stack, component)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(prop.Value) {
+				return nil, errInvalidICal
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, errInvalidICal
+			}
+			current := stack[len(stack)-1]
+			current.Props = append(current.Props, prop)
+		}
+	}
+
+	if root == nil || len(stack) > 0 {
+		return nil, errInvalidICal
+	}
+
+	return root, nil
+}
+
+// parseICalLine 解析 name;param=value:value 格式的内容行
+func parseICalLine(line string) (icalProperty, bool) {
+	prop := icalProperty{}
+
+	// 参数值可用引号包围，其中可能包含冒号和分号
+	quoted, sep := false, -1
+	for i := 0; i < len(line); i++ {
+		if line[i] == '"' {
+			quoted = !quoted
+		} else if line[i] == ':' && !quoted {
+			sep = i
+			break
+		}
+	}
+	if sep <= 0 {
+		return prop, false
+	}
+
+	prop.Value = line[sep+1:]
+	params := splitICalParams(line[:sep])
+	prop.Name = strings.ToUpper(params[0])
+	// vCard 属性可带分组前缀，如 item1.EMAIL
+	if dot := strings.LastIndex(prop.Name, "."); dot >= 0 {
+		prop.Name = prop.Name[dot+1:]
+	}
+
+	for _, param := range params[1:] {
+		if eq := strings.Index(param, "="); eq > 0 {
+			if prop.Params == nil {
+				prop.Params = make(map[string]string)
+			}
+			prop.Params[strings.ToUpper(param[:eq])] = strings.Trim(param[eq+1:], `"`)
+		}
+	}
+
+	return prop, prop.Name != ""
+}
+
+func splitICalParams(s string) []string {
+	var (
+		res    []string
+		quoted bool
+		start  int
+	)
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' {
+			quoted = !quoted
+		} else if s[i] == ';' && !quoted {
+			res = append(res, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(res, s[start:])
+}
+
+// calendarObject 从日历对象中提取的索引信息
+type calendarObject struct {
+	Component string
+	UID       string
+	Start     *time.Time
+	End       *time.Time
+}
+
+// inspectCalendar 检查日历对象并提取索引信息。RFC 4791 要求一个日历对象中的
+// 组件类型及 UID 相同（时区定义除外）
+func inspectCalendar(root *icalComponent) (*calendarObject, error) {
+	if root.Name != "VCALENDAR" {
+		return nil, errInvalidICal
+	}
+
+	var (
+		res                *calendarObject
+		unknown, unbounded bool
+	)
+	for _, component := range root.Components {
+		if component.Name == "VTIMEZONE" {
+			continue
+		}
+
+		uid := component.prop("UID")
+		if uid == nil || uid.Value == "" {
+			return nil, errInvalidICal
+		}
+
+		if res == nil {
+			res = &calendarObject{Component: component.Name, UID: uid.Value}
+		} else if res.Component != component.Name || res.UID != uid.Value {
+			return nil, errInvalidICal
+		}
+
+		// 各组件（如重复日程的例外）时间范围的并集
+		start, end := componentTimeRange(component)
+		if start == nil {
+			unknown = true
+			continue
+		}
+		if res.Start == nil || start.Before(*res.Start) {
+			res.Start = start
+		}
+		if end == nil {
+			unbounded = true
+		} else if res.End == nil || end.After(*res.End) {
+			res.End = end
+		}
+	}
+
+	if res != nil && unknown {
+		res.Start, res.End = nil, nil
+	} else if res != nil && unbounded {
+		res.End = nil
+	}
+
+	if res == nil {
+		return nil, errInvalidICal
+	}
+
+	return res, nil
+}
+
+// componentTimeRange 返回组件的起止时间。无法确定起始时间时均返回空；
+// 重复组件的结束时间返回空，表示可能与之后的任意时间范围重叠
+func componentTimeRange(component *icalComponent) (start, end *time.Time) {
+	dtstart := component.prop("DTSTART")
+	if dtstart == nil {
+		return nil, nil
+	}
+
+	s, isDate, err := parseICalTime(dtstart)
+	if err != nil {
+		return nil, nil
+	}
+	start = &s
+
+	if component.prop("RRULE") != nil || component.prop("RDATE") != nil {
+		return start, nil
+	}
+
+	var e time.Time
+	if dtend := component.prop("DTEND"); dtend != nil {
+		if e, _, err = parseICalTime(dtend); err != nil {
+			return start, nil
+		}
+	} else if due := component.prop("DUE"); due != nil {
+		if e, _, err = parseICalTime(due); err != nil {
+			return start, nil
+		}
+	} else if duration := component.prop("DURATION"); duration != nil {
+		d, err := parseICalDuration(duration.Value)
+		if err != nil {
+			return start, nil
+		}
+		e = s.Add(d)
+	} else if isDate {
+		// 全天日程默认持续一天
+		e = s.AddDate(0, 0, 1)
+	} else {
+		e = s
+	}
+
+	return start, &e
+}
+
+// parseICalTime 解析日期或日期时间属性，未指定时区时按 UTC 处理
+func parseICalTime(prop *icalProperty) (time.Time, bool, error) {
+	value := prop.Value
+	if prop.Params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.Parse("20060102", value)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+
+	loc := time.UTC
+	if tzid, ok := prop.Params["TZID"]; ok {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t.UTC(), false, err
+}
+
+var icalDurationRegex = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseICalDuration 解析 RFC 5545 时长，如 P1DT2H
+func parseICalDuration(value string) (time.Duration, error) {
+	match := icalDurationRegex.FindStringSubmatch(value)
+	if match == nil {
+		return 0, errInvalidICal
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var res time.Duration
+	for i, unit := range units {
+		if match[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(match[i+2])
+		if err != nil {
+			return 0, err
+		}
+		res += time.Duration(n) * unit
+	}
+
+	if match[1] == "-" {
+		res = -res
+	}
+	return res, nil
+}
+
+// matchText 按 RFC 4790 排序规则匹配文本，matchType 为空时视为包含
+func matchText(value, pattern, collation, matchType string) bool {
+	if collation != "i;octet" {
+		value, pattern = strings.ToLower(value), strings.ToLower(pattern)
+	}
+
+	switch matchType {
+	case "equals":
+		return value == pattern
+	case "starts-with":
+		return strings.HasPrefix(value, pattern)
+	case "ends-with":
+		return strings.HasSuffix(value, pattern)
+	default:
+		return strings.Contains(value, pattern)
+	}
+}
@@ -0,0 +1,218 @@
+package webdav
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// lockHoldTimeout 单个请求占用锁的最长时间，超时后视为请求已中断
+	lockHoldTimeout = 30 * time.Minute
+	// lockMutexTTL 创建锁时互斥租约的有效期
+	lockMutexTTL = 10 * time.Second
+	// lockMutexRetry 等待互斥租约的重试次数及间隔
+	lockMutexRetry    = 50
+	lockMutexInterval = 100 * time.Millisecond
+)
+
+// NewDBLS 返回基于数据库的 LockSystem，锁在多个实例间共享并在重启后保留。
+// root 为 WebDAV 根目录在用户文件系统中的完整路径，锁以完整路径保存，
+// 以便不同根目录的 WebDAV 账号及网页端操作共同遵守
+func NewDBLS(uid uint, root string) LockSystem {
+	return &dbLS{uid: uid, root: slashClean(root)}
+}
+
+type dbLS struct {
+	uid  uint
+	root string
+}
+
+// fullPath 将 WebDAV 路径转换为用户文件系统中的完整路径
+func (m *dbLS) fullPath(name string) string {
+	return path.Join(m.root, slashClean(name))
+}
+
+// relativePath 将用户文件系统中的完整路径转换为 WebDAV 路径
+func (m *dbLS) relativePath(name string) string {
+	if m.root == "/" {
+		return name
+	}
+
+	return slashClean(strings.TrimPrefix(name, m.root))
+}
+
+func (m *dbLS) details(lock *model.WebdavLock) LockDetails {
+	return LockDetails{
+		Root:      m.relativePath(lock.Root),
+		Duration:  lock.Duration,
+		OwnerXML:  lock.OwnerXML,
+		ZeroDepth: lock.ZeroDepth,
+	}
+}
+
+// mutex 取得当前用户创建锁的互斥租约，避免多个实例同时创建相互冲突的锁
+func (m *dbLS) mutex() (func(), error) {
+	name := fmt.Sprintf("webdav_lock_%d", m.uid)
+	holder := util.RandStringRunes(16)
+	for i := 0; i < lockMutexRetry; i++ {
+		ok, err := model.AcquireLease(name, holder, lockMutexTTL)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			return func() {
+				model.ReleaseLease(name, holder)
+			}, nil
+		}
+
+		time.Sleep(lockMutexInterval)
+	}
+
+	return nil, ErrLocked
+}
+
+func (m *dbLS) Confirm(now time.Time, name0, name1 string, conditions ...Condition) (func(), error) {
+	var n0, n1 *model.WebdavLock
+	if name0 != "" {
+		if n0 = m.lookup(now, m.fullPath(name0), conditions...); n0 == nil {
+			return nil, ErrConfirmationFailed
+		}
+	}
+	if name1 != "" {
+		if n1 = m.lookup(now, m.fullPath(name1), conditions...); n1 == nil {
+			return nil, ErrConfirmationFailed
+		}
+	}
+
+	// 不重复占用同一个锁
+	if n0 != nil && n1 != nil && n0.Token == n1.Token {
+		n1 = nil
+	}
+
+	until := now.Add(lockHoldTimeout)
+	held := make([]*model.WebdavLock, 0, 2)
+	release := func() {
+		for _, lock := range held {
+			if err := lock.Unhold(); err != nil {
+				util.Log().Warning("无法释放 WebDAV 锁 [%s]，%s", lock.Token, err)
+			}
+		}
+	}
+
+	for _, lock := range []*model.WebdavLock{n0, n1} {
+		if lock == nil {
+			continue
+		}
+
+		ok, err := lock.Hold(now, until)
+		if err != nil {
+			release()
+			return nil, err
+		}
+
+		// 锁已被其他请求占用
+		if !ok {
+			release()
+			return nil, ErrConfirmationFailed
+		}
+		held = append(held, lock)
+	}
+
+	return release, nil
+}
+
+// lookup 返回作用于 name 且与任一条件匹配、未被占用的锁，不存在时返回 nil
+func (m *dbLS) lookup(now time.Time, name string, conditions ...Condition) *model.WebdavLock {
+	// TODO: 支持 Condition.Not 及 Condition.ETag
+	for _, c := range conditions {
+		if c.Token == "" {
+			continue
+		}
+
+		lock, err := model.GetWebdavLockByToken(c.Token, m.uid, now)
+		if err != nil || lock.IsHeld(now) {
+			continue
+		}
+
+		if lock.Covers(name) {
+			return lock
+		}
+	}
+
+	return nil
+}
+
+func (m *dbLS) Create(now time.Time, details LockDetails) (string, error) {
+	unlock, err := m.mutex()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	name := m.fullPath(details.Root)
+	locks, err := model.GetWebdavLocks(m.uid, now)
+	if err != nil {
+		return "", err
+	}
+
+	for _, lock := range locks {
+		// 目标对象或其上级目录已被锁定，或请求锁定整个目录而其下已有对象被锁定
+		if lock.Covers(name) || (!details.ZeroDepth && lock.Within(name)) {
+			return "", ErrLocked
+		}
+	}
+
+	lock := &model.WebdavLock{
+		Token:     "opaquelocktoken:" + uuid.Must(uuid.NewV4()).String(),
+		UserID:    m.uid,
+		Root:      name,
+		ZeroDepth: details.ZeroDepth,
+		OwnerXML:  details.OwnerXML,
+		Duration:  details.Duration,
+	}
+	if details.Duration >= 0 {
+		expires := now.Add(details.Duration)
+		lock.ExpiresAt = &expires
+	}
+
+	if err := lock.Create(); err != nil {
+		return "", err
+	}
+
+	return lock.Token, nil
+}
+
+func (m *dbLS) Refresh(now time.Time, token string, duration time.Duration) (LockDetails, error) {
+	lock, err := model.GetWebdavLockByToken(token, m.uid, now)
+	if err != nil {
+		return LockDetails{}, ErrNoSuchLock
+	}
+	if lock.IsHeld(now) {
+		return LockDetails{}, ErrLocked
+	}
+
+	if err := lock.Refresh(now, duration); err != nil {
+		return LockDetails{}, err
+	}
+
+	return m.details(lock), nil
+}
+
+func (m *dbLS) Unlock(now time.Time, token string) error {
+	lock, err := model.GetWebdavLockByToken(token, m.uid, now)
+	if err != nil {
+		return ErrNoSuchLock
+	}
+	if lock.IsHeld(now) {
+		return ErrLocked
+	}
+
+	return lock.Delete()
+}
@@ -0,0 +1,38 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+)
+
+var errPermissionDenied = errors.New("webdav: permission denied")
+
+// Permission WebDAV 账号的操作限制
+type Permission struct {
+	ReadOnly   bool // 只读
+	NoDelete   bool // 禁止删除及覆盖已有对象
+	UploadOnly bool // 仅上传，禁止下载及删除、覆盖、移动已有对象
+}
+
+type permissionKey struct{}
+
+// WithPermission 返回携带操作限制的上下文，处理请求时据此拒绝不允许的操作
+func WithPermission(ctx context.Context, permission Permission) context.Context {
+	return context.WithValue(ctx, permissionKey{}, permission)
+}
+
+// permissionFromContext 读取请求的操作限制，未设定时不限制
+func permissionFromContext(ctx context.Context) Permission {
+	permission, _ := ctx.Value(permissionKey{}).(Permission)
+	return permission
+}
+
+// canDelete 是否允许删除对象
+func (p Permission) canDelete() bool {
+	return !p.ReadOnly && !p.NoDelete && !p.UploadOnly
+}
+
+// canOverwrite 是否允许覆盖已有对象
+func (p Permission) canOverwrite() bool {
+	return p.canDelete()
+}
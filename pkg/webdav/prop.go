@@ -14,8 +14,10 @@ import (
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"sync"
 	"time"
 
+	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 )
 
@@ -115,6 +117,8 @@ var liveProps = map[xml.Name]struct {
 	findFn func(context.Context, *filesystem.FileSystem, LockSystem, string, FileInfo) (string, error)
 	// dir is true if the property applies to directories.
 	dir bool
+	// optional 为 true 时属性计算开销较大，仅在客户端明确请求时返回，不包含在 allprop 中
+	optional bool
 }{
 	{Space: "DAV:", Local: "resourcetype"}: {
 		findFn: findResourceType,
@@ -167,6 +171,25 @@ var liveProps = map[xml.Name]struct {
 		findFn: findSupportedLock,
 		dir:    true,
 	},
+
+	// RFC 5397 当前用户的 CalDAV / CardDAV 主体，供客户端发现日历及通讯录
+	{Space: "DAV:", Local: "current-user-principal"}: {
+		findFn:   findCurrentUserPrincipal,
+		dir:      true,
+		optional: true,
+	},
+
+	// RFC 4331 配额属性，以用户容量计算
+	{Space: "DAV:", Local: "quota-available-bytes"}: {
+		findFn:   findQuotaAvailableBytes,
+		dir:      true,
+		optional: true,
+	},
+	{Space: "DAV:", Local: "quota-used-bytes"}: {
+		findFn:   findQuotaUsedBytes,
+		dir:      true,
+		optional: true,
+	},
 }
 
 // TODO(nigeltao) merge props and allprop?
@@ -176,10 +199,24 @@ var liveProps = map[xml.Name]struct {
 // Each Propstat has a unique status and each property name will only be part
 // of one Propstat element.
 func props(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi FileInfo, pnames []xml.Name) ([]Propstat, error) {
-	isDir := fi.IsDir()
-
+	// 仅在请求了非活属性时读取自定义属性
 	var deadProps map[xml.Name]Property
+	for _, pn := range pnames {
+		if _, ok := liveProps[pn]; !ok {
+			var err error
+			if deadProps, err = loadDeadProps(fi); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	return findProps(ctx, fs, ls, fi, pnames, deadProps)
+}
 
+// findProps 在已读取的自定义属性 deadProps 及活属性中查找 pnames
+func findProps(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi FileInfo, pnames []xml.Name, deadProps map[xml.Name]Property) ([]Propstat, error) {
+	isDir := fi.IsDir()
 	pstatOK := Propstat{Status: http.StatusOK}
 	pstatNotFound := Propstat{Status: http.StatusNotFound}
 	for _, pn := range pnames {
@@ -191,6 +228,12 @@ func props(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi Fil
 		// Otherwise, it must either be a live property or we don't know it.
 		if prop := liveProps[pn]; prop.findFn != nil && (prop.dir || !isDir) {
 			innerXML, err := prop.findFn(ctx, fs, ls, fi.GetName(), fi)
+			if err == errPropNotFound {
+				pstatNotFound.Props = append(pstatNotFound.Props, Property{
+					XMLName: pn,
+				})
+				continue
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -209,17 +252,27 @@ func props(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi Fil
 
 // Propnames returns the property names defined for resource name.
 func propnames(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi FileInfo) ([]xml.Name, error) {
-	isDir := fi.IsDir()
+	deadProps, err := loadDeadProps(fi)
+	if err != nil {
+		return nil, err
+	}
 
-	var deadProps map[xml.Name]Property
+	return namesOf(fi, deadProps), nil
+}
 
+// namesOf 返回对象的活属性名称及已读取的自定义属性 deadProps 的名称
+func namesOf(fi FileInfo, deadProps map[xml.Name]Property) []xml.Name {
+	isDir := fi.IsDir()
 	pnames := make([]xml.Name, 0, len(liveProps)+len(deadProps))
 	for pn, prop := range liveProps {
 		if prop.findFn != nil && (prop.dir || !isDir) {
 			pnames = append(pnames, pn)
 		}
 	}
-	return pnames, nil
+	for pn := range deadProps {
+		pnames = append(pnames, pn)
+	}
+	return pnames
 }
 
 // Allprop returns the properties defined for resource name and the properties
@@ -231,10 +284,22 @@ func propnames(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi
 //
 // See http://www.webdav.org/specs/rfc4918.html#METHOD_PROPFIND
 func allprop(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, info FileInfo, include []xml.Name) ([]Propstat, error) {
-	pnames, err := propnames(ctx, fs, ls, info)
+	// 自定义属性只读取一次，同时用于列出名称和查找属性
+	deadProps, err := loadDeadProps(info)
 	if err != nil {
 		return nil, err
 	}
+
+	pnames := namesOf(info, deadProps)
+	// 开销较大的属性仅在 include 中明确请求时返回
+	filtered := pnames[:0]
+	for _, pn := range pnames {
+		if !liveProps[pn].optional {
+			filtered = append(filtered, pn)
+		}
+	}
+	pnames = filtered
+
 	// Add names from include if they are not already covered in pnames.
 	nameset := make(map[xml.Name]bool)
 	for _, pn := range pnames {
@@ -245,12 +310,12 @@ func allprop(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, info
 			pnames = append(pnames, pn)
 		}
 	}
-	return props(ctx, fs, ls, info, pnames)
+	return findProps(ctx, fs, ls, info, pnames, deadProps)
 }
 
 // Patch patches the properties of resource name. The return values are
 // constrained in the same manner as DeadPropsHolder.Patch.
-func patch(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, name string, patches []Proppatch) ([]Propstat, error) {
+func patch(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi FileInfo, patches []Proppatch) ([]Propstat, error) {
 	conflict := false
 loop:
 	for _, patch := range patches {
@@ -281,17 +346,122 @@ loop:
 		return makePropstats(pstatForbidden, pstatFailedDep), nil
 	}
 
-	// The file doesn't implement the optional DeadPropsHolder interface, so
-	// all patches are forbidden.
+	isDir, id, ok := objectOf(fi)
+	if !ok {
+		return nil, errNotAnObject
+	}
+
 	pstat := Propstat{Status: http.StatusOK}
+	deadPatches := make([]model.DeadPropertyPatch, 0, len(patches))
 	for _, patch := range patches {
 		for _, p := range patch.Props {
 			pstat.Props = append(pstat.Props, Property{XMLName: p.XMLName})
+			deadPatches = append(deadPatches, model.DeadPropertyPatch{
+				Remove: patch.Remove,
+				Property: model.DeadProperty{
+					Namespace: p.XMLName.Space,
+					Name:      p.XMLName.Local,
+					Lang:      p.Lang,
+					InnerXML:  string(p.InnerXML),
+				},
+			})
 		}
 	}
+
+	if err := model.PatchDeadProperties(fs.User.ID, isDir, id, deadPatches); err != nil {
+		return nil, err
+	}
 	return []Propstat{pstat}, nil
 }
 
+// objectOf 返回 fi 对应的对象类型及ID
+func objectOf(fi FileInfo) (isDir bool, id uint, ok bool) {
+	switch object := fi.(type) {
+	case *model.File:
+		return false, object.ID, true
+	case *model.Folder:
+		return true, object.ID, true
+	}
+	return false, 0, false
+}
+
+// copyDeadProps 将 src 及其下对象的自定义属性复制到复制出的 dst 中对应的对象，
+// 对象按名称对应，见 RFC 4918 9.8.2
+func copyDeadProps(fs *filesystem.FileSystem, src, dst FileInfo) error {
+	files := make(map[uint]uint)
+	folders := make(map[uint]uint)
+	pairCopiedObjects(src, dst, files, folders)
+
+	if err := model.CopyDeadProperties(fs.User.ID, false, files); err != nil {
+		return err
+	}
+	return model.CopyDeadProperties(fs.User.ID, true, folders)
+}
+
+// pairCopiedObjects 递归记录源对象与复制出的对象的ID
+func pairCopiedObjects(src, dst FileInfo, files, folders map[uint]uint) {
+	switch object := src.(type) {
+	case *model.File:
+		if copied, ok := dst.(*model.File); ok {
+			files[object.ID] = copied.ID
+		}
+	case *model.Folder:
+		copied, ok := dst.(*model.Folder)
+		if !ok {
+			return
+		}
+		folders[object.ID] = copied.ID
+
+		srcFiles, _ := object.GetChildFiles()
+		dstFiles, _ := copied.GetChildFiles()
+		copiedFiles := make(map[string]*model.File, len(dstFiles))
+		for i := range dstFiles {
+			copiedFiles[dstFiles[i].Name] = &dstFiles[i]
+		}
+		for i := range srcFiles {
+			if file, ok := copiedFiles[srcFiles[i].Name]; ok {
+				files[srcFiles[i].ID] = file.ID
+			}
+		}
+
+		srcFolders, _ := object.GetChildFolder()
+		dstFolders, _ := copied.GetChildFolder()
+		copiedFolders := make(map[string]*model.Folder, len(dstFolders))
+		for i := range dstFolders {
+			copiedFolders[dstFolders[i].Name] = &dstFolders[i]
+		}
+		for i := range srcFolders {
+			if folder, ok := copiedFolders[srcFolders[i].Name]; ok {
+				pairCopiedObjects(&srcFolders[i], folder, files, folders)
+			}
+		}
+	}
+}
+
+// loadDeadProps 读取对象的自定义属性
+func loadDeadProps(fi FileInfo) (map[xml.Name]Property, error) {
+	isDir, id, ok := objectOf(fi)
+	if !ok {
+		return nil, nil
+	}
+
+	props, err := model.GetDeadProperties(isDir, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[xml.Name]Property, len(props))
+	for _, prop := range props {
+		name := xml.Name{Space: prop.Namespace, Local: prop.Name}
+		res[name] = Property{
+			XMLName:  name,
+			Lang:     prop.Lang,
+			InnerXML: []byte(prop.InnerXML),
+		}
+	}
+	return res, nil
+}
+
 func escapeXML(s string) string {
 	for i := 0; i < len(s); i++ {
 		// As an optimization, if s contains only ASCII letters, digits or a
@@ -405,9 +575,68 @@ type ETager interface {
 }
 
 func findETag(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, reqPath string, fi FileInfo) (string, error) {
+	// 文件内容变化时 ETag 随之改变，移动或重命名不影响 ETag
+	if file, ok := fi.(*model.File); ok {
+		return fmt.Sprintf(`"%s"`, file.ContentHash()), nil
+	}
 	return fmt.Sprintf(`"%x%x"`, fi.ModTime().UnixNano(), fi.GetSize()), nil
 }
 
+// errPropNotFound 属性对当前资源不可用
+var errPropNotFound = errors.New("webdav: property not found")
+
+type principalKey struct{}
+
+// withPrincipal 返回携带当前用户主体地址的上下文
+func withPrincipal(ctx context.Context, href string) context.Context {
+	return context.WithValue(ctx, principalKey{}, href)
+}
+
+func findCurrentUserPrincipal(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, name string, fi FileInfo) (string, error) {
+	href, ok := ctx.Value(principalKey{}).(string)
+	if !ok {
+		return "", errPropNotFound
+	}
+	return `<D:href xmlns:D="DAV:">` + escape(href) + `</D:href>`, nil
+}
+
+type quotaKey struct{}
+
+// quota 单次请求内共享的用户容量，避免对每个对象重复查询容量包
+type quota struct {
+	once      sync.Once
+	used      uint64
+	available uint64
+}
+
+// withQuota 返回可在单次请求内缓存用户容量的上下文
+func withQuota(ctx context.Context) context.Context {
+	return context.WithValue(ctx, quotaKey{}, &quota{})
+}
+
+func getQuota(ctx context.Context, fs *filesystem.FileSystem) (used, available uint64) {
+	q, ok := ctx.Value(quotaKey{}).(*quota)
+	if !ok {
+		q = &quota{}
+	}
+
+	q.once.Do(func() {
+		q.used = fs.User.Storage
+		q.available = fs.User.GetRemainingCapacity()
+	})
+	return q.used, q.available
+}
+
+func findQuotaAvailableBytes(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, name string, fi FileInfo) (string, error) {
+	_, available := getQuota(ctx, fs)
+	return strconv.FormatUint(available, 10), nil
+}
+
+func findQuotaUsedBytes(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, name string, fi FileInfo) (string, error) {
+	used, _ := getQuota(ctx, fs)
+	return strconv.FormatUint(used, 10), nil
+}
+
 func findSupportedLock(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, name string, fi FileInfo) (string, error) {
 	return `` +
 		`<D:lockentry xmlns:D="DAV:">` +
@@ -14,24 +14,23 @@ import (
 	"path"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/traffic"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
 type Handler struct {
 	// Prefix is the URL path prefix to strip from WebDAV resource paths.
 	Prefix string
-	// LockSystem is the lock management system.
-	LockSystem map[uint]LockSystem
 	// Logger is an optional error logger. If non-nil, it will be called
 	// for all HTTP requests.
 	Logger func(*http.Request, error)
-	Mutex  *sync.Mutex
+	// Groupware 为 true 时在保留路径下提供 CalDAV / CardDAV 服务
+	Groupware bool
 }
 
 func (h *Handler) stripPrefix(p string, uid uint) (string, int, error) {
@@ -60,34 +59,26 @@ func isPathExist(ctx context.Context, fs *filesystem.FileSystem, path string) (b
 	return false, nil
 }
 
-func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) {
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, ls LockSystem) {
 	status, err := http.StatusBadRequest, errUnsupportedMethod
-	h.Mutex.Lock()
-	if h.LockSystem == nil {
-		h.Mutex.Unlock()
+	if ls == nil {
 		status, err = http.StatusInternalServerError, errNoLockSystem
+	} else if reqPath, ok := h.groupwarePath(r.URL.Path); ok {
+		status, err = h.serveGroupware(w, r, fs, reqPath)
 	} else {
-		// 检查并新建 LockSystem
-		ls, ok := h.LockSystem[fs.User.ID]
-		if !ok {
-			h.LockSystem[fs.User.ID] = NewMemLS()
-			ls = h.LockSystem[fs.User.ID]
-		}
-		h.Mutex.Unlock()
-
 		switch r.Method {
 		case "OPTIONS":
 			status, err = h.handleOptions(w, r, fs)
 		case "GET", "HEAD", "POST":
 			status, err = h.handleGetHeadPost(w, r, fs)
 		case "DELETE":
-			status, err = h.handleDelete(w, r, fs)
+			status, err = h.handleDelete(w, r, fs, ls)
 		case "PUT":
-			status, err = h.handlePut(w, r, fs)
+			status, err = h.handlePut(w, r, fs, ls)
 		case "MKCOL":
-			status, err = h.handleMkcol(w, r, fs)
+			status, err = h.handleMkcol(w, r, fs, ls)
 		case "COPY", "MOVE":
-			status, err = h.handleCopyMove(w, r, fs)
+			status, err = h.handleCopyMove(w, r, fs, ls)
 		case "LOCK":
 			status, err = h.handleLock(w, r, fs, ls)
 		case "UNLOCK":
@@ -110,112 +101,91 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, fs *filesyst
 	}
 }
 
-// OK
-func (h *Handler) lock(now time.Time, root string, fs *filesystem.FileSystem, ls LockSystem) (token string, status int, err error) {
-	//token, err = ls.Create(now, LockDetails{
-	//	Root:      root,
-	//	Duration:  infiniteTimeout,
-	//	ZeroDepth: true,
-	//})
-	//if err != nil {
-	//	if err == ErrLocked {
-	//		return "", StatusLocked, err
-	//	}
-	//	return "", http.StatusInternalServerError, err
-	//}
-
-	return fmt.Sprintf("%d", time.Now().Unix()), 0, nil
+// lock 为不携带锁令牌的请求创建临时锁，请求结束后释放。
+// 临时锁设有期限，实例在请求过程中异常退出时不会永久锁定对象
+func (h *Handler) lock(now time.Time, root string, ls LockSystem) (token string, status int, err error) {
+	token, err = ls.Create(now, LockDetails{
+		Root:      root,
+		Duration:  lockHoldTimeout,
+		ZeroDepth: true,
+	})
+	if err != nil {
+		if err == ErrLocked {
+			return "", StatusLocked, err
+		}
+		return "", http.StatusInternalServerError, err
+	}
+	return token, 0, nil
 }
 
-// ok
-func (h *Handler) confirmLocks(r *http.Request, src, dst string, fs *filesystem.FileSystem) (release func(), status int, err error) {
-
-	//hdr := r.Header.Get("If")
-	//h.Mutex.Lock()
-	//ls,ok := h.LockSystem[fs.User.ID]
-	//h.Mutex.Unlock()
-	//if !ok{
-	//	return nil, http.StatusInternalServerError, errNoLockSystem
-	//}
-	//
-	//if hdr == "" {
-	//	// An empty If header means that the client hasn't previously created locks.
-	//	// Even if this client doesn't care about locks, we still need to check that
-	//	// the resources aren't locked by another client, so we create temporary
-	//	// locks that would conflict with another client's locks. These temporary
-	//	// locks are unlocked at the end of the HTTP request.
-	//	now, srcToken, dstToken := time.Now(), "", ""
-	//	if src != "" {
-	//		srcToken, status, err = h.lock(now, src, fs,ls)
-	//		if err != nil {
-	//			return nil, status, err
-	//		}
-	//	}
-	//	if dst != "" {
-	//		dstToken, status, err = h.lock(now, dst, fs,ls)
-	//		if err != nil {
-	//			if srcToken != "" {
-	//				ls.Unlock(now, srcToken)
-	//			}
-	//			return nil, status, err
-	//		}
-	//	}
-	//
-	//	return func() {
-	//		if dstToken != "" {
-	//			ls.Unlock(now, dstToken)
-	//		}
-	//		if srcToken != "" {
-	//			ls.Unlock(now, srcToken)
-	//		}
-	//	}, 0, nil
-	//}
-	//
-	//ih, ok := parseIfHeader(hdr)
-	//if !ok {
-	//	return nil, http.StatusBadRequest, errInvalidIfHeader
-	//}
-	//// ih is a disjunction (OR) of ifLists, so any ifList will do.
-	//for _, l := range ih.lists {
-	//	lsrc := l.resourceTag
-	//	if lsrc == "" {
-	//		lsrc = src
-	//	} else {
-	//		u, err := url.Parse(lsrc)
-	//		if err != nil {
-	//			continue
-	//		}
-	//		//if u.Host != r.Host {
-	//		//	continue
-	//		//}
-	//		lsrc, status, err = h.stripPrefix(u.Path, fs.User.ID)
-	//		if err != nil {
-	//			return nil, status, err
-	//		}
-	//	}
-	//	release, err = ls.Confirm(
-	//		time.Now(),
-	//		lsrc,
-	//		dst,
-	//		l.conditions...,
-	//	)
-	//	if err == ErrConfirmationFailed {
-	//		continue
-	//	}
-	//	if err != nil {
-	//		return nil, http.StatusInternalServerError, err
-	//	}
-	//	return release, 0, nil
-	//}
-	//// Section 10.4.1 says that "If this header is evaluated and all state lists
-	//// fail, then the request must fail with a 412 (Precondition Failed) status."
-	//// We follow the spec even though the cond_put_corrupt_token test case from
-	//// the litmus test warns on seeing a 412 instead of a 423 (Locked).
-	//return nil, http.StatusPreconditionFailed, ErrLocked
+func (h *Handler) confirmLocks(r *http.Request, src, dst string, fs *filesystem.FileSystem, ls LockSystem) (release func(), status int, err error) {
+	hdr := r.Header.Get("If")
+	if hdr == "" {
+		// An empty If header means that the client hasn't previously created locks.
+		// Even if this client doesn't care about locks, we still need to check that
+		// the resources aren't locked by another client, so we create temporary
+		// locks that would conflict with another client's locks. These temporary
+		// locks are unlocked at the end of the HTTP request.
+		now, srcToken, dstToken := time.Now(), "", ""
+		if src != "" {
+			srcToken, status, err = h.lock(now, src, ls)
+			if err != nil {
+				return nil, status, err
+			}
+		}
+		if dst != "" {
+			dstToken, status, err = h.lock(now, dst, ls)
+			if err != nil {
+				if srcToken != "" {
+					ls.Unlock(now, srcToken)
+				}
+				return nil, status, err
+			}
+		}
 
-	return func() {
+		return func() {
+			if dstToken != "" {
+				ls.Unlock(now, dstToken)
+			}
+			if srcToken != "" {
+				ls.Unlock(now, srcToken)
+			}
+		}, 0, nil
+	}
 
-	}, 0, nil
+	ih, ok := parseIfHeader(hdr)
+	if !ok {
+		return nil, http.StatusBadRequest, errInvalidIfHeader
+	}
+	// ih is a disjunction (OR) of ifLists, so any ifList will do.
+	for _, l := range ih.lists {
+		lsrc := l.resourceTag
+		if lsrc == "" {
+			lsrc = src
+		} else {
+			u, err := url.Parse(lsrc)
+			if err != nil {
+				continue
+			}
+			lsrc, status, err = h.stripPrefix(u.Path, fs.User.ID)
+			if err != nil {
+				return nil, status, err
+			}
+		}
+		release, err = ls.Confirm(time.Now(), lsrc, dst, l.conditions...)
+		if err == ErrConfirmationFailed {
+			continue
+		}
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		return release, 0, nil
+	}
+	// Section 10.4.1 says that "If this header is evaluated and all state lists
+	// fail, then the request must fail with a 412 (Precondition Failed) status."
+	// We follow the spec even though the cond_put_corrupt_token test case from
+	// the litmus test warns on seeing a 412 instead of a 423 (Locked).
+	return nil, http.StatusPreconditionFailed, ErrLocked
 }
 
 //OK
@@ -235,7 +205,11 @@ func (h *Handler) handleOptions(w http.ResponseWriter, r *http.Request, fs *file
 	}
 	w.Header().Set("Allow", allow)
 	// http://www.webdav.org/specs/rfc4918.html#dav.compliance.classes
-	w.Header().Set("DAV", "1, 2")
+	if h.Groupware {
+		w.Header().Set("DAV", groupwareCompliance)
+	} else {
+		w.Header().Set("DAV", "1, 2")
+	}
 	// http://msdn.microsoft.com/en-au/library/cc250217.aspx
 	w.Header().Set("MS-Author-Via", "DAV")
 	return 0, nil
@@ -252,6 +226,11 @@ func (h *Handler) handleGetHeadPost(w http.ResponseWriter, r *http.Request, fs *
 
 	ctx := r.Context()
 
+	// 仅上传的账号不能读取文件内容
+	if permissionFromContext(ctx).UploadOnly && r.Method != "HEAD" {
+		return http.StatusForbidden, errPermissionDenied
+	}
+
 	exist, file := fs.IsFileExist(reqPath)
 	if !exist {
 		return http.StatusNotFound, nil
@@ -263,6 +242,9 @@ func (h *Handler) handleGetHeadPost(w http.ResponseWriter, r *http.Request, fs *
 		if err == filesystem.ErrObjectNotExist {
 			return http.StatusNotFound, err
 		}
+		if err == traffic.ErrQuotaExceeded {
+			return http.StatusForbidden, err
+		}
 		return http.StatusInternalServerError, err
 	}
 
@@ -274,8 +256,13 @@ func (h *Handler) handleGetHeadPost(w http.ResponseWriter, r *http.Request, fs *
 
 	if !rs.Redirect {
 		defer rs.Content.Close()
+		if err := traffic.Check(fs.FileTarget[0].UserID); err != nil {
+			return http.StatusForbidden, err
+		}
+
 		// 获取文件内容
 		http.ServeContent(w, r, reqPath, fs.FileTarget[0].UpdatedAt, rs.Content)
+		traffic.Record(fs.FileTarget[0].UserID, traffic.Written(w))
 		return 0, nil
 	}
 
@@ -285,7 +272,7 @@ func (h *Handler) handleGetHeadPost(w http.ResponseWriter, r *http.Request, fs *
 }
 
 // OK
-func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) (status int, err error) {
+func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, ls LockSystem) (status int, err error) {
 	defer fs.Recycle()
 
 	reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
@@ -293,7 +280,11 @@ func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request, fs *files
 		return status, err
 	}
 
-	release, status, err := h.confirmLocks(r, reqPath, "", fs)
+	if !permissionFromContext(r.Context()).canDelete() {
+		return http.StatusForbidden, errPermissionDenied
+	}
+
+	release, status, err := h.confirmLocks(r, reqPath, "", fs, ls)
 	if err != nil {
 		return status, err
 	}
@@ -321,12 +312,16 @@ func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request, fs *files
 }
 
 // OK
-func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) (status int, err error) {
+func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, ls LockSystem) (status int, err error) {
 	reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
 	if err != nil {
 		return status, err
 	}
-	release, status, err := h.confirmLocks(r, reqPath, "", fs)
+	permission := permissionFromContext(r.Context())
+	if permission.ReadOnly {
+		return http.StatusForbidden, errPermissionDenied
+	}
+	release, status, err := h.confirmLocks(r, reqPath, "", fs, ls)
 	if err != nil {
 		return status, err
 	}
@@ -356,6 +351,9 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request, fs *filesyst
 	exist, originFile := fs.IsFileExist(reqPath)
 	if exist {
 		// 已存在，为更新操作
+		if !permission.canOverwrite() {
+			return http.StatusForbidden, errPermissionDenied
+		}
 
 		// 检查此文件是否有软链接
 		fileList, err := model.RemoveFilesWithSoftLinks([]model.File{*originFile})
@@ -405,14 +403,17 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request, fs *filesyst
 }
 
 // OK
-func (h *Handler) handleMkcol(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) (status int, err error) {
+func (h *Handler) handleMkcol(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, ls LockSystem) (status int, err error) {
 	defer fs.Recycle()
 
 	reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
 	if err != nil {
 		return status, err
 	}
-	release, status, err := h.confirmLocks(r, reqPath, "", fs)
+	if permissionFromContext(r.Context()).ReadOnly {
+		return http.StatusForbidden, errPermissionDenied
+	}
+	release, status, err := h.confirmLocks(r, reqPath, "", fs, ls)
 	if err != nil {
 		return status, err
 	}
@@ -431,7 +432,7 @@ func (h *Handler) handleMkcol(w http.ResponseWriter, r *http.Request, fs *filesy
 }
 
 // OK
-func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) (status int, err error) {
+func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, ls LockSystem) (status int, err error) {
 	defer fs.Recycle()
 
 	hdr := r.Header.Get("Destination")
@@ -465,6 +466,13 @@ func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request, fs *fil
 
 	ctx := r.Context()
 
+	// 受限账号不能覆盖目标位置已有的对象，仅上传的账号不能移动已有对象
+	permission := permissionFromContext(ctx)
+	if permission.ReadOnly || (permission.UploadOnly && r.Method == "MOVE") {
+		return http.StatusForbidden, errPermissionDenied
+	}
+	canOverwrite := permission.canOverwrite()
+
 	isExist, target := isPathExist(ctx, fs, src)
 
 	if !isExist {
@@ -477,7 +485,7 @@ func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request, fs *fil
 		// even though a COPY doesn't modify the source, if a concurrent
 		// operation modifies the source. However, the litmus test explicitly
 		// checks that COPYing a locked-by-another source is OK.
-		release, status, err := h.confirmLocks(r, "", dst, fs)
+		release, status, err := h.confirmLocks(r, "", dst, fs, ls)
 		if err != nil {
 			return status, err
 		}
@@ -494,12 +502,12 @@ func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request, fs *fil
 				return http.StatusBadRequest, errInvalidDepth
 			}
 		}
-		return copyFiles(ctx, fs, target, dst, r.Header.Get("Overwrite") != "F", depth, 0)
+		return copyFiles(ctx, fs, target, dst, canOverwrite && r.Header.Get("Overwrite") != "F", depth, 0)
 	}
 
 	// windows下，某些情况下（网盘根目录下）Office保存文件时附带的锁token只包含源文件，
 	// 此处暂时去除了对dst锁的检查
-	release, status, err := h.confirmLocks(r, src, "", fs)
+	release, status, err := h.confirmLocks(r, src, "", fs, ls)
 	if err != nil {
 		return status, err
 	}
@@ -513,137 +521,120 @@ func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request, fs *fil
 			return http.StatusBadRequest, errInvalidDepth
 		}
 	}
-	return moveFiles(ctx, fs, target, dst, r.Header.Get("Overwrite") == "T")
+	return moveFiles(ctx, fs, target, dst, canOverwrite && r.Header.Get("Overwrite") == "T")
 }
 
 // OK
 func (h *Handler) handleLock(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, ls LockSystem) (retStatus int, retErr error) {
 	defer fs.Recycle()
 
+	if permissionFromContext(r.Context()).ReadOnly {
+		return http.StatusForbidden, errPermissionDenied
+	}
+
 	duration, err := parseTimeout(r.Header.Get("Timeout"))
 	if err != nil {
 		return http.StatusBadRequest, err
 	}
-
-	reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
+	li, status, err := readLockInfo(r.Body)
 	if err != nil {
 		return status, err
 	}
 
-	////ctx := r.Context()
-	//token, ld, now, created := "", LockDetails{}, time.Now(), false
-	//if li == (lockInfo{}) {
-	//	// An empty lockInfo means to refresh the lock.
-	//	ih, ok := parseIfHeader(r.Header.Get("If"))
-	//	if !ok {
-	//		return http.StatusBadRequest, errInvalidIfHeader
-	//	}
-	//	if len(ih.lists) == 1 && len(ih.lists[0].conditions) == 1 {
-	//		token = ih.lists[0].conditions[0].Token
-	//	}
-	//	if token == "" {
-	//		return http.StatusBadRequest, errInvalidLockToken
-	//	}
-	//	ld, err = ls.Refresh(now, token, duration)
-	//	if err != nil {
-	//		if err == ErrNoSuchLock {
-	//			return http.StatusPreconditionFailed, err
-	//		}
-	//		return http.StatusInternalServerError, err
-	//	}
-	//
-	//} else {
-	//	// Section 9.10.3 says that "If no Depth header is submitted on a LOCK request,
-	//	// then the request MUST act as if a "Depth:infinity" had been submitted."
-	//	depth := infiniteDepth
-	//	if hdr := r.Header.Get("Depth"); hdr != "" {
-	//		depth = parseDepth(hdr)
-	//		if depth != 0 && depth != infiniteDepth {
-	//			// Section 9.10.3 says that "Values other than 0 or infinity must not be
-	//			// used with the Depth header on a LOCK method".
-	//			return http.StatusBadRequest, errInvalidDepth
-	//		}
-	//	}
-	//	reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
-	//	if err != nil {
-	//		return status, err
-	//	}
-	//	ld = LockDetails{
-	//		Root:      reqPath,
-	//		Duration:  duration,
-	//		OwnerXML:  li.Owner.InnerXML,
-	//		ZeroDepth: depth == 0,
-	//	}
-	//	token, err = ls.Create(now, ld)
-	//	if err != nil {
-	//		if err == ErrLocked {
-	//			return StatusLocked, err
-	//		}
-	//		return http.StatusInternalServerError, err
-	//	}
-	//	defer func() {
-	//		if retErr != nil {
-	//			ls.Unlock(now, token)
-	//		}
-	//	}()
-	//
-	//	// Create the resource if it didn't previously exist.
-	//	//if _, err := h.FileSystem.Stat(ctx, reqPath); err != nil {
-	//	//	f, err := h.FileSystem.OpenFile(ctx, reqPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
-	//	//	if err != nil {
-	//	//		// TODO: detect missing intermediate dirs and return http.StatusConflict?
-	//	//		return http.StatusInternalServerError, err
-	//	//	}
-	//	//	f.Close()
-	//	//	created = true
-	//	//}
-	//
-	//	// http://www.webdav.org/specs/rfc4918.html#HEADER_Lock-Token says that the
-	//	// Lock-Token value is a Coded-URL. We add angle brackets.
-	//	w.Header().Set("Lock-Token", "<"+token+">")
-	//}
-	//
-	//w.Header().Set("Content-Type", "application/xml; charset=utf-8")
-	//if created {
-	//	// This is "w.WriteHeader(http.StatusCreated)" and not "return
-	//	// http.StatusCreated, nil" because we write our own (XML) response to w
-	//	// and Handler.ServeHTTP would otherwise write "Created".
-	//	w.WriteHeader(http.StatusCreated)
-	//}
+	token, ld, now := "", LockDetails{}, time.Now()
+	if li == (lockInfo{}) {
+		// An empty lockInfo means to refresh the lock.
+		ih, ok := parseIfHeader(r.Header.Get("If"))
+		if !ok {
+			return http.StatusBadRequest, errInvalidIfHeader
+		}
+		if len(ih.lists) == 1 && len(ih.lists[0].conditions) == 1 {
+			token = ih.lists[0].conditions[0].Token
+		}
+		if token == "" {
+			return http.StatusBadRequest, errInvalidLockToken
+		}
+		ld, err = ls.Refresh(now, token, duration)
+		if err != nil {
+			if err == ErrNoSuchLock {
+				return http.StatusPreconditionFailed, err
+			}
+			if err == ErrLocked {
+				return StatusLocked, err
+			}
+			return http.StatusInternalServerError, err
+		}
 
-	writeLockInfo(w, fmt.Sprintf("%d", time.Now().UnixNano()), LockDetails{
-		Duration: duration,
-		OwnerXML: fs.User.Email,
-		Root:     reqPath,
-	})
+	} else {
+		// Section 9.10.3 says that "If no Depth header is submitted on a LOCK request,
+		// then the request MUST act as if a "Depth:infinity" had been submitted."
+		depth := infiniteDepth
+		if hdr := r.Header.Get("Depth"); hdr != "" {
+			depth = parseDepth(hdr)
+			if depth != 0 && depth != infiniteDepth {
+				// Section 9.10.3 says that "Values other than 0 or infinity must not be
+				// used with the Depth header on a LOCK method".
+				return http.StatusBadRequest, errInvalidDepth
+			}
+		}
+		reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
+		if err != nil {
+			return status, err
+		}
+		ld = LockDetails{
+			Root:      reqPath,
+			Duration:  duration,
+			OwnerXML:  li.Owner.InnerXML,
+			ZeroDepth: depth == 0,
+		}
+		token, err = ls.Create(now, ld)
+		if err != nil {
+			if err == ErrLocked {
+				return StatusLocked, err
+			}
+			return http.StatusInternalServerError, err
+		}
+
+		// 锁定尚不存在的路径时不创建空文件，客户端随后的 PUT 请求携带此锁即可创建文件
+
+		// http://www.webdav.org/specs/rfc4918.html#HEADER_Lock-Token says that the
+		// Lock-Token value is a Coded-URL. We add angle brackets.
+		w.Header().Set("Lock-Token", "<"+token+">")
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	writeLockInfo(w, token, ld)
 	return 0, nil
 }
 
 // OK
 func (h *Handler) handleUnlock(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, ls LockSystem) (status int, err error) {
 	defer fs.Recycle()
-	return http.StatusNoContent, err
 
-	//// http://www.webdav.org/specs/rfc4918.html#HEADER_Lock-Token says that the
-	//// Lock-Token value is a Coded-URL. We strip its angle brackets.
-	//t := r.Header.Get("Lock-Token")
-	//if len(t) < 2 || t[0] != '<' || t[len(t)-1] != '>' {
-	//	return http.StatusBadRequest, errInvalidLockToken
-	//}
-	//t = t[1 : len(t)-1]
-	//
-	//switch err = ls.Unlock(time.Now(), t); err {
-	//case nil:
-	//	return http.StatusNoContent, err
-	//case ErrForbidden:
-	//	return http.StatusForbidden, err
-	//case ErrLocked:
-	//	return StatusLocked, err
-	//case ErrNoSuchLock:
-	//	return http.StatusConflict, err
-	//default:
-	//	return http.StatusInternalServerError, err
-	//}
+	if permissionFromContext(r.Context()).ReadOnly {
+		return http.StatusForbidden, errPermissionDenied
+	}
+
+	// http://www.webdav.org/specs/rfc4918.html#HEADER_Lock-Token says that the
+	// Lock-Token value is a Coded-URL. We strip its angle brackets.
+	t := r.Header.Get("Lock-Token")
+	if len(t) < 2 || t[0] != '<' || t[len(t)-1] != '>' {
+		return http.StatusBadRequest, errInvalidLockToken
+	}
+	t = t[1 : len(t)-1]
+
+	switch err = ls.Unlock(time.Now(), t); err {
+	case nil:
+		return http.StatusNoContent, err
+	case ErrForbidden:
+		return http.StatusForbidden, err
+	case ErrLocked:
+		return StatusLocked, err
+	case ErrNoSuchLock:
+		return http.StatusConflict, err
+	default:
+		return http.StatusInternalServerError, err
+	}
 }
 
 // OK
@@ -654,7 +645,10 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request, fs *fil
 	if err != nil {
 		return status, err
 	}
-	ctx := r.Context()
+	ctx := withQuota(r.Context())
+	if h.Groupware {
+		ctx = withPrincipal(ctx, h.PrincipalHref())
+	}
 	ok, fi := isPathExist(ctx, fs, reqPath)
 	if !ok {
 		return http.StatusNotFound, err
@@ -723,7 +717,10 @@ func (h *Handler) handleProppatch(w http.ResponseWriter, r *http.Request, fs *fi
 	if err != nil {
 		return status, err
 	}
-	release, status, err := h.confirmLocks(r, reqPath, "", fs)
+	if permissionFromContext(r.Context()).ReadOnly {
+		return http.StatusForbidden, errPermissionDenied
+	}
+	release, status, err := h.confirmLocks(r, reqPath, "", fs, ls)
 	if err != nil {
 		return status, err
 	}
@@ -731,14 +728,15 @@ func (h *Handler) handleProppatch(w http.ResponseWriter, r *http.Request, fs *fi
 
 	ctx := r.Context()
 
-	if exist, _ := isPathExist(ctx, fs, reqPath); !exist {
+	exist, fi := isPathExist(ctx, fs, reqPath)
+	if !exist {
 		return http.StatusNotFound, nil
 	}
 	patches, status, err := readProppatch(r.Body)
 	if err != nil {
 		return status, err
 	}
-	pstats, err := patch(ctx, fs, ls, reqPath, patches)
+	pstats, err := patch(ctx, fs, ls, fi, patches)
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
@@ -838,6 +836,7 @@ var (
 	errInvalidResponse         = errors.New("webdav: invalid response")
 	errInvalidTimeout          = errors.New("webdav: invalid timeout")
 	errNoFileSystem            = errors.New("webdav: no file system")
+	errNotAnObject             = errors.New("webdav: not a file or folder")
 	errNoLockSystem            = errors.New("webdav: no lock system")
 	errNotADirectory           = errors.New("webdav: not a directory")
 	errPrefixMismatch          = errors.New("webdav: prefix mismatch")
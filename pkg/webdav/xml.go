@@ -313,6 +313,9 @@ type multistatusWriter struct {
 	// written.
 	responseDescription string
 
+	// syncToken 为 sync-collection 报告返回的同步令牌，为空时不输出
+	syncToken string
+
 	w   http.ResponseWriter
 	enc *ixml.Encoder
 }
@@ -376,10 +379,23 @@ func (w *multistatusWriter) writeHeader() error {
 // return value and field enc of w are nil, then no multistatus response has
 // been written.
 func (w *multistatusWriter) close() error {
+	if w.syncToken != "" {
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
 	if w.enc == nil {
 		return nil
 	}
 	var end []ixml.Token
+	if w.syncToken != "" {
+		name := ixml.Name{Space: "DAV:", Local: "sync-token"}
+		end = append(end,
+			ixml.StartElement{Name: name},
+			ixml.CharData(w.syncToken),
+			ixml.EndElement{Name: name},
+		)
+	}
 	if w.responseDescription != "" {
 		name := ixml.Name{Space: "DAV:", Local: "responsedescription"}
 		end = append(end,
@@ -0,0 +1,229 @@
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/leader"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// signTTL 投递签名有效期，秒
+const signTTL = 600
+
+// queueSize 等待投递队列长度
+const queueSize = 1000
+
+// Default 默认的投递器，为 nil 时不投递事件
+var Default *Dispatcher
+
+// Dispatcher Webhook 后台投递器
+type Dispatcher struct {
+	queue  chan uint
+	client request.Client
+
+	maxAttempts   int
+	retryInterval time.Duration
+	timeout       time.Duration
+	keep          int
+}
+
+// Init 初始化投递器，多实例部署时仅由主节点恢复未完成的投递
+func Init() {
+	workers := model.GetIntSetting("webhook_worker_num", 5)
+	Default = NewDispatcher(
+		workers,
+		model.GetIntSetting("webhook_max_attempts", 5),
+		time.Duration(model.GetIntSetting("webhook_retry_interval", 30))*time.Second,
+		time.Duration(model.GetIntSetting("webhook_timeout", 10))*time.Second,
+		model.GetIntSetting("webhook_delivery_keep", 200),
+	)
+	util.Log().Info("初始化 Webhook 投递队列，WorkerNum = %d", workers)
+
+	leader.OnElected(Default.Resume)
+}
+
+// NewDispatcher 新建投递器并启动 workers 个后台投递协程
+func NewDispatcher(workers, maxAttempts int, retryInterval, timeout time.Duration, keep int) *Dispatcher {
+	dispatcher := &Dispatcher{
+		queue:         make(chan uint, queueSize),
+		client:        request.NewClient(request.WithTransport(newGuardedTransport())),
+		maxAttempts:   maxAttempts,
+		retryInterval: retryInterval,
+		timeout:       timeout,
+		keep:          keep,
+	}
+
+	for i := 0; i < workers; i++ {
+		go dispatcher.work()
+	}
+
+	return dispatcher
+}
+
+// Enqueue 在 delay 后将投递加入队列
+func (dispatcher *Dispatcher) Enqueue(id uint, delay time.Duration) {
+	if delay > 0 {
+		time.AfterFunc(delay, func() {
+			dispatcher.Enqueue(id, 0)
+		})
+		return
+	}
+
+	select {
+	case dispatcher.queue <- id:
+	default:
+		// 队列已满时异步等待
+		go func() {
+			dispatcher.queue <- id
+		}()
+	}
+}
+
+// Resume 从数据库中恢复未完成的投递
+func (dispatcher *Dispatcher) Resume() {
+	deliveries := model.GetPendingWebhookDeliveries()
+	if len(deliveries) == 0 {
+		return
+	}
+	util.Log().Info("从数据库中恢复 %d 个未完成的 Webhook 投递", len(deliveries))
+
+	for _, delivery := range deliveries {
+		dispatcher.Enqueue(delivery.ID, time.Until(delivery.NextRetry))
+	}
+}
+
+// Ping 向给定订阅同步发送一次测试投递
+func (dispatcher *Dispatcher) Ping(hook *model.Webhook) (*model.WebhookDelivery, error) {
+	payload, err := NewPayload(hook.UserID, EventPing, map[string]interface{}{
+		"webhook": hook.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &model.WebhookDelivery{
+		WebhookID: hook.ID,
+		Event:     EventPing,
+		Payload:   string(payload),
+	}
+	if _, err := delivery.Create(); err != nil {
+		return nil, err
+	}
+
+	// 测试投递不重试
+	delivery.Attempts = 1
+	delivery.Status = model.WebhookDeliveryFailed
+	if dispatcher.send(hook, delivery) {
+		delivery.Status = model.WebhookDeliverySuccess
+	}
+
+	return delivery, delivery.Save()
+}
+
+// work 持续处理队列中的投递
+func (dispatcher *Dispatcher) work() {
+	for id := range dispatcher.queue {
+		dispatcher.deliver(id)
+	}
+}
+
+// deliver 执行一次投递，失败时按指数退避安排重试
+func (dispatcher *Dispatcher) deliver(id uint) {
+	delivery, err := model.GetWebhookDeliveryByID(id)
+	if err != nil || delivery.Status != model.WebhookDeliveryPending {
+		return
+	}
+
+	// 未到重试时间的投递已由其他实例安排
+	if time.Until(delivery.NextRetry) > time.Second {
+		return
+	}
+
+	hook, err := model.GetWebhookByID(delivery.WebhookID, 0)
+	if err != nil || !hook.Enabled {
+		delivery.Status = model.WebhookDeliveryFailed
+		delivery.Error = "webhook is disabled or deleted"
+		delivery.Save()
+		return
+	}
+
+	// 主节点切换时同一投递可能在多个实例中排队
+	if ok, err := delivery.Claim(); !ok {
+		if err != nil {
+			util.Log().Warning("无法领取 Webhook 投递 [%d], %s", delivery.ID, err)
+		}
+		return
+	}
+
+	if dispatcher.send(hook, delivery) {
+		delivery.Status = model.WebhookDeliverySuccess
+	} else if delivery.Attempts >= dispatcher.maxAttempts {
+		delivery.Status = model.WebhookDeliveryFailed
+	} else {
+		delay := dispatcher.retryInterval * time.Duration(1<<uint(delivery.Attempts-1))
+		delivery.NextRetry = time.Now().Add(delay)
+		dispatcher.Enqueue(delivery.ID, delay)
+	}
+
+	if err := delivery.Save(); err != nil {
+		util.Log().Warning("无法保存 Webhook 投递记录, %s", err)
+	}
+
+	if delivery.Status != model.WebhookDeliveryPending && dispatcher.keep > 0 {
+		model.PruneWebhookDeliveries(hook.ID, dispatcher.keep)
+	}
+}
+
+// send 发送投递请求并记录结果，返回是否成功
+func (dispatcher *Dispatcher) send(hook *model.Webhook, delivery *model.WebhookDelivery) bool {
+	delivery.ResponseCode = 0
+	delivery.Error = ""
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(auth.CrHeaderPrefix+"Event", delivery.Event)
+	header.Set(auth.CrHeaderPrefix+"Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+
+	// 签名包含请求路径，接收方看到的路径至少为 "/"
+	target, err := url.Parse(hook.URL)
+	if err != nil {
+		delivery.Error = err.Error()
+		return false
+	}
+	if target.Path == "" {
+		target.Path = "/"
+	}
+
+	resp := dispatcher.client.Request(
+		"POST",
+		target.String(),
+		bytes.NewReader([]byte(delivery.Payload)),
+		request.WithHeader(header),
+		request.WithMasterMeta(),
+		request.WithTimeout(dispatcher.timeout),
+		request.WithCredential(auth.HMACAuth{SecretKey: []byte(hook.Secret)}, signTTL),
+		request.WithContentLength(int64(len(delivery.Payload))),
+	)
+	if resp.Err != nil {
+		delivery.Error = resp.Err.Error()
+		return false
+	}
+
+	resp.Response.Body.Close()
+
+	delivery.ResponseCode = resp.Response.StatusCode
+	if resp.Response.StatusCode < 200 || resp.Response.StatusCode >= 300 {
+		delivery.Error = fmt.Sprintf("unexpected status code %d", resp.Response.StatusCode)
+		return false
+	}
+
+	return true
+}
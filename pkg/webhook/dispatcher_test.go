@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"database/sql"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+
+	cache.Set("setting_siteURL", "http://cloudreve.org", 0)
+	cache.Set("setting_siteID", "site", 0)
+	m.Run()
+}
+
+func TestTrigger(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 投递器未初始化
+	{
+		Default = nil
+		Trigger(1, EventFileUploaded, nil)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 仅投递已订阅的事件
+	{
+		Default = NewDispatcher(0, 3, time.Second, time.Second, 0)
+		mock.ExpectQuery("SELECT(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "events"}).
+				AddRow(1, "").
+				AddRow(2, EventShareCreated))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectCommit()
+		trigger(1, EventFileUploaded, map[string]string{"src": "/a.txt"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Len(Default.queue, 1)
+		asserts.EqualValues(3, <-Default.queue)
+		Default = nil
+	}
+}
+
+func TestDispatcher_send(t *testing.T) {
+	asserts := assert.New(t)
+	dispatcher := NewDispatcher(0, 3, time.Second, time.Second, 0)
+	hook := &model.Webhook{URL: "", Secret: "secret"}
+
+	// 拒绝投递到本地地址
+	{
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		hook.URL = server.URL
+		delivery := &model.WebhookDelivery{Event: EventFileUploaded, Payload: "{}"}
+		asserts.False(dispatcher.send(hook, delivery))
+		asserts.Equal(0, delivery.ResponseCode)
+		asserts.Contains(delivery.Error, ErrPrivateAddress.Error())
+		server.Close()
+	}
+
+	// 测试服务器监听本地地址，以下使用不限制地址的客户端
+	dispatcher.client = request.NewClient()
+
+	// 签名有效
+	{
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			asserts.Equal(EventFileUploaded, r.Header.Get("X-Cr-Event"))
+			asserts.NoError(auth.CheckRequest(auth.HMACAuth{SecretKey: []byte("secret")}, r))
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		hook.URL = server.URL
+		delivery := &model.WebhookDelivery{Event: EventFileUploaded, Payload: `{"event":"file.uploaded"}`}
+		asserts.True(dispatcher.send(hook, delivery))
+		asserts.Equal(http.StatusNoContent, delivery.ResponseCode)
+		asserts.Empty(delivery.Error)
+		server.Close()
+	}
+
+	// 状态码错误
+	{
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		hook.URL = server.URL
+		delivery := &model.WebhookDelivery{Event: EventFileUploaded, Payload: "{}", Attempts: 1}
+		asserts.False(dispatcher.send(hook, delivery))
+		asserts.Equal(http.StatusInternalServerError, delivery.ResponseCode)
+		asserts.NotEmpty(delivery.Error)
+		server.Close()
+	}
+
+	// 无法连接
+	{
+		hook.URL = "http://127.0.0.1:0"
+		delivery := &model.WebhookDelivery{Event: EventFileUploaded, Payload: "{}"}
+		asserts.False(dispatcher.send(hook, delivery))
+		asserts.NotEmpty(delivery.Error)
+	}
+}
+
+func TestDispatcher_deliver(t *testing.T) {
+	asserts := assert.New(t)
+	dispatcher := NewDispatcher(0, 2, time.Hour, time.Second, 0)
+	dispatcher.client = request.NewClient()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	// Webhook 已删除
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "status"}).AddRow(1, 1, model.WebhookDeliveryPending))
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		dispatcher.deliver(1)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 失败后安排重试
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "status"}).AddRow(1, 1, model.WebhookDeliveryPending))
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "url", "enabled"}).AddRow(1, server.URL, true))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)attempts(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), model.WebhookDeliveryPending, 1, http.StatusBadGateway, sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		dispatcher.deliver(1)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 超过最大尝试次数
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "status", "attempts"}).AddRow(1, 1, model.WebhookDeliveryPending, 1))
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "url", "enabled"}).AddRow(1, server.URL, true))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)attempts(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), model.WebhookDeliveryFailed, 2, http.StatusBadGateway, sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		dispatcher.deliver(1)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 已由其他实例领取
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "status", "attempts"}).AddRow(1, 1, model.WebhookDeliveryPending, 1))
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "url", "enabled"}).AddRow(1, server.URL, true))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)attempts(.+)").WithArgs(1, 1, model.WebhookDeliveryPending, 1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		dispatcher.deliver(1)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 未到重试时间
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "status", "next_retry"}).
+			AddRow(1, 1, model.WebhookDeliveryPending, time.Now().Add(time.Hour)))
+		dispatcher.deliver(1)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	asserts := assert.New(t)
+	for _, ip := range []string{"127.0.0.1", "::1", "10.0.0.1", "172.16.0.1", "192.168.1.1", "169.254.169.254", "fe80::1", "fd00::1", "0.0.0.0", "100.64.0.1", "::ffff:127.0.0.1"} {
+		asserts.False(isPublicIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"1.1.1.1", "8.8.8.8", "2606:4700:4700::1111"} {
+		asserts.True(isPublicIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestIsValidEvent(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.True(IsValidEvent(EventFileUploaded))
+	asserts.False(IsValidEvent(EventPing))
+	asserts.False(IsValidEvent("unknown"))
+}
@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// 事件类型
+const (
+	// EventPing 测试投递
+	EventPing = "ping"
+	// EventFileUploaded 文件上传完成
+	EventFileUploaded = "file.uploaded"
+	// EventFileDeleted 文件被删除
+	EventFileDeleted = "file.deleted"
+	// EventFileMoved 文件被移动或重命名
+	EventFileMoved = "file.moved"
+	// EventShareCreated 创建分享
+	EventShareCreated = "share.created"
+	// EventShareDownloaded 分享被下载
+	EventShareDownloaded = "share.downloaded"
+	// EventDownloadFinished 离线下载完成并已转存
+	EventDownloadFinished = "download.finished"
+	// EventTaskFailed 离线下载或后台任务失败
+	EventTaskFailed = "task.failed"
+)
+
+// Events 可订阅的事件
+var Events = []string{
+	EventFileUploaded,
+	EventFileDeleted,
+	EventFileMoved,
+	EventShareCreated,
+	EventShareDownloaded,
+	EventDownloadFinished,
+	EventTaskFailed,
+}
+
+// Payload 投递正文
+type Payload struct {
+	Event string      `json:"event"`
+	User  string      `json:"user"`
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data"`
+}
+
+// IsValidEvent 是否为可订阅的事件
+func IsValidEvent(event string) bool {
+	return util.ContainsString(Events, event)
+}
+
+// NewPayload 构建投递正文
+func NewPayload(uid uint, event string, data interface{}) ([]byte, error) {
+	return json.Marshal(Payload{
+		Event: event,
+		User:  hashid.HashID(uid, hashid.UserID),
+		Time:  time.Now(),
+		Data:  data,
+	})
+}
+
+// Trigger 为订阅了用户 uid 事件的所有 Webhook 创建投递，查询订阅在后台进行，不阻塞调用方
+func Trigger(uid uint, event string, data interface{}) {
+	if Default == nil {
+		return
+	}
+
+	go trigger(uid, event, data)
+}
+
+// trigger 查找订阅并创建投递
+func trigger(uid uint, event string, data interface{}) {
+	hooks := model.GetSubscribedWebhooks(uid)
+	if len(hooks) == 0 {
+		return
+	}
+
+	payload, err := NewPayload(uid, event, data)
+	if err != nil {
+		util.Log().Warning("无法序列化 Webhook 事件 %s, %s", event, err)
+		return
+	}
+
+	dispatcher := Default
+	if dispatcher == nil {
+		return
+	}
+
+	for _, hook := range hooks {
+		if !hook.Subscribed(event) {
+			continue
+		}
+
+		delivery := &model.WebhookDelivery{
+			WebhookID: hook.ID,
+			Event:     event,
+			Payload:   string(payload),
+			Status:    model.WebhookDeliveryPending,
+			NextRetry: time.Now(),
+		}
+		if _, err := delivery.Create(); err != nil {
+			util.Log().Warning("无法创建 Webhook 投递记录, %s", err)
+			continue
+		}
+
+		dispatcher.Enqueue(delivery.ID, 0)
+	}
+}
@@ -7,10 +7,12 @@ import (
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2"
+	"github.com/cloudreve/Cloudreve/v3/pkg/audit"
 	"github.com/cloudreve/Cloudreve/v3/pkg/email"
 	"github.com/cloudreve/Cloudreve/v3/pkg/request"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/service/admin"
+	"github.com/cloudreve/Cloudreve/v3/service/setting"
 	"github.com/gin-gonic/gin"
 )
 
@@ -42,7 +44,7 @@ func AdminNews(c *gin.Context) {
 func AdminChangeSetting(c *gin.Context) {
 	var service admin.BatchSettingChangeService
 	if err := c.ShouldBindJSON(&service); err == nil {
-		res := service.Change()
+		res := service.Change(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -79,8 +81,13 @@ func AdminReloadService(c *gin.Context) {
 		email.Init()
 	case "aria2":
 		aria2.Init(true, cluster.Default, mq.GlobalMQ)
+	case "audit":
+		audit.Init()
 	}
 
+	audit.Record(c, audit.ActionServiceReload, nil, map[string]audit.Change{
+		"service": {After: service},
+	})
 	c.JSON(200, serializer.Response{})
 }
 
@@ -149,7 +156,7 @@ func AdminTestSlave(c *gin.Context) {
 func AdminAddPolicy(c *gin.Context) {
 	var service admin.AddPolicyService
 	if err := c.ShouldBindJSON(&service); err == nil {
-		res := service.Add()
+		res := service.Add(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -204,7 +211,7 @@ func AdminGetPolicy(c *gin.Context) {
 func AdminDeletePolicy(c *gin.Context) {
 	var service admin.PolicyService
 	if err := c.ShouldBindUri(&service); err == nil {
-		res := service.Delete()
+		res := service.Delete(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -226,7 +233,7 @@ func AdminListGroup(c *gin.Context) {
 func AdminAddGroup(c *gin.Context) {
 	var service admin.AddGroupService
 	if err := c.ShouldBindJSON(&service); err == nil {
-		res := service.Add()
+		res := service.Add(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -237,7 +244,7 @@ func AdminAddGroup(c *gin.Context) {
 func AdminDeleteGroup(c *gin.Context) {
 	var service admin.GroupService
 	if err := c.ShouldBindUri(&service); err == nil {
-		res := service.Delete()
+		res := service.Delete(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -270,7 +277,7 @@ func AdminListUser(c *gin.Context) {
 func AdminAddUser(c *gin.Context) {
 	var service admin.AddUserService
 	if err := c.ShouldBindJSON(&service); err == nil {
-		res := service.Add()
+		res := service.Add(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -292,7 +299,7 @@ func AdminGetUser(c *gin.Context) {
 func AdminDeleteUser(c *gin.Context) {
 	var service admin.UserBatchService
 	if err := c.ShouldBindJSON(&service); err == nil {
-		res := service.Delete()
+		res := service.Delete(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -303,7 +310,7 @@ func AdminDeleteUser(c *gin.Context) {
 func AdminBanUser(c *gin.Context) {
 	var service admin.UserService
 	if err := c.ShouldBindUri(&service); err == nil {
-		res := service.Ban()
+		res := service.Ban(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -417,6 +424,28 @@ func AdminDeleteTask(c *gin.Context) {
 	}
 }
 
+// AdminCancelTask 批量取消任务
+func AdminCancelTask(c *gin.Context) {
+	var service admin.TaskBatchService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Cancel(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminSetTaskPriority 设定任务优先级
+func AdminSetTaskPriority(c *gin.Context) {
+	var service admin.TaskPriorityService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Set(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // AdminCreateImportTask 新建文件导入任务
 func AdminCreateImportTask(c *gin.Context) {
 	var service admin.ImportTaskService
@@ -454,7 +483,7 @@ func AdminListNodes(c *gin.Context) {
 func AdminAddNode(c *gin.Context) {
 	var service admin.AddNodeService
 	if err := c.ShouldBindJSON(&service); err == nil {
-		res := service.Add()
+		res := service.Add(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -465,7 +494,7 @@ func AdminAddNode(c *gin.Context) {
 func AdminToggleNode(c *gin.Context) {
 	var service admin.ToggleNodeService
 	if err := c.ShouldBindUri(&service); err == nil {
-		res := service.Toggle()
+		res := service.Toggle(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -476,7 +505,7 @@ func AdminToggleNode(c *gin.Context) {
 func AdminDeleteNode(c *gin.Context) {
 	var service admin.NodeService
 	if err := c.ShouldBindUri(&service); err == nil {
-		res := service.Delete()
+		res := service.Delete(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -493,3 +522,155 @@ func AdminGetNode(c *gin.Context) {
 		c.JSON(200, ErrorResponse(err))
 	}
 }
+
+// AdminListAuditLogs 列出审计日志
+func AdminListAuditLogs(c *gin.Context) {
+	var service admin.AuditLogService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.List()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminExportAuditLogs 导出审计日志
+func AdminExportAuditLogs(c *gin.Context) {
+	var service admin.AuditLogService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		service.Export(c)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListWebhooks 列出 Webhook
+func AdminListWebhooks(c *gin.Context) {
+	var service admin.AdminListService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Webhooks()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminAddWebhook 新建接收所有用户事件的 Webhook
+func AdminAddWebhook(c *gin.Context) {
+	var service admin.AddWebhookService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Add(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminUpdateWebhook 更新 Webhook
+func AdminUpdateWebhook(c *gin.Context) {
+	var (
+		service admin.WebhookService
+		props   setting.WebhookUpdateService
+	)
+	if err := c.ShouldBindUri(&service); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	if err := c.ShouldBindJSON(&props); err == nil {
+		res := service.Update(c, &props)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminDeleteWebhook 删除 Webhook
+func AdminDeleteWebhook(c *gin.Context) {
+	var service admin.WebhookService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminPingWebhook 发送 Webhook 测试投递
+func AdminPingWebhook(c *gin.Context) {
+	var service admin.WebhookService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Ping()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListWebhookDeliveries 列出 Webhook 投递记录
+func AdminListWebhookDeliveries(c *gin.Context) {
+	var (
+		service admin.WebhookService
+		list    setting.WebhookDeliveryListService
+	)
+	if err := c.ShouldBindUri(&service); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	if err := c.ShouldBindQuery(&list); err == nil {
+		res := service.Deliveries(&list)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListRateLimitLocks 列出限流锁定
+func AdminListRateLimitLocks(c *gin.Context) {
+	c.JSON(200, admin.ListRateLimitLocks())
+}
+
+// AdminListRedeems 列出兑换码
+func AdminListRedeems(c *gin.Context) {
+	var service admin.AdminListService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Redeems()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminGenerateRedeems 批量生成兑换码
+func AdminGenerateRedeems(c *gin.Context) {
+	var service admin.GenerateRedeemsService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Generate(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminDeleteRedeems 删除兑换码
+func AdminDeleteRedeems(c *gin.Context) {
+	var service admin.RedeemBatchService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Delete(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminUnlockRateLimit 解除限流锁定
+func AdminUnlockRateLimit(c *gin.Context) {
+	var service admin.RateLimitUnlockService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Unlock(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
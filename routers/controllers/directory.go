@@ -26,3 +26,22 @@ func ListDirectory(c *gin.Context) {
 		c.JSON(200, ErrorResponse(err))
 	}
 }
+
+// SubscribeDirectoryChanges 通过 WebSocket 订阅目录变更
+func SubscribeDirectoryChanges(c *gin.Context) {
+	var service explorer.DirectoryChangesService
+	if res := service.Subscribe(c, CurrentUser(c)); res.Code != 0 {
+		c.JSON(200, res)
+	}
+}
+
+// GetDelta 列出游标之后的文件系统变更，供同步客户端增量拉取
+func GetDelta(c *gin.Context) {
+	var service explorer.DeltaService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Delta(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/cloudreve/Cloudreve/v3/service/share"
@@ -235,3 +236,145 @@ func GetUserShare(c *gin.Context) {
 		c.JSON(200, ErrorResponse(err))
 	}
 }
+
+// CreateInternalShare 创建站内共享
+func CreateInternalShare(c *gin.Context) {
+	var service share.InternalShareCreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListInternalShare 列出我创建的站内共享
+func ListInternalShare(c *gin.Context) {
+	var service share.InternalShareService
+	res := service.List(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// ListReceivedInternalShare 列出共享给我的目录
+func ListReceivedInternalShare(c *gin.Context) {
+	var service share.InternalShareService
+	res := service.Received(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// DeleteInternalShare 取消站内共享
+func DeleteInternalShare(c *gin.Context) {
+	var service share.InternalShareService
+	res := service.Delete(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// ListInternalSharedFolder 列出共享给我的目录下的对象
+func ListInternalSharedFolder(c *gin.Context) {
+	var service share.InternalShareService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.ListFolder(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// GetInternalShareDownload 创建共享给我的文件的下载会话
+func GetInternalShareDownload(c *gin.Context) {
+	var service share.InternalShareService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.CreateDownloadSession(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// CreateInternalShareDirectory 在共享给我的目录下创建目录
+func CreateInternalShareDirectory(c *gin.Context) {
+	var service share.InternalShareService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.CreateDirectory(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteInternalShareObject 删除共享给我的目录下的对象
+func DeleteInternalShareObject(c *gin.Context) {
+	var service share.InternalShareDeleteService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Delete(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// GetInternalShareUploadSession 在共享给我的目录下创建上传会话
+func GetInternalShareUploadSession(c *gin.Context) {
+	var service share.InternalShareUploadService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// GetShareUploadSession 在上传分享中创建上传会话
+func GetShareUploadSession(c *gin.Context) {
+	var service share.UploadSessionService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ShareUpload 向上传分享上传文件分片
+func ShareUpload(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var service share.UploadService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Upload(ctx, c)
+		c.JSON(200, res)
+		request.BlackHole(c.Request.Body)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListShareEvents 列出分享的访问记录
+func ListShareEvents(c *gin.Context) {
+	var service share.EventListService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.List(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// GetShareEventStat 按天统计分享的访问次数
+func GetShareEventStat(c *gin.Context) {
+	var service share.EventStatService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Stat(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ExportShareEvents 导出分享的访问记录
+func ExportShareEvents(c *gin.Context) {
+	var service share.EventListService
+	service.Export(c, CurrentUser(c))
+}
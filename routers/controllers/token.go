@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/service/setting"
+	"github.com/gin-gonic/gin"
+)
+
+// GetAccessTokens 列出访问令牌
+func GetAccessTokens(c *gin.Context) {
+	var service setting.AccessTokenListService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Tokens(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// CreateAccessToken 创建访问令牌
+func CreateAccessToken(c *gin.Context) {
+	var service setting.AccessTokenCreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteAccessToken 吊销访问令牌
+func DeleteAccessToken(c *gin.Context) {
+	var service setting.AccessTokenService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/authn"
@@ -147,6 +148,50 @@ func FinishRegAuthn(c *gin.Context) {
 	})
 }
 
+// StartOIDCLogin 发起 OpenID Connect 登录或绑定
+func StartOIDCLogin(c *gin.Context) {
+	var service user.OIDCLoginService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Start(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// FinishOIDCLogin 完成 OpenID Connect 登录或绑定
+func FinishOIDCLogin(c *gin.Context) {
+	var service user.OIDCCallbackService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Callback(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UnlinkOIDC 解除 OpenID Connect 身份绑定
+func UnlinkOIDC(c *gin.Context) {
+	var service user.OIDCUnlinkService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Unlink(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// LinkLDAP 将目录用户绑定到当前用户
+func LinkLDAP(c *gin.Context) {
+	var service user.LDAPLinkService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Link(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // UserLogin 用户登录
 func UserLogin(c *gin.Context) {
 	var service user.UserLoginService
@@ -233,6 +278,33 @@ func UserStorage(c *gin.Context) {
 	c.JSON(200, res)
 }
 
+// UserTraffic 获取用户本月流量及最近一年的流量记录
+func UserTraffic(c *gin.Context) {
+	currUser := CurrentUser(c)
+	res := serializer.BuildUserTrafficResponse(
+		*currUser,
+		model.TrafficMonth(time.Now()),
+		model.ListTraffic(currUser.ID, 12),
+	)
+	c.JSON(200, res)
+}
+
+// UserStoragePacks 列出用户尚未过期的容量包
+func UserStoragePacks(c *gin.Context) {
+	c.JSON(200, serializer.BuildStoragePackList(model.ListStoragePacks(CurrentUser(c).ID)))
+}
+
+// UserRedeem 使用兑换码
+func UserRedeem(c *gin.Context) {
+	var service user.RedeemService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Redeem(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // UserTasks 获取任务队列
 func UserTasks(c *gin.Context) {
 	var service user.SettingListService
@@ -244,6 +316,36 @@ func UserTasks(c *gin.Context) {
 	}
 }
 
+// UserCancelTask 取消任务
+func UserCancelTask(c *gin.Context) {
+	var service user.TaskCancelService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Cancel(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserProgress 推送任务及离线下载实时进度
+func UserProgress(c *gin.Context) {
+	var service user.ProgressService
+	if res := service.Stream(c, CurrentUser(c)); res.Code != 0 {
+		c.JSON(200, res)
+	}
+}
+
+// UserActivities 获取文件操作记录
+func UserActivities(c *gin.Context) {
+	var service user.ActivityListService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.List(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // UserSetting 获取用户设定
 func UserSetting(c *gin.Context) {
 	var service user.SettingService
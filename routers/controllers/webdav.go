@@ -1,47 +1,205 @@
 package controllers
 
 import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/activity"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/cloudreve/Cloudreve/v3/pkg/webdav"
 	"github.com/cloudreve/Cloudreve/v3/service/setting"
+	"github.com/cloudreve/Cloudreve/v3/service/share"
 	"github.com/gin-gonic/gin"
-	"sync"
 )
 
 var handler *webdav.Handler
 
+// webDAVGrowMethods 会写入新数据的 WebDAV 请求方法
+var webDAVGrowMethods = map[string]bool{
+	"PUT":   true,
+	"MKCOL": true,
+	"COPY":  true,
+}
+
+// webDAVWriteMethods 会修改文件的 WebDAV 请求方法
+var webDAVWriteMethods = map[string]bool{
+	"PUT":       true,
+	"DELETE":    true,
+	"MKCOL":     true,
+	"COPY":      true,
+	"MOVE":      true,
+	"PROPPATCH": true,
+	"LOCK":      true,
+	"UNLOCK":    true,
+}
+
 func init() {
 	handler = &webdav.Handler{
-		Prefix:     "/dav",
-		LockSystem: make(map[uint]webdav.LockSystem),
-		Mutex:      &sync.Mutex{},
+		Prefix:    "/dav",
+		Groupware: true,
 	}
 }
 
+// withWebDAVPermission 将 WebDAV 账号的操作限制附加到请求上下文
+func withWebDAVPermission(c *gin.Context) {
+	webdavCtx, ok := c.Get("webdav")
+	if !ok {
+		return
+	}
+
+	application := webdavCtx.(*model.Webdav)
+	c.Request = c.Request.WithContext(webdav.WithPermission(c.Request.Context(), webdav.Permission{
+		ReadOnly:   application.HasPermission(model.WebdavReadOnly),
+		NoDelete:   application.HasPermission(model.WebdavNoDelete),
+		UploadOnly: application.HasPermission(model.WebdavUploadOnly),
+	}))
+}
+
 // ServeWebDAV 处理WebDAV相关请求
 func ServeWebDAV(c *gin.Context) {
+	withWebDAVPermission(c)
+
+	// 日历及通讯录不属于任何目录，限定了根目录的账号无法访问
+	if webdavCtx, ok := c.Get("webdav"); ok && webdavCtx.(*model.Webdav).Root != "/" && handler.IsGroupwareRequest(c.Request) {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
 	fs, err := filesystem.NewFileSystemFromContext(c)
 	if err != nil {
 		util.Log().Warning("无法为WebDAV初始化文件系统，%s", err)
 		return
 	}
 
+	// 超额使用被限制为只读
+	if fs.User.ReadOnly && webDAVGrowMethods[c.Request.Method] {
+		fs.Recycle()
+		c.Status(http.StatusInsufficientStorage)
+		return
+	}
+
+	root := "/"
 	if webdavCtx, ok := c.Get("webdav"); ok {
 		application := webdavCtx.(*model.Webdav)
 
 		// 重定根目录
 		if application.Root != "/" {
-			if exist, root := fs.IsPathExist(application.Root); exist {
-				root.Position = ""
-				root.Name = "/"
-				fs.Root = root
+			if exist, folder := fs.IsPathExist(application.Root); exist {
+				folder.Position = ""
+				folder.Name = "/"
+				fs.Root = folder
+				root = application.Root
 			}
 		}
 	}
 
-	handler.ServeHTTP(c.Writer, c.Request, fs)
+	// 请求处理完毕后文件系统会被回收，需提前记录用户
+	uid := fs.User.ID
+	handler.ServeHTTP(c.Writer, c.Request, fs, webdav.NewDBLS(uid, root))
+
+	// 日历及通讯录的变更不属于文件操作
+	if !handler.IsGroupwareRequest(c.Request) {
+		recordWebDAVActivity(c, handler.Prefix, uid, root)
+	}
+}
+
+// WellKnownDAV 将 CalDAV / CardDAV 客户端的服务发现请求重定向至当前用户的主体地址
+func WellKnownDAV(c *gin.Context) {
+	c.Redirect(http.StatusMovedPermanently, handler.PrincipalHref())
+}
+
+// ServeSharedWebDAV 处理站内共享目录的WebDAV请求，请求由共享者的文件系统处理
+func ServeSharedWebDAV(c *gin.Context) {
+	withWebDAVPermission(c)
+	user := CurrentUser(c)
+
+	// 限定了根目录的账号只能访问自己的文件
+	if webdavCtx, ok := c.Get("webdav"); ok && webdavCtx.(*model.Webdav).Root != "/" {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	internalShare := model.GetInternalShareByHashID(c.Param("id"))
+	if internalShare == nil || !internalShare.AccessibleBy(user) || !internalShare.IsAvailable() {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	if !internalShare.Writable && webDAVWriteMethods[c.Request.Method] {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	fs, err := share.NewInternalShareFileSystem(internalShare)
+	if err != nil {
+		util.Log().Warning("无法为WebDAV初始化文件系统，%s", err)
+		return
+	}
+
+	// 共享者超额使用被限制为只读
+	if fs.User.ReadOnly && webDAVGrowMethods[c.Request.Method] {
+		fs.Recycle()
+		c.Status(http.StatusInsufficientStorage)
+		return
+	}
+
+	// 锁保存在数据库中，处理器不持有状态，按请求创建
+	prefix := "/dav-shared/" + c.Param("id")
+	sharedHandler := &webdav.Handler{
+		Prefix: prefix,
+	}
+
+	// 操作记录在共享者名下
+	uid := fs.User.ID
+	root := share.InternalSharePath(internalShare, "/")
+	sharedHandler.ServeHTTP(c.Writer, c.Request, fs, webdav.NewDBLS(uid, root))
+	recordWebDAVActivity(c, prefix, uid, root)
+}
+
+// recordWebDAVActivity 根据请求方法和响应状态记录 WebDAV 操作
+func recordWebDAVActivity(c *gin.Context, prefix string, uid uint, root string) {
+	if c.Writer.Status() < 200 || c.Writer.Status() >= 300 {
+		return
+	}
+
+	var activityType int
+	switch c.Request.Method {
+	case "PUT":
+		activityType = model.ActivityUpload
+	case "MKCOL":
+		activityType = model.ActivityCreate
+	case "DELETE":
+		activityType = model.ActivityDelete
+	case "COPY":
+		activityType = model.ActivityCopy
+	case "MOVE":
+		activityType = model.ActivityMove
+	default:
+		return
+	}
+
+	src := webDAVActivityPath(prefix, root, c.Request.URL.Path)
+	dst := ""
+	if activityType == model.ActivityCopy || activityType == model.ActivityMove {
+		if u, err := url.Parse(c.Request.Header.Get("Destination")); err == nil {
+			dst = webDAVActivityPath(prefix, root, u.Path)
+		}
+
+		if activityType == model.ActivityMove && path.Dir(src) == path.Dir(dst) {
+			activityType = model.ActivityRename
+		}
+	}
+
+	activity.Record(activity.ActorFromContext(c), uid, activityType, src, dst)
+}
+
+// webDAVActivityPath 将 WebDAV 请求路径转换为用户文件系统中的路径
+func webDAVActivityPath(prefix, root, reqPath string) string {
+	return path.Join(root, util.RemoveSlash(strings.TrimPrefix(reqPath, prefix)))
 }
 
 // GetWebDAVAccounts 获取webdav账号列表
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/service/setting"
+	"github.com/gin-gonic/gin"
+)
+
+// GetWebhooks 列出 Webhook
+func GetWebhooks(c *gin.Context) {
+	var service setting.WebhookListService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Webhooks(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// CreateWebhook 创建 Webhook
+func CreateWebhook(c *gin.Context) {
+	var service setting.WebhookCreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UpdateWebhook 更新 Webhook
+func UpdateWebhook(c *gin.Context) {
+	var (
+		target  setting.WebhookService
+		service setting.WebhookUpdateService
+	)
+	if err := c.ShouldBindUri(&target); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Update(c, CurrentUser(c), target.ID)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteWebhook 删除 Webhook
+func DeleteWebhook(c *gin.Context) {
+	var service setting.WebhookService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// PingWebhook 发送 Webhook 测试投递
+func PingWebhook(c *gin.Context) {
+	var service setting.WebhookService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Ping(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListWebhookDeliveries 列出 Webhook 投递记录
+func ListWebhookDeliveries(c *gin.Context) {
+	var (
+		target  setting.WebhookService
+		service setting.WebhookDeliveryListService
+	)
+	if err := c.ShouldBindUri(&target); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.List(c, CurrentUser(c), target.ID)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
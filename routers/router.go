@@ -6,6 +6,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ratelimit"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/cloudreve/Cloudreve/v3/routers/controllers"
 	"github.com/gin-contrib/cors"
@@ -15,13 +16,23 @@ import (
 
 // InitRouter 初始化路由
 func InitRouter() *gin.Engine {
+	var r *gin.Engine
 	if conf.SystemConfig.Mode == "master" {
 		util.Log().Info("当前运行模式：Master")
-		return InitMasterRouter()
+		r = InitMasterRouter()
+	} else {
+		util.Log().Info("当前运行模式：Slave")
+		r = InitSlaveRouter()
 	}
-	util.Log().Info("当前运行模式：Slave")
-	return InitSlaveRouter()
 
+	// 仅信任配置的反向代理提供的客户端地址
+	if len(conf.SystemConfig.TrustedProxies) > 0 {
+		if err := r.SetTrustedProxies(conf.SystemConfig.TrustedProxies); err != nil {
+			util.Log().Warning("无法解析可信代理地址, %s", err)
+		}
+	}
+
+	return r
 }
 
 // InitSlaveRouter 初始化从机模式路由
@@ -189,6 +200,16 @@ func InitMasterRouter() *gin.Engine {
 				middleware.IsFunctionEnabled("authn_enabled"),
 				controllers.FinishLoginAuthn,
 			)
+			// OpenID Connect 登录初始化
+			user.GET("oidc",
+				middleware.IsFunctionEnabled("oidc_enabled"),
+				controllers.StartOIDCLogin,
+			)
+			// OpenID Connect 登录回调
+			user.POST("oidc/callback",
+				middleware.IsFunctionEnabled("oidc_enabled"),
+				controllers.FinishOIDCLogin,
+			)
 			// 获取用户主页展示用分享
 			user.GET("profile/:id",
 				middleware.HashID(hashid.UserID),
@@ -357,6 +378,17 @@ func InitMasterRouter() *gin.Engine {
 				middleware.ShareCanPreview(),
 				controllers.ShareThumb,
 			)
+			// 在上传分享中创建上传会话
+			share.PUT("upload/:id",
+				middleware.CheckShareUnlocked(),
+				middleware.RateLimit(ratelimit.RuleUpload),
+				controllers.GetShareUploadSession,
+			)
+			// 向上传分享上传文件分片
+			share.POST("upload/:id/:sessionId/:index",
+				middleware.CheckShareUnlocked(),
+				controllers.ShareUpload,
+			)
 			// 搜索公共分享
 			v3.Group("share").GET("search", controllers.SearchShare)
 		}
@@ -364,6 +396,7 @@ func InitMasterRouter() *gin.Engine {
 		// 需要登录保护的
 		auth := v3.Group("")
 		auth.Use(middleware.AuthRequired())
+		auth.Use(middleware.TokenScopeRequired())
 		{
 			// 管理
 			admin := auth.Group("admin", middleware.IsAdmin())
@@ -475,10 +508,56 @@ func InitMasterRouter() *gin.Engine {
 					task.POST("list", controllers.AdminListTask)
 					// 删除
 					task.POST("delete", controllers.AdminDeleteTask)
+					// 取消
+					task.POST("cancel", controllers.AdminCancelTask)
+					// 设定优先级
+					task.POST("priority", controllers.AdminSetTaskPriority)
 					// 新建文件导入任务
 					task.POST("import", controllers.AdminCreateImportTask)
 				}
 
+				audit := admin.Group("audit")
+				{
+					// 列出审计日志
+					audit.POST("list", controllers.AdminListAuditLogs)
+					// 导出审计日志
+					audit.GET("export", controllers.AdminExportAuditLogs)
+				}
+
+				webhook := admin.Group("webhook")
+				{
+					// 列出 Webhook
+					webhook.POST("list", controllers.AdminListWebhooks)
+					// 创建接收所有用户事件的 Webhook
+					webhook.POST("", controllers.AdminAddWebhook)
+					// 更新 Webhook
+					webhook.PATCH(":id", controllers.AdminUpdateWebhook)
+					// 删除 Webhook
+					webhook.DELETE(":id", controllers.AdminDeleteWebhook)
+					// 发送测试投递
+					webhook.POST(":id/test", controllers.AdminPingWebhook)
+					// 列出投递记录
+					webhook.GET(":id/deliveries", controllers.AdminListWebhookDeliveries)
+				}
+
+				rateLimit := admin.Group("ratelimit")
+				{
+					// 列出限流锁定
+					rateLimit.GET("locks", controllers.AdminListRateLimitLocks)
+					// 解除限流锁定
+					rateLimit.POST("unlock", controllers.AdminUnlockRateLimit)
+				}
+
+				redeem := admin.Group("redeem")
+				{
+					// 列出兑换码
+					redeem.POST("list", controllers.AdminListRedeems)
+					// 生成兑换码
+					redeem.POST("", controllers.AdminGenerateRedeems)
+					// 删除兑换码
+					redeem.POST("delete", controllers.AdminDeleteRedeems)
+				}
+
 				node := admin.Group("node")
 				{
 					// 列出从机节点
@@ -504,6 +583,16 @@ func InitMasterRouter() *gin.Engine {
 				user.GET("me", controllers.UserMe)
 				// 存储信息
 				user.GET("storage", controllers.UserStorage)
+				// 流量信息
+				user.GET("traffic", controllers.UserTraffic)
+				// 容量包
+				user.GET("packs", controllers.UserStoragePacks)
+				// 使用兑换码
+				user.POST("redeem", controllers.UserRedeem)
+				// 任务及离线下载实时进度
+				user.GET("progress", controllers.UserProgress)
+				// 订阅目录变更
+				user.GET("changes", controllers.SubscribeDirectoryChanges)
 				// 退出登录
 				user.DELETE("session", controllers.UserSignOut)
 
@@ -520,6 +609,10 @@ func InitMasterRouter() *gin.Engine {
 				{
 					// 任务队列
 					setting.GET("tasks", controllers.UserTasks)
+					// 取消任务
+					setting.DELETE("tasks/:id", controllers.UserCancelTask)
+					// 文件操作记录
+					setting.GET("activities", controllers.UserActivities)
 					// 获取当前用户设定
 					setting.GET("", controllers.UserSetting)
 					// 从文件上传头像
@@ -530,6 +623,13 @@ func InitMasterRouter() *gin.Engine {
 					setting.PATCH(":option", controllers.UpdateOption)
 					// 获得二步验证初始化信息
 					setting.GET("2fa", controllers.UserInit2FA)
+					// 解除 OpenID Connect 身份绑定
+					setting.DELETE("oidc/:id", controllers.UnlinkOIDC)
+					// 绑定 LDAP 目录用户
+					setting.PUT("ldap",
+						middleware.IsFunctionEnabled("ldap_enabled"),
+						controllers.LinkLDAP,
+					)
 				}
 			}
 
@@ -540,18 +640,22 @@ func InitMasterRouter() *gin.Engine {
 				upload := file.Group("upload")
 				{
 					// 文件上传
-					upload.POST(":sessionId/:index", controllers.FileUpload)
-					// 创建上传会话
-					upload.PUT("", controllers.GetUploadSession)
+					upload.POST(":sessionId/:index", middleware.WritableRequired(), controllers.FileUpload)
+					// 创建上传会话，仅对创建会话计数，大文件的多个分片不受限制
+					upload.PUT("",
+						middleware.RateLimit(ratelimit.RuleUpload),
+						middleware.WritableRequired(),
+						controllers.GetUploadSession,
+					)
 					// 删除给定上传会话
 					upload.DELETE(":sessionId", controllers.DeleteUploadSession)
 					// 删除全部上传会话
 					upload.DELETE("", controllers.DeleteAllUploadSession)
 				}
 				// 更新文件
-				file.PUT("update/:id", controllers.PutContent)
+				file.PUT("update/:id", middleware.WritableRequired(), controllers.PutContent)
 				// 创建空白文件
-				file.POST("create", controllers.CreateFile)
+				file.POST("create", middleware.WritableRequired(), controllers.CreateFile)
 				// 创建文件下载会话
 				file.PUT("download/:id", controllers.CreateDownloadSession)
 				// 预览文件
@@ -567,9 +671,9 @@ func InitMasterRouter() *gin.Engine {
 				// 打包要下载的文件
 				file.POST("archive", controllers.Archive)
 				// 创建文件压缩任务
-				file.POST("compress", controllers.Compress)
+				file.POST("compress", middleware.WritableRequired(), controllers.Compress)
 				// 创建文件解压缩任务
-				file.POST("decompress", controllers.Decompress)
+				file.POST("decompress", middleware.WritableRequired(), controllers.Decompress)
 				// 创建文件解压缩任务
 				file.GET("search/:type/:keywords", controllers.SearchFile)
 			}
@@ -578,9 +682,18 @@ func InitMasterRouter() *gin.Engine {
 			aria2 := auth.Group("aria2")
 			{
 				// 创建URL下载任务
-				aria2.POST("url", controllers.AddAria2URL)
+				aria2.POST("url",
+					middleware.WritableRequired(),
+					middleware.RateLimit(ratelimit.RuleAria2),
+					controllers.AddAria2URL,
+				)
 				// 创建种子下载任务
-				aria2.POST("torrent/:id", middleware.HashID(hashid.FileID), controllers.AddAria2Torrent)
+				aria2.POST("torrent/:id",
+					middleware.HashID(hashid.FileID),
+					middleware.WritableRequired(),
+					middleware.RateLimit(ratelimit.RuleAria2),
+					controllers.AddAria2Torrent,
+				)
 				// 重新选择要下载的文件
 				aria2.PUT("select/:gid", controllers.SelectAria2File)
 				// 取消或删除下载任务
@@ -591,11 +704,14 @@ func InitMasterRouter() *gin.Engine {
 				aria2.GET("finished", controllers.ListFinished)
 			}
 
+			// 同步客户端增量拉取文件系统变更
+			auth.GET("delta", controllers.GetDelta)
+
 			// 目录
 			directory := auth.Group("directory")
 			{
 				// 创建目录
-				directory.PUT("", controllers.CreateDirectory)
+				directory.PUT("", middleware.WritableRequired(), controllers.CreateDirectory)
 				// 列出目录下内容
 				directory.GET("*path", controllers.ListDirectory)
 			}
@@ -608,7 +724,7 @@ func InitMasterRouter() *gin.Engine {
 				// 移动对象
 				object.PATCH("", controllers.Move)
 				// 复制对象
-				object.POST("copy", controllers.Copy)
+				object.POST("copy", middleware.WritableRequired(), controllers.Copy)
 				// 重命名对象
 				object.POST("rename", controllers.Rename)
 				// 获取对象属性
@@ -632,6 +748,50 @@ func InitMasterRouter() *gin.Engine {
 				share.DELETE(":id",
 					controllers.DeleteShare,
 				)
+				// 列出分享访问记录
+				share.GET(":id/events", controllers.ListShareEvents)
+				// 导出分享访问记录
+				share.GET(":id/events/export", controllers.ExportShareEvents)
+				// 按天统计分享访问次数
+				share.GET(":id/stats", controllers.GetShareEventStat)
+			}
+
+			// 站内共享
+			shared := auth.Group("shared")
+			{
+				// 创建或更新站内共享
+				shared.POST("", controllers.CreateInternalShare)
+				// 列出我创建的站内共享
+				shared.GET("", controllers.ListInternalShare)
+				// 列出共享给我的目录
+				shared.GET("received", controllers.ListReceivedInternalShare)
+				// 取消站内共享
+				shared.DELETE(":id", controllers.DeleteInternalShare)
+				// 列出共享目录下的对象
+				shared.GET("list/:id/*path",
+					middleware.InternalShareAccessible(),
+					controllers.ListInternalSharedFolder,
+				)
+				// 创建文件下载会话
+				shared.PUT("download/:id",
+					middleware.InternalShareAccessible(),
+					controllers.GetInternalShareDownload,
+				)
+				// 创建目录
+				shared.PUT("directory/:id",
+					middleware.InternalShareAccessible(),
+					controllers.CreateInternalShareDirectory,
+				)
+				// 删除对象
+				shared.DELETE("object/:id",
+					middleware.InternalShareAccessible(),
+					controllers.DeleteInternalShareObject,
+				)
+				// 创建上传会话
+				shared.PUT("upload/:id",
+					middleware.InternalShareAccessible(),
+					controllers.GetInternalShareUploadSession,
+				)
 			}
 
 			// 用户标签
@@ -656,12 +816,47 @@ func InitMasterRouter() *gin.Engine {
 				webdav.DELETE("accounts/:id", controllers.DeleteWebDAVAccounts)
 			}
 
+			// 个人访问令牌管理相关
+			token := auth.Group("token")
+			{
+				// 列出令牌
+				token.GET("", controllers.GetAccessTokens)
+				// 新建令牌
+				token.POST("", controllers.CreateAccessToken)
+				// 吊销令牌
+				token.DELETE(":id", controllers.DeleteAccessToken)
+			}
+
+			// Webhook 管理相关
+			webhook := auth.Group("webhook")
+			{
+				// 列出 Webhook
+				webhook.GET("", controllers.GetWebhooks)
+				// 新建 Webhook
+				webhook.POST("", controllers.CreateWebhook)
+				// 更新 Webhook
+				webhook.PATCH(":id", controllers.UpdateWebhook)
+				// 删除 Webhook
+				webhook.DELETE(":id", controllers.DeleteWebhook)
+				// 发送测试投递
+				webhook.POST(":id/test", controllers.PingWebhook)
+				// 列出投递记录
+				webhook.GET(":id/deliveries", controllers.ListWebhookDeliveries)
+			}
+
 		}
 
 	}
 
 	// 初始化WebDAV相关路由
 	initWebDAV(r.Group("dav"))
+	initSharedWebDAV(r.Group("dav-shared"))
+
+	// CalDAV / CardDAV 服务发现
+	for _, service := range []string{"caldav", "carddav"} {
+		r.Any("/.well-known/"+service, controllers.WellKnownDAV)
+		r.Handle("PROPFIND", "/.well-known/"+service, controllers.WellKnownDAV)
+	}
 	return r
 }
 
@@ -680,6 +875,26 @@ func initWebDAV(group *gin.RouterGroup) {
 		group.Handle("PROPPATCH", "/*path", controllers.ServeWebDAV)
 		group.Handle("COPY", "/*path", controllers.ServeWebDAV)
 		group.Handle("MOVE", "/*path", controllers.ServeWebDAV)
+		group.Handle("REPORT", "/*path", controllers.ServeWebDAV)
+		group.Handle("MKCALENDAR", "/*path", controllers.ServeWebDAV)
+
+	}
+}
+
+// initSharedWebDAV 初始化站内共享目录的WebDAV相关路由
+func initSharedWebDAV(group *gin.RouterGroup) {
+	{
+		group.Use(middleware.WebDAVAuth())
 
+		group.Any("/:id/*path", controllers.ServeSharedWebDAV)
+		group.Any("/:id", controllers.ServeSharedWebDAV)
+		group.Handle("PROPFIND", "/:id/*path", controllers.ServeSharedWebDAV)
+		group.Handle("PROPFIND", "/:id", controllers.ServeSharedWebDAV)
+		group.Handle("MKCOL", "/:id/*path", controllers.ServeSharedWebDAV)
+		group.Handle("LOCK", "/:id/*path", controllers.ServeSharedWebDAV)
+		group.Handle("UNLOCK", "/:id/*path", controllers.ServeSharedWebDAV)
+		group.Handle("PROPPATCH", "/:id/*path", controllers.ServeSharedWebDAV)
+		group.Handle("COPY", "/:id/*path", controllers.ServeSharedWebDAV)
+		group.Handle("MOVE", "/:id/*path", controllers.ServeSharedWebDAV)
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/downloader"
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/request"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
@@ -15,15 +16,28 @@ import (
 
 // Aria2TestService aria2连接测试服务
 type Aria2TestService struct {
-	Server string          `json:"server"`
-	RPC    string          `json:"rpc" binding:"required"`
-	Secret string          `json:"secret"`
-	Token  string          `json:"token"`
-	Type   model.ModelType `json:"type"`
+	Server     string          `json:"server"`
+	RPC        string          `json:"rpc"`
+	Secret     string          `json:"secret"`
+	Token      string          `json:"token"`
+	Type       model.ModelType `json:"type"`
+	Downloader string          `json:"downloader"`
+	TempPath   string          `json:"temp_path"`
 }
 
-// Test 测试aria2连接
+// Test 测试aria2连接，使用内置下载器时测试临时下载目录是否可写
 func (service *Aria2TestService) TestMaster() serializer.Response {
+	if service.Downloader == model.DownloaderBuiltin {
+		if err := downloader.TestTempPath(service.TempPath); err != nil {
+			return serializer.ParamErr("Temp path is not writable: "+err.Error(), err)
+		}
+		return serializer.Response{Data: downloader.Version}
+	}
+
+	if service.RPC == "" {
+		return serializer.ParamErr("RPC server address is required", nil)
+	}
+
 	res, err := aria2.TestRPCConnection(service.RPC, service.Token, 5)
 	if err != nil {
 		return serializer.ParamErr("Failed to connect to RPC server: "+err.Error(), err)
@@ -0,0 +1,120 @@
+package admin
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// auditExportBatch 导出审计日志时每批读取的条数
+const auditExportBatch = 500
+
+// AuditLogService 审计日志查询服务
+type AuditLogService struct {
+	Page     int    `json:"page" form:"page" binding:"min=0"`
+	PageSize int    `json:"page_size" form:"page_size" binding:"min=0,max=100"`
+	AdminID  uint   `json:"admin_id" form:"admin_id"`
+	Action   string `json:"action" form:"action"`
+	Target   uint   `json:"target" form:"target"`
+	IP       string `json:"ip" form:"ip"`
+	Start    int64  `json:"start" form:"start"` // 起始时间戳
+	End      int64  `json:"end" form:"end"`     // 截止时间戳
+}
+
+// query 根据过滤条件构建查询
+func (service *AuditLogService) query() *gorm.DB {
+	tx := model.DB.Model(&model.AuditLog{})
+	if service.AdminID > 0 {
+		tx = tx.Where("admin_id = ?", service.AdminID)
+	}
+
+	if service.Action != "" {
+		tx = tx.Where("action = ?", service.Action)
+	}
+
+	if service.Target > 0 {
+		target := strconv.FormatUint(uint64(service.Target), 10)
+		tx = tx.Where("targets = ? OR targets like ? OR targets like ? OR targets like ?",
+			"["+target+"]", "["+target+",%", "%,"+target+",%", "%,"+target+"]")
+	}
+
+	if service.IP != "" {
+		tx = tx.Where("ip = ?", service.IP)
+	}
+
+	if service.Start > 0 {
+		tx = tx.Where("created_at >= ?", time.Unix(service.Start, 0))
+	}
+
+	if service.End > 0 {
+		tx = tx.Where("created_at <= ?", time.Unix(service.End, 0))
+	}
+
+	return tx
+}
+
+// List 列出审计日志
+func (service *AuditLogService) List() serializer.Response {
+	if service.Page == 0 {
+		service.Page = 1
+	}
+
+	if service.PageSize == 0 {
+		service.PageSize = 20
+	}
+
+	var res []model.AuditLog
+	total := 0
+
+	tx := service.query()
+
+	// 计算总数用于分页
+	tx.Count(&total)
+
+	// 查询记录
+	tx.Order("id desc").Limit(service.PageSize).Offset((service.Page - 1) * service.PageSize).Find(&res)
+
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": res,
+	}}
+}
+
+// Export 以 CSV 格式导出审计日志
+func (service *AuditLogService) Export(c *gin.Context) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"audit_%s.csv\"", time.Now().Format("20060102150405")))
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"id", "created_at", "admin_id", "action", "targets", "ip", "diff"})
+
+	var lastID uint
+	for {
+		var logs []model.AuditLog
+		service.query().Where("id > ?", lastID).Order("id asc").Limit(auditExportBatch).Find(&logs)
+
+		for _, log := range logs {
+			writer.Write([]string{
+				strconv.FormatUint(uint64(log.ID), 10),
+				log.CreatedAt.Format(time.RFC3339),
+				strconv.FormatUint(uint64(log.AdminID), 10),
+				log.Action,
+				log.Targets,
+				log.IP,
+				log.Diff,
+			})
+		}
+
+		writer.Flush()
+		if len(logs) < auditExportBatch {
+			return
+		}
+		lastID = logs[len(logs)-1].ID
+	}
+}
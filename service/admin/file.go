@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/audit"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
@@ -117,6 +118,8 @@ func (service *FileBatchService) Delete(c *gin.Context) serializer.Response {
 		userFile[files[i].UserID] = append(userFile[files[i].UserID], files[i])
 	}
 
+	audit.Record(c, audit.ActionFileDelete, service.ID, nil)
+
 	// 异步执行删除
 	go func(files map[uint][]model.File) {
 		for uid, file := range files {
@@ -2,7 +2,9 @@ package admin
 
 import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/audit"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
 	"strconv"
 )
 
@@ -27,7 +29,7 @@ func (service *GroupService) Get() serializer.Response {
 }
 
 // Delete 删除用户组
-func (service *GroupService) Delete() serializer.Response {
+func (service *GroupService) Delete(c *gin.Context) serializer.Response {
 	// 查找用户组
 	group, err := model.GetGroupByID(service.ID)
 	if err != nil {
@@ -49,13 +51,19 @@ func (service *GroupService) Delete() serializer.Response {
 	}
 
 	model.DB.Delete(&group)
+	audit.Record(c, audit.ActionGroupDelete, []uint{group.ID}, audit.Diff(group, nil))
 
 	return serializer.Response{}
 }
 
 // Add 添加用户组
-func (service *AddGroupService) Add() serializer.Response {
+func (service *AddGroupService) Add(c *gin.Context) serializer.Response {
+	var before interface{}
 	if service.Group.ID > 0 {
+		if group, err := model.GetGroupByID(service.Group.ID); err == nil {
+			before = group
+		}
+
 		if err := model.DB.Save(&service.Group).Error; err != nil {
 			return serializer.DBErr("Failed to save group record", err)
 		}
@@ -65,6 +73,8 @@ func (service *AddGroupService) Add() serializer.Response {
 		}
 	}
 
+	audit.Record(c, audit.ActionGroupSave, []uint{service.Group.ID}, audit.Diff(before, service.Group))
+
 	return serializer.Response{Data: service.Group.ID}
 }
 
@@ -2,8 +2,10 @@ package admin
 
 import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/audit"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
 	"strings"
 )
 
@@ -13,8 +15,19 @@ type AddNodeService struct {
 }
 
 // Add 添加节点
-func (service *AddNodeService) Add() serializer.Response {
+func (service *AddNodeService) Add(c *gin.Context) serializer.Response {
+	switch service.Node.Aria2OptionsSerialized.Downloader {
+	case "", model.DownloaderAria2, model.DownloaderBuiltin:
+	default:
+		return serializer.ParamErr("Unknown offline downloader", nil)
+	}
+
+	var before interface{}
 	if service.Node.ID > 0 {
+		if node, err := model.GetNodeByID(service.Node.ID); err == nil {
+			before = node
+		}
+
 		if err := model.DB.Save(&service.Node).Error; err != nil {
 			return serializer.DBErr("Failed to save node record", err)
 		}
@@ -28,6 +41,8 @@ func (service *AddNodeService) Add() serializer.Response {
 		cluster.Default.Add(&service.Node)
 	}
 
+	audit.Record(c, audit.ActionNodeSave, []uint{service.Node.ID}, audit.Diff(before, service.Node))
+
 	return serializer.Response{Data: service.Node.ID}
 }
 
@@ -81,7 +96,7 @@ type ToggleNodeService struct {
 }
 
 // Toggle 开关节点
-func (service *ToggleNodeService) Toggle() serializer.Response {
+func (service *ToggleNodeService) Toggle(c *gin.Context) serializer.Response {
 	node, err := model.GetNodeByID(service.ID)
 	if err != nil {
 		return serializer.DBErr("Node not found", err)
@@ -98,8 +113,10 @@ func (service *ToggleNodeService) Toggle() serializer.Response {
 
 	if service.Desired == model.NodeActive {
 		cluster.Default.Add(&node)
+		audit.Record(c, audit.ActionNodeEnable, []uint{node.ID}, nil)
 	} else {
 		cluster.Default.Delete(node.ID)
+		audit.Record(c, audit.ActionNodeDisable, []uint{node.ID}, nil)
 	}
 
 	return serializer.Response{}
@@ -111,7 +128,7 @@ type NodeService struct {
 }
 
 // Delete 删除节点
-func (service *NodeService) Delete() serializer.Response {
+func (service *NodeService) Delete(c *gin.Context) serializer.Response {
 	// 查找用户组
 	node, err := model.GetNodeByID(service.ID)
 	if err != nil {
@@ -127,6 +144,7 @@ func (service *NodeService) Delete() serializer.Response {
 	if err := model.DB.Delete(&node).Error; err != nil {
 		return serializer.DBErr("Failed to delete node record", err)
 	}
+	audit.Record(c, audit.ActionNodeDelete, []uint{node.ID}, audit.Diff(node, nil))
 
 	return serializer.Response{}
 }
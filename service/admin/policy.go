@@ -14,6 +14,7 @@ import (
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/audit"
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
@@ -56,7 +57,7 @@ type PolicyService struct {
 }
 
 // Delete 删除存储策略
-func (service *PolicyService) Delete() serializer.Response {
+func (service *PolicyService) Delete(c *gin.Context) serializer.Response {
 	// 禁止删除默认策略
 	if service.ID == 1 {
 		return serializer.Err(serializer.CodeDeleteDefaultPolicy, "", nil)
@@ -89,6 +90,7 @@ func (service *PolicyService) Delete() serializer.Response {
 
 	model.DB.Delete(&policy)
 	policy.ClearCache()
+	audit.Record(c, audit.ActionPolicyDelete, []uint{policy.ID}, audit.Diff(policy, nil))
 
 	return serializer.Response{}
 }
@@ -260,12 +262,17 @@ func (service *SlaveTestService) Test() serializer.Response {
 }
 
 // Add 添加存储策略
-func (service *AddPolicyService) Add() serializer.Response {
+func (service *AddPolicyService) Add(c *gin.Context) serializer.Response {
 	if service.Policy.Type != "local" && service.Policy.Type != "remote" {
 		service.Policy.DirNameRule = strings.TrimPrefix(service.Policy.DirNameRule, "/")
 	}
 
+	var before interface{}
 	if service.Policy.ID > 0 {
+		if policy, err := model.GetPolicyByID(service.Policy.ID); err == nil {
+			before = policy
+		}
+
 		if err := model.DB.Save(&service.Policy).Error; err != nil {
 			return serializer.DBErr("Failed to save policy", err)
 		}
@@ -276,6 +283,7 @@ func (service *AddPolicyService) Add() serializer.Response {
 	}
 
 	service.Policy.ClearCache()
+	audit.Record(c, audit.ActionPolicySave, []uint{service.Policy.ID}, audit.Diff(before, service.Policy))
 
 	return serializer.Response{Data: service.Policy.ID}
 }
@@ -0,0 +1,31 @@
+package admin
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/pkg/audit"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ratelimit"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitUnlockService 解除限流锁定服务
+type RateLimitUnlockService struct {
+	Rule string `json:"rule" binding:"required"`
+	Key  string `json:"key" binding:"required"`
+}
+
+// ListRateLimitLocks 列出当前被限流锁定的 IP、用户和分享
+func ListRateLimitLocks() serializer.Response {
+	return serializer.Response{Data: ratelimit.Locks()}
+}
+
+// Unlock 解除锁定
+func (service *RateLimitUnlockService) Unlock(c *gin.Context) serializer.Response {
+	if err := ratelimit.Unlock(service.Rule, service.Key); err != nil {
+		return serializer.Err(serializer.CodeCacheOperation, "Failed to unlock", err)
+	}
+
+	audit.Record(c, audit.ActionRateLimitUnlock, nil, map[string]audit.Change{
+		service.Rule: {Before: service.Key},
+	})
+	return serializer.Response{}
+}
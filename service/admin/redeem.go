@@ -0,0 +1,112 @@
+package admin
+
+import (
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/audit"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// GenerateRedeemsService 兑换码生成服务
+type GenerateRedeemsService struct {
+	Num      int    `json:"num" binding:"required,min=1,max=100"`
+	Type     int    `json:"type" binding:"min=0,max=1"`
+	Name     string `json:"name" binding:"max=255"`
+	Size     uint64 `json:"size"`
+	GroupID  uint   `json:"group_id"`
+	Duration int64  `json:"duration" binding:"required,min=1"`
+}
+
+// RedeemBatchService 兑换码批量操作服务
+type RedeemBatchService struct {
+	ID []uint `json:"id" binding:"min=1"`
+}
+
+// Generate 批量生成兑换码
+func (service *GenerateRedeemsService) Generate(c *gin.Context) serializer.Response {
+	switch service.Type {
+	case model.RedeemTypePack:
+		if service.Size == 0 {
+			return serializer.ParamErr("Storage pack size cannot be zero", nil)
+		}
+	case model.RedeemTypeGroup:
+		// 游客用户组不能兑换
+		group, err := model.GetGroupByID(service.GroupID)
+		if err != nil || group.ID == 3 {
+			return serializer.Err(serializer.CodeGroupNotFound, "", err)
+		}
+	}
+
+	redeems := make([]model.Redeem, service.Num)
+	codes := make([]string, service.Num)
+	for i := range redeems {
+		codes[i] = util.RandStringRunes(32)
+		redeems[i] = model.Redeem{
+			Type:     service.Type,
+			Name:     service.Name,
+			Size:     service.Size,
+			GroupID:  service.GroupID,
+			Duration: service.Duration,
+			Code:     codes[i],
+		}
+	}
+
+	if err := model.CreateRedeems(redeems); err != nil {
+		return serializer.DBErr("Failed to create redeem codes", err)
+	}
+
+	ids := make([]uint, 0, len(redeems))
+	for _, redeem := range redeems {
+		ids = append(ids, redeem.ID)
+	}
+	audit.Record(c, audit.ActionRedeemGenerate, ids, nil)
+
+	return serializer.Response{Data: codes}
+}
+
+// Delete 删除兑换码
+func (service *RedeemBatchService) Delete(c *gin.Context) serializer.Response {
+	if err := model.DeleteRedeems(service.ID); err != nil {
+		return serializer.DBErr("Failed to delete redeem codes", err)
+	}
+	audit.Record(c, audit.ActionRedeemDelete, service.ID, nil)
+	return serializer.Response{}
+}
+
+// Redeems 列出兑换码
+func (service *AdminListService) Redeems() serializer.Response {
+	var res []model.Redeem
+	total := 0
+
+	tx := model.DB.Model(&model.Redeem{})
+	if service.OrderBy != "" {
+		tx = tx.Order(service.OrderBy)
+	}
+
+	for k, v := range service.Conditions {
+		tx = tx.Where(k+" = ?", v)
+	}
+
+	if len(service.Searches) > 0 {
+		search := ""
+		for k, v := range service.Searches {
+			search += k + " like '%" + v + "%' OR "
+		}
+		search = strings.TrimSuffix(search, " OR ")
+		tx = tx.Where(search)
+	}
+
+	// 计算总数用于分页
+	tx.Count(&total)
+
+	// 查询记录
+	tx.Limit(service.PageSize).Offset((service.Page - 1) * service.PageSize).Find(&res)
+
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": res,
+	}}
+}
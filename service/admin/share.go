@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/audit"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/gin-gonic/gin"
@@ -19,6 +20,7 @@ func (service *ShareBatchService) Delete(c *gin.Context) serializer.Response {
 	if err := model.DB.Where("id in (?)", service.ID).Delete(&model.Share{}).Error; err != nil {
 		return serializer.DBErr("Failed to delete share record", err)
 	}
+	audit.Record(c, audit.ActionShareDelete, service.ID, nil)
 	return serializer.Response{}
 }
 
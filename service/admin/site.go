@@ -5,10 +5,12 @@ import (
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/audit"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/email"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
 )
 
 func init() {
@@ -56,8 +58,15 @@ func (service *BatchSettingGet) Get() serializer.Response {
 }
 
 // Change 批量更改站点设定
-func (service *BatchSettingChangeService) Change() serializer.Response {
+func (service *BatchSettingChangeService) Change(c *gin.Context) serializer.Response {
 	cacheClean := make([]string, 0, len(service.Options))
+	keys := make([]string, 0, len(service.Options))
+	after := make(map[string]string, len(service.Options))
+	for _, setting := range service.Options {
+		keys = append(keys, setting.Key)
+		after[setting.Key] = setting.Value
+	}
+	before := model.GetSettingByNames(keys...)
 	tx := model.DB.Begin()
 
 	for _, setting := range service.Options {
@@ -76,6 +85,7 @@ func (service *BatchSettingChangeService) Change() serializer.Response {
 	}
 
 	cache.Deletes(cacheClean, "setting_")
+	audit.Record(c, audit.ActionSettingUpdate, nil, audit.Diff(before, after))
 
 	return serializer.Response{}
 }
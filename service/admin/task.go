@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/audit"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task"
 	"github.com/gin-gonic/gin"
@@ -14,6 +15,12 @@ type TaskBatchService struct {
 	ID []uint `json:"id" binding:"min=1"`
 }
 
+// TaskPriorityService 任务优先级设定服务
+type TaskPriorityService struct {
+	ID       []uint `json:"id" binding:"min=1"`
+	Priority int    `json:"priority"`
+}
+
 // ImportTaskService 导入任务
 type ImportTaskService struct {
 	UID       uint   `json:"uid" binding:"required"`
@@ -31,6 +38,7 @@ func (service *ImportTaskService) Create(c *gin.Context, user *model.User) seria
 		return serializer.DBErr("Failed to create task record.", err)
 	}
 	task.TaskPoll.Submit(job)
+	audit.Record(c, audit.ActionTaskImport, []uint{service.UID}, audit.Diff(nil, service))
 	return serializer.Response{}
 }
 
@@ -39,6 +47,7 @@ func (service *TaskBatchService) Delete(c *gin.Context) serializer.Response {
 	if err := model.DB.Where("id in (?)", service.ID).Delete(&model.Download{}).Error; err != nil {
 		return serializer.DBErr("Failed to delete task records", err)
 	}
+	audit.Record(c, audit.ActionDownloadDelete, service.ID, nil)
 	return serializer.Response{}
 }
 
@@ -47,6 +56,37 @@ func (service *TaskBatchService) DeleteGeneral(c *gin.Context) serializer.Respon
 	if err := model.DB.Where("id in (?)", service.ID).Delete(&model.Task{}).Error; err != nil {
 		return serializer.DBErr("Failed to delete task records", err)
 	}
+	audit.Record(c, audit.ActionTaskDelete, service.ID, nil)
+	return serializer.Response{}
+}
+
+// Cancel 取消排队中或执行中的常规任务
+func (service *TaskBatchService) Cancel(c *gin.Context) serializer.Response {
+	canceled := make([]uint, 0, len(service.ID))
+	for _, id := range service.ID {
+		ok, err := task.Cancel(id, 0)
+		if err != nil {
+			return serializer.DBErr("Failed to cancel task", err)
+		}
+
+		if ok {
+			canceled = append(canceled, id)
+		}
+	}
+
+	if len(canceled) > 0 {
+		audit.Record(c, audit.ActionTaskCancel, canceled, nil)
+	}
+	return serializer.Response{Data: canceled}
+}
+
+// Set 设定任务优先级，仅对尚未开始执行的任务生效
+func (service *TaskPriorityService) Set(c *gin.Context) serializer.Response {
+	if err := model.DB.Model(&model.Task{}).Where("id in (?)", service.ID).
+		Update("priority", service.Priority).Error; err != nil {
+		return serializer.DBErr("Failed to update task priority", err)
+	}
+	audit.Record(c, audit.ActionTaskPriority, service.ID, nil)
 	return serializer.Response{}
 }
 
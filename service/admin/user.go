@@ -5,8 +5,10 @@ import (
 	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/audit"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
 )
 
 // AddUserService 用户添加服务
@@ -26,7 +28,7 @@ type UserBatchService struct {
 }
 
 // Ban 封禁/解封用户
-func (service *UserService) Ban() serializer.Response {
+func (service *UserService) Ban(c *gin.Context) serializer.Response {
 	user, err := model.GetUserByID(service.ID)
 	if err != nil {
 		return serializer.Err(serializer.CodeUserNotFound, "", err)
@@ -38,15 +40,17 @@ func (service *UserService) Ban() serializer.Response {
 
 	if user.Status == model.Active {
 		user.SetStatus(model.Baned)
+		audit.Record(c, audit.ActionUserBan, []uint{user.ID}, nil)
 	} else {
 		user.SetStatus(model.Active)
+		audit.Record(c, audit.ActionUserUnban, []uint{user.ID}, nil)
 	}
 
 	return serializer.Response{Data: user.Status}
 }
 
 // Delete 删除用户
-func (service *UserBatchService) Delete() serializer.Response {
+func (service *UserBatchService) Delete(c *gin.Context) serializer.Response {
 	for _, uid := range service.ID {
 		user, err := model.GetUserByID(uid)
 		if err != nil {
@@ -78,8 +82,12 @@ func (service *UserBatchService) Delete() serializer.Response {
 		// 删除WebDAV账号
 		model.DB.Where("user_id = ?", uid).Delete(&model.Webdav{})
 
+		// 删除日历及通讯录
+		model.DeleteDavCollectionsByUser(uid)
+
 		// 删除此用户
 		model.DB.Unscoped().Delete(user)
+		audit.Record(c, audit.ActionUserDelete, []uint{uid}, audit.Diff(user, nil))
 
 	}
 	return serializer.Response{}
@@ -96,10 +104,11 @@ func (service *UserService) Get() serializer.Response {
 }
 
 // Add 添加用户
-func (service *AddUserService) Add() serializer.Response {
+func (service *AddUserService) Add(c *gin.Context) serializer.Response {
 	if service.User.ID > 0 {
 
 		user, _ := model.GetUserByID(service.User.ID)
+		before := user
 		if service.Password != "" {
 			user.SetPassword(service.Password)
 		}
@@ -110,6 +119,12 @@ func (service *AddUserService) Add() serializer.Response {
 		user.GroupID = service.User.GroupID
 		user.Status = service.User.Status
 
+		// 手动调整用户组后取消限时用户组
+		if user.GroupID != before.GroupID {
+			user.PreviousGroupID = 0
+			user.GroupExpires = nil
+		}
+
 		// 检查愚蠢操作
 		if user.ID == 1 && user.GroupID != 1 {
 			return serializer.Err(serializer.CodeChangeGroupForDefaultUser, "", nil)
@@ -118,11 +133,18 @@ func (service *AddUserService) Add() serializer.Response {
 		if err := model.DB.Save(&user).Error; err != nil {
 			return serializer.DBErr("Failed to save user record", err)
 		}
+
+		diff := audit.Diff(before, user)
+		if service.Password != "" {
+			diff["Password"] = audit.Change{Before: "******", After: "******"}
+		}
+		audit.Record(c, audit.ActionUserSave, []uint{user.ID}, diff)
 	} else {
 		service.User.SetPassword(service.Password)
 		if err := model.DB.Create(&service.User).Error; err != nil {
 			return serializer.DBErr("Failed to create user record", err)
 		}
+		audit.Record(c, audit.ActionUserSave, []uint{service.User.ID}, audit.Diff(nil, service.User))
 	}
 
 	return serializer.Response{Data: service.User.ID}
@@ -0,0 +1,118 @@
+package admin
+
+import (
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/audit"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/service/setting"
+	"github.com/gin-gonic/gin"
+)
+
+// AddWebhookService 管理员 Webhook 添加服务
+type AddWebhookService struct {
+	setting.WebhookCreateService
+}
+
+// WebhookService Webhook ID服务
+type WebhookService struct {
+	ID uint `uri:"id" json:"id" binding:"required"`
+}
+
+// Add 添加接收所有用户事件的 Webhook
+func (service *AddWebhookService) Add(c *gin.Context, user *model.User) serializer.Response {
+	res := service.Save(user.ID, true)
+	if res.Code == 0 {
+		id := res.Data.(map[string]interface{})["id"].(uint)
+		audit.Record(c, audit.ActionWebhookSave, []uint{id}, audit.Diff(nil, service.WebhookCreateService))
+	}
+
+	return res
+}
+
+// Update 更新 Webhook
+func (service *WebhookService) Update(c *gin.Context, props *setting.WebhookUpdateService) serializer.Response {
+	hook, err := model.GetWebhookByID(service.ID, 0)
+	if err != nil {
+		return serializer.Err(serializer.CodeWebhookNotFound, "", err)
+	}
+
+	before := *hook
+	res := props.Apply(hook)
+	if res.Code == 0 {
+		audit.Record(c, audit.ActionWebhookSave, []uint{hook.ID}, audit.Diff(before, hook))
+	}
+
+	return res
+}
+
+// Delete 删除 Webhook
+func (service *WebhookService) Delete(c *gin.Context) serializer.Response {
+	hook, err := model.GetWebhookByID(service.ID, 0)
+	if err != nil {
+		return serializer.Err(serializer.CodeWebhookNotFound, "", err)
+	}
+
+	if err := model.DeleteWebhookByID(hook.ID, 0); err != nil {
+		return serializer.DBErr("Failed to delete webhook", err)
+	}
+
+	audit.Record(c, audit.ActionWebhookDelete, []uint{hook.ID}, audit.Diff(hook, nil))
+	return serializer.Response{}
+}
+
+// Ping 发送测试投递
+func (service *WebhookService) Ping() serializer.Response {
+	hook, err := model.GetWebhookByID(service.ID, 0)
+	if err != nil {
+		return serializer.Err(serializer.CodeWebhookNotFound, "", err)
+	}
+
+	return setting.PingWebhook(hook)
+}
+
+// Deliveries 列出投递记录
+func (service *WebhookService) Deliveries(list *setting.WebhookDeliveryListService) serializer.Response {
+	hook, err := model.GetWebhookByID(service.ID, 0)
+	if err != nil {
+		return serializer.Err(serializer.CodeWebhookNotFound, "", err)
+	}
+
+	return list.Deliveries(hook)
+}
+
+// Webhooks 列出所有 Webhook
+func (service *AdminListService) Webhooks() serializer.Response {
+	var res []model.Webhook
+	total := 0
+
+	tx := model.DB.Model(&model.Webhook{})
+	if service.OrderBy != "" {
+		tx = tx.Order(service.OrderBy)
+	}
+
+	for k, v := range service.Conditions {
+		tx = tx.Where(k+" = ?", v)
+	}
+
+	if len(service.Searches) > 0 {
+		search := ""
+		for k, v := range service.Searches {
+			search += k + " like '%" + v + "%' OR "
+		}
+		search = strings.TrimSuffix(search, " OR ")
+		tx = tx.Where(search)
+	}
+
+	// 计算总数用于分页
+	tx.Count(&total)
+
+	// 查询记录
+	tx.Limit(service.PageSize).Offset((service.Page - 1) * service.PageSize).Find(&res)
+
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": res,
+	}}
+}
@@ -2,9 +2,9 @@ package aria2
 
 import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/activity"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/common"
-	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/monitor"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
@@ -86,6 +86,11 @@ func (service *AddURLService) Add(c *gin.Context, fs *filesystem.FileSystem, tas
 		}
 	}
 
+	// 存放目录不能被 WebDAV 客户端锁定
+	if err := fs.CheckNotLocked(service.Dst); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
 	downloads := model.GetDownloadsByStatusAndUser(0, fs.User.ID, common.Downloading, common.Paused, common.Ready)
 	limit := fs.User.Group.OptionsSerialized.Aria2BatchSize
 	if limit > 0 && len(downloads)+1 > limit {
@@ -124,7 +129,9 @@ func (service *AddURLService) Add(c *gin.Context, fs *filesystem.FileSystem, tas
 	}
 
 	// 创建任务监控
-	monitor.NewMonitor(task, cluster.Default, mq.GlobalMQ)
+	aria2.Watch(task, cluster.Default, mq.GlobalMQ)
+
+	activity.Record(activity.ActorFromContext(c), fs.User.ID, model.ActivityOfflineDownload, service.URL, service.Dst)
 
 	return serializer.Response{}
 }
@@ -4,14 +4,17 @@ import (
 	"context"
 	"fmt"
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"path"
 	"strings"
 
+	"github.com/cloudreve/Cloudreve/v3/pkg/activity"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/cos"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/onedrive"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/s3"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/service/share"
 	"github.com/gin-gonic/gin"
 )
 
@@ -148,6 +151,16 @@ func ProcessCallback(service CallbackProcessService, c *gin.Context) serializer.
 		return serializer.Err(serializer.CodeUploadFailed, err.Error(), err)
 	}
 
+	// 回调请求可能由存储服务商发起，不记录其 IP
+	activity.Record(
+		activity.Actor{UserID: fs.User.ID, Client: activity.ClientWeb},
+		fs.User.ID,
+		model.ActivityUpload,
+		path.Join(uploadSession.VirtualPath, uploadSession.Name),
+		"",
+	)
+	share.UploadReceived(uploadSession)
+
 	return serializer.Response{}
 }
 
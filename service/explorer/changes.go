@@ -0,0 +1,155 @@
+package explorer
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/changes"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// changesWriteWait 单次写入的超时时间
+	changesWriteWait = 10 * time.Second
+	// changesPongWait 未收到客户端响应的最长时间
+	changesPongWait = 60 * time.Second
+	// changesPingInterval 向客户端发送 Ping 的间隔，需小于 changesPongWait
+	changesPingInterval = changesPongWait * 9 / 10
+)
+
+var changesUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     checkChangesOrigin,
+}
+
+// DirectoryChangesService 目录变更订阅服务
+type DirectoryChangesService struct {
+}
+
+// changesWatchRequest 客户端发送的订阅请求，每次请求替换此前订阅的目录
+type changesWatchRequest struct {
+	Folders []string `json:"folders"`
+}
+
+// changeEvent 推送给客户端的目录变更
+type changeEvent struct {
+	Action string    `json:"action"`
+	Folder string    `json:"folder"`
+	From   string    `json:"from,omitempty"`
+	Files  []string  `json:"files"`
+	Dirs   []string  `json:"dirs"`
+	Name   string    `json:"name,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+func buildChangeEvent(event changes.Event) changeEvent {
+	res := changeEvent{
+		Action: event.Action,
+		Folder: hashid.HashID(event.Folder, hashid.FolderID),
+		Files:  make([]string, 0, len(event.Files)),
+		Dirs:   make([]string, 0, len(event.Dirs)),
+		Name:   event.Name,
+		Time:   event.Time,
+	}
+
+	if event.From > 0 {
+		res.From = hashid.HashID(event.From, hashid.FolderID)
+	}
+	for _, id := range event.Files {
+		res.Files = append(res.Files, hashid.HashID(id, hashid.FileID))
+	}
+	for _, id := range event.Dirs {
+		res.Dirs = append(res.Dirs, hashid.HashID(id, hashid.FolderID))
+	}
+
+	return res
+}
+
+// checkChangesOrigin 仅允许同源或跨域配置中允许的来源建立连接，避免跨站劫持登录状态
+func checkChangesOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err == nil && u.Host == r.Host {
+		return true
+	}
+
+	for _, allowed := range conf.CORSConfig.AllowOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Subscribe 通过 WebSocket 推送当前用户所订阅目录的变更。
+// 客户端发送 {"folders": [目录ID]} 设定要订阅的目录，通常为正在浏览的目录
+func (service *DirectoryChangesService) Subscribe(c *gin.Context, user *model.User) serializer.Response {
+	conn, err := changesUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade 失败时已向客户端返回错误
+		util.Log().Debug("无法建立目录变更订阅连接，%s", err)
+		return serializer.Response{}
+	}
+	defer conn.Close()
+
+	sub := changes.Default.Subscribe(user.ID)
+	defer changes.Default.Unsubscribe(user.ID, sub)
+
+	// 读取订阅请求
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+
+		conn.SetReadDeadline(time.Now().Add(changesPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(changesPongWait))
+		})
+
+		for {
+			var req changesWatchRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+
+			folders := make([]uint, 0, len(req.Folders))
+			for _, raw := range req.Folders {
+				if id, err := hashid.DecodeHashID(raw, hashid.FolderID); err == nil {
+					folders = append(folders, id)
+				}
+			}
+			sub.Watch(folders)
+		}
+	}()
+
+	ping := time.NewTicker(changesPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return serializer.Response{}
+		case event := <-sub.C:
+			conn.SetWriteDeadline(time.Now().Add(changesWriteWait))
+			if err := conn.WriteJSON(buildChangeEvent(event)); err != nil {
+				return serializer.Response{}
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(changesWriteWait)); err != nil {
+				return serializer.Response{}
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package explorer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// DeltaService 增量变更服务
+type DeltaService struct {
+	Cursor *uint `form:"cursor"`
+	Limit  int   `form:"limit" binding:"min=0,max=1000"`
+}
+
+// changeSettleDuration 变更日志写入后需经过的时长才会被列出。变更日志与文件操作在同一事务中写入，
+// 较小的游标可能晚于较大的游标提交，等待一段时间以免已列出的游标越过尚未提交的变更
+const changeSettleDuration = 5 * time.Second
+
+// Delta 列出当前用户在游标之后的文件系统变更。未指定游标、游标之后的变更日志已被清理
+// 或游标无效时返回重置信号，客户端应重新列出全部文件并从返回的游标开始增量同步
+func (service *DeltaService) Delta(c *gin.Context, user *model.User) serializer.Response {
+	if service.Limit == 0 {
+		service.Limit = 200
+	}
+
+	latest := model.GetLatestChangeCursor()
+	if service.Cursor == nil || *service.Cursor > latest || *service.Cursor+1 < model.GetEarliestChangeCursor() {
+		return serializer.BuildDelta(nil, latest, false, true)
+	}
+
+	changes, err := model.ListChanges(user.ID, *service.Cursor, time.Now().Add(-changeSettleDuration), service.Limit+1)
+	if err != nil {
+		return serializer.DBErr("Failed to list changes", err)
+	}
+
+	hasMore := len(changes) > service.Limit
+	if hasMore {
+		changes = changes[:service.Limit]
+	}
+
+	// 游标只前进到已列出的变更，不会越过尚未提交的变更
+	cursor := *service.Cursor
+	if len(changes) > 0 {
+		cursor = changes[len(changes)-1].ID
+	}
+
+	return serializer.BuildDelta(changes, cursor, hasMore, false)
+}
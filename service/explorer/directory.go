@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/activity"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"regexp"
@@ -147,11 +148,17 @@ func (service *DirectoryService) CreateDirectory(c *gin.Context) serializer.Resp
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	if err := fs.CheckNotLocked(service.Path); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
 	// 创建目录
 	_, err = fs.CreateDirectory(ctx, service.Path)
 	if err != nil {
 		return serializer.Err(serializer.CodeCreateFolderFailed, err.Error(), err)
 	}
+
+	activity.Record(activity.ActorFromContext(c), fs.User.ID, model.ActivityCreate, service.Path, "")
 	return serializer.Response{
 		Code: 0,
 	}
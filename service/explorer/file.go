@@ -14,10 +14,12 @@ import (
 	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/activity"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/traffic"
 	"github.com/gin-gonic/gin"
 )
 
@@ -63,6 +65,10 @@ func (service *SingleFileService) Create(c *gin.Context) serializer.Response {
 	fs.Use("BeforeUpload", filesystem.HookValidateFile)
 	fs.Use("AfterUpload", filesystem.GenericAfterUpload)
 
+	if err := fs.CheckNotLocked(service.Path); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
 	// 上传空文件
 	err = fs.Upload(ctx, &fsctx.FileStream{
 		File:        ioutil.NopCloser(strings.NewReader("")),
@@ -74,6 +80,8 @@ func (service *SingleFileService) Create(c *gin.Context) serializer.Response {
 		return serializer.Err(serializer.CodeUploadFailed, err.Error(), err)
 	}
 
+	activity.Record(activity.ActorFromContext(c), fs.User.ID, model.ActivityCreate, service.Path, "")
+
 	return serializer.Response{
 		Code: 0,
 	}
@@ -105,6 +113,11 @@ func (service *ArchiveService) DownloadArchived(ctx context.Context, c *gin.Cont
 	}
 	user := userRaw.(model.User)
 
+	// 检查打包者的流量配额
+	if err := traffic.Check(user.ID); err != nil {
+		return serializer.Err(serializer.CodeTrafficQuotaExceeded, "", err)
+	}
+
 	// 创建文件系统
 	fs, err := filesystem.NewFileSystem(&user)
 	if err != nil {
@@ -125,6 +138,7 @@ func (service *ArchiveService) DownloadArchived(ctx context.Context, c *gin.Cont
 	items := itemService.Raw()
 	ctx = context.WithValue(ctx, fsctx.GinCtx, c)
 	err = fs.Compress(ctx, c.Writer, items.Dirs, items.Items, true)
+	traffic.Record(user.ID, traffic.Written(c.Writer))
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, "Failed to compress file", err)
 	}
@@ -148,6 +162,11 @@ func (service *FileAnonymousGetService) Download(ctx context.Context, c *gin.Con
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
 
+	// 检查文件所有者的流量配额
+	if err := traffic.Check(fs.FileTarget[0].UserID); err != nil {
+		return serializer.Err(serializer.CodeTrafficQuotaExceeded, "", err)
+	}
+
 	// 获取文件流
 	rs, err := fs.GetDownloadContent(ctx, 0)
 	defer rs.Close()
@@ -157,6 +176,7 @@ func (service *FileAnonymousGetService) Download(ctx context.Context, c *gin.Con
 
 	// 发送文件
 	http.ServeContent(c.Writer, c.Request, service.Name, fs.FileTarget[0].UpdatedAt, rs)
+	traffic.Record(fs.FileTarget[0].UserID, traffic.Written(c.Writer))
 
 	return serializer.Response{
 		Code: 0,
@@ -278,6 +298,11 @@ func (service *DownloadService) Download(ctx context.Context, c *gin.Context) se
 	}
 	fs.FileTarget = []model.File{file.(model.File)}
 
+	// 检查文件所有者的流量配额
+	if err := traffic.Check(fs.FileTarget[0].UserID); err != nil {
+		return serializer.Err(serializer.CodeTrafficQuotaExceeded, "", err)
+	}
+
 	// 开始处理下载
 	ctx = context.WithValue(ctx, fsctx.GinCtx, c)
 	rs, err := fs.GetDownloadContent(ctx, 0)
@@ -296,6 +321,7 @@ func (service *DownloadService) Download(ctx context.Context, c *gin.Context) se
 
 	// 发送文件
 	http.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, fs.FileTarget[0].UpdatedAt, rs)
+	traffic.Record(fs.FileTarget[0].UserID, traffic.Written(c.Writer))
 
 	return serializer.Response{
 		Code: 0,
@@ -350,11 +376,16 @@ func (service *FileIDService) PreviewContent(ctx context.Context, c *gin.Context
 	// 直接返回文件内容
 	defer resp.Content.Close()
 
+	if err := traffic.Check(fs.FileTarget[0].UserID); err != nil {
+		return serializer.Err(serializer.CodeTrafficQuotaExceeded, "", err)
+	}
+
 	if isText {
 		c.Header("Cache-Control", "no-cache")
 	}
 
 	http.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, fs.FileTarget[0].UpdatedAt, resp.Content)
+	traffic.Record(fs.FileTarget[0].UserID, traffic.Written(c.Writer))
 
 	return serializer.Response{
 		Code: 0,
@@ -396,6 +427,12 @@ func (service *FileIDService) PutContent(ctx context.Context, c *gin.Context) se
 	}
 	fileData.Name = originFile[0].Name
 
+	if paths := activity.ResolvePaths(fs.User.ID, nil, []uint{originFile[0].ID}); len(paths) > 0 {
+		if err := fs.CheckNotLocked(paths...); err != nil {
+			return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+		}
+	}
+
 	// 检查此文件是否有软链接
 	fileList, err := model.RemoveFilesWithSoftLinks([]model.File{originFile[0]})
 	if err == nil && len(fileList) == 0 {
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/activity"
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
@@ -143,6 +144,11 @@ func (service *ItemDecompressService) CreateDecompressTask(c *gin.Context) seria
 		return serializer.Err(serializer.CodeUnsupportedArchiveType, "", nil)
 	}
 
+	// 解压目录不能被 WebDAV 客户端锁定
+	if err := fs.CheckNotLocked(service.Dst); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
 	// 创建任务
 	job, err := task.NewDecompressTask(fs.User, service.Src, service.Dst, service.Encoding)
 	if err != nil {
@@ -220,6 +226,10 @@ func (service *ItemCompressService) CreateCompressTask(c *gin.Context) serialize
 		return serializer.Err(serializer.CodeInsufficientCapacity, "", err)
 	}
 
+	if err := fs.CheckNotLocked(path.Join(service.Dst, service.Name)); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
 	// 创建任务
 	job, err := task.NewCompressTask(fs.User, path.Join(service.Dst, service.Name), service.Src.Raw().Dirs,
 		service.Src.Raw().Items)
@@ -274,11 +284,18 @@ func (service *ItemIDService) Delete(ctx context.Context, c *gin.Context) serial
 
 	// 删除对象
 	items := service.Raw()
+	paths := activity.ResolvePaths(fs.User.ID, items.Dirs, items.Items)
+	if err := fs.CheckNotLocked(paths...); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
 	err = fs.DeleteTransaction(ctx, items.Dirs, items.Items, false, nil)
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
 
+	activity.RecordPaths(activity.ActorFromContext(c), fs.User.ID, model.ActivityDelete, paths, "")
+
 	return serializer.Response{
 		Code: 0,
 	}
@@ -296,11 +313,18 @@ func (service *ItemMoveService) Move(ctx context.Context, c *gin.Context) serial
 
 	// 移动对象
 	items := service.Src.Raw()
+	paths := activity.ResolvePaths(fs.User.ID, items.Dirs, items.Items)
+	if err := fs.CheckNotLocked(append(paths, targetPaths(service.Dst, paths)...)...); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
 	err = fs.Move(ctx, items.Dirs, items.Items, service.SrcDir, service.Dst)
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
 
+	activity.RecordPaths(activity.ActorFromContext(c), fs.User.ID, model.ActivityMove, paths, service.Dst)
+
 	return serializer.Response{
 		Code: 0,
 	}
@@ -322,11 +346,18 @@ func (service *ItemMoveService) Copy(ctx context.Context, c *gin.Context) serial
 	defer fs.Recycle()
 
 	// 复制对象
+	paths := activity.ResolvePaths(fs.User.ID, service.Src.Raw().Dirs, service.Src.Raw().Items)
+	if err := fs.CheckNotLocked(targetPaths(service.Dst, paths)...); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
 	err = fs.Copy(ctx, service.Src.Raw().Dirs, service.Src.Raw().Items, service.SrcDir, service.Dst)
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
 
+	activity.RecordPaths(activity.ActorFromContext(c), fs.User.ID, model.ActivityCopy, paths, service.Dst)
+
 	return serializer.Response{
 		Code: 0,
 	}
@@ -348,11 +379,24 @@ func (service *ItemRenameService) Rename(ctx context.Context, c *gin.Context) se
 	defer fs.Recycle()
 
 	// 重命名对象
+	paths := activity.ResolvePaths(fs.User.ID, service.Src.Raw().Dirs, service.Src.Raw().Items)
+	renamed := make([]string, 0, len(paths))
+	for _, src := range paths {
+		renamed = append(renamed, path.Join(path.Dir(src), service.NewName))
+	}
+	if err := fs.CheckNotLocked(append(paths, renamed...)...); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
 	err = fs.Rename(ctx, service.Src.Raw().Dirs, service.Src.Raw().Items, service.NewName)
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
 
+	for i, src := range paths {
+		activity.Record(activity.ActorFromContext(c), fs.User.ID, model.ActivityRename, src, renamed[i])
+	}
+
 	return serializer.Response{
 		Code: 0,
 	}
@@ -457,3 +501,13 @@ func (service *ItemPropertyService) GetProperty(ctx context.Context, c *gin.Cont
 		Data: props,
 	}
 }
+
+// targetPaths 返回 paths 中的对象移动或复制到 dst 目录下后的完整路径
+func targetPaths(dst string, paths []string) []string {
+	res := make([]string, 0, len(paths))
+	for _, src := range paths {
+		res = append(res, path.Join(dst, path.Base(src)))
+	}
+
+	return res
+}
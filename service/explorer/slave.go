@@ -6,13 +6,16 @@ import (
 	"encoding/json"
 	"fmt"
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task/slavetask"
+	"github.com/cloudreve/Cloudreve/v3/pkg/traffic"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
@@ -88,10 +91,35 @@ func (service *SlaveDownloadService) ServeFile(ctx context.Context, c *gin.Conte
 
 	// 发送文件
 	http.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, time.Now(), rs)
+	reportTraffic(c, string(fileSource))
 
 	return serializer.Response{}
 }
 
+// reportTraffic 向主机回报本次下载的流量
+func reportTraffic(c *gin.Context, sourcePath string) {
+	tag := c.Query("traffic")
+	size := traffic.Written(c.Writer)
+	if tag == "" || size == 0 {
+		return
+	}
+
+	siteID, uid, ok := traffic.ParseTag(auth.General, sourcePath, tag)
+	if !ok {
+		return
+	}
+
+	go func() {
+		msg := mq.Message{
+			TriggeredBy: siteID,
+			Content:     traffic.Report{UserID: uid, Size: size},
+		}
+		if err := cluster.DefaultController.SendNotification(siteID, traffic.Topic, msg); err != nil {
+			util.Log().Warning("无法向主机回报下载流量, %s", err)
+		}
+	}()
+}
+
 // Delete 通过签名的URL删除从机文件
 func (service *SlaveFilesService) Delete(ctx context.Context, c *gin.Context) serializer.Response {
 	// 创建文件系统
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/activity"
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
@@ -14,6 +15,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
 	"io/ioutil"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -56,6 +58,10 @@ func (service *CreateUploadSessionService) Create(ctx context.Context, c *gin.Co
 		lastModified := time.UnixMilli(service.LastModified)
 		file.LastModified = &lastModified
 	}
+	if err := fs.CheckNotLocked(path.Join(service.Path, service.Name)); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
 	credential, err := fs.CreateUploadSession(ctx, file)
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
@@ -88,9 +94,29 @@ func (service *UploadService) LocalUpload(ctx context.Context, c *gin.Context) s
 	}
 
 	if uploadSession.UID != fs.User.ID {
-		return serializer.Err(serializer.CodeUploadSessionExpired, "", nil)
+		if uploadSession.Delegate == 0 || uploadSession.Delegate != fs.User.ID {
+			return serializer.Err(serializer.CodeUploadSessionExpired, "", nil)
+		}
+
+		// 代为上传到站内共享目录时，使用共享者的文件系统
+		owner, err := model.GetActiveUserByID(uploadSession.UID)
+		if err != nil {
+			return serializer.Err(serializer.CodeUploadSessionExpired, "", err)
+		}
+
+		fs.Recycle()
+		fs, err = filesystem.NewFileSystem(&owner)
+		if err != nil {
+			return serializer.Err(serializer.CodePolicyNotAllowed, err.Error(), err)
+		}
 	}
 
+	return service.LocalUploadTo(ctx, c, fs, &uploadSession)
+}
+
+// LocalUploadTo 使用给定的文件系统处理本机文件分片上传，
+// 调用方需确保 fs 属于上传会话的发起者
+func (service *UploadService) LocalUploadTo(ctx context.Context, c *gin.Context, fs *filesystem.FileSystem, uploadSession *serializer.UploadSession) serializer.Response {
 	// 查找上传会话创建的占位文件
 	file, err := model.GetFilesByUploadSession(service.ID, fs.User.ID)
 	if err != nil {
@@ -121,7 +147,7 @@ func (service *UploadService) LocalUpload(ctx context.Context, c *gin.Context) s
 		util.Log().Info("Trying to overwrite chunk[%d] Start=%d", service.Index, actualSizeStart)
 	}
 
-	return processChunkUpload(ctx, c, fs, &uploadSession, service.Index, file, fsctx.Append)
+	return processChunkUpload(ctx, c, fs, uploadSession, service.Index, file, fsctx.Append)
 }
 
 // SlaveUpload 处理从机文件分片上传
@@ -213,6 +239,10 @@ func processChunkUpload(ctx context.Context, c *gin.Context, fs *filesystem.File
 		return serializer.Err(serializer.CodeUploadFailed, err.Error(), err)
 	}
 
+	if file != nil && isLastChunk {
+		activity.Record(activity.ActorFromContext(c), fs.User.ID, model.ActivityUpload, path.Join(session.VirtualPath, session.Name), "")
+	}
+
 	return serializer.Response{}
 }
 
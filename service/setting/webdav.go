@@ -1,6 +1,9 @@
 package setting
 
 import (
+	"strings"
+	"time"
+
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
@@ -18,8 +21,18 @@ type WebDAVAccountService struct {
 
 // WebDAVAccountCreateService WebDAV 账号创建服务
 type WebDAVAccountCreateService struct {
-	Path string `json:"path" binding:"required,min=1,max=65535"`
-	Name string `json:"name" binding:"required,min=1,max=255"`
+	Path        string   `json:"path" binding:"required,min=1,max=65535"`
+	Name        string   `json:"name" binding:"required,min=1,max=255"`
+	Permissions []string `json:"permissions"`
+	Expire      int      `json:"expire" binding:"min=0,max=3650"` // 有效天数，0 表示永不过期
+	AllowedIPs  []string `json:"allowed_ips"`                     // 允许访问的 IP 或 CIDR，为空表示不限制
+}
+
+// webDAVPermissions 可设定的 WebDAV 账号权限限制
+var webDAVPermissions = []string{
+	model.WebdavReadOnly,
+	model.WebdavNoDelete,
+	model.WebdavUploadOnly,
 }
 
 // WebDAVMountCreateService WebDAV 挂载创建服务
@@ -30,11 +43,34 @@ type WebDAVMountCreateService struct {
 
 // Create 创建WebDAV账户
 func (service *WebDAVAccountCreateService) Create(c *gin.Context, user *model.User) serializer.Response {
+	for _, permission := range service.Permissions {
+		if !util.ContainsString(webDAVPermissions, permission) {
+			return serializer.ParamErr("Unknown permission: "+permission, nil)
+		}
+	}
+
+	if util.ContainsString(service.Permissions, model.WebdavReadOnly) &&
+		util.ContainsString(service.Permissions, model.WebdavUploadOnly) {
+		return serializer.ParamErr("Read-only and upload-only cannot be combined", nil)
+	}
+
+	for _, rule := range service.AllowedIPs {
+		if !model.IsValidWebdavIPRule(rule) {
+			return serializer.ParamErr("Invalid IP or CIDR: "+rule, nil)
+		}
+	}
+
 	account := model.Webdav{
-		Name:     service.Name,
-		Password: util.RandStringRunes(32),
-		UserID:   user.ID,
-		Root:     service.Path,
+		Name:        service.Name,
+		Password:    util.RandStringRunes(32),
+		UserID:      user.ID,
+		Root:        service.Path,
+		Permissions: strings.Join(service.Permissions, ","),
+		AllowedIPs:  strings.Join(service.AllowedIPs, ","),
+	}
+	if service.Expire > 0 {
+		expires := time.Now().Add(time.Duration(service.Expire) * 24 * time.Hour)
+		account.ExpiresAt = &expires
 	}
 
 	if _, err := account.Create(); err != nil {
@@ -45,6 +81,7 @@ func (service *WebDAVAccountCreateService) Create(c *gin.Context, user *model.Us
 		Data: map[string]interface{}{
 			"id":         account.ID,
 			"password":   account.Password,
+			"expires_at": account.ExpiresAt,
 			"created_at": account.CreatedAt,
 		},
 	}
@@ -0,0 +1,194 @@
+package setting
+
+import (
+	"errors"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/cloudreve/Cloudreve/v3/pkg/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookListService Webhook 列表服务
+type WebhookListService struct {
+}
+
+// WebhookService Webhook 管理服务
+type WebhookService struct {
+	ID uint `uri:"id" binding:"required,min=1"`
+}
+
+// WebhookCreateService Webhook 创建服务
+type WebhookCreateService struct {
+	URL    string   `json:"url" binding:"required,url,max=65535"`
+	Events []string `json:"events"`
+}
+
+// WebhookUpdateService Webhook 更新服务
+type WebhookUpdateService struct {
+	URL     string   `json:"url" binding:"omitempty,url,max=65535"`
+	Events  []string `json:"events"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// WebhookDeliveryListService Webhook 投递记录列表服务
+type WebhookDeliveryListService struct {
+	Page     int `form:"page" binding:"min=0"`
+	PageSize int `form:"page_size" binding:"min=0,max=100"`
+}
+
+// ValidateWebhookEvents 检查订阅事件并转换为存储格式
+func ValidateWebhookEvents(events []string) (string, error) {
+	for _, event := range events {
+		if !webhook.IsValidEvent(event) {
+			return "", errors.New("Unsupported event: " + event)
+		}
+	}
+
+	return strings.Join(events, ","), nil
+}
+
+// Create 创建当前用户的 Webhook
+func (service *WebhookCreateService) Create(c *gin.Context, user *model.User) serializer.Response {
+	return service.Save(user.ID, false)
+}
+
+// Save 创建 Webhook，global 为 true 时接收所有用户的事件
+func (service *WebhookCreateService) Save(uid uint, global bool) serializer.Response {
+	events, err := ValidateWebhookEvents(service.Events)
+	if err != nil {
+		return serializer.Err(serializer.CodeInvalidWebhookEvent, err.Error(), nil)
+	}
+
+	hook := model.Webhook{
+		UserID:  uid,
+		Global:  global,
+		URL:     service.URL,
+		Secret:  util.RandStringRunes(32),
+		Events:  events,
+		Enabled: true,
+	}
+
+	if _, err := hook.Create(); err != nil {
+		return serializer.DBErr("Failed to create webhook", err)
+	}
+
+	return serializer.Response{
+		Data: map[string]interface{}{
+			"id":         hook.ID,
+			"secret":     hook.Secret,
+			"created_at": hook.CreatedAt,
+		},
+	}
+}
+
+// Update 更新当前用户的 Webhook
+func (service *WebhookUpdateService) Update(c *gin.Context, user *model.User, id uint) serializer.Response {
+	hook, err := model.GetWebhookByID(id, user.ID)
+	if err != nil || hook.Global {
+		return serializer.Err(serializer.CodeWebhookNotFound, "", err)
+	}
+
+	return service.Apply(hook)
+}
+
+// Apply 将更新应用到给定 Webhook
+func (service *WebhookUpdateService) Apply(hook *model.Webhook) serializer.Response {
+	props := make(map[string]interface{})
+	if service.URL != "" {
+		props["url"] = service.URL
+	}
+
+	if service.Events != nil {
+		events, err := ValidateWebhookEvents(service.Events)
+		if err != nil {
+			return serializer.Err(serializer.CodeInvalidWebhookEvent, err.Error(), nil)
+		}
+		props["events"] = events
+	}
+
+	if service.Enabled != nil {
+		props["enabled"] = *service.Enabled
+	}
+
+	if err := hook.Update(props); err != nil {
+		return serializer.DBErr("Failed to update webhook", err)
+	}
+
+	return serializer.Response{Data: hook}
+}
+
+// Delete 删除当前用户的 Webhook
+func (service *WebhookService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	hook, err := model.GetWebhookByID(service.ID, user.ID)
+	if err != nil || hook.Global {
+		return serializer.Err(serializer.CodeWebhookNotFound, "", err)
+	}
+
+	if err := model.DeleteWebhookByID(hook.ID, user.ID); err != nil {
+		return serializer.DBErr("Failed to delete webhook", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Ping 向当前用户的 Webhook 发送测试投递
+func (service *WebhookService) Ping(c *gin.Context, user *model.User) serializer.Response {
+	hook, err := model.GetWebhookByID(service.ID, user.ID)
+	if err != nil || hook.Global {
+		return serializer.Err(serializer.CodeWebhookNotFound, "", err)
+	}
+
+	return PingWebhook(hook)
+}
+
+// PingWebhook 向给定 Webhook 发送测试投递并返回投递结果
+func PingWebhook(hook *model.Webhook) serializer.Response {
+	if webhook.Default == nil {
+		return serializer.Err(serializer.CodeFeatureNotEnabled, "Webhook dispatcher is not running", nil)
+	}
+
+	delivery, err := webhook.Default.Ping(hook)
+	if err != nil {
+		return serializer.DBErr("Failed to record webhook delivery", err)
+	}
+
+	return serializer.Response{Data: delivery}
+}
+
+// Webhooks 列出当前用户的 Webhook
+func (service *WebhookListService) Webhooks(c *gin.Context, user *model.User) serializer.Response {
+	return serializer.Response{Data: map[string]interface{}{
+		"webhooks": model.ListWebhooks(user.ID),
+		"events":   webhook.Events,
+	}}
+}
+
+// List 列出当前用户 Webhook 的投递记录
+func (service *WebhookDeliveryListService) List(c *gin.Context, user *model.User, id uint) serializer.Response {
+	hook, err := model.GetWebhookByID(id, user.ID)
+	if err != nil || hook.Global {
+		return serializer.Err(serializer.CodeWebhookNotFound, "", err)
+	}
+
+	return service.Deliveries(hook)
+}
+
+// Deliveries 分页列出给定 Webhook 的投递记录
+func (service *WebhookDeliveryListService) Deliveries(hook *model.Webhook) serializer.Response {
+	if service.Page == 0 {
+		service.Page = 1
+	}
+
+	if service.PageSize == 0 {
+		service.PageSize = 20
+	}
+
+	deliveries, total := model.ListWebhookDeliveries(hook.ID, service.Page, service.PageSize)
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": deliveries,
+	}}
+}
@@ -0,0 +1,181 @@
+package share
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// eventExportBatch 导出访问记录时每批读取的条数
+const eventExportBatch = 500
+
+// eventTypeNames 访问类型在统计和导出中的名称
+var eventTypeNames = map[int]string{
+	model.ShareEventView:     "view",
+	model.ShareEventPreview:  "preview",
+	model.ShareEventDownload: "download",
+	model.ShareEventArchive:  "archive",
+}
+
+// EventListService 分享访问记录列表服务
+type EventListService struct {
+	Page     int   `form:"page" binding:"required,min=1"`
+	PageSize int   `form:"page_size" binding:"min=0,max=100"`
+	Types    []int `form:"type"`
+}
+
+// EventStatService 分享访问统计服务
+type EventStatService struct {
+	Days int `form:"days" binding:"min=0,max=365"`
+}
+
+// recordShareEvent 记录一次分享访问，sub 为目录分享下的相对路径
+func recordShareEvent(c *gin.Context, share *model.Share, eventType int, sub string) {
+	event := model.ShareEvent{
+		ShareID:   share.ID,
+		Type:      eventType,
+		Path:      sub,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Referer:   c.Request.Referer(),
+	}
+
+	if user, ok := c.Get("user"); ok {
+		if u, ok := user.(*model.User); ok {
+			event.UserID = u.ID
+		}
+	}
+
+	// 记录失败不影响原操作
+	event.Create()
+}
+
+// ownedShare 查找当前用户创建的分享，已失效的分享同样可以查看记录
+func ownedShare(c *gin.Context, user *model.User) *model.Share {
+	share := model.GetShareByHashID(c.Param("id"))
+	if share == nil || share.UserID != user.ID {
+		return nil
+	}
+	return share
+}
+
+// List 列出分享的访问记录
+func (service *EventListService) List(c *gin.Context, user *model.User) serializer.Response {
+	share := ownedShare(c, user)
+	if share == nil {
+		return serializer.Err(serializer.CodeShareLinkNotFound, "", nil)
+	}
+
+	if service.PageSize == 0 {
+		service.PageSize = 20
+	}
+
+	events, total := model.ListShareEvents(share.ID, service.Page, service.PageSize, service.Types)
+	return serializer.BuildShareEventList(events, total)
+}
+
+// Stat 按天统计最近若干天的访问次数
+func (service *EventStatService) Stat(c *gin.Context, user *model.User) serializer.Response {
+	share := ownedShare(c, user)
+	if share == nil {
+		return serializer.Err(serializer.CodeShareLinkNotFound, "", nil)
+	}
+
+	if service.Days == 0 {
+		service.Days = 30
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	since := today.AddDate(0, 0, 1-service.Days)
+
+	days := make([]map[string]interface{}, service.Days)
+	index := make(map[string]map[string]interface{}, service.Days)
+	for i := range days {
+		date := since.AddDate(0, 0, i).Format("2006-01-02")
+		days[i] = map[string]interface{}{"date": date}
+		for _, name := range eventTypeNames {
+			days[i][name] = 0
+		}
+		index[date] = days[i]
+	}
+
+	for _, count := range model.CountShareEventsByDay(share.ID, since) {
+		day, ok := index[count.Date]
+		if !ok {
+			continue
+		}
+		if name, ok := eventTypeNames[count.Type]; ok {
+			day[name] = day[name].(int) + count.Count
+		}
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"views":     share.Views,
+		"downloads": share.Downloads,
+		"days":      days,
+	}}
+}
+
+// Export 以 CSV 格式导出分享的全部访问记录
+func (service *EventListService) Export(c *gin.Context, user *model.User) {
+	share := ownedShare(c, user)
+	if share == nil {
+		c.JSON(200, serializer.Err(serializer.CodeShareLinkNotFound, "", nil))
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"share_%s_%s.csv\"",
+		c.Param("id"), time.Now().Format("20060102150405")))
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"id", "created_at", "type", "path", "user", "ip", "user_agent", "referer"})
+
+	// 缓存访问者昵称
+	users := make(map[uint]string)
+	var lastID uint
+	for {
+		events := model.ListShareEventsAfter(share.ID, lastID, eventExportBatch)
+		for _, event := range events {
+			nick, ok := users[event.UserID]
+			if !ok && event.UserID > 0 {
+				if visitor, err := model.GetUserByID(event.UserID); err == nil {
+					nick = visitor.Nick
+				}
+				users[event.UserID] = nick
+			}
+
+			writer.Write([]string{
+				strconv.FormatUint(uint64(event.ID), 10),
+				event.CreatedAt.Format(time.RFC3339),
+				eventTypeNames[event.Type],
+				csvSafe(event.Path),
+				csvSafe(nick),
+				csvSafe(event.IP),
+				csvSafe(event.UserAgent),
+				csvSafe(event.Referer),
+			})
+		}
+
+		writer.Flush()
+		if len(events) < eventExportBatch {
+			return
+		}
+		lastID = events[len(events)-1].ID
+	}
+}
+
+// csvSafe 为可能被电子表格解析为公式的单元格加上前缀，防止 CSV 注入
+func csvSafe(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
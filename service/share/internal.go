@@ -0,0 +1,336 @@
+package share
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/activity"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/service/explorer"
+	"github.com/gin-gonic/gin"
+)
+
+// InternalShareCreateService 创建站内共享服务，Email 与 GroupID 二选一
+type InternalShareCreateService struct {
+	SourceID string `json:"id" binding:"required"`
+	Email    string `json:"email" binding:"omitempty,email"`
+	GroupID  uint   `json:"group_id"`
+	Writable bool   `json:"writable"`
+}
+
+// InternalShareService 对站内共享目录进行操作的服务，path 为相对于共享目录的路径
+type InternalShareService struct {
+	Path string `form:"path" uri:"path" json:"path" binding:"max=65535"`
+}
+
+// InternalShareDeleteService 删除站内共享目录下对象的服务
+type InternalShareDeleteService struct {
+	Path  string   `json:"path" binding:"required,max=65535"`
+	Items []string `json:"items"`
+	Dirs  []string `json:"dirs"`
+}
+
+// InternalShareUploadService 在站内共享目录下创建上传会话的服务
+type InternalShareUploadService struct {
+	Path         string `json:"path" binding:"required"`
+	Size         uint64 `json:"size" binding:"min=0"`
+	Name         string `json:"name" binding:"required"`
+	LastModified int64  `json:"last_modified"`
+}
+
+// NewInternalShareFileSystem 使用共享者的文件系统访问共享目录，
+// 根目录被重设为共享目录，容量及存储策略均以共享者为准
+func NewInternalShareFileSystem(share *model.InternalShare) (*filesystem.FileSystem, error) {
+	fs, err := filesystem.NewFileSystem(share.Creator())
+	if err != nil {
+		return nil, err
+	}
+
+	root := *share.SourceFolder()
+	root.Position = ""
+	root.Name = "/"
+	fs.Root = &root
+	return fs, nil
+}
+
+// InternalSharePath 返回共享目录下的相对路径在共享者文件系统中的完整路径
+func InternalSharePath(share *model.InternalShare, sub string) string {
+	folder := *share.SourceFolder()
+	folder.TraceRoot()
+	return path.Join("/", folder.Position, folder.Name, path.Clean("/"+sub))
+}
+
+// Create 创建站内共享，已存在对同一接收者的共享时更新其权限
+func (service *InternalShareCreateService) Create(c *gin.Context, user *model.User) serializer.Response {
+	if !user.Group.ShareEnabled {
+		return serializer.Err(serializer.CodeGroupNotAllowed, "", nil)
+	}
+
+	folderID, err := hashid.DecodeHashID(service.SourceID, hashid.FolderID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "", nil)
+	}
+
+	folders, err := model.GetFoldersByIDs([]uint{folderID}, user.ID)
+	if err != nil || len(folders) == 0 {
+		return serializer.Err(serializer.CodeNotFound, "", err)
+	}
+
+	if folders[0].ParentID == nil {
+		return serializer.Err(serializer.CodeRootProtected, "", nil)
+	}
+
+	// 确定接收者
+	var targetUserID, targetGroupID uint
+	if (service.Email == "") == (service.GroupID == 0) {
+		return serializer.ParamErr("Either email or group_id must be specified", nil)
+	}
+
+	if service.Email != "" {
+		target, err := model.GetActiveUserByEmail(strings.ToLower(service.Email))
+		if err != nil || target.ID == user.ID {
+			return serializer.Err(serializer.CodeInvalidShareTarget, "", err)
+		}
+		targetUserID = target.ID
+	} else {
+		// 游客用户组不能作为接收者
+		group, err := model.GetGroupByID(service.GroupID)
+		if err != nil || group.ID == 3 {
+			return serializer.Err(serializer.CodeInvalidShareTarget, "", err)
+		}
+		targetGroupID = group.ID
+	}
+
+	share, err := model.GetInternalShare(folderID, targetUserID, targetGroupID)
+	if err == nil && share.UserID == user.ID {
+		if err := share.Update(map[string]interface{}{"writable": service.Writable}); err != nil {
+			return serializer.DBErr("Failed to update share record", err)
+		}
+	} else {
+		share = &model.InternalShare{
+			UserID:        user.ID,
+			FolderID:      folderID,
+			TargetUserID:  targetUserID,
+			TargetGroupID: targetGroupID,
+			Writable:      service.Writable,
+		}
+		if _, err := share.Create(); err != nil {
+			return serializer.DBErr("Failed to create share record", err)
+		}
+	}
+
+	uid := hashid.HashID(share.ID, hashid.InternalShareID)
+	for _, src := range activity.ResolvePaths(user.ID, []uint{folderID}, nil) {
+		activity.Record(activity.ActorFromContext(c), user.ID, model.ActivityShareCreate, src, "/shared/"+uid)
+	}
+
+	return serializer.Response{Data: uid}
+}
+
+// List 列出用户创建的站内共享
+func (service *InternalShareService) List(c *gin.Context, user *model.User) serializer.Response {
+	return serializer.BuildInternalShareList(model.ListInternalShares(user.ID), false)
+}
+
+// Received 列出共享给用户的目录
+func (service *InternalShareService) Received(c *gin.Context, user *model.User) serializer.Response {
+	return serializer.BuildInternalShareList(model.ListReceivedInternalShares(user), true)
+}
+
+// Delete 取消站内共享
+func (service *InternalShareService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	share := model.GetInternalShareByHashID(c.Param("id"))
+	if share == nil || share.UserID != user.ID {
+		return serializer.Err(serializer.CodeInternalShareNotFound, "", nil)
+	}
+
+	if err := share.Delete(); err != nil {
+		return serializer.DBErr("Failed to delete share record", err)
+	}
+
+	return serializer.Response{}
+}
+
+// ListFolder 列出共享目录下的对象
+func (service *InternalShareService) ListFolder(c *gin.Context) serializer.Response {
+	share := c.MustGet("internal_share").(*model.InternalShare)
+
+	if !path.IsAbs(service.Path) {
+		return serializer.ParamErr("Invalid path", nil)
+	}
+
+	fs, err := NewInternalShareFileSystem(share)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	objects, err := fs.List(ctx, service.Path, nil)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: serializer.BuildObjectList(0, objects, nil),
+	}
+}
+
+// CreateDownloadSession 创建共享目录下文件的下载会话
+func (service *InternalShareService) CreateDownloadSession(c *gin.Context) serializer.Response {
+	share := c.MustGet("internal_share").(*model.InternalShare)
+
+	fs, err := NewInternalShareFileSystem(share)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	ctx := context.Background()
+	if err := fs.ResetFileIfNotExist(ctx, service.Path); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	downloadURL, err := fs.GetDownloadURL(ctx, 0, "download_timeout")
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: downloadURL,
+	}
+}
+
+// CreateDirectory 在共享目录下创建目录
+func (service *InternalShareService) CreateDirectory(c *gin.Context) serializer.Response {
+	share := c.MustGet("internal_share").(*model.InternalShare)
+	if !share.Writable {
+		return serializer.Err(serializer.CodeInternalShareReadOnly, "", nil)
+	}
+
+	fs, err := filesystem.NewFileSystem(share.Creator())
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	fullPath := InternalSharePath(share, service.Path)
+	if err := fs.CheckNotLocked(fullPath); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	if _, err := fs.CreateDirectory(context.Background(), fullPath); err != nil {
+		return serializer.Err(serializer.CodeCreateFolderFailed, err.Error(), err)
+	}
+
+	activity.Record(activity.ActorFromContext(c), share.UserID, model.ActivityCreate, fullPath, "")
+	return serializer.Response{}
+}
+
+// Delete 删除共享目录下给定父目录中的对象
+func (service *InternalShareDeleteService) Delete(c *gin.Context) serializer.Response {
+	share := c.MustGet("internal_share").(*model.InternalShare)
+	if !share.Writable {
+		return serializer.Err(serializer.CodeInternalShareReadOnly, "", nil)
+	}
+
+	fs, err := NewInternalShareFileSystem(share)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	exist, parent := fs.IsPathExist(service.Path)
+	if !exist {
+		return serializer.Err(serializer.CodeParentNotExist, "", nil)
+	}
+
+	// 只允许删除父目录的直接子项，防止越出共享目录
+	items := (&explorer.ItemIDService{Dirs: service.Dirs, Items: service.Items}).Raw()
+	files, _ := model.GetFilesByIDs(items.Items, share.UserID)
+	folders, _ := model.GetFoldersByIDs(items.Dirs, share.UserID)
+	if len(files) != len(items.Items) || len(folders) != len(items.Dirs) {
+		return serializer.Err(serializer.CodeNotFound, "", nil)
+	}
+	for _, file := range files {
+		if file.FolderID != parent.ID {
+			return serializer.Err(serializer.CodeNotFound, "", nil)
+		}
+	}
+	for _, folder := range folders {
+		if folder.ParentID == nil || *folder.ParentID != parent.ID {
+			return serializer.Err(serializer.CodeNotFound, "", nil)
+		}
+	}
+
+	paths := activity.ResolvePaths(share.UserID, items.Dirs, items.Items)
+	if err := fs.CheckNotLocked(paths...); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	if err := fs.Delete(context.Background(), items.Dirs, items.Items, false); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	activity.RecordPaths(activity.ActorFromContext(c), share.UserID, model.ActivityDelete, paths, "")
+	return serializer.Response{}
+}
+
+// Create 在共享目录下创建上传会话，文件使用共享者的存储策略和容量
+func (service *InternalShareUploadService) Create(c *gin.Context, user *model.User) serializer.Response {
+	share := c.MustGet("internal_share").(*model.InternalShare)
+	if !share.Writable {
+		return serializer.Err(serializer.CodeInternalShareReadOnly, "", nil)
+	}
+
+	// 共享者超额使用被限制为只读
+	if share.Creator().ReadOnly {
+		return serializer.Err(serializer.CodeOveruseReadOnly, "", nil)
+	}
+
+	fs, err := filesystem.NewFileSystem(share.Creator())
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	file := &fsctx.FileStream{
+		Size:        service.Size,
+		Name:        service.Name,
+		VirtualPath: InternalSharePath(share, service.Path),
+		File:        ioutil.NopCloser(strings.NewReader("")),
+	}
+	if service.LastModified > 0 {
+		lastModified := time.UnixMilli(service.LastModified)
+		file.LastModified = &lastModified
+	}
+	if err := fs.CheckNotLocked(path.Join(file.VirtualPath, service.Name)); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	credential, err := fs.CreateUploadSession(context.Background(), file)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	// 授权接收者继续向此会话上传分片
+	updateUploadSession(credential.SessionID, func(session *serializer.UploadSession) {
+		session.Delegate = user.ID
+	})
+
+	return serializer.Response{
+		Code: 0,
+		Data: credential,
+	}
+}
@@ -2,24 +2,38 @@ package share
 
 import (
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/activity"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/service/explorer"
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 )
 
 // ShareCreateService 创建新分享服务
 type ShareCreateService struct {
-	SourceID        string `json:"id" binding:"required"`
+	SourceID        string `json:"id"`
 	IsDir           bool   `json:"is_dir"`
 	Password        string `json:"password" binding:"max=255"`
 	RemainDownloads int    `json:"downloads"`
 	Expire          int    `json:"expire"`
 	Preview         bool   `json:"preview"`
+
+	// 上传分享，仅对目录有效
+	Upload           bool     `json:"upload"`
+	UploadMaxSize    uint64   `json:"upload_max_size"`
+	UploadExtensions []string `json:"upload_extensions"`
+	UploadCapacity   uint64   `json:"upload_capacity"`
+
+	// 多对象分享，指定后忽略 id 和 is_dir
+	Items []string `json:"items"`
+	Dirs  []string `json:"dirs"`
 }
 
 // ShareUpdateService 分享更新服务
@@ -78,6 +92,10 @@ func (service *ShareCreateService) Create(c *gin.Context) serializer.Response {
 		return serializer.Err(serializer.CodeGroupNotAllowed, "", nil)
 	}
 
+	if len(service.Items)+len(service.Dirs) > 0 {
+		return service.createMulti(c, user)
+	}
+
 	// 源对象真实ID
 	var (
 		sourceID   uint
@@ -93,6 +111,10 @@ func (service *ShareCreateService) Create(c *gin.Context) serializer.Response {
 		return serializer.Err(serializer.CodeNotFound, "", nil)
 	}
 
+	if service.Upload && !service.IsDir {
+		return serializer.ParamErr("Only folders can accept uploads", nil)
+	}
+
 	// 开始事务
 	tx := model.DB.Begin()
 	if tx.Error != nil {
@@ -123,11 +145,39 @@ func (service *ShareCreateService) Create(c *gin.Context) serializer.Response {
 		return serializer.Err(serializer.CodeNotFound, "", nil)
 	}
 
-	newShare := model.Share{
+	newShare := service.newShare(user, sourceName)
+	newShare.IsDir = service.IsDir
+	newShare.SourceID = sourceID
+
+	// 上传分享可以不限制下载次数，仅设定过期时间
+	if service.Upload {
+		newShare.UploadEnabled = true
+		newShare.UploadMaxSize = service.UploadMaxSize
+		newShare.UploadCapacity = service.UploadCapacity
+		exts := make([]string, 0, len(service.UploadExtensions))
+		for _, ext := range service.UploadExtensions {
+			if ext = strings.ToLower(strings.TrimLeft(strings.TrimSpace(ext), ".")); ext != "" {
+				exts = append(exts, ext)
+			}
+		}
+		newShare.UploadExtensions = strings.Join(exts, ",")
+		if service.RemainDownloads <= 0 && service.Expire > 0 {
+			expires := time.Now().Add(time.Duration(service.Expire) * time.Second)
+			newShare.Expires = &expires
+		}
+	}
+
+	if service.IsDir {
+		return commitShare(c, tx, newShare, []uint{sourceID}, nil)
+	}
+	return commitShare(c, tx, newShare, nil, []uint{sourceID})
+}
+
+// newShare 根据请求参数构建新分享的公共属性
+func (service *ShareCreateService) newShare(user *model.User, sourceName string) *model.Share {
+	newShare := &model.Share{
 		Password:        service.Password,
-		IsDir:           service.IsDir,
 		UserID:          user.ID,
-		SourceID:        sourceID,
 		RemainDownloads: -1,
 		PreviewEnabled:  service.Preview,
 		SourceName:      sourceName,
@@ -140,6 +190,66 @@ func (service *ShareCreateService) Create(c *gin.Context) serializer.Response {
 		newShare.Expires = &expires
 	}
 
+	return newShare
+}
+
+// createMulti 创建引用多个文件和目录的分享
+func (service *ShareCreateService) createMulti(c *gin.Context, user *model.User) serializer.Response {
+	if service.Upload {
+		return serializer.ParamErr("Only folders can accept uploads", nil)
+	}
+
+	objects := explorer.ItemIDService{Dirs: service.Dirs, Items: service.Items}
+	raw := objects.Raw()
+	if len(raw.Dirs) != len(service.Dirs) || len(raw.Items) != len(service.Items) {
+		return serializer.Err(serializer.CodeNotFound, "", nil)
+	}
+
+	// 开始事务
+	tx := model.DB.Begin()
+	if tx.Error != nil {
+		tx.Rollback()
+		util.Log().Error("事务创建失败 %s", tx.Error.Error())
+		return serializer.Err(serializer.CodeDBError, "分享链接创建失败", tx.Error)
+	}
+
+	// 对象是否全部存在
+	folders, err := model.GetFoldersByIDsTransaction(raw.Dirs, user.ID, tx)
+	if (err != nil && len(raw.Dirs) > 0) || len(folders) != len(raw.Dirs) {
+		tx.Rollback()
+		return serializer.Err(serializer.CodeNotFound, "", nil)
+	}
+	files, err := model.GetFilesByIDsTransaction(raw.Items, user.ID, tx)
+	if (err != nil && len(raw.Items) > 0) || len(files) != len(raw.Items) {
+		tx.Rollback()
+		return serializer.Err(serializer.CodeNotFound, "", nil)
+	}
+
+	// 虚拟根目录下的对象不能重名
+	names := make([]string, 0, len(folders)+len(files))
+	for _, folder := range folders {
+		names = append(names, folder.Name)
+	}
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	for i := range names {
+		if util.ContainsString(names[:i], names[i]) {
+			tx.Rollback()
+			return serializer.Err(serializer.CodeObjectExist, "Shared objects cannot have the same name", nil)
+		}
+	}
+
+	newShare := service.newShare(user, strings.Join(names, " "))
+	newShare.IsDir = true
+	newShare.IsMulti = true
+	newShare.ObjectsSerialized = model.ShareObjects{Dirs: raw.Dirs, Files: raw.Items}
+
+	return commitShare(c, tx, newShare, raw.Dirs, raw.Items)
+}
+
+// commitShare 在事务中保存新分享，并为引用的对象记录分享操作
+func commitShare(c *gin.Context, tx *gorm.DB, newShare *model.Share, dirs, files []uint) serializer.Response {
 	// 创建分享
 	id, err := newShare.CreateTransaction(tx)
 	if err != nil {
@@ -163,9 +273,12 @@ func (service *ShareCreateService) Create(c *gin.Context) serializer.Response {
 		return serializer.Err(serializer.CodeDBError, "分享链接创建失败", tx.Error)
 	}
 
+	for _, src := range activity.ResolvePaths(newShare.UserID, dirs, files) {
+		activity.Record(activity.ActorFromContext(c), newShare.UserID, model.ActivityShareCreate, src, "/s/"+uid)
+	}
+
 	return serializer.Response{
 		Code: 0,
 		Data: shareURL.String(),
 	}
-
 }
@@ -0,0 +1,184 @@
+package share
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/cloudreve/Cloudreve/v3/service/explorer"
+	"github.com/gin-gonic/gin"
+)
+
+// UploadSessionService 访客在上传分享中创建上传会话的服务，path 为相对于分享目录的路径
+type UploadSessionService struct {
+	Path         string `json:"path" binding:"required"`
+	Size         uint64 `json:"size" binding:"min=0"`
+	Name         string `json:"name" binding:"required"`
+	LastModified int64  `json:"last_modified"`
+}
+
+// UploadService 访客在上传分享中上传文件分片的服务
+type UploadService struct {
+	explorer.UploadService
+}
+
+// updateUploadSession 修改已缓存的上传会话
+func updateUploadSession(key string, update func(session *serializer.UploadSession)) {
+	sessionRaw, ok := cache.Get(filesystem.UploadSessionCachePrefix + key)
+	if !ok {
+		return
+	}
+
+	session := sessionRaw.(serializer.UploadSession)
+	update(&session)
+	cache.Set(
+		filesystem.UploadSessionCachePrefix+key,
+		session,
+		model.GetIntSetting("upload_session_timeout", 86400),
+	)
+}
+
+// Create 创建上传会话，文件使用分享者的存储策略和容量
+func (service *UploadSessionService) Create(c *gin.Context) serializer.Response {
+	share := c.MustGet("share").(*model.Share)
+	if !share.IsDir || !share.UploadEnabled {
+		return serializer.Err(serializer.CodeNoPermissionErr, "This share does not accept uploads", nil)
+	}
+
+	if share.Creator().ReadOnly {
+		return serializer.Err(serializer.CodeOveruseReadOnly, "", nil)
+	}
+
+	if share.UploadMaxSize > 0 && service.Size > share.UploadMaxSize {
+		return serializer.Err(serializer.CodeFileTooLarge, "", nil)
+	}
+
+	if share.UploadExtensions != "" &&
+		!filesystem.IsInExtensionList(strings.Split(share.UploadExtensions, ","), service.Name) {
+		return serializer.Err(serializer.CodeFileTypeNotAllowed, "", nil)
+	}
+
+	// 创建会话时预留累计上传大小，避免并发的会话合计超出限制
+	if !share.ReserveUploadSize(service.Size) {
+		return serializer.Err(serializer.CodeShareUploadCapacityExceeded, "", nil)
+	}
+
+	credential, err := service.createSession(share)
+	if err != nil {
+		share.ReleaseUploadSize(service.Size)
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	// 会话过期后由定时任务释放预留大小
+	if err := share.AddUploadReservation(credential.SessionID, service.Size); err != nil {
+		share.ReleaseUploadSize(service.Size)
+		return serializer.DBErr("Failed to reserve upload capacity", err)
+	}
+
+	// 允许访客经由分享继续上传分片
+	updateUploadSession(credential.SessionID, func(session *serializer.UploadSession) {
+		session.ShareID = share.ID
+	})
+
+	return serializer.Response{
+		Code: 0,
+		Data: credential,
+	}
+}
+
+// createSession 在分享目录下创建上传会话
+func (service *UploadSessionService) createSession(share *model.Share) (*serializer.UploadCredential, error) {
+	fs, err := filesystem.NewFileSystem(share.Creator())
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Recycle()
+
+	folder := *share.SourceFolder()
+	folder.TraceRoot()
+	file := &fsctx.FileStream{
+		Size:        service.Size,
+		Name:        service.Name,
+		VirtualPath: path.Join("/", folder.Position, folder.Name, path.Clean("/"+service.Path)),
+		File:        ioutil.NopCloser(strings.NewReader("")),
+	}
+	if service.LastModified > 0 {
+		lastModified := time.UnixMilli(service.LastModified)
+		file.LastModified = &lastModified
+	}
+	if err := fs.CheckNotLocked(path.Join(file.VirtualPath, service.Name)); err != nil {
+		return nil, err
+	}
+
+	return fs.CreateUploadSession(context.Background(), file)
+}
+
+// Upload 处理访客上传的本机文件分片
+func (service *UploadService) Upload(ctx context.Context, c *gin.Context) serializer.Response {
+	share := c.MustGet("share").(*model.Share)
+
+	uploadSessionRaw, ok := cache.Get(filesystem.UploadSessionCachePrefix + service.ID)
+	if !ok {
+		return serializer.Err(serializer.CodeUploadSessionExpired, "", nil)
+	}
+
+	uploadSession := uploadSessionRaw.(serializer.UploadSession)
+	if uploadSession.ShareID != share.ID || uploadSession.UID != share.UserID {
+		return serializer.Err(serializer.CodeUploadSessionExpired, "", nil)
+	}
+
+	fs, err := filesystem.NewFileSystem(share.Creator())
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	res := service.LocalUploadTo(ctx, c, fs, &uploadSession)
+
+	// 最后一个分片写入后上传会话即被删除
+	if _, ok := cache.Get(filesystem.UploadSessionCachePrefix + service.ID); res.Code == 0 && !ok {
+		UploadReceived(&uploadSession)
+	}
+
+	return res
+}
+
+// UploadReceived 访客经由上传分享完成上传后，将预留大小计入累计上传大小并异步通知分享者。
+// 同一上传会话仅处理一次
+func UploadReceived(session *serializer.UploadSession) {
+	if session.ShareID == 0 {
+		return
+	}
+
+	if _, ok := model.ClaimShareUploadReservation(session.Key); !ok {
+		return
+	}
+
+	var share model.Share
+	if err := model.DB.First(&share, session.ShareID).Error; err != nil {
+		return
+	}
+
+	user := share.Creator()
+	shareURL := model.GetSiteURL().ResolveReference(&url.URL{
+		Path: "/s/" + hashid.HashID(share.ID, hashid.ShareID),
+	})
+
+	title, body := email.NewShareUploadEmail(user.Nick, share.SourceName, session.Name, shareURL.String())
+	go func() {
+		if err := email.Send(user.Email, title, body); err != nil {
+			util.Log().Warning("无法发送上传分享通知邮件, %s", err)
+		}
+	}()
+}
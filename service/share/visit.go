@@ -5,11 +5,15 @@ import (
 	"fmt"
 	"net/http"
 	"path"
+	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/activity"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ratelimit"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/cloudreve/Cloudreve/v3/service/explorer"
@@ -125,16 +129,26 @@ func (service *ShareGetService) Get(c *gin.Context) serializer.Response {
 		sessionKey := fmt.Sprintf("share_unlock_%d", share.ID)
 		unlocked = util.GetSession(c, sessionKey) != nil
 		if !unlocked && service.Password != "" {
-			// 如果未解锁，且指定了密码，则尝试解锁
+			// 如果未解锁，且指定了密码，则尝试解锁，错误次数过多时暂时锁定
+			limiter := ratelimit.New(ratelimit.RuleShare)
+			ip := auth.ConnectingIP(c)
+			keys := []string{ratelimit.IPKey(ip), ratelimit.ShareKey(share.ID, ip)}
+			if wait := limiter.Locked(keys...); wait > 0 {
+				return ratelimit.LockedErr(wait)
+			}
+
 			if service.Password == share.Password {
 				unlocked = true
 				util.SetSession(c, map[string]interface{}{sessionKey: true})
+			} else {
+				limiter.Fail(keys...)
 			}
 		}
 	}
 
 	if unlocked {
 		share.Viewed()
+		recordShareEvent(c, share, model.ShareEventView, "")
 	}
 
 	return serializer.Response{
@@ -157,23 +171,26 @@ func (service *Service) CreateDownloadSession(c *gin.Context) serializer.Respons
 	}
 	defer fs.Recycle()
 
-	// 重设文件系统处理目标为源文件
-	err = fs.SetTargetByInterface(share.Source())
-	if err != nil {
-		return serializer.Err(serializer.CodeFileNotFound, "", err)
-	}
-
 	ctx := context.Background()
-
-	// 重设根目录
-	if share.IsDir {
-		fs.Root = &fs.DirTarget[0]
+	folder, file, sub := share.ResolvePath(service.Path)
+	if file != nil {
+		// 重设文件系统处理目标为源文件
+		err = fs.SetTargetByInterface(file)
+		if err != nil {
+			return serializer.Err(serializer.CodeFileNotFound, "", err)
+		}
+	} else if folder != nil {
+		// 重设根目录
+		root := *folder
+		fs.Root = &root
 
 		// 找到目标文件
-		err = fs.ResetFileIfNotExist(ctx, service.Path)
+		err = fs.ResetFileIfNotExist(ctx, sub)
 		if err != nil {
 			return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 		}
+	} else {
+		return serializer.Err(serializer.CodeFileNotFound, "", nil)
 	}
 
 	// 取得下载地址
@@ -182,12 +199,37 @@ func (service *Service) CreateDownloadSession(c *gin.Context) serializer.Respons
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
 
+	recordShareDownload(c, share, service.Path)
+	recordShareEvent(c, share, model.ShareEventDownload, service.Path)
+
 	return serializer.Response{
 		Code: 0,
 		Data: downloadURL,
 	}
 }
 
+// recordShareDownload 为分享者记录分享下载操作，p 为目录分享下的相对路径
+func recordShareDownload(c *gin.Context, share *model.Share, p string) {
+	var paths []string
+	folder, file, sub := share.ResolvePath(p)
+	if file != nil {
+		paths = activity.ResolvePaths(share.UserID, nil, []uint{file.ID})
+	} else if folder != nil {
+		paths = activity.ResolvePaths(share.UserID, []uint{folder.ID}, nil)
+		for i := range paths {
+			paths[i] = path.Join(paths[i], sub)
+		}
+	} else if sub == "/" {
+		// 多对象分享的虚拟根目录
+		paths = activity.ResolvePaths(share.UserID, share.ObjectsSerialized.Dirs, share.ObjectsSerialized.Files)
+	}
+
+	for _, src := range paths {
+		activity.Record(activity.ActorFromContext(c), share.UserID, model.ActivityShareDownload, src,
+			"/s/"+hashid.HashID(share.ID, hashid.ShareID))
+	}
+}
+
 // PreviewContent 预览文件，需要登录会话, isText - 是否为文本文件，文本文件会
 // 强制经由服务端中转
 func (service *Service) PreviewContent(ctx context.Context, c *gin.Context, isText bool) serializer.Response {
@@ -195,15 +237,32 @@ func (service *Service) PreviewContent(ctx context.Context, c *gin.Context, isTe
 	share := shareCtx.(*model.Share)
 
 	// 用于调下层service
-	if share.IsDir {
-		ctx = context.WithValue(ctx, fsctx.FolderModelCtx, share.Source())
-		ctx = context.WithValue(ctx, fsctx.PathCtx, service.Path)
-	} else {
-		ctx = context.WithValue(ctx, fsctx.FileModelCtx, share.Source())
+	ctx, ok := withShareFile(ctx, share, service.Path)
+	if !ok {
+		return serializer.Err(serializer.CodeFileNotFound, "", nil)
 	}
 	subService := explorer.FileIDService{}
 
-	return subService.PreviewContent(ctx, c, isText)
+	res := subService.PreviewContent(ctx, c, isText)
+	if res.Code == 0 || res.Code == -301 {
+		recordShareEvent(c, share, model.ShareEventPreview, service.Path)
+	}
+
+	return res
+}
+
+// withShareFile 将分享内的文件路径解析为下层 service 使用的上下文，
+// 找不到文件所属的对象时返回 false
+func withShareFile(ctx context.Context, share *model.Share, p string) (context.Context, bool) {
+	folder, file, sub := share.ResolvePath(p)
+	if file != nil {
+		return context.WithValue(ctx, fsctx.FileModelCtx, file), true
+	}
+	if folder != nil {
+		ctx = context.WithValue(ctx, fsctx.FolderModelCtx, folder)
+		return context.WithValue(ctx, fsctx.PathCtx, sub), true
+	}
+	return ctx, false
 }
 
 // CreateDocPreviewSession 创建Office预览会话，返回预览地址
@@ -213,15 +272,18 @@ func (service *Service) CreateDocPreviewSession(c *gin.Context) serializer.Respo
 
 	// 用于调下层service
 	ctx := context.Background()
-	if share.IsDir {
-		ctx = context.WithValue(ctx, fsctx.FolderModelCtx, share.Source())
-		ctx = context.WithValue(ctx, fsctx.PathCtx, service.Path)
-	} else {
-		ctx = context.WithValue(ctx, fsctx.FileModelCtx, share.Source())
+	ctx, ok := withShareFile(ctx, share, service.Path)
+	if !ok {
+		return serializer.Err(serializer.CodeFileNotFound, "", nil)
 	}
 	subService := explorer.FileIDService{}
 
-	return subService.CreateDocPreviewSession(ctx, c)
+	res := subService.CreateDocPreviewSession(ctx, c)
+	if res.Code == 0 {
+		recordShareEvent(c, share, model.ShareEventPreview, service.Path)
+	}
+
+	return res
 }
 
 // List 列出分享的目录下的对象
@@ -248,15 +310,38 @@ func (service *Service) List(c *gin.Context) serializer.Response {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// 重设根目录
-	fs.Root = share.Source().(*model.Folder)
-	fs.Root.Name = "/"
-
 	// 分享Key上下文
 	ctx = context.WithValue(ctx, fsctx.ShareKeyCtx, hashid.HashID(share.ID, hashid.ShareID))
 
+	folder, _, sub := share.ResolvePath(service.Path)
+	if folder == nil {
+		if sub != "/" {
+			return serializer.Err(serializer.CodeParentNotExist, "", nil)
+		}
+
+		// 多对象分享的虚拟根目录下列出所有引用对象
+		folders, files := share.SourceObjects()
+		return serializer.Response{
+			Code: 0,
+			Data: serializer.BuildObjectList(0, fs.ListObjects(ctx, "/", files, folders), nil),
+		}
+	}
+
+	// 多对象分享中对象路径需要加上引用目录的名称
+	var pathProcessor func(string) string
+	if share.IsMulti {
+		name := folder.Name
+		pathProcessor = func(p string) string {
+			return path.Join("/", name, p)
+		}
+	}
+
+	// 重设根目录
+	fs.Root = folder
+	fs.Root.Name = "/"
+
 	// 获取子项目
-	objects, err := fs.List(ctx, service.Path, nil)
+	objects, err := fs.List(ctx, sub, pathProcessor)
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
@@ -283,23 +368,30 @@ func (service *Service) Thumb(c *gin.Context) serializer.Response {
 	}
 	defer fs.Recycle()
 
-	// 重设根目录
-	fs.Root = share.Source().(*model.Folder)
-
-	// 找到缩略图的父目录
-	exist, parent := fs.IsPathExist(service.Path)
-	if !exist {
-		return serializer.Err(serializer.CodeParentNotExist, "", nil)
-	}
-
-	ctx := context.WithValue(context.Background(), fsctx.LimitParentCtx, parent)
-
 	// 获取文件ID
 	fileID, err := hashid.DecodeHashID(c.Param("file"), hashid.FileID)
 	if err != nil {
 		return serializer.Err(serializer.CodeNotFound, "", err)
 	}
 
+	ctx := context.Background()
+	folder, _, sub := share.ResolvePath(service.Path)
+	if folder != nil {
+		// 重设根目录
+		fs.Root = folder
+
+		// 找到缩略图的父目录
+		exist, parent := fs.IsPathExist(sub)
+		if !exist {
+			return serializer.Err(serializer.CodeParentNotExist, "", nil)
+		}
+
+		ctx = context.WithValue(ctx, fsctx.LimitParentCtx, parent)
+	} else if sub != "/" || !share.ContainsObjects(nil, []uint{fileID}) {
+		// 多对象分享的虚拟根目录下只能获取引用文件的缩略图
+		return serializer.Err(serializer.CodeParentNotExist, "", nil)
+	}
+
 	// 获取缩略图
 	resp, err := fs.GetThumb(ctx, uint(fileID))
 	if err != nil {
@@ -342,29 +434,43 @@ func (service *ArchiveService) Archive(c *gin.Context) serializer.Response {
 	}
 	defer fs.Recycle()
 
-	// 重设根目录
-	fs.Root = share.Source().(*model.Folder)
+	subService := explorer.ItemIDService{
+		Dirs:  service.Dirs,
+		Items: service.Items,
+	}
 
-	// 找到要打包文件的父目录
-	exist, parent := fs.IsPathExist(service.Path)
-	if !exist {
+	ctx := context.Background()
+	folder, _, sub := share.ResolvePath(service.Path)
+	if folder != nil {
+		// 重设根目录
+		fs.Root = folder
+
+		// 找到要打包文件的父目录
+		exist, parent := fs.IsPathExist(sub)
+		if !exist {
+			return serializer.Err(serializer.CodeParentNotExist, "", nil)
+		}
+
+		// 限制操作范围为父目录下
+		ctx = context.WithValue(ctx, fsctx.LimitParentCtx, parent)
+	} else if raw := subService.Raw(); sub != "/" || !share.ContainsObjects(raw.Dirs, raw.Items) {
+		// 多对象分享的虚拟根目录下只能打包引用的对象
 		return serializer.Err(serializer.CodeParentNotExist, "", nil)
 	}
 
-	// 限制操作范围为父目录下
-	ctx := context.WithValue(context.Background(), fsctx.LimitParentCtx, parent)
-
 	// 用于调下层service
 	tempUser := share.Creator()
 	tempUser.Group.OptionsSerialized.ArchiveDownload = true
 	c.Set("user", tempUser)
 
-	subService := explorer.ItemIDService{
-		Dirs:  service.Dirs,
-		Items: service.Items,
+	res := subService.Archive(ctx, c)
+	if res.Code == 0 {
+		c.Set("user", user)
+		recordShareDownload(c, share, service.Path)
+		recordShareEvent(c, share, model.ShareEventArchive, service.Path)
 	}
 
-	return subService.Archive(ctx, c)
+	return res
 }
 
 // SearchService 对分享的目录进行搜索
@@ -396,11 +502,44 @@ func (service *SearchService) Search(c *gin.Context) serializer.Response {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// 分享Key上下文
+	ctx = context.WithValue(ctx, fsctx.ShareKeyCtx, hashid.HashID(share.ID, hashid.ShareID))
+
+	folder, _, sub := share.ResolvePath(service.Path)
+	if folder == nil {
+		if !share.IsMulti || path.Clean("/"+service.Path) != "/" {
+			return serializer.Err(serializer.CodeParentNotExist, "", nil)
+		}
+
+		// 多对象分享的虚拟根目录下，在所有引用对象中搜索
+		folders, files := share.SourceObjects()
+		objects := make([]serializer.Object, 0)
+		for i := range folders {
+			fs.Root = &folders[i]
+			res, err := service.search(ctx, fs, share, folders[i].Name)
+			if err != nil {
+				return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+			}
+			objects = append(objects, res...)
+		}
+
+		matched := make([]model.File, 0, len(files))
+		for _, file := range files {
+			if strings.Contains(strings.ToLower(file.Name), strings.ToLower(service.Keywords)) {
+				matched = append(matched, file)
+			}
+		}
+		objects = append(objects, fs.ListObjects(ctx, "/", matched, nil)...)
+
+		return searchResult(objects)
+	}
+
 	// 重设根目录
-	fs.Root = share.Source().(*model.Folder)
+	name := folder.Name
+	fs.Root = folder
 	fs.Root.Name = "/"
-	if service.Path != "" {
-		ok, parent := fs.IsPathExist(service.Path)
+	if sub != "" {
+		ok, parent := fs.IsPathExist(sub)
 		if !ok {
 			return serializer.Err(serializer.CodeParentNotExist, "Cannot find parent folder", nil)
 		}
@@ -408,8 +547,34 @@ func (service *SearchService) Search(c *gin.Context) serializer.Response {
 		fs.Root = parent
 	}
 
-	// 分享Key上下文
-	ctx = context.WithValue(ctx, fsctx.ShareKeyCtx, hashid.HashID(share.ID, hashid.ShareID))
+	objects, err := service.search(ctx, fs, share, name)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return searchResult(objects)
+}
+
+// search 在 fs 的根目录下搜索，多对象分享中对象路径需要加上引用目录的名称 name
+func (service *SearchService) search(ctx context.Context, fs *filesystem.FileSystem, share *model.Share, name string) ([]serializer.Object, error) {
+	objects, err := fs.Search(ctx, "%"+service.Keywords+"%")
+	if err != nil || !share.IsMulti {
+		return objects, err
+	}
 
-	return service.SearchKeywords(c, fs, "%"+service.Keywords+"%")
+	for i := range objects {
+		objects[i].Path = path.Join("/", name, objects[i].Path)
+	}
+	return objects, nil
+}
+
+// searchResult 构建与站内搜索一致的搜索结果
+func searchResult(objects []serializer.Object) serializer.Response {
+	return serializer.Response{
+		Code: 0,
+		Data: map[string]interface{}{
+			"parent":  0,
+			"objects": objects,
+		},
+	}
 }
@@ -0,0 +1,24 @@
+package user
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// ActivityListService 操作记录列表服务
+type ActivityListService struct {
+	Page     int   `form:"page" binding:"required,min=1"`
+	PageSize int   `form:"page_size" binding:"min=0,max=100"`
+	Types    []int `form:"type"`
+}
+
+// List 列出当前用户的操作记录
+func (service *ActivityListService) List(c *gin.Context, user *model.User) serializer.Response {
+	if service.PageSize == 0 {
+		service.PageSize = 20
+	}
+
+	activities, total := model.ListActivities(user.ID, service.Page, service.PageSize, "created_at desc", service.Types)
+	return serializer.BuildActivityList(activities, total)
+}
@@ -0,0 +1,30 @@
+package user
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ldap"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// LDAPLinkService 将目录用户绑定到当前登录用户的服务
+type LDAPLinkService struct {
+	UserName string `json:"userName" binding:"required"`
+	Password string `json:"Password" binding:"required"`
+}
+
+// Link 使用目录密码确认所有权后绑定，绑定后需使用目录密码登录
+func (service *LDAPLinkService) Link(c *gin.Context, user *model.User) serializer.Response {
+	switch err := ldap.Link(user, service.UserName, service.Password); err {
+	case nil:
+		return serializer.Response{}
+	case ldap.ErrNotEnabled:
+		return serializer.Err(serializer.CodeFeatureNotEnabled, "LDAP authentication is not enabled", nil)
+	case ldap.ErrInvalidCredentials, ldap.ErrUserNotFound:
+		return serializer.Err(serializer.CodeCredentialInvalid, "Wrong password or user name", nil)
+	case ldap.ErrLinkedByOthers:
+		return serializer.Err(serializer.CodeLDAPLinkedByOthers, "This directory account is linked to another account", nil)
+	default:
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to link directory account", err)
+	}
+}
@@ -3,9 +3,12 @@ package user
 import (
 	"fmt"
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/email"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ldap"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ratelimit"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
@@ -104,10 +107,17 @@ func (service *Enable2FA) Login(c *gin.Context) serializer.Response {
 			return serializer.Err(serializer.CodeUserNotFound, "User not found", nil)
 		}
 
-		// 验证二步验证代码
+		// 验证二步验证代码，错误次数过多时暂时锁定
+		limiter := ratelimit.New(ratelimit.RuleLogin)
+		keys := []string{ratelimit.IPKey(auth.ConnectingIP(c)), ratelimit.UserKey(uid)}
+		if wait := limiter.Locked(keys...); wait > 0 {
+			return ratelimit.LockedErr(wait)
+		}
 		if !totp.Validate(service.Code, expectedUser.TwoFactor) {
+			limiter.Fail(keys...)
 			return serializer.Err(serializer.Code2FACodeErr, "2FA code not correct", nil)
 		}
+		limiter.Reset(keys[1])
 
 		//登陆成功，清空并设置session
 		util.DeleteSession(c, "2fa_user_id")
@@ -121,16 +131,46 @@ func (service *Enable2FA) Login(c *gin.Context) serializer.Response {
 	return serializer.Err(serializer.CodeLoginSessionNotExist, "Login session not exist", nil)
 }
 
-// Login 用户登录函数
+// Login 用户登录函数，同一 IP 或同一 IP 下的账号连续登录失败过多时暂时锁定
 func (service *UserLoginService) Login(c *gin.Context) serializer.Response {
-	expectedUser, err := model.GetUserByEmail(service.UserName)
-	// 一系列校验
-	if err != nil {
-		return serializer.Err(serializer.CodeCredentialInvalid, "Wrong password or email address", err)
+	var expectedUser model.User
+
+	limiter := ratelimit.New(ratelimit.RuleLogin)
+	keys := []string{ratelimit.IPKey(auth.ConnectingIP(c)), ratelimit.UserIPKey(service.UserName, auth.ConnectingIP(c))}
+	if wait := limiter.Locked(keys...); wait > 0 {
+		return ratelimit.LockedErr(wait)
 	}
-	if authOK, _ := expectedUser.CheckPassword(service.Password); !authOK {
+
+	// 优先使用 LDAP 目录认证，目录中不存在的用户继续使用本地密码
+	directoryUser, err := ldap.Login(service.UserName, service.Password)
+	switch err {
+	case nil:
+		expectedUser = *directoryUser
+	case ldap.ErrInvalidCredentials:
+		limiter.Fail(keys...)
 		return serializer.Err(serializer.CodeCredentialInvalid, "Wrong password or email address", nil)
+	case ldap.ErrNotLinked:
+		return serializer.Err(serializer.CodeLDAPNotLinked, "Directory account is not linked, please sign in with local password and link it in settings", nil)
+	default:
+		if err != ldap.ErrNotEnabled && err != ldap.ErrUserNotFound {
+			util.Log().Warning("LDAP 认证失败, %s", err)
+		}
+
+		expectedUser, err = model.GetUserByEmail(service.UserName)
+		// 一系列校验
+		if err != nil {
+			limiter.Fail(keys...)
+			return serializer.Err(serializer.CodeCredentialInvalid, "Wrong password or email address", err)
+		}
+		if authOK, _ := expectedUser.CheckPassword(service.Password); !authOK || ldap.IsDirectoryUser(&expectedUser) {
+			limiter.Fail(keys...)
+			return serializer.Err(serializer.CodeCredentialInvalid, "Wrong password or email address", nil)
+		}
 	}
+
+	// 密码正确后清除账号的失败计数，IP 计数保留以免被正常账号刷新
+	limiter.Reset(keys[1])
+
 	if expectedUser.Status == model.Baned || expectedUser.Status == model.OveruseBaned {
 		return serializer.Err(serializer.CodeUserBaned, "This account has been blocked", nil)
 	}
@@ -0,0 +1,268 @@
+package user
+
+import (
+	"encoding/json"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/oidc"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCLoginService 发起 OpenID Connect 登录的服务
+type OIDCLoginService struct {
+	// 为 true 时将身份绑定到当前登录的用户，而不是登录
+	Link bool `form:"link"`
+}
+
+// OIDCCallbackService 完成 OpenID Connect 登录的服务
+type OIDCCallbackService struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// OIDCUnlinkService 解除 OpenID Connect 身份绑定的服务
+type OIDCUnlinkService struct {
+	ID uint `uri:"id" binding:"required,min=1"`
+}
+
+// Start 生成授权请求地址，state、nonce 和 PKCE 校验码保存在会话中
+func (service *OIDCLoginService) Start(c *gin.Context, user *model.User) serializer.Response {
+	provider, err := oidc.NewProviderFromSetting()
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "OpenID Connect is not configured", err)
+	}
+
+	state, err := oidc.RandomString(16)
+	if err != nil {
+		return serializer.Err(serializer.CodeEncryptError, "Failed to generate state", err)
+	}
+	nonce, err := oidc.RandomString(16)
+	if err != nil {
+		return serializer.Err(serializer.CodeEncryptError, "Failed to generate nonce", err)
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return serializer.Err(serializer.CodeEncryptError, "Failed to generate code verifier", err)
+	}
+
+	target, err := provider.AuthCodeURL(state, nonce, challenge)
+	if err != nil {
+		return serializer.Err(serializer.CodeOIDCLoginFailed, "Failed to contact identity provider", err)
+	}
+
+	var linkUID uint
+	if service.Link {
+		if user == nil || user.IsAnonymous() {
+			return serializer.CheckLogin()
+		}
+		linkUID = user.ID
+	}
+
+	util.SetSession(c, map[string]interface{}{
+		"oidc_state":        state,
+		"oidc_nonce":        nonce,
+		"oidc_verifier":     verifier,
+		"oidc_link_user_id": linkUID,
+	})
+
+	return serializer.Response{Data: target}
+}
+
+// Callback 校验授权结果，完成登录或绑定
+func (service *OIDCCallbackService) Callback(c *gin.Context, user *model.User) serializer.Response {
+	state, _ := util.GetSession(c, "oidc_state").(string)
+	nonce, _ := util.GetSession(c, "oidc_nonce").(string)
+	verifier, _ := util.GetSession(c, "oidc_verifier").(string)
+	linkUID, _ := util.GetSession(c, "oidc_link_user_id").(uint)
+	for _, key := range []string{"oidc_state", "oidc_nonce", "oidc_verifier", "oidc_link_user_id"} {
+		util.DeleteSession(c, key)
+	}
+
+	if state == "" || state != service.State {
+		return serializer.Err(serializer.CodeLoginSessionNotExist, "Login session not exist", nil)
+	}
+
+	provider, err := oidc.NewProviderFromSetting()
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "OpenID Connect is not configured", err)
+	}
+
+	token, err := provider.Exchange(service.Code, verifier)
+	if err != nil {
+		return serializer.Err(serializer.CodeOIDCLoginFailed, "Failed to exchange authorization code", err)
+	}
+
+	claims, err := provider.VerifyIDToken(token.IDToken, nonce)
+	if err != nil {
+		return serializer.Err(serializer.CodeOIDCLoginFailed, "Invalid ID token", err)
+	}
+
+	if claims.Subject() == "" {
+		return serializer.Err(serializer.CodeOIDCLoginFailed, "Missing subject in ID token", nil)
+	}
+
+	if linkUID != 0 {
+		if user == nil || user.ID != linkUID {
+			return serializer.Err(serializer.CodeLoginSessionNotExist, "Login session not exist", nil)
+		}
+		return linkOpenIDIdentity(provider.Issuer, claims, user)
+	}
+
+	expectedUser, err := userFromOIDCClaims(provider.Issuer, claims)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, "", err)
+	}
+
+	if expectedUser.Status == model.Baned || expectedUser.Status == model.OveruseBaned {
+		return serializer.Err(serializer.CodeUserBaned, "This account has been blocked", nil)
+	}
+	if expectedUser.Status == model.NotActivicated {
+		return serializer.Err(serializer.CodeUserNotActivated, "This account is not activated", nil)
+	}
+
+	if expectedUser.TwoFactor != "" {
+		// 需要二步验证
+		util.SetSession(c, map[string]interface{}{
+			"2fa_user_id": expectedUser.ID,
+		})
+		return serializer.Response{Code: 203}
+	}
+
+	//登陆成功，清空并设置session
+	util.SetSession(c, map[string]interface{}{
+		"user_id": expectedUser.ID,
+	})
+
+	return serializer.BuildUserResponse(*expectedUser)
+}
+
+// Unlink 解除身份绑定
+func (service *OIDCUnlinkService) Unlink(c *gin.Context, user *model.User) serializer.Response {
+	if err := model.DeleteOpenIDIdentityByID(service.ID, user.ID); err != nil {
+		return serializer.DBErr("Failed to unlink identity", err)
+	}
+	return serializer.Response{}
+}
+
+// linkOpenIDIdentity 将身份绑定到已登录的用户
+func linkOpenIDIdentity(issuer string, claims oidc.Claims, user *model.User) serializer.Response {
+	if identity, err := model.GetOpenIDIdentity(issuer, claims.Subject()); err == nil {
+		if identity.UserID != user.ID {
+			return serializer.Err(serializer.CodeOIDCLinkedByOthers, "This identity is linked to another account", nil)
+		}
+		return serializer.Response{}
+	}
+
+	identity := &model.OpenIDIdentity{
+		UserID:  user.ID,
+		Issuer:  issuer,
+		Subject: claims.Subject(),
+		Email:   claims.String(model.GetSettingByName("oidc_email_claim")),
+	}
+	if _, err := identity.Create(); err != nil {
+		return serializer.DBErr("Failed to link identity", err)
+	}
+
+	return serializer.Response{}
+}
+
+// userFromOIDCClaims 查找身份绑定的用户，未绑定时根据设置按邮箱绑定已有用户或创建新用户
+func userFromOIDCClaims(issuer string, claims oidc.Claims) (*model.User, error) {
+	options := model.GetSettingByNames(
+		"oidc_email_claim",
+		"oidc_nick_claim",
+		"oidc_auto_register",
+		"oidc_link_by_email",
+		"oidc_group_claim",
+		"oidc_group_mapping",
+	)
+	group := mappedOIDCGroup(claims, options["oidc_group_claim"], options["oidc_group_mapping"])
+
+	if identity, err := model.GetOpenIDIdentity(issuer, claims.Subject()); err == nil {
+		user, err := model.GetUserByID(identity.UserID)
+		if err != nil {
+			return nil, serializer.NewError(serializer.CodeUserNotFound, "User not found", err)
+		}
+
+		// 同步用户组，初始管理员不受影响
+		if group != 0 && group != user.GroupID && user.ID != 1 {
+			if err := user.Update(map[string]interface{}{"group_id": group}); err != nil {
+				return nil, serializer.NewError(serializer.CodeDBError, "Failed to update user group", err)
+			}
+			user.GroupID = group
+		}
+
+		return &user, nil
+	}
+
+	email := strings.ToLower(claims.String(options["oidc_email_claim"]))
+	if email == "" {
+		return nil, serializer.NewError(serializer.CodeOIDCLoginFailed, "Missing email in ID token", nil)
+	}
+
+	var user model.User
+	if existed, err := model.GetUserByEmail(email); err == nil {
+		// 仅在身份提供方确认邮箱所有权时绑定同邮箱的已有用户
+		if !model.IsTrueVal(options["oidc_link_by_email"]) || !claims.Bool("email_verified") {
+			return nil, serializer.NewError(serializer.CodeOIDCNotLinked, "Identity is not linked to any account", nil)
+		}
+		user = existed
+	} else {
+		if !model.IsTrueVal(options["oidc_auto_register"]) {
+			return nil, serializer.NewError(serializer.CodeOIDCNotLinked, "Identity is not linked to any account", nil)
+		}
+
+		user = model.NewUser()
+		user.Email = email
+		user.Nick = claims.String(options["oidc_nick_claim"])
+		if user.Nick == "" {
+			user.Nick = strings.Split(email, "@")[0]
+		}
+		user.SetPassword(util.RandStringRunes(32))
+		user.Status = model.Active
+		user.GroupID = uint(model.GetIntSetting("oidc_default_group", 2))
+		if group != 0 {
+			user.GroupID = group
+		}
+
+		if err := model.DB.Create(&user).Error; err != nil {
+			return nil, serializer.NewError(serializer.CodeDBError, "Failed to create user", err)
+		}
+	}
+
+	identity := &model.OpenIDIdentity{
+		UserID:  user.ID,
+		Issuer:  issuer,
+		Subject: claims.Subject(),
+		Email:   email,
+	}
+	if _, err := identity.Create(); err != nil {
+		return nil, serializer.NewError(serializer.CodeDBError, "Failed to link identity", err)
+	}
+
+	return &user, nil
+}
+
+// mappedOIDCGroup 根据声明中的用户组属性和映射关系查找用户组，未命中时返回 0
+func mappedOIDCGroup(claims oidc.Claims, claim, mapping string) uint {
+	if claim == "" {
+		return 0
+	}
+
+	groups := make(map[string]uint)
+	if err := json.Unmarshal([]byte(mapping), &groups); err != nil {
+		util.Log().Warning("无法解析 OpenID Connect 用户组映射, %s", err)
+		return 0
+	}
+
+	for _, value := range claims.Strings(claim) {
+		if id, ok := groups[value]; ok {
+			return id
+		}
+	}
+
+	return 0
+}
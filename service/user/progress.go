@@ -0,0 +1,48 @@
+package user
+
+import (
+	"io"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/progress"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// keepAliveInterval 无进度更新时发送心跳的间隔，避免连接被代理断开
+const keepAliveInterval = 30 * time.Second
+
+// ProgressService 实时进度推送服务
+type ProgressService struct {
+}
+
+// Stream 以 Server-Sent Events 推送当前用户的任务及离线下载进度，连接建立时先推送进行中任务的最新进度
+func (service *ProgressService) Stream(c *gin.Context, user *model.User) serializer.Response {
+	events := progress.Default.Subscribe(user.ID)
+	defer progress.Default.Unsubscribe(user.ID, events)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	for _, event := range progress.Default.Snapshot(user.ID) {
+		c.SSEvent("progress", event)
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event := <-events:
+			c.SSEvent("progress", event)
+		case <-keepAlive.C:
+			c.SSEvent("ping", time.Now().Unix())
+		}
+		return true
+	})
+
+	return serializer.Response{}
+}
@@ -0,0 +1,29 @@
+package user
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// RedeemService 兑换码使用服务
+type RedeemService struct {
+	Code string `json:"code" binding:"required,max=64"`
+}
+
+// Redeem 兑换容量包或限时用户组
+func (service *RedeemService) Redeem(c *gin.Context, user *model.User) serializer.Response {
+	redeem, err := model.GetAvailableRedeem(service.Code)
+	if err != nil {
+		return serializer.Err(serializer.CodeInvalidRedeemCode, "", err)
+	}
+
+	if err := redeem.Apply(user); err != nil {
+		if err == model.ErrRedeemUsed {
+			return serializer.Err(serializer.CodeInvalidRedeemCode, "", err)
+		}
+		return serializer.DBErr("Failed to redeem", err)
+	}
+
+	return serializer.Response{}
+}
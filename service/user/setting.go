@@ -11,6 +11,7 @@ import (
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
 	"github.com/pquerna/otp/totp"
@@ -25,6 +26,11 @@ type SettingListService struct {
 	Page int `form:"page" binding:"required,min=1"`
 }
 
+// TaskCancelService 取消任务服务
+type TaskCancelService struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
 // AvatarService 头像服务
 type AvatarService struct {
 	Size string `uri:"size" binding:"required,eq=l|eq=m|eq=s"`
@@ -241,6 +247,20 @@ func (service *SettingListService) ListTasks(c *gin.Context, user *model.User) s
 	return serializer.BuildTaskList(tasks, total)
 }
 
+// Cancel 取消排队中或执行中的任务
+func (service *TaskCancelService) Cancel(c *gin.Context, user *model.User) serializer.Response {
+	ok, err := task.Cancel(service.ID, user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to cancel task", err)
+	}
+
+	if !ok {
+		return serializer.Err(serializer.CodeNotFound, "Task not exist or already finished", nil)
+	}
+
+	return serializer.Response{}
+}
+
 // Settings 获取用户设定
 func (service *SettingService) Settings(c *gin.Context, user *model.User) serializer.Response {
 	return serializer.Response{
@@ -251,6 +271,7 @@ func (service *SettingService) Settings(c *gin.Context, user *model.User) serial
 			"prefer_theme": user.OptionsSerialized.PreferredTheme,
 			"themes":       model.GetSettingByName("themes"),
 			"authn":        serializer.BuildWebAuthnList(user.WebAuthnCredentials()),
+			"oidc":         model.ListOpenIDIdentities(user.ID),
 		},
 	}
 }